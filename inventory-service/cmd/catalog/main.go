@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/catalog"
+)
+
+const usage = `Imports and exports the product catalog of the inventory service.
+
+Usage:
+  catalog import [-url URL] [-format csv|json] [-dry-run] FILE
+  catalog export [-url URL] [-format csv|json] [-o FILE]
+
+The format defaults to the file extension on import and to csv on export.
+The service URL defaults to INVENTORY_SERVICE_URL, else http://localhost:8003.
+`
+
+// Catalog talks to the inventory service API, so the catalog is managed
+// without access to its database
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// apiResponse the envelope of the inventory service responses
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Error   *struct {
+		Details map[string]json.RawMessage `json:"details"`
+	} `json:"error"`
+}
+
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	serviceURL := flags.String("url", defaultServiceURL(), "inventory service URL")
+	formatFlag := flags.String("format", "", "csv or json, by default the file extension")
+	dryRun := flags.Bool("dry-run", false, "only show what the import would change")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("import needs exactly one file")
+	}
+	path := flags.Arg(0)
+
+	if *formatFlag == "" {
+		*formatFlag = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	format, err := catalog.ParseFormat(*formatFlag)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"format": {string(format)}, "dry_run": {strconv.FormatBool(*dryRun)}}
+	response, err := httpClient().Post(*serviceURL+"/api/v1/products/import?"+query.Encode(), format.ContentType(), bytes.NewReader(file))
+	if err != nil {
+		return fmt.Errorf("import request error: %v", err)
+	}
+	defer response.Body.Close()
+
+	var body apiResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return fmt.Errorf("import response error (HTTP %d): %v", response.StatusCode, err)
+	}
+
+	// Rejected imports carry the report in the error details
+	reportData := body.Data
+	if !body.Success && body.Error != nil {
+		reportData = body.Error.Details["report"]
+		if reportData == nil {
+			details, _ := json.Marshal(body.Error.Details)
+			return fmt.Errorf("%s: %s", body.Message, details)
+		}
+	}
+
+	var report catalog.Report
+	if err := json.Unmarshal(reportData, &report); err != nil {
+		return fmt.Errorf("import report error: %v", err)
+	}
+	printReport(os.Stdout, &report)
+
+	if !body.Success {
+		return fmt.Errorf("%s", body.Message)
+	}
+	if report.Summary.Failed > 0 {
+		return fmt.Errorf("%d product(s) failed to import", report.Summary.Failed)
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	serviceURL := flags.String("url", defaultServiceURL(), "inventory service URL")
+	formatFlag := flags.String("format", string(catalog.FormatCSV), "csv or json")
+	output := flags.String("o", "", "file to write, standard output if empty")
+	flags.Parse(args)
+
+	format, err := catalog.ParseFormat(*formatFlag)
+	if err != nil {
+		return err
+	}
+
+	response, err := httpClient().Get(*serviceURL + "/api/v1/products/export?format=" + string(format))
+	if err != nil {
+		return fmt.Errorf("export request error: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("export failed (HTTP %d): %s", response.StatusCode, message)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	_, err = io.Copy(w, response.Body)
+	return err
+}
+
+// printReport shows the diff one record per line, with the changed fields below
+func printReport(w io.Writer, report *catalog.Report) {
+	for _, item := range report.Items {
+		fmt.Fprintf(w, "row %-5d %-10s %s\n", item.Row, item.Action, item.SKU)
+		for _, change := range item.Changes {
+			if change.From == "" {
+				fmt.Fprintf(w, "          %s: %s\n", change.Field, change.To)
+			} else {
+				fmt.Fprintf(w, "          %s: %s -> %s\n", change.Field, change.From, change.To)
+			}
+		}
+		if item.Error != "" {
+			fmt.Fprintf(w, "          error: %s\n", item.Error)
+		}
+	}
+
+	summary := report.Summary
+	state := "applied"
+	if !report.Applied {
+		state = "not applied"
+	}
+	fmt.Fprintf(w, "\n%d created, %d updated, %d unchanged, %d invalid, %d failed (%s)\n",
+		summary.Created, summary.Updated, summary.Unchanged, summary.Invalid, summary.Failed, state)
+}
+
+func defaultServiceURL() string {
+	if value := os.Getenv("INVENTORY_SERVICE_URL"); value != "" {
+		return strings.TrimSuffix(value, "/")
+	}
+	return "http://localhost:8003"
+}
+
+func httpClient() *http.Client {
+	return &http.Client{Timeout: 5 * time.Minute}
+}
@@ -1,74 +1,105 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/allocation"
 	"github.com/distributed-ecommerce-saga/inventory-service/internal/handlers"
 	"github.com/distributed-ecommerce-saga/inventory-service/internal/repository"
 	"github.com/distributed-ecommerce-saga/inventory-service/internal/service"
+	"github.com/distributed-ecommerce-saga/shared-domain/lifecycle"
+	"github.com/distributed-ecommerce-saga/shared-domain/logging"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/tracing"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	log.Println("🚀 Starting Inventory Service...")
+	logging.Init("inventory-service")
+	slog.Info("Starting Inventory Service")
+
+	shutdown := lifecycle.NewManager("Inventory Service", lifecycle.TimeoutFromEnv(30*time.Second))
+
+	shutdownTracing, err := tracing.Init(context.Background(), "inventory-service")
+	if err != nil {
+		logging.Fatal("Tracing init error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageTelemetry, "trace exporter", shutdownTracing)
+
+	metrics.Init("inventory-service")
 
 	db, err := initDatabase()
 	if err != nil {
-		log.Fatalf("Database connection error: %v", err)
+		logging.Fatal("Database connection error", "error", err)
 	}
-	defer db.Close()
+	shutdown.Register(lifecycle.StageDatabase, "postgres", func(ctx context.Context) error {
+		return db.Close()
+	})
 
 	rabbitConfig := messaging.NewRabbitMQConfig()
 	rabbitClient := messaging.NewRabbitMQClient(rabbitConfig)
 
 	if err := rabbitClient.Connect(); err != nil {
-		log.Fatalf("RabbitMQ connection error: %v", err)
+		logging.Fatal("RabbitMQ connection error", "error", err)
 	}
-	defer rabbitClient.Close()
+	shutdown.Register(lifecycle.StageBroker, "rabbitmq", func(ctx context.Context) error {
+		return rabbitClient.Close()
+	})
 
 	publisher := messaging.NewPublisher(rabbitClient)
+	shutdown.Register(lifecycle.StageOutbox, "event publisher", publisher.Flush)
 	consumer := messaging.NewConsumer(rabbitClient, "inventory-service-queue", "inventory-service")
 
+	allocator, err := allocation.New(getEnvOrDefault("ALLOCATION_STRATEGY", allocation.NearestStrategy))
+	if err != nil {
+		logging.Fatal("Allocation strategy error", "error", err)
+	}
+
 	inventoryRepo := repository.NewInventoryRepository(db)
-	inventoryService := service.NewInventoryService(inventoryRepo, publisher)
+	inventoryService := service.NewInventoryService(inventoryRepo, publisher, allocator)
 	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+	reservationSweeper := service.NewReservationSweeper(inventoryService, service.ReservationSweeperConfig{
+		Interval:  getEnvDuration("RESERVATION_SWEEP_INTERVAL", time.Minute),
+		BatchSize: 100,
+	})
+	ledgerVerifier := service.NewLedgerVerifier(inventoryService, getEnvDuration("LEDGER_VERIFY_INTERVAL", time.Hour))
 
 	app := setupFiberApp()
 	setupRoutes(app, inventoryHandler)
 
-	go func() {
-		log.Println("🐰 Starting RabbitMQ event consumption...")
-		if err := inventoryHandler.StartConsuming(consumer); err != nil {
-			log.Printf("RabbitMQ consumption error: %v", err)
-		}
-	}()
+	if err := inventoryHandler.StartConsuming(consumer); err != nil {
+		slog.Error("RabbitMQ consumption error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageConsumers, "rabbitmq consumer", consumer.Stop)
+	reservationSweeper.Start()
+	shutdown.Register(lifecycle.StageConsumers, "reservation sweeper", reservationSweeper.Stop)
+	ledgerVerifier.Start()
+	shutdown.Register(lifecycle.StageConsumers, "stock ledger verifier", ledgerVerifier.Stop)
+	shutdown.Register(lifecycle.StageDrain, "in-flight event handlers", consumer.Drain)
+	shutdown.Register(lifecycle.StageHTTP, "fiber", app.ShutdownWithContext)
 
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+	port := getEnvOrDefault("PORT", "8003")
+	slog.Info("Inventory Service listening", "port", port)
 
-		log.Println("🛑 Shutting down Inventory Service...")
-		if err := app.Shutdown(); err != nil {
-			log.Printf("Shutdown error: %v", err)
+	go func() {
+		if err := app.Listen(":" + port); err != nil {
+			slog.Error("Server startup error", "error", err)
+			shutdown.Trigger()
 		}
 	}()
 
-	port := getEnvOrDefault("PORT", "8003")
-	log.Printf("🌍 Inventory Service running on: http://localhost:%s", port)
-
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatalf("Server startup error: %v", err)
+	if err := shutdown.Wait(); err != nil {
+		logging.Fatal("Shutdown error", "error", err)
 	}
 }
 
@@ -96,7 +127,7 @@ func initDatabase() (*sql.DB, error) {
 		return nil, fmt.Errorf("database ping error: %v", err)
 	}
 
-	log.Printf("✅ Database connection successful: %s", dbName)
+	slog.Info("Database connection successful", "database", dbName)
 	return db, nil
 }
 
@@ -107,9 +138,8 @@ func setupFiberApp() *fiber.App {
 	})
 
 	app.Use(recover.New())
-	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${method} ${path} - ${latency}\n",
-	}))
+	app.Use(tracing.FiberMiddleware("inventory-service"))
+	app.Use(logging.FiberMiddleware())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
@@ -120,9 +150,41 @@ func setupFiberApp() *fiber.App {
 }
 
 func setupRoutes(app *fiber.App, inventoryHandler *handlers.InventoryHandler) {
+	// Prometheus scrape endpoint
+	app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+
 	api := app.Group("/api/v1")
 	api.Get("/health", inventoryHandler.HealthCheck)
 
+	// Product catalog and stock management
+	products := api.Group("/products")
+	products.Get("/", inventoryHandler.ListProducts)
+	products.Post("/", inventoryHandler.CreateProduct)
+	// Before /:id, which would take these for an ID
+	products.Get("/low-stock", inventoryHandler.GetLowStockProducts)
+	products.Post("/import", inventoryHandler.ImportProducts)
+	products.Get("/export", inventoryHandler.ExportProducts)
+	products.Get("/:id", inventoryHandler.GetProduct)
+	products.Put("/:id", inventoryHandler.UpdateProduct)
+	products.Delete("/:id", inventoryHandler.DeleteProduct)
+	products.Get("/:id/stock", inventoryHandler.GetProductStock)
+	products.Post("/:id/adjustments", inventoryHandler.AdjustStock)
+	products.Get("/:id/adjustments", inventoryHandler.GetStockAdjustments)
+	products.Get("/:id/reservations", inventoryHandler.GetProductReservations)
+	products.Get("/:id/backorders", inventoryHandler.GetProductBackorders)
+	products.Get("/:id/warehouses", inventoryHandler.GetProductWarehouseStock)
+	api.Get("/orders/:order_id/reservations", inventoryHandler.GetOrderReservations)
+
+	// Warehouses stock is kept at and shipped from
+	warehouses := api.Group("/warehouses")
+	warehouses.Get("/", inventoryHandler.ListWarehouses)
+	warehouses.Post("/", inventoryHandler.CreateWarehouse)
+	warehouses.Get("/:id", inventoryHandler.GetWarehouse)
+
+	// Stock ledger
+	api.Get("/movements", inventoryHandler.GetMovements)
+	api.Get("/movements/verification", inventoryHandler.VerifyLedger)
+
 	app.Use("*", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"success": false,
@@ -140,7 +202,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 		message = e.Message
 	}
 
-	log.Printf("Error: %v", err)
+	slog.ErrorContext(c.UserContext(), "Request error", "path", c.Path(), "error", err)
 
 	return c.Status(code).JSON(fiber.Map{
 		"success":   false,
@@ -156,3 +218,12 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
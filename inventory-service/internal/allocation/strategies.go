@@ -0,0 +1,145 @@
+package allocation
+
+import (
+	"sort"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// Nearest takes every product from the warehouses closest to the shipping
+// address first
+type Nearest struct{}
+
+func (Nearest) Name() string { return NearestStrategy }
+
+func (Nearest) Allocate(request Request) ([]domain.Allocation, error) {
+	less := closer(request.Address)
+	return request.fill(func(warehouses []*domain.Warehouse, _ map[uuid.UUID]bool) {
+		sort.Slice(warehouses, func(i, j int) bool { return less(warehouses[i], warehouses[j]) })
+	})
+}
+
+// LowestCost takes every product from the warehouse that adds the least
+// shipping cost: one already shipping part of the order adds nothing, any
+// other adds its shipment cost. Costs are only compared in the currency most
+// warehouses price shipping in; warehouses pricing in another currency come
+// after those, by distance.
+type LowestCost struct{}
+
+func (LowestCost) Name() string { return LowestCostStrategy }
+
+func (LowestCost) Allocate(request Request) ([]domain.Allocation, error) {
+	less := closer(request.Address)
+	currency := costCurrency(request.Warehouses)
+	return request.fill(func(warehouses []*domain.Warehouse, used map[uuid.UUID]bool) {
+		// addedCost is false for costs in another currency, which cannot be compared
+		addedCost := func(w *domain.Warehouse) (int64, bool) {
+			if used[w.ID] {
+				return 0, true
+			}
+			return w.ShippingCost.Amount, w.ShippingCost.Currency == currency
+		}
+		sort.Slice(warehouses, func(i, j int) bool {
+			a, b := warehouses[i], warehouses[j]
+			ca, knownA := addedCost(a)
+			cb, knownB := addedCost(b)
+			if knownA != knownB {
+				return knownA
+			}
+			if knownA && ca != cb {
+				return ca < cb
+			}
+			return less(a, b)
+		})
+	})
+}
+
+// costCurrency the currency most warehouses price shipping in, the first by
+// code on a tie
+func costCurrency(warehouses map[uuid.UUID]*domain.Warehouse) types.Currency {
+	counts := map[types.Currency]int{}
+	for _, warehouse := range warehouses {
+		counts[warehouse.ShippingCost.Currency]++
+	}
+
+	var currency types.Currency
+	for c, count := range counts {
+		if count > counts[currency] || (count == counts[currency] && c < currency) {
+			currency = c
+		}
+	}
+	return currency
+}
+
+// FewestSplits ships the order from as few warehouses as possible: it keeps
+// picking the warehouse that covers the most of the remaining items, counting
+// fully covered products first, until every item is allocated. Ties go to the
+// closer warehouse.
+type FewestSplits struct{}
+
+func (FewestSplits) Name() string { return FewestSplitsStrategy }
+
+func (FewestSplits) Allocate(request Request) ([]domain.Allocation, error) {
+	available := request.available()
+	remaining := make(map[uuid.UUID]int, len(request.Items))
+	for productID, quantity := range request.Items {
+		remaining[productID] = quantity
+	}
+	less := closer(request.Address)
+
+	// covers counts the products a warehouse can ship completely and the units
+	// it can ship at all
+	covers := func(warehouseID uuid.UUID) (products, units int) {
+		for productID, needed := range remaining {
+			units += min(needed, available[warehouseID][productID])
+			if needed > 0 && available[warehouseID][productID] >= needed {
+				products++
+			}
+		}
+		return products, units
+	}
+
+	var allocations []domain.Allocation
+	for {
+		var best *domain.Warehouse
+		var bestProducts, bestUnits int
+		for warehouseID := range available {
+			products, units := covers(warehouseID)
+			if units == 0 {
+				continue
+			}
+			warehouse := request.Warehouses[warehouseID]
+			if best == nil || products > bestProducts ||
+				(products == bestProducts && units > bestUnits) ||
+				(products == bestProducts && units == bestUnits && less(warehouse, best)) {
+				best, bestProducts, bestUnits = warehouse, products, units
+			}
+		}
+		if best == nil {
+			break
+		}
+
+		for _, productID := range request.products() {
+			quantity := min(remaining[productID], available[best.ID][productID])
+			if quantity == 0 {
+				continue
+			}
+			allocations = append(allocations, domain.Allocation{
+				WarehouseID: best.ID,
+				ProductID:   productID,
+				Quantity:    quantity,
+			})
+			remaining[productID] -= quantity
+		}
+		delete(available, best.ID)
+	}
+
+	for _, productID := range request.products() {
+		if remaining[productID] > 0 {
+			return nil, request.shortfall(productID)
+		}
+	}
+	return allocations, nil
+}
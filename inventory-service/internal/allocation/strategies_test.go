@@ -0,0 +1,224 @@
+package allocation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+var (
+	productA = uuid.MustParse("aaaaaaaa-0000-0000-0000-000000000000")
+	productB = uuid.MustParse("bbbbbbbb-0000-0000-0000-000000000000")
+
+	customer = &types.ShippingAddress{City: "Izmir", State: "Izmir", ZipCode: "35000", Country: "TR"}
+)
+
+func warehouse(code, city string, cost types.Money) *domain.Warehouse {
+	return &domain.Warehouse{
+		ID:           uuid.NewSHA1(uuid.NameSpaceOID, []byte(code)),
+		Code:         code,
+		Address:      types.ShippingAddress{City: city, Country: "TR"},
+		ShippingCost: cost,
+	}
+}
+
+func try(amount int64) types.Money { return types.NewMoney(amount, types.CurrencyTRY) }
+
+var (
+	// Izmir is closest to the customer, but shipping from Ankara is cheapest
+	izmir    = warehouse("IZMIR", "Izmir", try(3000))
+	istanbul = warehouse("ISTANBUL", "Istanbul", try(2000))
+	ankara   = warehouse("ANKARA", "Ankara", try(1000))
+	// Cheapest in number, but priced in another currency
+	frankfurt = warehouse("FRANKFURT", "Frankfurt", types.NewMoney(500, types.CurrencyEUR))
+)
+
+// requestOf the items and the available stock per warehouse and product
+func requestOf(items map[uuid.UUID]int, stock map[*domain.Warehouse]map[uuid.UUID]int) Request {
+	request := Request{Items: items, Address: customer, Warehouses: map[uuid.UUID]*domain.Warehouse{}}
+	for w, products := range stock {
+		request.Warehouses[w.ID] = w
+		for productID, units := range products {
+			request.Stock = append(request.Stock, domain.WarehouseStock{
+				WarehouseID: w.ID,
+				ProductID:   productID,
+				StockLevels: domain.StockLevels{Stock: units + 1, ReservedStock: 1},
+			})
+		}
+	}
+	return request
+}
+
+func allocate(w *domain.Warehouse, productID uuid.UUID, quantity int) domain.Allocation {
+	return domain.Allocation{WarehouseID: w.ID, ProductID: productID, Quantity: quantity}
+}
+
+func TestStrategies(t *testing.T) {
+	// A is spread over every warehouse, only Istanbul has all of B
+	spread := map[*domain.Warehouse]map[uuid.UUID]int{
+		izmir:    {productA: 2, productB: 1},
+		istanbul: {productA: 5, productB: 4},
+		ankara:   {productA: 3},
+	}
+
+	tests := []struct {
+		name     string
+		strategy Strategy
+		items    map[uuid.UUID]int
+		stock    map[*domain.Warehouse]map[uuid.UUID]int
+		want     []domain.Allocation
+	}{
+		{
+			name:     "nearest takes from the closest warehouse first",
+			strategy: Nearest{},
+			items:    map[uuid.UUID]int{productA: 4},
+			stock:    spread,
+			want:     []domain.Allocation{allocate(izmir, productA, 2), allocate(ankara, productA, 2)},
+		},
+		{
+			name:     "nearest splits a product over warehouses",
+			strategy: Nearest{},
+			items:    map[uuid.UUID]int{productA: 4, productB: 3},
+			stock:    spread,
+			want: []domain.Allocation{
+				allocate(izmir, productA, 2), allocate(ankara, productA, 2),
+				allocate(izmir, productB, 1), allocate(istanbul, productB, 2),
+			},
+		},
+		{
+			name:     "lowest cost takes from the cheapest warehouse first",
+			strategy: LowestCost{},
+			items:    map[uuid.UUID]int{productA: 4},
+			stock:    spread,
+			want:     []domain.Allocation{allocate(ankara, productA, 3), allocate(istanbul, productA, 1)},
+		},
+		{
+			name:     "lowest cost reuses warehouses already shipping",
+			strategy: LowestCost{},
+			items:    map[uuid.UUID]int{productA: 4, productB: 1},
+			stock:    spread,
+			// Istanbul ships A already, so B from there adds nothing
+			want: []domain.Allocation{
+				allocate(ankara, productA, 3), allocate(istanbul, productA, 1),
+				allocate(istanbul, productB, 1),
+			},
+		},
+		{
+			name:     "lowest cost ranks costs in another currency last",
+			strategy: LowestCost{},
+			items:    map[uuid.UUID]int{productA: 9},
+			stock: map[*domain.Warehouse]map[uuid.UUID]int{
+				frankfurt: {productA: 5},
+				izmir:     {productA: 2},
+				istanbul:  {productA: 5},
+				ankara:    {productA: 1},
+			},
+			want: []domain.Allocation{
+				allocate(ankara, productA, 1), allocate(istanbul, productA, 5),
+				allocate(izmir, productA, 2), allocate(frankfurt, productA, 1),
+			},
+		},
+		{
+			name:     "fewest splits ships from one warehouse when it can",
+			strategy: FewestSplits{},
+			items:    map[uuid.UUID]int{productA: 4, productB: 3},
+			stock:    spread,
+			want:     []domain.Allocation{allocate(istanbul, productA, 4), allocate(istanbul, productB, 3)},
+		},
+		{
+			name:     "fewest splits adds the warehouse covering most of the rest",
+			strategy: FewestSplits{},
+			items:    map[uuid.UUID]int{productA: 8, productB: 1},
+			stock:    spread,
+			want: []domain.Allocation{
+				allocate(istanbul, productA, 5), allocate(istanbul, productB, 1),
+				allocate(ankara, productA, 3),
+			},
+		},
+		{
+			name:     "fewest splits breaks ties by distance",
+			strategy: FewestSplits{},
+			items:    map[uuid.UUID]int{productA: 2},
+			stock:    spread,
+			want:     []domain.Allocation{allocate(izmir, productA, 2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.strategy.Allocate(requestOf(tt.items, tt.stock))
+			if err != nil {
+				t.Fatalf("allocate: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrategiesFailWhenShort(t *testing.T) {
+	stock := map[*domain.Warehouse]map[uuid.UUID]int{
+		izmir:  {productA: 2, productB: 1},
+		ankara: {productA: 3},
+	}
+
+	for _, strategy := range []Strategy{Nearest{}, LowestCost{}, FewestSplits{}} {
+		_, err := strategy.Allocate(requestOf(map[uuid.UUID]int{productA: 5, productB: 2}, stock))
+
+		var itemErr *domain.ReservationError
+		if !errors.As(err, &itemErr) || itemErr.ProductID != productB || !errors.Is(err, domain.ErrInsufficientStock) {
+			t.Errorf("%s: got %v, want insufficient stock for product B", strategy.Name(), err)
+		}
+	}
+}
+
+func TestStrategiesIgnoreUnknownWarehouses(t *testing.T) {
+	request := requestOf(map[uuid.UUID]int{productA: 2}, map[*domain.Warehouse]map[uuid.UUID]int{izmir: {productA: 1}})
+	// Stock of a warehouse that was removed from the request
+	request.Stock = append(request.Stock, domain.WarehouseStock{
+		WarehouseID: ankara.ID, ProductID: productA, StockLevels: domain.StockLevels{Stock: 10},
+	})
+
+	for _, strategy := range []Strategy{Nearest{}, LowestCost{}, FewestSplits{}} {
+		if _, err := strategy.Allocate(request); !errors.Is(err, domain.ErrInsufficientStock) {
+			t.Errorf("%s: got %v, want insufficient stock", strategy.Name(), err)
+		}
+	}
+}
+
+func TestCostCurrency(t *testing.T) {
+	tests := []struct {
+		warehouses []*domain.Warehouse
+		want       types.Currency
+	}{
+		{[]*domain.Warehouse{izmir, istanbul, frankfurt}, types.CurrencyTRY},
+		{[]*domain.Warehouse{izmir, frankfurt}, types.CurrencyEUR}, // Tie, first by code
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		byID := map[uuid.UUID]*domain.Warehouse{}
+		for _, w := range tt.warehouses {
+			byID[w.ID] = w
+		}
+		if got := costCurrency(byID); got != tt.want {
+			t.Errorf("costCurrency of %d warehouses = %q, want %q", len(tt.warehouses), got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{NearestStrategy, LowestCostStrategy, FewestSplitsStrategy} {
+		if strategy, err := New(name); err != nil || strategy.Name() != name {
+			t.Errorf("New(%q) = %v, %v", name, strategy, err)
+		}
+	}
+	if _, err := New("cheapest"); !errors.Is(err, ErrUnknownStrategy) {
+		t.Errorf("New(\"cheapest\") error = %v, want ErrUnknownStrategy", err)
+	}
+}
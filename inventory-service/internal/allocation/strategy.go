@@ -0,0 +1,171 @@
+package allocation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+var ErrUnknownStrategy = errors.New("unknown allocation strategy")
+
+// Strategy names, as configured with ALLOCATION_STRATEGY
+const (
+	NearestStrategy      = "nearest"
+	LowestCostStrategy   = "lowest_cost"
+	FewestSplitsStrategy = "fewest_splits"
+)
+
+// Strategy decides which warehouses the items of an order are taken from
+type Strategy interface {
+	Name() string
+	// Allocate splits every requested quantity over warehouses with available
+	// stock, or fails with a *domain.ReservationError for the first product
+	// that cannot be covered
+	Allocate(request Request) ([]domain.Allocation, error)
+}
+
+// Request the items to allocate and the stock they can be allocated from
+type Request struct {
+	Items      map[uuid.UUID]int      // Units needed per product
+	Address    *types.ShippingAddress // Where the order ships to, nil if unknown
+	Warehouses map[uuid.UUID]*domain.Warehouse
+	Stock      []domain.WarehouseStock // Stock of the requested products per warehouse
+}
+
+// New returns the strategy registered under name
+func New(name string) (Strategy, error) {
+	switch name {
+	case NearestStrategy:
+		return Nearest{}, nil
+	case LowestCostStrategy:
+		return LowestCost{}, nil
+	case FewestSplitsStrategy:
+		return FewestSplits{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, name)
+}
+
+// available units per warehouse and product
+func (r Request) available() map[uuid.UUID]map[uuid.UUID]int {
+	available := map[uuid.UUID]map[uuid.UUID]int{}
+	for _, s := range r.Stock {
+		if _, ok := r.Warehouses[s.WarehouseID]; !ok || s.Available() <= 0 {
+			continue
+		}
+		if available[s.WarehouseID] == nil {
+			available[s.WarehouseID] = map[uuid.UUID]int{}
+		}
+		available[s.WarehouseID][s.ProductID] += s.Available()
+	}
+	return available
+}
+
+// Available units of the product the warehouses of the request can ship
+func (r Request) Available(productID uuid.UUID) int {
+	units := 0
+	for _, products := range r.available() {
+		units += products[productID]
+	}
+	return units
+}
+
+// products in ID order, so allocations are deterministic
+func (r Request) products() []uuid.UUID {
+	productIDs := make([]uuid.UUID, 0, len(r.Items))
+	for productID := range r.Items {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Slice(productIDs, func(i, j int) bool {
+		return productIDs[i].String() < productIDs[j].String()
+	})
+	return productIDs
+}
+
+// fill allocates product by product, taking from the warehouses holding the
+// product in the order rank sorts them. used holds the warehouses already
+// picked for earlier products.
+func (r Request) fill(rank func(warehouses []*domain.Warehouse, used map[uuid.UUID]bool)) ([]domain.Allocation, error) {
+	available := r.available()
+	used := map[uuid.UUID]bool{}
+
+	var allocations []domain.Allocation
+	for _, productID := range r.products() {
+		var candidates []*domain.Warehouse
+		for warehouseID, products := range available {
+			if products[productID] > 0 {
+				candidates = append(candidates, r.Warehouses[warehouseID])
+			}
+		}
+		rank(candidates, used)
+
+		needed := r.Items[productID]
+		for _, warehouse := range candidates {
+			if needed == 0 {
+				break
+			}
+			quantity := min(needed, available[warehouse.ID][productID])
+			allocations = append(allocations, domain.Allocation{
+				WarehouseID: warehouse.ID,
+				ProductID:   productID,
+				Quantity:    quantity,
+			})
+			needed -= quantity
+			used[warehouse.ID] = true
+		}
+		if needed > 0 {
+			return nil, r.shortfall(productID)
+		}
+	}
+	return allocations, nil
+}
+
+func (r Request) shortfall(productID uuid.UUID) error {
+	available := 0
+	for _, s := range r.Stock {
+		if s.ProductID == productID && s.Available() > 0 {
+			available += s.Available()
+		}
+	}
+	return &domain.ReservationError{
+		ProductID: productID,
+		Err: fmt.Errorf("%w: available=%d, requested=%d",
+			domain.ErrInsufficientStock, available, r.Items[productID]),
+	}
+}
+
+// distance from the warehouse to the address in rough steps: same zip code,
+// city, state, country, or anywhere else. Addresses carry no coordinates, so
+// this is as near as the data allows.
+func distance(warehouse *domain.Warehouse, to *types.ShippingAddress) int {
+	if to == nil {
+		return 4
+	}
+	from := warehouse.Address
+	if !strings.EqualFold(from.Country, to.Country) {
+		return 4
+	}
+	switch {
+	case to.ZipCode != "" && strings.EqualFold(from.ZipCode, to.ZipCode):
+		return 0
+	case to.City != "" && strings.EqualFold(from.City, to.City):
+		return 1
+	case to.State != "" && strings.EqualFold(from.State, to.State):
+		return 2
+	}
+	return 3
+}
+
+// closer orders warehouses by distance to the address, then by code
+func closer(to *types.ShippingAddress) func(a, b *domain.Warehouse) bool {
+	return func(a, b *domain.Warehouse) bool {
+		if da, db := distance(a, to), distance(b, to); da != db {
+			return da < db
+		}
+		return a.Code < b.Code
+	}
+}
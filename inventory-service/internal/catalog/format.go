@@ -0,0 +1,264 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown catalog format")
+	ErrInvalidFile   = errors.New("invalid catalog file")
+)
+
+// Format of an imported or exported catalog file
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+)
+
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case FormatCSV, FormatJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, value)
+}
+
+// ContentType of files in the format
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv"
+	}
+	return "application/json"
+}
+
+// Columns of a CSV catalog, in export order. sku, name and price are required.
+var Columns = []string{"sku", "name", "price", "currency", "stock", "low_stock_threshold"}
+
+// Record one product of a catalog file. Products are matched by SKU.
+type Record struct {
+	Row   int          `json:"-"` // Line of a CSV file or position in a JSON file, from 1
+	SKU   string       `json:"sku"`
+	Name  string       `json:"name"`
+	Price *types.Money `json:"price"`
+	// Stock the total stock the product should have, nil leaves it unchanged
+	Stock *int `json:"stock,omitempty"`
+	// LowStockThreshold nil leaves it unchanged
+	LowStockThreshold *int `json:"low_stock_threshold,omitempty"`
+}
+
+// RowError a record that could not be read or applied
+type RowError struct {
+	Row     int    `json:"row"`
+	SKU     string `json:"sku,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// Decode reads the records of a catalog file. A file that cannot be read at
+// all fails with ErrInvalidFile; records with bad values are returned as row
+// errors, so every problem of the file is reported at once.
+func Decode(format Format, r io.Reader) ([]Record, []RowError, error) {
+	switch format {
+	case FormatCSV:
+		return decodeCSV(r)
+	case FormatJSON:
+		return decodeJSON(r)
+	}
+	return nil, nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// Encode writes the records as a catalog file that Decode reads back
+func Encode(format Format, w io.Writer, records []Record) error {
+	switch format {
+	case FormatCSV:
+		return encodeCSV(w, records)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+func decodeCSV(r io.Reader) ([]Record, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w: empty file", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	index := map[string]int{}
+	for i, column := range header {
+		// Spreadsheet exports may start with a byte order mark
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if !knownColumn(column) {
+			return nil, nil, fmt.Errorf("%w: unknown column %q", ErrInvalidFile, column)
+		}
+		if _, ok := index[column]; ok {
+			return nil, nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidFile, column)
+		}
+		index[column] = i
+	}
+	for _, column := range []string{"sku", "name", "price"} {
+		if _, ok := index[column]; !ok {
+			return nil, nil, fmt.Errorf("%w: missing column %q", ErrInvalidFile, column)
+		}
+	}
+
+	var records []Record
+	var rowErrors []RowError
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		value := func(column string) string {
+			if i, ok := index[column]; ok {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		record := Record{Row: line, SKU: value("sku"), Name: value("name")}
+		var problems []string
+
+		currency := types.DefaultCurrency
+		if code := value("currency"); code != "" {
+			if currency, err = types.ParseCurrency(code); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		if amount := value("price"); amount != "" {
+			if price, err := types.ParseMoney(amount, currency); err != nil {
+				problems = append(problems, fmt.Sprintf("price: %v", err))
+			} else {
+				record.Price = &price
+			}
+		}
+		if record.Stock, err = optionalInt(value("stock")); err != nil {
+			problems = append(problems, fmt.Sprintf("stock: %v", err))
+		}
+		if record.LowStockThreshold, err = optionalInt(value("low_stock_threshold")); err != nil {
+			problems = append(problems, fmt.Sprintf("low_stock_threshold: %v", err))
+		}
+
+		if len(problems) > 0 {
+			rowErrors = append(rowErrors, RowError{Row: line, SKU: record.SKU, Message: strings.Join(problems, "; ")})
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, rowErrors, nil
+}
+
+func decodeJSON(r io.Reader) ([]Record, []RowError, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, nil, fmt.Errorf("%w: expected an array of products: %v", ErrInvalidFile, err)
+	}
+
+	var records []Record
+	var rowErrors []RowError
+	for i, data := range raw {
+		var record Record
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			rowErrors = append(rowErrors, RowError{Row: i + 1, SKU: record.SKU, Message: err.Error()})
+			continue
+		}
+
+		record.Row = i + 1
+		record.SKU = strings.TrimSpace(record.SKU)
+		record.Name = strings.TrimSpace(record.Name)
+		if record.Price != nil {
+			price, err := record.Price.InCurrency(types.DefaultCurrency)
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Row: i + 1, SKU: record.SKU, Message: fmt.Sprintf("price: %v", err)})
+				continue
+			}
+			record.Price = &price
+		}
+		records = append(records, record)
+	}
+
+	return records, rowErrors, nil
+}
+
+func encodeCSV(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(Columns); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		price, currency := "", ""
+		if record.Price != nil {
+			price, currency = record.Price.Decimal(), string(record.Price.Currency)
+		}
+		if err := writer.Write([]string{
+			record.SKU,
+			record.Name,
+			price,
+			currency,
+			formatOptionalInt(record.Stock),
+			formatOptionalInt(record.LowStockThreshold),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func knownColumn(column string) bool {
+	for _, known := range Columns {
+		if column == known {
+			return true
+		}
+	}
+	return false
+}
+
+func optionalInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a whole number", value)
+	}
+	return &parsed, nil
+}
+
+func formatOptionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
@@ -0,0 +1,193 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+func money(amount int64, currency types.Currency) *types.Money {
+	price := types.NewMoney(amount, currency)
+	return &price
+}
+
+func intPtr(value int) *int { return &value }
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	records := []Record{
+		{SKU: "LAPTOP-1", Name: "Laptop, 14\"", Price: money(129999, types.CurrencyUSD), Stock: intPtr(10), LowStockThreshold: intPtr(2)},
+		{SKU: "MUG-1", Name: "Mug", Price: money(1500, types.CurrencyJPY), Stock: intPtr(0), LowStockThreshold: intPtr(0)},
+		{SKU: "CABLE-1", Name: "Cable", Price: money(-5, types.CurrencyEUR)},
+	}
+
+	for _, format := range []Format{FormatCSV, FormatJSON} {
+		var file bytes.Buffer
+		if err := Encode(format, &file, records); err != nil {
+			t.Fatalf("%s: encode: %v", format, err)
+		}
+
+		decoded, rowErrors, err := Decode(format, &file)
+		if err != nil || len(rowErrors) > 0 {
+			t.Fatalf("%s: decode: %v %+v", format, err, rowErrors)
+		}
+		for i := range decoded {
+			// Rows are where the record was read, CSV rows start after the header
+			wantRow := i + 1
+			if format == FormatCSV {
+				wantRow = i + 2
+			}
+			if decoded[i].Row != wantRow {
+				t.Errorf("%s: record %d read from row %d, want %d", format, i, decoded[i].Row, wantRow)
+			}
+			decoded[i].Row = 0
+		}
+		if !reflect.DeepEqual(decoded, records) {
+			t.Errorf("%s: decoded %+v, want %+v", format, decoded, records)
+		}
+	}
+}
+
+func TestDecodeCSV(t *testing.T) {
+	file := "\ufeffSKU, Name, Price, Currency, Stock\n" +
+		"A-1, Apple, 1.50, , 5\n" +
+		"B-1, Banana, 200, jpy,\n"
+
+	records, rowErrors, err := Decode(FormatCSV, strings.NewReader(file))
+	if err != nil || len(rowErrors) > 0 {
+		t.Fatalf("decode: %v %+v", err, rowErrors)
+	}
+
+	want := []Record{
+		// No currency is the default currency
+		{Row: 2, SKU: "A-1", Name: "Apple", Price: money(150, types.DefaultCurrency), Stock: intPtr(5)},
+		{Row: 3, SKU: "B-1", Name: "Banana", Price: money(200, types.CurrencyJPY)},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %+v, want %+v", records, want)
+	}
+}
+
+func TestDecodeCSVRowErrors(t *testing.T) {
+	file := "sku,name,price,currency,stock,low_stock_threshold\n" +
+		"OK-1,Fine,1.00,USD,1,0\n" +
+		"BAD-PRICE,Bad price,1.x,USD,,\n" +
+		"BAD-CENTS,Bad cents,1.50,JPY,,\n" +
+		"BAD-CURRENCY,Bad currency,1.00,DOLLARS,,\n" +
+		"BAD-STOCK,Bad stock,1.00,USD,many,-\n" +
+		"NO-PRICE,No price,,,,\n"
+
+	records, rowErrors, err := Decode(FormatCSV, strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	// Rows without a price are read, Plan reports them
+	if len(records) != 2 || records[0].SKU != "OK-1" || records[1].SKU != "NO-PRICE" || records[1].Price != nil {
+		t.Errorf("records = %+v, want OK-1 and NO-PRICE", records)
+	}
+
+	want := []struct {
+		row      int
+		sku      string
+		contains []string
+	}{
+		{3, "BAD-PRICE", []string{"price:", "invalid amount"}},
+		{4, "BAD-CENTS", []string{"price:", "invalid amount"}},
+		{5, "BAD-CURRENCY", []string{"invalid currency"}},
+		{6, "BAD-STOCK", []string{"stock:", "low_stock_threshold:"}},
+	}
+	if len(rowErrors) != len(want) {
+		t.Fatalf("row errors = %+v, want %d", rowErrors, len(want))
+	}
+	for i, w := range want {
+		got := rowErrors[i]
+		if got.Row != w.row || got.SKU != w.sku {
+			t.Errorf("row error %d = %+v, want row %d sku %s", i, got, w.row, w.sku)
+		}
+		for _, part := range w.contains {
+			if !strings.Contains(got.Message, part) {
+				t.Errorf("row %d message %q does not mention %q", got.Row, got.Message, part)
+			}
+		}
+	}
+}
+
+func TestDecodeInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		file    string
+		wantErr error
+	}{
+		{"empty csv", FormatCSV, "", ErrInvalidFile},
+		{"unknown column", FormatCSV, "sku,name,price,color\n", ErrInvalidFile},
+		{"duplicate column", FormatCSV, "sku,name,price,Price\n", ErrInvalidFile},
+		{"missing price column", FormatCSV, "sku,name\nA-1,Apple\n", ErrInvalidFile},
+		{"missing sku column", FormatCSV, "name,price\nApple,1.00\n", ErrInvalidFile},
+		{"ragged row", FormatCSV, "sku,name,price\nA-1,Apple\n", ErrInvalidFile},
+		{"empty json", FormatJSON, "", ErrInvalidFile},
+		{"json object", FormatJSON, `{"sku": "A-1"}`, ErrInvalidFile},
+		{"broken json", FormatJSON, `[{"sku": "A-1"`, ErrInvalidFile},
+		{"unknown format", Format("xml"), "<catalog/>", ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, rowErrors, err := Decode(tt.format, strings.NewReader(tt.file))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+			if records != nil || rowErrors != nil {
+				t.Errorf("got records %+v and row errors %+v, want none", records, rowErrors)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	file := `[
+		{"sku": " A-1 ", "name": "Apple", "price": "1.50", "stock": 5},
+		{"sku": "B-1", "name": "Banana", "price": {"amount": "200", "currency": "JPY"}},
+		{"sku": "C-1", "name": "Cherry", "price": "1.x"},
+		{"sku": "D-1", "name": "Date", "price": {"amount": "1.50", "currency": "JPY"}},
+		{"sku": "E-1", "name": "Elderberry", "price": "1.00", "color": "black"},
+		{"sku": "F-1", "name": "Fig", "stock": "many"}
+	]`
+
+	records, rowErrors, err := Decode(FormatJSON, strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := []Record{
+		// A bare price is in the default currency
+		{Row: 1, SKU: "A-1", Name: "Apple", Price: money(150, types.DefaultCurrency), Stock: intPtr(5)},
+		{Row: 2, SKU: "B-1", Name: "Banana", Price: money(200, types.CurrencyJPY)},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %+v, want %+v", records, want)
+	}
+
+	var rows []int
+	for _, rowErr := range rowErrors {
+		rows = append(rows, rowErr.Row)
+	}
+	if !reflect.DeepEqual(rows, []int{3, 4, 5, 6}) {
+		t.Errorf("row errors = %+v, want rows 3 to 6", rowErrors)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for value, want := range map[string]Format{"csv": FormatCSV, " JSON ": FormatJSON} {
+		if format, err := ParseFormat(value); err != nil || format != want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q", value, format, err, want)
+		}
+	}
+	if _, err := ParseFormat("xlsx"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("ParseFormat(\"xlsx\") error = %v, want ErrUnknownFormat", err)
+	}
+}
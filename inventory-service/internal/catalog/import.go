@@ -0,0 +1,217 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Action what an import does with one record
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+	ActionInvalid   Action = "invalid" // Fails validation, nothing of the file is imported
+	ActionFailed    Action = "failed"  // Valid, but storing it failed
+)
+
+// Change of one product field, formatted for reading
+type Change struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to"`
+}
+
+// Item the planned, and once applied the actual, outcome of one record
+type Item struct {
+	Row       int       `json:"row"`
+	SKU       string    `json:"sku"`
+	Action    Action    `json:"action"`
+	ProductID uuid.UUID `json:"product_id,omitempty"`
+	Changes   []Change  `json:"changes,omitempty"`
+	Error     string    `json:"error,omitempty"`
+
+	// What applying the item stores
+	Create     *domain.CreateProductRequest `json:"-"`
+	Update     *domain.UpdateProductRequest `json:"-"` // Catalog fields, nil if unchanged
+	StockDelta int                          `json:"-"`
+}
+
+type Summary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Invalid   int `json:"invalid"`
+	Failed    int `json:"failed"`
+}
+
+// Report the diff of a catalog import against the stored products. Nothing is
+// applied for a dry run or when any record is invalid.
+type Report struct {
+	Format  Format  `json:"format"`
+	DryRun  bool    `json:"dry_run"`
+	Applied bool    `json:"applied"`
+	Summary Summary `json:"summary"`
+	Items   []Item  `json:"items"`
+}
+
+// Plan compares the records with the existing products, keyed by SKU. New SKUs
+// are created and known ones updated; SKUs of deleted products are not reused.
+// Records are validated as if created or updated through the API, and rows
+// that could not be read are reported as invalid.
+func Plan(records []Record, rowErrors []RowError, existing map[string]*domain.InventoryAggregate, deletedSKUs map[string]bool) *Report {
+	report := &Report{Items: []Item{}}
+	for _, rowErr := range rowErrors {
+		report.Items = append(report.Items, Item{Row: rowErr.Row, SKU: rowErr.SKU, Action: ActionInvalid, Error: rowErr.Message})
+	}
+
+	firstRow := map[string]int{}
+	for _, record := range records {
+		item := Item{Row: record.Row, SKU: record.SKU}
+
+		if row, ok := firstRow[record.SKU]; ok && record.SKU != "" {
+			item.invalid(fmt.Sprintf("sku %s already appears in row %d", record.SKU, row))
+		} else if deletedSKUs[record.SKU] {
+			item.invalid(fmt.Sprintf("sku %s belongs to a deleted product", record.SKU))
+		} else if record.Price == nil {
+			item.invalid("price is required")
+		} else if product, ok := existing[record.SKU]; ok {
+			item.planUpdate(record, product)
+		} else {
+			item.planCreate(record)
+		}
+
+		firstRow[record.SKU] = record.Row
+		report.Items = append(report.Items, item)
+	}
+
+	sort.SliceStable(report.Items, func(i, j int) bool { return report.Items[i].Row < report.Items[j].Row })
+	report.Summarize()
+	return report
+}
+
+// Summarize counts the items per action
+func (r *Report) Summarize() {
+	r.Summary = Summary{}
+	for _, item := range r.Items {
+		switch item.Action {
+		case ActionCreate:
+			r.Summary.Created++
+		case ActionUpdate:
+			r.Summary.Updated++
+		case ActionUnchanged:
+			r.Summary.Unchanged++
+		case ActionInvalid:
+			r.Summary.Invalid++
+		case ActionFailed:
+			r.Summary.Failed++
+		}
+	}
+}
+
+// Fail records that applying the item failed
+func (i *Item) Fail(err error) {
+	i.Action = ActionFailed
+	i.Error = err.Error()
+}
+
+func (i *Item) invalid(message string) {
+	i.Action = ActionInvalid
+	i.Error = message
+}
+
+func (i *Item) planCreate(record Record) {
+	request := domain.CreateProductRequest{
+		Name:  record.Name,
+		SKU:   record.SKU,
+		Price: *record.Price,
+	}
+	if record.Stock != nil {
+		request.Stock = *record.Stock
+	}
+	if record.LowStockThreshold != nil {
+		request.LowStockThreshold = *record.LowStockThreshold
+	}
+
+	product, err := domain.NewProduct(request)
+	if err != nil {
+		i.invalid(err.Error())
+		return
+	}
+
+	i.Action = ActionCreate
+	i.Create = &request
+	i.Changes = []Change{
+		{Field: "name", To: product.Name},
+		{Field: "price", To: product.Price.String()},
+		{Field: "stock", To: strconv.Itoa(product.Stock)},
+		{Field: "low_stock_threshold", To: strconv.Itoa(product.LowStockThreshold)},
+	}
+}
+
+func (i *Item) planUpdate(record Record, product *domain.InventoryAggregate) {
+	i.ProductID = product.ID
+
+	request := domain.UpdateProductRequest{
+		Name:              &record.Name,
+		Price:             record.Price,
+		LowStockThreshold: record.LowStockThreshold,
+	}
+	// Validated on a copy, the stored product is only changed when applied
+	updated := *product
+	productCopy := *product.Product
+	updated.Product = &productCopy
+	if err := updated.Update(request); err != nil {
+		i.invalid(err.Error())
+		return
+	}
+
+	if updated.Name != product.Name {
+		i.Changes = append(i.Changes, Change{Field: "name", From: product.Name, To: updated.Name})
+	}
+	if updated.Price != product.Price {
+		i.Changes = append(i.Changes, Change{Field: "price", From: product.Price.String(), To: updated.Price.String()})
+	}
+	if updated.LowStockThreshold != product.LowStockThreshold {
+		i.Changes = append(i.Changes, Change{Field: "low_stock_threshold",
+			From: strconv.Itoa(product.LowStockThreshold), To: strconv.Itoa(updated.LowStockThreshold)})
+	}
+	if len(i.Changes) > 0 {
+		i.Update = &request
+	}
+
+	if record.Stock != nil && *record.Stock != product.Stock {
+		if *record.Stock < product.ReservedStock {
+			i.Changes = nil
+			i.Update = nil
+			i.invalid(fmt.Sprintf("%v: stock %d, reserved %d", domain.ErrStockBelowReserved, *record.Stock, product.ReservedStock))
+			return
+		}
+		i.StockDelta = *record.Stock - product.Stock
+		i.Changes = append(i.Changes, Change{Field: "stock", From: strconv.Itoa(product.Stock), To: strconv.Itoa(*record.Stock)})
+	}
+
+	i.Action = ActionUnchanged
+	if len(i.Changes) > 0 {
+		i.Action = ActionUpdate
+	}
+}
+
+// RecordFromProduct the record exporting the product, which imports it unchanged
+func RecordFromProduct(product *domain.InventoryAggregate) Record {
+	price := product.Price
+	stock := product.Stock
+	threshold := product.LowStockThreshold
+	return Record{
+		SKU:               product.SKU,
+		Name:              product.Name,
+		Price:             &price,
+		Stock:             &stock,
+		LowStockThreshold: &threshold,
+	}
+}
@@ -0,0 +1,196 @@
+package catalog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// storedProduct 10 in stock of which 3 reserved, at 5.00
+func storedProduct(sku string) *domain.InventoryAggregate {
+	return &domain.InventoryAggregate{
+		Product: &types.Product{
+			ID:            uuid.NewSHA1(uuid.NameSpaceOID, []byte(sku)),
+			Name:          "Stored " + sku,
+			SKU:           sku,
+			Price:         types.NewMoney(500, types.CurrencyUSD),
+			Stock:         10,
+			ReservedStock: 3,
+		},
+		LowStockThreshold: 2,
+	}
+}
+
+func actions(report *Report) []Action {
+	var got []Action
+	for _, item := range report.Items {
+		got = append(got, item.Action)
+	}
+	return got
+}
+
+func TestPlan(t *testing.T) {
+	stored := storedProduct("OLD-1")
+	existing := map[string]*domain.InventoryAggregate{"OLD-1": stored}
+	deleted := map[string]bool{"GONE-1": true}
+
+	records := []Record{
+		{Row: 2, SKU: "NEW-1", Name: "New", Price: money(100, types.CurrencyUSD), Stock: intPtr(4)},
+		{Row: 3, SKU: "OLD-1", Name: "Stored OLD-1", Price: money(500, types.CurrencyUSD)},
+		{Row: 5, SKU: "NEW-1", Name: "New again", Price: money(100, types.CurrencyUSD)},
+		{Row: 6, SKU: "GONE-1", Name: "Gone", Price: money(100, types.CurrencyUSD)},
+		{Row: 7, SKU: "NO-PRICE", Name: "No price"},
+		{Row: 8, SKU: "NO-NAME", Price: money(100, types.CurrencyUSD)},
+	}
+	rowErrors := []RowError{{Row: 4, SKU: "BAD-1", Message: "price: invalid amount"}}
+
+	report := Plan(records, rowErrors, existing, deleted)
+
+	want := []struct {
+		row    int
+		action Action
+		error  string
+	}{
+		{2, ActionCreate, ""},
+		{3, ActionUnchanged, ""},
+		{4, ActionInvalid, "price: invalid amount"},
+		{5, ActionInvalid, "already appears in row 2"},
+		{6, ActionInvalid, "deleted product"},
+		{7, ActionInvalid, "price is required"},
+		{8, ActionInvalid, "name is required"},
+	}
+	if len(report.Items) != len(want) {
+		t.Fatalf("items = %+v, want %d", report.Items, len(want))
+	}
+	for i, w := range want {
+		item := report.Items[i]
+		if item.Row != w.row || item.Action != w.action || !strings.Contains(item.Error, w.error) || (w.error == "") != (item.Error == "") {
+			t.Errorf("item %d = row %d %s %q, want row %d %s %q", i, item.Row, item.Action, item.Error, w.row, w.action, w.error)
+		}
+	}
+
+	if want := (Summary{Created: 1, Unchanged: 1, Invalid: 5}); report.Summary != want {
+		t.Errorf("summary = %+v, want %+v", report.Summary, want)
+	}
+
+	create := report.Items[0]
+	if create.Create == nil || create.Create.SKU != "NEW-1" || create.Create.Stock != 4 {
+		t.Errorf("create = %+v, want NEW-1 with 4 in stock", create.Create)
+	}
+	if unchanged := report.Items[1]; unchanged.ProductID != stored.ID || unchanged.Update != nil || unchanged.StockDelta != 0 {
+		t.Errorf("unchanged item = %+v, want nothing to store for %s", unchanged, stored.ID)
+	}
+}
+
+func TestPlanUpdateDiffs(t *testing.T) {
+	tests := []struct {
+		name       string
+		record     Record
+		action     Action
+		changes    []Change
+		stockDelta int
+		update     bool
+	}{
+		{
+			name:   "same values",
+			record: Record{Name: "Stored OLD-1", Price: money(500, types.CurrencyUSD), Stock: intPtr(10), LowStockThreshold: intPtr(2)},
+			action: ActionUnchanged,
+		},
+		{
+			name:   "name and price",
+			record: Record{Name: "Renamed", Price: money(650, types.CurrencyUSD)},
+			action: ActionUpdate,
+			changes: []Change{
+				{Field: "name", From: "Stored OLD-1", To: "Renamed"},
+				{Field: "price", From: "5.00 USD", To: "6.50 USD"},
+			},
+			update: true,
+		},
+		{
+			name:       "stock only",
+			record:     Record{Name: "Stored OLD-1", Price: money(500, types.CurrencyUSD), Stock: intPtr(4)},
+			action:     ActionUpdate,
+			changes:    []Change{{Field: "stock", From: "10", To: "4"}},
+			stockDelta: -6,
+		},
+		{
+			name:   "threshold and stock",
+			record: Record{Name: "Stored OLD-1", Price: money(500, types.CurrencyUSD), Stock: intPtr(15), LowStockThreshold: intPtr(5)},
+			action: ActionUpdate,
+			changes: []Change{
+				{Field: "low_stock_threshold", From: "2", To: "5"},
+				{Field: "stock", From: "10", To: "15"},
+			},
+			stockDelta: 5,
+			update:     true,
+		},
+		{
+			name:   "stock below reserved",
+			record: Record{Name: "Renamed", Price: money(500, types.CurrencyUSD), Stock: intPtr(2)},
+			action: ActionInvalid,
+		},
+		{
+			name:   "negative price",
+			record: Record{Name: "Stored OLD-1", Price: money(-1, types.CurrencyUSD)},
+			action: ActionInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := storedProduct("OLD-1")
+			tt.record.Row, tt.record.SKU = 2, "OLD-1"
+
+			report := Plan([]Record{tt.record}, nil, map[string]*domain.InventoryAggregate{"OLD-1": stored}, nil)
+			item := report.Items[0]
+
+			if item.Action != tt.action {
+				t.Fatalf("action = %s (%s), want %s", item.Action, item.Error, tt.action)
+			}
+			if !reflect.DeepEqual(item.Changes, tt.changes) {
+				t.Errorf("changes = %+v, want %+v", item.Changes, tt.changes)
+			}
+			if item.StockDelta != tt.stockDelta || (item.Update != nil) != tt.update {
+				t.Errorf("stock delta %d, update %+v, want %d and update %v", item.StockDelta, item.Update, tt.stockDelta, tt.update)
+			}
+			// Planning never changes the stored product
+			if !reflect.DeepEqual(stored, storedProduct("OLD-1")) {
+				t.Errorf("stored product changed to %+v", stored.Product)
+			}
+		})
+	}
+}
+
+func TestExportedProductImportsUnchanged(t *testing.T) {
+	stored := storedProduct("OLD-1")
+
+	for _, format := range []Format{FormatCSV, FormatJSON} {
+		var file strings.Builder
+		if err := Encode(format, &file, []Record{RecordFromProduct(stored)}); err != nil {
+			t.Fatalf("%s: encode: %v", format, err)
+		}
+		records, rowErrors, err := Decode(format, strings.NewReader(file.String()))
+		if err != nil {
+			t.Fatalf("%s: decode: %v", format, err)
+		}
+
+		report := Plan(records, rowErrors, map[string]*domain.InventoryAggregate{"OLD-1": stored}, nil)
+		if got := actions(report); !reflect.DeepEqual(got, []Action{ActionUnchanged}) {
+			t.Errorf("%s: actions = %v (%+v), want unchanged", format, got, report.Items)
+		}
+	}
+}
+
+func TestItemFail(t *testing.T) {
+	report := Plan([]Record{{Row: 2, SKU: "NEW-1", Name: "New", Price: money(100, types.CurrencyUSD)}}, nil, nil, nil)
+	report.Items[0].Fail(domain.ErrInvalidProduct)
+	report.Summarize()
+
+	if want := (Summary{Failed: 1}); report.Summary != want || report.Items[0].Error != domain.ErrInvalidProduct.Error() {
+		t.Errorf("summary = %+v, item %+v, want one failed", report.Summary, report.Items[0])
+	}
+}
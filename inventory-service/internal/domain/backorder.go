@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// BackorderAggregate units an allow_backorder order could not reserve. Once
+// enough stock arrives it is handed to a follow-up saga, whose ID is stored in
+// the embedded SagaID.
+type BackorderAggregate struct {
+	*types.Backorder
+	OrderSagaID   uuid.UUID `json:"order_saga_id" db:"order_saga_id"`   // Checkout saga that backordered the units
+	CorrelationID uuid.UUID `json:"correlation_id" db:"correlation_id"` // Of the checkout saga
+}
+
+func NewBackorderAggregate(orderID, orderSagaID, correlationID, productID uuid.UUID, quantity int) *BackorderAggregate {
+	now := time.Now()
+	return &BackorderAggregate{
+		Backorder: &types.Backorder{
+			ID:        uuid.New(),
+			OrderID:   orderID,
+			ProductID: productID,
+			Quantity:  quantity,
+			Status:    types.BackorderStatusWaiting,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		OrderSagaID:   orderSagaID,
+		CorrelationID: correlationID,
+	}
+}
+
+// ReservationResult what a reservation request reserved. Items and Backorders
+// are only filled for policies that accept less than every item.
+type ReservationResult struct {
+	Reservations []*ReservationAggregate
+	Items        []types.ItemOutcome
+	Backorders   []*BackorderAggregate
+}
+
+// BackorderFilter pages the backorders of a product
+type BackorderFilter struct {
+	Status types.BackorderStatus // Empty for all
+	Page   int
+	Limit  int
+}
+
+func (f *BackorderFilter) Normalize() {
+	f.Page, f.Limit = normalizePage(f.Page, f.Limit)
+}
+
+func (f BackorderFilter) Offset() int {
+	return (f.Page - 1) * f.Limit
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -8,29 +9,56 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	ErrProductNotFound   = errors.New("product not found")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrNoItems           = errors.New("reservation request has no items")
+)
+
+// ReservationError the item of a reservation that could not be reserved
+type ReservationError struct {
+	ProductID uuid.UUID
+	Err       error
+}
+
+func (e *ReservationError) Error() string {
+	return fmt.Sprintf("product %s: %v", e.ProductID, e.Err)
+}
+
+func (e *ReservationError) Unwrap() error {
+	return e.Err
+}
+
 type InventoryAggregate struct {
 	*types.Product
-	SagaID uuid.UUID `json:"saga_id" db:"saga_id"`
+	// LowStockThreshold ops are alerted when available stock falls below it, 0 never
+	LowStockThreshold int       `json:"low_stock_threshold" db:"low_stock_threshold"`
+	SagaID            uuid.UUID `json:"saga_id" db:"saga_id"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type ReservationAggregate struct {
 	*types.InventoryReservation
-	SagaID uuid.UUID `json:"saga_id" db:"saga_id"`
+	SagaID        uuid.UUID `json:"saga_id" db:"saga_id"`
+	CorrelationID uuid.UUID `json:"correlation_id" db:"correlation_id"` // Of the saga, for events sent without a command
 }
 
-func NewReservationAggregate(orderID, productID, sagaID uuid.UUID, quantity int) *ReservationAggregate {
+func NewReservationAggregate(orderID, sagaID, correlationID uuid.UUID, allocation Allocation) *ReservationAggregate {
 	return &ReservationAggregate{
 		InventoryReservation: &types.InventoryReservation{
-			ID:         uuid.New(),
-			OrderID:    orderID,
-			ProductID:  productID,
-			Quantity:   quantity,
-			Status:     types.InventoryStatusReserved,
-			ReservedAt: time.Now(),
-			ExpiresAt:  time.Now().Add(time.Hour * 24),
-			UpdatedAt:  time.Now(),
+			ID:          uuid.New(),
+			OrderID:     orderID,
+			ProductID:   allocation.ProductID,
+			Quantity:    allocation.Quantity,
+			Status:      types.InventoryStatusReserved,
+			WarehouseID: allocation.WarehouseID,
+			ReservedAt:  time.Now(),
+			ExpiresAt:   time.Now().Add(time.Hour * 24),
+			UpdatedAt:   time.Now(),
 		},
-		SagaID: sagaID,
+		SagaID:        sagaID,
+		CorrelationID: correlationID,
 	}
 }
 
@@ -39,11 +67,27 @@ func (r *ReservationAggregate) Release() {
 	r.UpdatedAt = time.Now()
 }
 
+// Expire the reservation outlived its saga step; its stock is free again
+func (r *ReservationAggregate) Expire() {
+	r.Status = types.InventoryStatusExpired
+	r.UpdatedAt = time.Now()
+}
+
 func (r *ReservationAggregate) Complete() {
 	r.Status = types.InventoryStatusSold
 	r.UpdatedAt = time.Now()
 }
 
+// Available units that can still be reserved
+func (i *InventoryAggregate) Available() int {
+	return i.Stock - i.ReservedStock
+}
+
+// LowOnStock reports whether available stock fell below the product's threshold
+func (i *InventoryAggregate) LowOnStock() bool {
+	return i.Available() < i.LowStockThreshold
+}
+
 func (i *InventoryAggregate) CanReserve(quantity int) bool {
 	availableStock := i.Stock - i.ReservedStock
 	return availableStock >= quantity
@@ -68,6 +112,12 @@ type InventoryReserveRequest struct {
 	SagaID  uuid.UUID         `json:"saga_id"`
 	OrderID uuid.UUID         `json:"order_id"`
 	Items   []ReservationItem `json:"items"`
+	// ShippingAddress the allocation strategy ships towards, nil if unknown
+	ShippingAddress *types.ShippingAddress `json:"shipping_address,omitempty"`
+	// Policy decides whether short items fail the request, are dropped or are backordered
+	Policy types.FulfillmentPolicy `json:"fulfillment_policy,omitempty"`
+	// BackorderID the backorder a follow-up saga reserves, uuid.Nil otherwise
+	BackorderID uuid.UUID `json:"backorder_id,omitempty"`
 }
 
 type ReservationItem struct {
@@ -80,3 +130,10 @@ type InventoryReleaseRequest struct {
 	OrderID        uuid.UUID   `json:"order_id"`
 	ReservationIDs []uuid.UUID `json:"reservation_ids"`
 }
+
+// InventoryRestockRequest items of a refunded order that go back into stock
+type InventoryRestockRequest struct {
+	SagaID  uuid.UUID         `json:"saga_id"`
+	OrderID uuid.UUID         `json:"order_id"`
+	Items   []ReservationItem `json:"items"`
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MovementType what changed a product's stock levels
+type MovementType string
+
+const (
+	MovementOpening MovementType = "opening" // Levels of a product when the ledger started
+	MovementCreate  MovementType = "create"  // Initial stock of a new product
+	MovementReserve MovementType = "reserve"
+	MovementRelease MovementType = "release"
+	MovementExpire  MovementType = "expire"
+	MovementSell    MovementType = "sell"
+	MovementAdjust  MovementType = "adjust"
+	MovementRestock MovementType = "restock"
+)
+
+func (t MovementType) Valid() bool {
+	switch t {
+	case MovementOpening, MovementCreate, MovementReserve, MovementRelease,
+		MovementExpire, MovementSell, MovementAdjust, MovementRestock:
+		return true
+	}
+	return false
+}
+
+// StockLevels of a product, or of a product at one warehouse
+type StockLevels struct {
+	Stock         int `json:"stock"`
+	ReservedStock int `json:"reserved_stock"`
+	SoldStock     int `json:"sold_stock"`
+}
+
+// StockMovement one entry of the append-only stock ledger. Summing the deltas
+// of a product's movements gives its current levels.
+type StockMovement struct {
+	ID            uuid.UUID    `json:"id" db:"id"`
+	Sequence      int64        `json:"sequence" db:"sequence"` // Ledger order
+	ProductID     uuid.UUID    `json:"product_id" db:"product_id"`
+	WarehouseID   uuid.UUID    `json:"warehouse_id" db:"warehouse_id"` // Nil before stock was kept per warehouse
+	Type          MovementType `json:"type" db:"type"`
+	SagaID        uuid.UUID    `json:"saga_id" db:"saga_id"`
+	OrderID       uuid.UUID    `json:"order_id" db:"order_id"`
+	ReferenceID   uuid.UUID    `json:"reference_id" db:"reference_id"` // Reservation or stock adjustment
+	StockDelta    int          `json:"stock_delta" db:"stock_delta"`
+	ReservedDelta int          `json:"reserved_delta" db:"reserved_delta"`
+	SoldDelta     int          `json:"sold_delta" db:"sold_delta"`
+	After         StockLevels  `json:"after"`
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+}
+
+// MovementFilter narrows and pages the ledger
+type MovementFilter struct {
+	ProductID   uuid.UUID
+	WarehouseID uuid.UUID
+	SagaID      uuid.UUID
+	OrderID     uuid.UUID
+	Type        MovementType // Empty for all
+	Since       time.Time
+	Page        int
+	Limit       int
+}
+
+func (f *MovementFilter) Normalize() {
+	f.Page, f.Limit = normalizePage(f.Page, f.Limit)
+}
+
+func (f MovementFilter) Offset() int {
+	return (f.Page - 1) * f.Limit
+}
+
+// LedgerDiscrepancy a product whose levels differ from the sum of its movements
+type LedgerDiscrepancy struct {
+	ProductID uuid.UUID   `json:"product_id"`
+	SKU       string      `json:"sku"`
+	Product   StockLevels `json:"product"`
+	Ledger    StockLevels `json:"ledger"`
+}
@@ -0,0 +1,230 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidProduct = errors.New("invalid product")
+	ErrDuplicateSKU   = errors.New("sku already exists")
+	// ErrProductInUse the product still has reserved stock
+	ErrProductInUse       = errors.New("product has reserved stock")
+	ErrInvalidAdjustment  = errors.New("invalid stock adjustment")
+	ErrStockBelowReserved = errors.New("stock would drop below reserved stock")
+)
+
+const (
+	maxSKULength         = 64
+	maxProductNameLength = 255
+	defaultPageLimit     = 20
+)
+
+// StockAdjustmentReason why stock was changed by hand rather than by a saga
+type StockAdjustmentReason string
+
+const (
+	AdjustmentReceived   StockAdjustmentReason = "received" // Delivery from a supplier
+	AdjustmentReturned   StockAdjustmentReason = "returned" // Returned outside a refund saga
+	AdjustmentDamaged    StockAdjustmentReason = "damaged"
+	AdjustmentLost       StockAdjustmentReason = "lost"
+	AdjustmentRecount    StockAdjustmentReason = "recount" // Stock take found a different count
+	AdjustmentCorrection StockAdjustmentReason = "correction"
+)
+
+func (r StockAdjustmentReason) Valid() bool {
+	switch r {
+	case AdjustmentReceived, AdjustmentReturned, AdjustmentDamaged,
+		AdjustmentLost, AdjustmentRecount, AdjustmentCorrection:
+		return true
+	}
+	return false
+}
+
+// StockAdjustment a manual change of a product's stock
+type StockAdjustment struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	ProductID uuid.UUID `json:"product_id" db:"product_id"`
+	// WarehouseID whose stock changed
+	WarehouseID uuid.UUID             `json:"warehouse_id" db:"warehouse_id"`
+	Quantity    int                   `json:"quantity" db:"quantity"` // Added to stock, negative removes
+	Reason      StockAdjustmentReason `json:"reason" db:"reason"`
+	Note        string                `json:"note,omitempty" db:"note"`
+	StockAfter  int                   `json:"stock_after" db:"stock_after"`
+	CreatedAt   time.Time             `json:"created_at" db:"created_at"`
+}
+
+type CreateProductRequest struct {
+	Name  string      `json:"name"`
+	SKU   string      `json:"sku"`
+	Price types.Money `json:"price"`
+	Stock int         `json:"stock"`
+	// WarehouseID the initial stock is kept at, the default warehouse if empty
+	WarehouseID       uuid.UUID `json:"warehouse_id"`
+	LowStockThreshold int       `json:"low_stock_threshold"`
+}
+
+// UpdateProductRequest changes the given catalog fields. Stock is changed with
+// a stock adjustment, so every change has a reason.
+type UpdateProductRequest struct {
+	Name              *string      `json:"name,omitempty"`
+	SKU               *string      `json:"sku,omitempty"`
+	Price             *types.Money `json:"price,omitempty"`
+	LowStockThreshold *int         `json:"low_stock_threshold,omitempty"`
+}
+
+type StockAdjustmentRequest struct {
+	Quantity int                   `json:"quantity"`
+	Reason   StockAdjustmentReason `json:"reason"`
+	Note     string                `json:"note"`
+	// WarehouseID whose stock changes, the default warehouse if empty
+	WarehouseID uuid.UUID `json:"warehouse_id"`
+}
+
+// ProductFilter narrows and pages the product list
+type ProductFilter struct {
+	Search  string // Part of the name or SKU, case insensitive
+	SKU     string
+	IDs     []uuid.UUID // Only these products, e.g. to price an order in one call
+	InStock *bool       // Whether any stock is available to reserve
+	// LowStock only products with less available than their low stock threshold
+	LowStock bool
+	Page     int
+	Limit    int
+}
+
+// ReservationFilter pages the reservations of a product
+type ReservationFilter struct {
+	Status types.InventoryStatus // Empty for all
+	Page   int
+	Limit  int
+}
+
+// Normalize falls back to the first page and the default limit for values out of range
+func (f *ProductFilter) Normalize() {
+	f.Page, f.Limit = normalizePage(f.Page, f.Limit)
+}
+
+func (f ProductFilter) Offset() int {
+	return (f.Page - 1) * f.Limit
+}
+
+func (f *ReservationFilter) Normalize() {
+	f.Page, f.Limit = normalizePage(f.Page, f.Limit)
+}
+
+func (f ReservationFilter) Offset() int {
+	return (f.Page - 1) * f.Limit
+}
+
+func normalizePage(page, limit int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = defaultPageLimit
+	}
+	return page, limit
+}
+
+// NewProduct validates the request and creates a product without reservations
+func NewProduct(request CreateProductRequest) (*InventoryAggregate, error) {
+	product := &InventoryAggregate{
+		Product: &types.Product{
+			ID:    uuid.New(),
+			Name:  strings.TrimSpace(request.Name),
+			SKU:   strings.TrimSpace(request.SKU),
+			Price: request.Price,
+			Stock: request.Stock,
+		},
+		LowStockThreshold: request.LowStockThreshold,
+	}
+	price, err := request.Price.InCurrency(types.DefaultCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: price: %v", ErrInvalidProduct, err)
+	}
+	product.Price = price
+	if request.Stock < 0 {
+		return nil, fmt.Errorf("%w: stock must not be negative", ErrInvalidProduct)
+	}
+	if err := product.validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	product.CreatedAt = now
+	product.UpdatedAt = now
+	return product, nil
+}
+
+// Update applies the catalog fields of the request
+func (i *InventoryAggregate) Update(request UpdateProductRequest) error {
+	if request.Name != nil {
+		i.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.SKU != nil {
+		i.SKU = strings.TrimSpace(*request.SKU)
+	}
+	if request.Price != nil {
+		price, err := request.Price.InCurrency(i.Price.Currency)
+		if err != nil {
+			return fmt.Errorf("%w: price: %v", ErrInvalidProduct, err)
+		}
+		i.Price = price
+	}
+	if request.LowStockThreshold != nil {
+		i.LowStockThreshold = *request.LowStockThreshold
+	}
+	if err := i.validate(); err != nil {
+		return err
+	}
+
+	i.UpdatedAt = time.Now()
+	return nil
+}
+
+func (i *InventoryAggregate) validate() error {
+	switch {
+	case i.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidProduct)
+	case len(i.Name) > maxProductNameLength:
+		return fmt.Errorf("%w: name is longer than %d characters", ErrInvalidProduct, maxProductNameLength)
+	case i.SKU == "":
+		return fmt.Errorf("%w: sku is required", ErrInvalidProduct)
+	case len(i.SKU) > maxSKULength:
+		return fmt.Errorf("%w: sku is longer than %d characters", ErrInvalidProduct, maxSKULength)
+	case !i.Price.Currency.Valid():
+		return fmt.Errorf("%w: %w: %q", ErrInvalidProduct, types.ErrInvalidCurrency, i.Price.Currency)
+	case i.Price.IsNegative():
+		return fmt.Errorf("%w: price must not be negative", ErrInvalidProduct)
+	case i.LowStockThreshold < 0:
+		return fmt.Errorf("%w: low stock threshold must not be negative", ErrInvalidProduct)
+	}
+	return nil
+}
+
+// NewStockAdjustment validates a manual stock change; whether the stock allows
+// it is checked when it is applied
+func NewStockAdjustment(productID uuid.UUID, request StockAdjustmentRequest) (*StockAdjustment, error) {
+	if request.Quantity == 0 {
+		return nil, fmt.Errorf("%w: quantity must not be zero", ErrInvalidAdjustment)
+	}
+	if !request.Reason.Valid() {
+		return nil, fmt.Errorf("%w: unknown reason %q", ErrInvalidAdjustment, request.Reason)
+	}
+
+	return &StockAdjustment{
+		ID:          uuid.New(),
+		ProductID:   productID,
+		WarehouseID: request.WarehouseID,
+		Quantity:    request.Quantity,
+		Reason:      request.Reason,
+		Note:        strings.TrimSpace(request.Note),
+		CreatedAt:   time.Now(),
+	}, nil
+}
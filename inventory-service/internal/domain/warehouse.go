@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWarehouseNotFound  = errors.New("warehouse not found")
+	ErrInvalidWarehouse   = errors.New("invalid warehouse")
+	ErrDuplicateWarehouse = errors.New("warehouse code already exists")
+)
+
+const maxWarehouseCodeLength = 32
+
+// Warehouse a location stock is kept at and shipped from
+type Warehouse struct {
+	ID      uuid.UUID             `json:"id" db:"id"`
+	Code    string                `json:"code" db:"code"`
+	Name    string                `json:"name" db:"name"`
+	Address types.ShippingAddress `json:"address"`
+	// ShippingCost of one shipment leaving the warehouse
+	ShippingCost types.Money `json:"shipping_cost" db:"shipping_cost"`
+	// Default receives the stock of products and adjustments that name no warehouse
+	Default   bool      `json:"default" db:"is_default"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateWarehouseRequest struct {
+	Code         string                `json:"code"`
+	Name         string                `json:"name"`
+	Address      types.ShippingAddress `json:"address"`
+	ShippingCost types.Money           `json:"shipping_cost"`
+}
+
+// WarehouseStock levels of one product at one warehouse. The levels of a
+// product add up over its warehouses.
+type WarehouseStock struct {
+	WarehouseID uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	ProductID   uuid.UUID `json:"product_id" db:"product_id"`
+	StockLevels
+}
+
+// Available units that can still be reserved
+func (s WarehouseStock) Available() int {
+	return s.Stock - s.ReservedStock
+}
+
+// Allocation units of a product to take from one warehouse
+type Allocation struct {
+	WarehouseID uuid.UUID `json:"warehouse_id"`
+	ProductID   uuid.UUID `json:"product_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+func NewWarehouse(request CreateWarehouseRequest) (*Warehouse, error) {
+	warehouse := &Warehouse{
+		ID:           uuid.New(),
+		Code:         strings.ToUpper(strings.TrimSpace(request.Code)),
+		Name:         strings.TrimSpace(request.Name),
+		Address:      request.Address,
+		ShippingCost: request.ShippingCost,
+		CreatedAt:    time.Now(),
+	}
+	shippingCost, err := request.ShippingCost.InCurrency(types.DefaultCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: shipping cost: %v", ErrInvalidWarehouse, err)
+	}
+	warehouse.ShippingCost = shippingCost
+
+	switch {
+	case warehouse.Code == "":
+		return nil, fmt.Errorf("%w: code is required", ErrInvalidWarehouse)
+	case len(warehouse.Code) > maxWarehouseCodeLength:
+		return nil, fmt.Errorf("%w: code is longer than %d characters", ErrInvalidWarehouse, maxWarehouseCodeLength)
+	case warehouse.Name == "":
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidWarehouse)
+	case len(warehouse.Name) > maxProductNameLength:
+		return nil, fmt.Errorf("%w: name is longer than %d characters", ErrInvalidWarehouse, maxProductNameLength)
+	case warehouse.Address.Country == "":
+		return nil, fmt.Errorf("%w: address country is required", ErrInvalidWarehouse)
+	case !warehouse.ShippingCost.Currency.Valid():
+		return nil, fmt.Errorf("%w: %w: %q", ErrInvalidWarehouse, types.ErrInvalidCurrency, warehouse.ShippingCost.Currency)
+	case warehouse.ShippingCost.IsNegative():
+		return nil, fmt.Errorf("%w: shipping cost must not be negative", ErrInvalidWarehouse)
+	}
+	return warehouse, nil
+}
+
+func WarehousesByID(warehouses []*Warehouse) map[uuid.UUID]*Warehouse {
+	byID := make(map[uuid.UUID]*Warehouse, len(warehouses))
+	for _, warehouse := range warehouses {
+		byID[warehouse.ID] = warehouse
+	}
+	return byID
+}
+
+// ShipmentOrigins groups reservations by the warehouse they were allocated to,
+// one origin per warehouse in code order
+func ShipmentOrigins(reservations []*ReservationAggregate, warehouses map[uuid.UUID]*Warehouse) []types.ShipmentOrigin {
+	byWarehouse := map[uuid.UUID]*types.ShipmentOrigin{}
+	for _, r := range reservations {
+		origin, ok := byWarehouse[r.WarehouseID]
+		if !ok {
+			origin = &types.ShipmentOrigin{WarehouseID: r.WarehouseID}
+			if warehouse, ok := warehouses[r.WarehouseID]; ok {
+				origin.WarehouseCode = warehouse.Code
+				origin.Address = warehouse.Address
+			}
+			byWarehouse[r.WarehouseID] = origin
+		}
+		origin.Items = append(origin.Items, types.ShipmentItem{ProductID: r.ProductID, Quantity: r.Quantity})
+	}
+
+	origins := make([]types.ShipmentOrigin, 0, len(byWarehouse))
+	for _, origin := range byWarehouse {
+		origins = append(origins, *origin)
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		return origins[i].WarehouseCode < origins[j].WarehouseCode
+	})
+	return origins
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/catalog"
+	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ImportProducts upserts products by SKU from a CSV or JSON file sent as the
+// request body. The format is taken from ?format=, else from the content type.
+// With ?dry_run=true only the diff is returned. A file with invalid records is
+// rejected as a whole, with the diff telling which records are invalid.
+func (h *InventoryHandler) ImportProducts(c *fiber.Ctx) error {
+	format, err := importFormat(c)
+	if err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid import format", map[string]interface{}{
+			"error":   err.Error(),
+			"allowed": []catalog.Format{catalog.FormatCSV, catalog.FormatJSON},
+		})
+	}
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			return sharedHTTP.BadRequestResponse(c, "Invalid dry_run flag", map[string]interface{}{
+				"dry_run": value,
+			})
+		}
+	}
+
+	report, err := h.inventoryService.ImportProducts(c.UserContext(), format, bytes.NewReader(c.Body()), dryRun)
+	if errors.Is(err, catalog.ErrInvalidFile) {
+		return sharedHTTP.BadRequestResponse(c, "Invalid catalog file", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return productErrorResponse(c, "Catalog import failed", err)
+	}
+
+	switch {
+	case report.Summary.Invalid > 0:
+		return sharedHTTP.BadRequestResponse(c, "Catalog import rejected, invalid records", map[string]interface{}{
+			"report": report,
+		})
+	case dryRun:
+		return sharedHTTP.SuccessResponse(c, "Catalog import planned", report)
+	default:
+		return sharedHTTP.SuccessResponse(c, "Catalog imported", report)
+	}
+}
+
+// ExportProducts downloads the catalog as CSV (default) or JSON with
+// ?format=, in the format ImportProducts accepts
+func (h *InventoryHandler) ExportProducts(c *fiber.Ctx) error {
+	format, err := catalog.ParseFormat(c.Query("format", string(catalog.FormatCSV)))
+	if err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid export format", map[string]interface{}{
+			"error":   err.Error(),
+			"allowed": []catalog.Format{catalog.FormatCSV, catalog.FormatJSON},
+		})
+	}
+
+	var buffer bytes.Buffer
+	if _, err := h.inventoryService.ExportProducts(format, &buffer); err != nil {
+		return productErrorResponse(c, "Catalog export failed", err)
+	}
+
+	c.Attachment("products." + string(format))
+	c.Set(fiber.HeaderContentType, format.ContentType())
+	return c.Send(buffer.Bytes())
+}
+
+// importFormat the ?format= of an import, else the format of its content type
+func importFormat(c *fiber.Ctx) (catalog.Format, error) {
+	if value := c.Query("format"); value != "" {
+		return catalog.ParseFormat(value)
+	}
+
+	contentType := strings.ToLower(c.Get(fiber.HeaderContentType))
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return catalog.FormatCSV, nil
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+		return catalog.FormatJSON, nil
+	}
+	return catalog.ParseFormat(contentType)
+}
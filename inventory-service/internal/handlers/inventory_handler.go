@@ -1,14 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
 	"github.com/distributed-ecommerce-saga/inventory-service/internal/service"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
 	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
@@ -30,24 +32,33 @@ func (h *InventoryHandler) HealthCheck(c *fiber.Ctx) error {
 	})
 }
 
-func (h *InventoryHandler) HandleSagaEvent(event events.SagaEvent) error {
-	log.Printf("Inventory service saga event received: %s from %s",
-		event.EventType, event.Service)
+func (h *InventoryHandler) HandleSagaEvent(ctx context.Context, event events.SagaEvent) error {
+	slog.DebugContext(ctx, "Inventory service saga event received", "from", event.Service)
 
 	switch event.EventType {
 	case "inventory.reserve":
-		return h.handleInventoryReserveCommand(event)
+		return h.handleInventoryReserveCommand(ctx, event)
 
 	case "inventory.release":
-		return h.handleInventoryReleaseCommand(event)
+		return h.handleInventoryReleaseCommand(ctx, event)
+
+	case "inventory.restock":
+		return h.handleInventoryRestockCommand(ctx, event)
+
+	case events.OrderCompletedEvent, events.OrderBackorderFulfilledEvent:
+		if err := h.inventoryService.CommitInventory(ctx, event.SagaID, event.OrderID); err != nil {
+			slog.ErrorContext(ctx, "Inventory commit error", "error", err)
+			return err
+		}
+		return nil
 
 	default:
-		log.Printf("Unhandled event type: %s", event.EventType)
+		slog.WarnContext(ctx, "Unhandled event type")
 		return nil
 	}
 }
 
-func (h *InventoryHandler) handleInventoryReserveCommand(event events.SagaEvent) error {
+func (h *InventoryHandler) handleInventoryReserveCommand(ctx context.Context, event events.SagaEvent) error {
 	payloadMap, ok := event.Payload.(map[string]interface{})
 	if !ok {
 		return h.logAndReturnError("Invalid payload format for inventory.reserve", event)
@@ -58,15 +69,15 @@ func (h *InventoryHandler) handleInventoryReserveCommand(event events.SagaEvent)
 		return h.logAndReturnError(fmt.Sprintf("Payload mapping error: %v", err), event)
 	}
 
-	if err := h.inventoryService.ReserveInventory(request); err != nil {
-		log.Printf("Inventory reserve error: %v", err)
+	if err := h.inventoryService.ReserveInventory(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Inventory reserve error", "error", err)
 		return err
 	}
 
 	return nil
 }
 
-func (h *InventoryHandler) handleInventoryReleaseCommand(event events.SagaEvent) error {
+func (h *InventoryHandler) handleInventoryReleaseCommand(ctx context.Context, event events.SagaEvent) error {
 	payloadMap, ok := event.Payload.(map[string]interface{})
 	if !ok {
 		return h.logAndReturnError("Invalid payload format for inventory.release", event)
@@ -77,8 +88,34 @@ func (h *InventoryHandler) handleInventoryReleaseCommand(event events.SagaEvent)
 		return h.logAndReturnError(fmt.Sprintf("Release payload mapping error: %v", err), event)
 	}
 
-	if err := h.inventoryService.ReleaseInventory(request); err != nil {
-		log.Printf("Inventory release error: %v", err)
+	if err := h.inventoryService.ReleaseInventory(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Inventory release error", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (h *InventoryHandler) handleInventoryRestockCommand(ctx context.Context, event events.SagaEvent) error {
+	payloadMap, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return h.logAndReturnError("Invalid payload format for inventory.restock", event)
+	}
+
+	// Same payload shape as a reservation: order_id and items
+	reserveRequest, err := h.mapToInventoryReserveRequest(event.SagaID, payloadMap)
+	if err != nil {
+		return h.logAndReturnError(fmt.Sprintf("Restock payload mapping error: %v", err), event)
+	}
+
+	request := domain.InventoryRestockRequest{
+		SagaID:  reserveRequest.SagaID,
+		OrderID: reserveRequest.OrderID,
+		Items:   reserveRequest.Items,
+	}
+
+	if err := h.inventoryService.RestockInventory(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Inventory restock error", "error", err)
 		return err
 	}
 
@@ -120,6 +157,31 @@ func (h *InventoryHandler) mapToInventoryReserveRequest(sagaID uuid.UUID, payloa
 		}
 	}
 
+	if policy, ok := payload["fulfillment_policy"].(string); ok {
+		request.Policy = types.FulfillmentPolicy(policy)
+		if !request.Policy.Valid() {
+			return request, fmt.Errorf("invalid fulfillment_policy: %s", policy)
+		}
+	}
+
+	if backorderIDStr, ok := payload["backorder_id"].(string); ok {
+		backorderID, err := uuid.Parse(backorderIDStr)
+		if err != nil {
+			return request, fmt.Errorf("invalid backorder_id format: %s", backorderIDStr)
+		}
+		request.BackorderID = backorderID
+	}
+
+	if addressData, ok := payload["shipping_address"].(map[string]interface{}); ok {
+		request.ShippingAddress = &types.ShippingAddress{
+			Street:  getStringFromPayload(addressData, "street"),
+			City:    getStringFromPayload(addressData, "city"),
+			State:   getStringFromPayload(addressData, "state"),
+			ZipCode: getStringFromPayload(addressData, "zip_code"),
+			Country: getStringFromPayload(addressData, "country"),
+		}
+	}
+
 	return request, nil
 }
 
@@ -147,8 +209,15 @@ func (h *InventoryHandler) mapToInventoryReleaseRequest(sagaID uuid.UUID, payloa
 	return request, nil
 }
 
+func getStringFromPayload(payload map[string]interface{}, key string) string {
+	if value, ok := payload[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
 func (h *InventoryHandler) logAndReturnError(message string, event events.SagaEvent) error {
-	log.Printf("%s - Event: %+v", message, event)
+	slog.Error(message, "event_id", event.ID, "event_type", event.EventType, "saga_id", event.SagaID)
 	return fmt.Errorf(message)
 }
 
@@ -156,6 +225,9 @@ func (h *InventoryHandler) StartConsuming(consumer *messaging.Consumer) error {
 	routingKeys := []string{
 		"saga.saga-orchestrator.inventory.reserve",
 		"saga.saga-orchestrator.inventory.release",
+		"saga.saga-orchestrator.inventory.restock",
+		"saga.saga-orchestrator.order.completed", // Reservations of completed orders are sold
+		"saga.saga-orchestrator.order.backorder_fulfilled",
 	}
 
 	return consumer.ConsumeEvents(routingKeys, h.HandleSagaEvent)
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetMovements pages the stock ledger, newest first, filtered by product_id,
+// warehouse_id, saga_id, order_id, type and since (RFC 3339)
+func (h *InventoryHandler) GetMovements(c *fiber.Ctx) error {
+	filter := domain.MovementFilter{
+		Type:  domain.MovementType(c.Query("type")),
+		Page:  c.QueryInt("page", 1),
+		Limit: c.QueryInt("limit", 20),
+	}
+	filter.Normalize()
+
+	if filter.Type != "" && !filter.Type.Valid() {
+		return sharedHTTP.BadRequestResponse(c, "Invalid movement type", map[string]interface{}{
+			"type": filter.Type,
+		})
+	}
+
+	for param, target := range map[string]*uuid.UUID{
+		"product_id":   &filter.ProductID,
+		"warehouse_id": &filter.WarehouseID,
+		"saga_id":      &filter.SagaID,
+		"order_id":     &filter.OrderID,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return sharedHTTP.BadRequestResponse(c, "Invalid "+param, map[string]interface{}{
+				param: value,
+			})
+		}
+		*target = id
+	}
+
+	if since := c.Query("since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return sharedHTTP.BadRequestResponse(c, "Invalid since, expected RFC 3339", map[string]interface{}{
+				"since": since,
+			})
+		}
+		filter.Since = parsed
+	}
+
+	movements, total, err := h.inventoryService.GetMovements(filter)
+	if err != nil {
+		return sharedHTTP.InternalServerErrorResponse(c, "Stock movements retrieval failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Stock movements retrieved successfully", map[string]interface{}{
+		"movements":  movements,
+		"pagination": pagination(filter.Page, filter.Limit, total),
+	})
+}
+
+// VerifyLedger runs the ledger check on demand and lists the products whose
+// levels do not match their movements
+func (h *InventoryHandler) VerifyLedger(c *fiber.Ctx) error {
+	discrepancies, err := h.inventoryService.VerifyLedger(c.UserContext())
+	if err != nil {
+		return sharedHTTP.InternalServerErrorResponse(c, "Ledger verification failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Ledger verified", map[string]interface{}{
+		"consistent":    len(discrepancies) == 0,
+		"discrepancies": discrepancies,
+	})
+}
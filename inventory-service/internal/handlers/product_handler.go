@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func (h *InventoryHandler) CreateProduct(c *fiber.Ctx) error {
+	var request domain.CreateProductRequest
+	if err := c.BodyParser(&request); err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid request body", map[string]interface{}{
+			"parse_error": err.Error(),
+		})
+	}
+
+	product, err := h.inventoryService.CreateProduct(c.UserContext(), request)
+	if err != nil {
+		return productErrorResponse(c, "Product creation failed", err)
+	}
+
+	return sharedHTTP.CreatedResponse(c, "Product created successfully", product)
+}
+
+// ListProducts pages the catalog, filtered by search (name or SKU), sku,
+// ids (comma separated) and in_stock
+func (h *InventoryHandler) ListProducts(c *fiber.Ctx) error {
+	filter := domain.ProductFilter{
+		Search: c.Query("search"),
+		SKU:    c.Query("sku"),
+		Page:   c.QueryInt("page", 1),
+		Limit:  c.QueryInt("limit", 20),
+	}
+	filter.Normalize()
+	if ids := c.Query("ids"); ids != "" {
+		for _, value := range strings.Split(ids, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(value))
+			if err != nil {
+				return sharedHTTP.BadRequestResponse(c, "Invalid ids filter", map[string]interface{}{
+					"id": value,
+				})
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+	}
+	if inStock := c.Query("in_stock"); inStock != "" {
+		value, err := strconv.ParseBool(inStock)
+		if err != nil {
+			return sharedHTTP.BadRequestResponse(c, "Invalid in_stock filter", map[string]interface{}{
+				"in_stock": inStock,
+			})
+		}
+		filter.InStock = &value
+	}
+
+	products, total, err := h.inventoryService.ListProducts(filter)
+	if err != nil {
+		return productErrorResponse(c, "Products retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Products retrieved successfully", map[string]interface{}{
+		"products":   products,
+		"pagination": pagination(filter.Page, filter.Limit, total),
+	})
+}
+
+// GetLowStockProducts pages the products whose available stock is below their
+// low stock threshold
+func (h *InventoryHandler) GetLowStockProducts(c *fiber.Ctx) error {
+	filter := domain.ProductFilter{
+		Page:  c.QueryInt("page", 1),
+		Limit: c.QueryInt("limit", 20),
+	}
+	filter.Normalize()
+
+	products, total, err := h.inventoryService.GetLowStockProducts(filter)
+	if err != nil {
+		return productErrorResponse(c, "Low stock products retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Low stock products retrieved successfully", map[string]interface{}{
+		"products":   products,
+		"pagination": pagination(filter.Page, filter.Limit, total),
+	})
+}
+
+func (h *InventoryHandler) GetProduct(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidProductID(c)
+	}
+
+	product, err := h.inventoryService.GetProduct(productID)
+	if err != nil {
+		return productErrorResponse(c, "Product retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Product retrieved successfully", product)
+}
+
+// UpdateProduct changes name, SKU, price or low stock threshold; stock goes
+// through adjustments
+func (h *InventoryHandler) UpdateProduct(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidProductID(c)
+	}
+
+	var request domain.UpdateProductRequest
+	if err := c.BodyParser(&request); err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid request body", map[string]interface{}{
+			"parse_error": err.Error(),
+		})
+	}
+
+	product, err := h.inventoryService.UpdateProduct(c.UserContext(), productID, request)
+	if err != nil {
+		return productErrorResponse(c, "Product update failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Product updated successfully", product)
+}
+
+func (h *InventoryHandler) DeleteProduct(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidProductID(c)
+	}
+
+	if err := h.inventoryService.DeleteProduct(c.UserContext(), productID); err != nil {
+		return productErrorResponse(c, "Product deletion failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Product deleted successfully", map[string]interface{}{
+		"product_id": productID,
+	})
+}
+
+// GetProductStock returns the stock levels of a product, including the units
+// sold by completed orders
+func (h *InventoryHandler) GetProductStock(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidProductID(c)
+	}
+
+	product, err := h.inventoryService.GetProduct(productID)
+	if err != nil {
+		return productErrorResponse(c, "Product retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Product stock retrieved successfully", map[string]interface{}{
+		"product_id":          product.ID,
+		"stock":               product.Stock,
+		"reserved_stock":      product.ReservedStock,
+		"available":           product.Available(),
+		"sold_stock":          product.SoldStock,
+		"low_stock_threshold": product.LowStockThreshold,
+		"low_stock":           product.LowOnStock(),
+	})
+}
+
+// AdjustStock adds (positive quantity) or removes stock with a reason code
+func (h *InventoryHandler) AdjustStock(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidProductID(c)
+	}
+
+	var request domain.StockAdjustmentRequest
+	if err := c.BodyParser(&request); err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid request body", map[string]interface{}{
+			"parse_error": err.Error(),
+		})
+	}
+
+	adjustment, err := h.inventoryService.AdjustStock(c.UserContext(), productID, request)
+	if err != nil {
+		return productErrorResponse(c, "Stock adjustment failed", err)
+	}
+
+	return sharedHTTP.CreatedResponse(c, "Stock adjusted successfully", adjustment)
+}
+
+func (h *InventoryHandler) GetStockAdjustments(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidProductID(c)
+	}
+
+	limit := 20
+	if l := c.QueryInt("limit", limit); l > 0 && l <= 100 {
+		limit = l
+	}
+
+	adjustments, err := h.inventoryService.GetStockAdjustments(productID, limit)
+	if err != nil {
+		return productErrorResponse(c, "Stock adjustments retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Stock adjustments retrieved successfully", map[string]interface{}{
+		"adjustments": adjustments,
+	})
+}
+
+// GetProductReservations pages the reservations of a product, optionally by status
+func (h *InventoryHandler) GetProductReservations(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidProductID(c)
+	}
+
+	filter := domain.ReservationFilter{
+		Status: types.InventoryStatus(c.Query("status")),
+		Page:   c.QueryInt("page", 1),
+		Limit:  c.QueryInt("limit", 20),
+	}
+	filter.Normalize()
+
+	reservations, total, err := h.inventoryService.GetProductReservations(productID, filter)
+	if err != nil {
+		return productErrorResponse(c, "Reservations retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Reservations retrieved successfully", map[string]interface{}{
+		"reservations": reservations,
+		"pagination":   pagination(filter.Page, filter.Limit, total),
+	})
+}
+
+// GetProductBackorders pages the backorders of a product, oldest first,
+// optionally by status
+func (h *InventoryHandler) GetProductBackorders(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidProductID(c)
+	}
+
+	filter := domain.BackorderFilter{
+		Status: types.BackorderStatus(c.Query("status")),
+		Page:   c.QueryInt("page", 1),
+		Limit:  c.QueryInt("limit", 20),
+	}
+	filter.Normalize()
+
+	backorders, total, err := h.inventoryService.GetProductBackorders(productID, filter)
+	if err != nil {
+		return productErrorResponse(c, "Backorders retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Backorders retrieved successfully", map[string]interface{}{
+		"backorders": backorders,
+		"pagination": pagination(filter.Page, filter.Limit, total),
+	})
+}
+
+func (h *InventoryHandler) GetOrderReservations(c *fiber.Ctx) error {
+	orderIDStr := c.Params("order_id")
+	orderID, err := uuid.Parse(orderIDStr)
+	if err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid order ID", map[string]interface{}{
+			"order_id": orderIDStr,
+		})
+	}
+
+	reservations, err := h.inventoryService.GetOrderReservations(orderID)
+	if err != nil {
+		return productErrorResponse(c, "Reservations retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Reservations retrieved successfully", map[string]interface{}{
+		"order_id":     orderID,
+		"reservations": reservations,
+	})
+}
+
+func invalidProductID(c *fiber.Ctx) error {
+	return sharedHTTP.BadRequestResponse(c, "Invalid product ID", map[string]interface{}{
+		"product_id": c.Params("id"),
+	})
+}
+
+func productErrorResponse(c *fiber.Ctx, message string, err error) error {
+	details := map[string]interface{}{"error": err.Error()}
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidProduct),
+		errors.Is(err, domain.ErrInvalidAdjustment),
+		errors.Is(err, domain.ErrInvalidWarehouse):
+		return sharedHTTP.BadRequestResponse(c, message, details)
+	case errors.Is(err, domain.ErrProductNotFound):
+		return sharedHTTP.NotFoundResponse(c, "Product not found")
+	case errors.Is(err, domain.ErrWarehouseNotFound):
+		return sharedHTTP.NotFoundResponse(c, "Warehouse not found")
+	case errors.Is(err, domain.ErrDuplicateSKU),
+		errors.Is(err, domain.ErrDuplicateWarehouse),
+		errors.Is(err, domain.ErrProductInUse),
+		errors.Is(err, domain.ErrStockBelowReserved):
+		return sharedHTTP.ConflictResponse(c, message, details)
+	default:
+		return sharedHTTP.InternalServerErrorResponse(c, message, details)
+	}
+}
+
+func pagination(page, limit, total int) map[string]interface{} {
+	return map[string]interface{}{
+		"page":     page,
+		"limit":    limit,
+		"total":    total,
+		"has_more": page*limit < total,
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func (h *InventoryHandler) CreateWarehouse(c *fiber.Ctx) error {
+	var request domain.CreateWarehouseRequest
+	if err := c.BodyParser(&request); err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid request body", map[string]interface{}{
+			"parse_error": err.Error(),
+		})
+	}
+
+	warehouse, err := h.inventoryService.CreateWarehouse(c.UserContext(), request)
+	if err != nil {
+		return productErrorResponse(c, "Warehouse creation failed", err)
+	}
+
+	return sharedHTTP.CreatedResponse(c, "Warehouse created successfully", warehouse)
+}
+
+func (h *InventoryHandler) ListWarehouses(c *fiber.Ctx) error {
+	warehouses, err := h.inventoryService.ListWarehouses()
+	if err != nil {
+		return productErrorResponse(c, "Warehouses retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Warehouses retrieved successfully", map[string]interface{}{
+		"warehouses": warehouses,
+	})
+}
+
+func (h *InventoryHandler) GetWarehouse(c *fiber.Ctx) error {
+	warehouseID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid warehouse ID", map[string]interface{}{
+			"warehouse_id": c.Params("id"),
+		})
+	}
+
+	warehouse, err := h.inventoryService.GetWarehouse(warehouseID)
+	if err != nil {
+		return productErrorResponse(c, "Warehouse retrieval failed", err)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Warehouse retrieved successfully", warehouse)
+}
+
+// GetProductWarehouseStock returns the stock levels of a product per warehouse
+func (h *InventoryHandler) GetProductWarehouseStock(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidProductID(c)
+	}
+
+	stock, err := h.inventoryService.GetProductWarehouseStock(productID)
+	if err != nil {
+		return productErrorResponse(c, "Warehouse stock retrieval failed", err)
+	}
+
+	levels := make([]map[string]interface{}, 0, len(stock))
+	for _, s := range stock {
+		levels = append(levels, map[string]interface{}{
+			"warehouse_id":   s.WarehouseID,
+			"stock":          s.Stock,
+			"reserved_stock": s.ReservedStock,
+			"available":      s.Available(),
+			"sold_stock":     s.SoldStock,
+		})
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Warehouse stock retrieved successfully", map[string]interface{}{
+		"product_id": productID,
+		"warehouses": levels,
+	})
+}
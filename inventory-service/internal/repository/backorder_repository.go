@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// backorderColumns selected by every backorder query, in scanBackorder order
+const backorderColumns = `
+	id, order_id, order_saga_id, correlation_id, product_id, quantity, status,
+	saga_id, created_at, updated_at`
+
+// ClaimBackorders hands waiting backorders of the product to follow-up sagas
+// while the available stock covers them. Backorders are served strictly
+// oldest first: one that does not fit holds back the younger ones, so a large
+// backorder is not starved by small ones. Stock promised to backorders already
+// being processed is not offered twice. Each claimed backorder gets the ID of
+// its follow-up saga.
+func (r *InventoryRepository) ClaimBackorders(productID uuid.UUID) ([]*domain.BackorderAggregate, error) {
+	defer metrics.ObserveDBQuery("ClaimBackorders", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	var available int
+	err = tx.QueryRow(`
+		SELECT stock - reserved_stock FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+	`, productID).Scan(&available)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("product lock error: %v", err)
+	}
+
+	var promised int
+	if err := tx.QueryRow(`
+		SELECT COALESCE(SUM(quantity), 0) FROM backorders WHERE product_id = $1 AND status = 'processing'
+	`, productID).Scan(&promised); err != nil {
+		return nil, fmt.Errorf("processing backorders sum error: %v", err)
+	}
+	available -= promised
+
+	rows, err := tx.Query(`
+		SELECT `+backorderColumns+`
+		FROM backorders
+		WHERE product_id = $1 AND status = 'waiting'
+		ORDER BY created_at, id
+		FOR UPDATE
+	`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("backorders retrieval error: %v", err)
+	}
+	waiting, err := scanBackorders(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := []*domain.BackorderAggregate{}
+	for _, backorder := range waiting {
+		if backorder.Quantity > available {
+			break
+		}
+		available -= backorder.Quantity
+
+		backorder.Status = types.BackorderStatusProcessing
+		backorder.SagaID = uuid.New()
+		backorder.UpdatedAt = time.Now()
+		if _, err := tx.Exec(`
+			UPDATE backorders SET status = $2, saga_id = $3, updated_at = $4 WHERE id = $1
+		`, backorder.ID, backorder.Status, backorder.SagaID, backorder.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("backorder claim error: %v", err)
+		}
+		claimed = append(claimed, backorder)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit error: %v", err)
+	}
+	return claimed, nil
+}
+
+// ReturnBackorder puts a backorder whose follow-up saga could not reserve it
+// back in the queue, keeping its place
+func (r *InventoryRepository) ReturnBackorder(backorderID, sagaID uuid.UUID) error {
+	defer metrics.ObserveDBQuery("ReturnBackorder", time.Now())
+
+	_, err := r.db.Exec(`
+		UPDATE backorders SET status = 'waiting', saga_id = NULL, updated_at = NOW()
+		WHERE id = $1 AND saga_id = $2 AND status = 'processing'
+	`, backorderID, sagaID)
+	if err != nil {
+		return fmt.Errorf("backorder return error: %v", err)
+	}
+	return nil
+}
+
+// SettleBackorder ends the reserved backorder of a follow-up saga with the
+// given status once the saga finished. Sagas without one change nothing.
+func (r *InventoryRepository) SettleBackorder(sagaID uuid.UUID, status types.BackorderStatus) error {
+	defer metrics.ObserveDBQuery("SettleBackorder", time.Now())
+
+	_, err := r.db.Exec(`
+		UPDATE backorders SET status = $2, updated_at = NOW()
+		WHERE saga_id = $1 AND status = 'reserved'
+	`, sagaID, status)
+	if err != nil {
+		return fmt.Errorf("backorder settle error: %v", err)
+	}
+	return nil
+}
+
+// GetBackordersByProductID pages the backorders of a product, oldest first
+func (r *InventoryRepository) GetBackordersByProductID(productID uuid.UUID, filter domain.BackorderFilter) ([]*domain.BackorderAggregate, int, error) {
+	defer metrics.ObserveDBQuery("GetBackordersByProductID", time.Now())
+	filter.Normalize()
+
+	where := "product_id = $1"
+	args := []interface{}{productID}
+	if filter.Status != "" {
+		where += " AND status = $2"
+		args = append(args, filter.Status)
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM backorders WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("backorders count error: %v", err)
+	}
+
+	offset := filter.Offset()
+	args = append(args, filter.Limit, offset)
+	rows, err := r.db.Query(`
+		SELECT `+backorderColumns+`
+		FROM backorders
+		WHERE `+where+`
+		ORDER BY created_at, id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("backorders retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	backorders, err := scanBackorders(rows)
+	return backorders, total, err
+}
+
+// reserveBackorder marks the backorder of a follow-up saga reserved, in the
+// transaction that reserved its stock
+func reserveBackorder(tx *sql.Tx, backorderID, sagaID uuid.UUID) error {
+	result, err := tx.Exec(`
+		UPDATE backorders SET status = 'reserved', updated_at = NOW()
+		WHERE id = $1 AND saga_id = $2 AND status = 'processing'
+	`, backorderID, sagaID)
+	if err != nil {
+		return fmt.Errorf("backorder reserve error: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("backorder %s is not processed by saga %s", backorderID, sagaID)
+	}
+	return nil
+}
+
+func insertBackorder(db execer, backorder *domain.BackorderAggregate) error {
+	_, err := db.Exec(`
+		INSERT INTO backorders (
+			id, order_id, order_saga_id, correlation_id, product_id, quantity, status,
+			saga_id, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`,
+		backorder.ID,
+		backorder.OrderID,
+		backorder.OrderSagaID,
+		nullUUID(backorder.CorrelationID),
+		backorder.ProductID,
+		backorder.Quantity,
+		backorder.Status,
+		nullUUID(backorder.SagaID),
+		backorder.CreatedAt,
+		backorder.UpdatedAt,
+	)
+	return err
+}
+
+func scanBackorders(rows *sql.Rows) ([]*domain.BackorderAggregate, error) {
+	backorders := []*domain.BackorderAggregate{}
+	for rows.Next() {
+		backorder := &domain.BackorderAggregate{Backorder: &types.Backorder{}}
+		var correlationID, sagaID uuid.NullUUID
+
+		if err := rows.Scan(
+			&backorder.ID,
+			&backorder.OrderID,
+			&backorder.OrderSagaID,
+			&correlationID,
+			&backorder.ProductID,
+			&backorder.Quantity,
+			&backorder.Status,
+			&sagaID,
+			&backorder.CreatedAt,
+			&backorder.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("backorder scan error: %v", err)
+		}
+		backorder.CorrelationID = correlationID.UUID
+		backorder.SagaID = sagaID.UUID
+		backorders = append(backorders, backorder)
+	}
+
+	return backorders, rows.Err()
+}
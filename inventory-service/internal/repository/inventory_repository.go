@@ -3,8 +3,12 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"sort"
+	"time"
 
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/allocation"
 	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
@@ -18,15 +22,162 @@ func NewInventoryRepository(db *sql.DB) *InventoryRepository {
 	return &InventoryRepository{db: db}
 }
 
-func (r *InventoryRepository) CreateReservation(reservation *domain.ReservationAggregate) error {
+// reservationColumns selected by every reservation query, in scanReservation order
+const reservationColumns = `
+	id, order_id, product_id, saga_id, quantity, status,
+	reserved_at, expires_at, updated_at, correlation_id, warehouse_id`
+
+// ReserveItems reserves the items of an order in one transaction. Under the
+// all or nothing policy either all items are reserved or none is; the other
+// policies reserve what is available and drop or backorder the rest, failing
+// only when nothing at all is available. The product and warehouse rows are
+// locked before strategy splits the items over warehouses, so concurrent
+// orders can never reserve more than is available. One reservation is made
+// per product and warehouse.
+func (r *InventoryRepository) ReserveItems(request domain.InventoryReserveRequest, correlationID uuid.UUID, strategy allocation.Strategy) (*domain.ReservationResult, error) {
+	defer metrics.ObserveDBQuery("ReserveItems", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	policy := request.Policy.OrDefault()
+	lenient := policy != types.FulfillAllOrNothing
+
+	// Lines of the same product are reserved together
+	quantities := map[uuid.UUID]int{}
+	for _, item := range request.Items {
+		quantities[item.ProductID] += item.Quantity
+	}
+	requested := lockOrder(quantities)
+
+	var stock []domain.WarehouseStock
+	missing := map[uuid.UUID]bool{}
+	for _, productID := range requested {
+		var locked int
+		err := tx.QueryRow(`
+			SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+		`, productID).Scan(&locked)
+		if err == sql.ErrNoRows && lenient {
+			missing[productID] = true
+			continue
+		}
+		if err == sql.ErrNoRows {
+			return nil, &domain.ReservationError{ProductID: productID, Err: domain.ErrProductNotFound}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("product lock error: %v", err)
+		}
+
+		productStock, err := lockWarehouseStock(tx, productID)
+		if err != nil {
+			return nil, err
+		}
+		stock = append(stock, productStock...)
+	}
+
+	warehouses, err := listWarehouses(tx)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planReservation(policy, quantities, missing, allocation.Request{
+		Address:    request.ShippingAddress,
+		Warehouses: domain.WarehousesByID(warehouses),
+		Stock:      stock,
+	}, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.ReservationResult{Items: plan.Items}
+	for _, item := range result.Items {
+		if item.Backordered == 0 {
+			continue
+		}
+		backorder := domain.NewBackorderAggregate(request.OrderID, request.SagaID, correlationID, item.ProductID, item.Backordered)
+		if err := insertBackorder(tx, backorder); err != nil {
+			return nil, fmt.Errorf("backorder creation error: %v", err)
+		}
+		result.Backorders = append(result.Backorders, backorder)
+	}
+
+	result.Reservations = make([]*domain.ReservationAggregate, 0, len(plan.Allocations))
+	for _, a := range plan.Allocations {
+		if err := reserveStock(tx, request.OrderID, request.SagaID, a); err != nil {
+			return nil, err
+		}
+
+		reservation := domain.NewReservationAggregate(request.OrderID, request.SagaID, correlationID, a)
+		if err := insertReservation(tx, reservation); err != nil {
+			return nil, fmt.Errorf("reservation creation error: %v", err)
+		}
+		result.Reservations = append(result.Reservations, reservation)
+	}
+
+	if request.BackorderID != uuid.Nil {
+		if err := reserveBackorder(tx, request.BackorderID, request.SagaID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit error: %v", err)
+	}
+	return result, nil
+}
+
+// lockOrder products in ID order; every transaction that changes several
+// products updates them in this order, so two never wait for each other's rows
+func lockOrder(quantities map[uuid.UUID]int) []uuid.UUID {
+	productIDs := make([]uuid.UUID, 0, len(quantities))
+	for productID := range quantities {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Slice(productIDs, func(i, j int) bool {
+		return productIDs[i].String() < productIDs[j].String()
+	})
+	return productIDs
+}
+
+func reserveStock(tx *sql.Tx, orderID, sagaID uuid.UUID, a domain.Allocation) error {
+	movement := &domain.StockMovement{
+		ProductID:   a.ProductID,
+		WarehouseID: a.WarehouseID,
+		Type:        domain.MovementReserve,
+		SagaID:      sagaID,
+		OrderID:     orderID,
+	}
+	err := moveStock(tx, movement, `
+		UPDATE products
+		SET reserved_stock = reserved_stock + $2, updated_at = NOW()
+		WHERE id = $1 AND stock - reserved_stock >= $2 AND deleted_at IS NULL
+	`, a.ProductID, a.Quantity)
+	if err == sql.ErrNoRows {
+		// The warehouses add up to the product, so the allocation never gets here
+		return &domain.ReservationError{ProductID: a.ProductID, Err: domain.ErrInsufficientStock}
+	}
+	if err != nil {
+		return fmt.Errorf("stock reserve error: %v", err)
+	}
+	return nil
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertReservation(db execer, reservation *domain.ReservationAggregate) error {
 	query := `
 		INSERT INTO inventory_reservations (
 			id, order_id, product_id, saga_id, quantity, status, 
-			reserved_at, expires_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			reserved_at, expires_at, updated_at, correlation_id, warehouse_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 
-	_, err := r.db.Exec(
+	_, err := db.Exec(
 		query,
 		reservation.ID,
 		reservation.OrderID,
@@ -37,12 +188,16 @@ func (r *InventoryRepository) CreateReservation(reservation *domain.ReservationA
 		reservation.ReservedAt,
 		reservation.ExpiresAt,
 		reservation.UpdatedAt,
+		nullUUID(reservation.CorrelationID),
+		reservation.WarehouseID,
 	)
 
 	return err
 }
 
 func (r *InventoryRepository) UpdateReservation(reservation *domain.ReservationAggregate) error {
+	defer metrics.ObserveDBQuery("UpdateReservation", time.Now())
+
 	query := `
 		UPDATE inventory_reservations 
 		SET status = $2, updated_at = $3
@@ -53,49 +208,87 @@ func (r *InventoryRepository) UpdateReservation(reservation *domain.ReservationA
 	return err
 }
 
-func (r *InventoryRepository) GetProductByID(productID uuid.UUID) (*domain.InventoryAggregate, error) {
-	query := `
-		SELECT id, name, price, stock, reserved_stock
-		FROM products 
-		WHERE id = $1
-	`
+// RestockProduct puts returned items back into stock once per saga and product,
+// at the warehouse that sold most of them to the order. restocked is false when
+// the saga already restocked the product.
+func (r *InventoryRepository) RestockProduct(sagaID, orderID, productID uuid.UUID, quantity int) (restocked bool, err error) {
+	defer metrics.ObserveDBQuery("RestockProduct", time.Now())
 
-	product := &domain.InventoryAggregate{Product: &types.Product{}}
-	err := r.db.QueryRow(query, productID).Scan(
-		&product.ID,
-		&product.Name,
-		&product.Price,
-		&product.Stock,
-		&product.ReservedStock,
-	)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO inventory_restocks (id, saga_id, order_id, product_id, quantity, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (saga_id, product_id) DO NOTHING
+	`, uuid.New(), sagaID, orderID, productID, quantity, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("restock insert error: %v", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return false, err
+	}
+
+	warehouseID, err := restockWarehouse(tx, orderID, productID)
+	if err != nil {
+		return false, err
+	}
 
+	movement := &domain.StockMovement{
+		ProductID:   productID,
+		WarehouseID: warehouseID,
+		Type:        domain.MovementRestock,
+		SagaID:      sagaID,
+		OrderID:     orderID,
+	}
+	err = moveStock(tx, movement, `
+		UPDATE products SET stock = stock + $2, updated_at = NOW() WHERE id = $1
+	`, productID, quantity)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("product not found: %s", productID)
+		return false, fmt.Errorf("%w: %s", domain.ErrProductNotFound, productID)
+	}
+	if err != nil {
+		return false, fmt.Errorf("product restock error: %v", err)
 	}
 
-	return product, err
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("transaction commit error: %v", err)
+	}
+	return true, nil
 }
 
-func (r *InventoryRepository) UpdateProduct(product *domain.InventoryAggregate) error {
-	query := `
-		UPDATE products 
-		SET stock = $2, reserved_stock = $3
-		WHERE id = $1
-	`
-
-	_, err := r.db.Exec(query, product.ID, product.Stock, product.ReservedStock)
-	return err
+// restockWarehouse the warehouse the order's sold units of the product came from,
+// the one that sold the most if several did, or the default warehouse
+func restockWarehouse(tx *sql.Tx, orderID, productID uuid.UUID) (uuid.UUID, error) {
+	var warehouseID uuid.UUID
+	err := tx.QueryRow(`
+		SELECT warehouse_id
+		FROM inventory_reservations
+		WHERE order_id = $1 AND product_id = $2 AND status = 'sold'
+		GROUP BY warehouse_id
+		ORDER BY SUM(quantity) DESC, warehouse_id
+		LIMIT 1
+	`, orderID, productID).Scan(&warehouseID)
+	if err == sql.ErrNoRows {
+		return resolveWarehouse(tx, uuid.Nil)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("restock warehouse receive error: %v", err)
+	}
+	return warehouseID, nil
 }
 
 func (r *InventoryRepository) GetReservationsBySagaID(sagaID uuid.UUID) ([]*domain.ReservationAggregate, error) {
-	query := `
-		SELECT id, order_id, product_id, saga_id, quantity, status,
-			   reserved_at, expires_at, updated_at
+	defer metrics.ObserveDBQuery("GetReservationsBySagaID", time.Now())
+
+	rows, err := r.db.Query(`
+		SELECT `+reservationColumns+`
 		FROM inventory_reservations
 		WHERE saga_id = $1
-	`
-
-	rows, err := r.db.Query(query, sagaID)
+	`, sagaID)
 	if err != nil {
 		return nil, err
 	}
@@ -103,27 +296,164 @@ func (r *InventoryRepository) GetReservationsBySagaID(sagaID uuid.UUID) ([]*doma
 
 	var reservations []*domain.ReservationAggregate
 	for rows.Next() {
-		reservation := &domain.ReservationAggregate{
-			InventoryReservation: &types.InventoryReservation{},
-		}
-
-		err := rows.Scan(
-			&reservation.ID,
-			&reservation.OrderID,
-			&reservation.ProductID,
-			&reservation.SagaID,
-			&reservation.Quantity,
-			&reservation.Status,
-			&reservation.ReservedAt,
-			&reservation.ExpiresAt,
-			&reservation.UpdatedAt,
-		)
+		reservation, err := scanReservation(rows)
 		if err != nil {
 			return nil, err
 		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, rows.Err()
+}
+
+// ReleaseReservations gives the stock of the saga's reservations back. Only
+// reservations still holding stock are released and returned, so a redelivered
+// release command or a reservation the sweeper already expired frees nothing twice.
+func (r *InventoryRepository) ReleaseReservations(sagaID uuid.UUID) ([]*domain.ReservationAggregate, error) {
+	defer metrics.ObserveDBQuery("ReleaseReservations", time.Now())
+
+	return r.settleReservations((*domain.ReservationAggregate).Release, domain.MovementRelease, releaseStock, `
+		SELECT `+reservationColumns+`
+		FROM inventory_reservations
+		WHERE saga_id = $1 AND status = 'reserved'
+		FOR UPDATE
+	`, sagaID)
+}
+
+// ExpireReservations releases up to limit reservations whose expires_at passed
+// before now and marks them expired. Rows locked by a concurrent release are
+// skipped and left to it.
+func (r *InventoryRepository) ExpireReservations(now time.Time, limit int) ([]*domain.ReservationAggregate, error) {
+	defer metrics.ObserveDBQuery("ExpireReservations", time.Now())
+
+	return r.settleReservations((*domain.ReservationAggregate).Expire, domain.MovementExpire, releaseStock, `
+		SELECT `+reservationColumns+`
+		FROM inventory_reservations
+		WHERE status = 'reserved' AND expires_at < $1
+		ORDER BY expires_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, now, limit)
+}
+
+// SellReservations turns the saga's reservations into sales once the order
+// completed: stock and reserved_stock both drop by the reserved quantity and
+// sold_stock grows by it. Reservations that are no longer reserved are skipped,
+// so a redelivered completion sells nothing twice.
+func (r *InventoryRepository) SellReservations(sagaID uuid.UUID) ([]*domain.ReservationAggregate, error) {
+	defer metrics.ObserveDBQuery("SellReservations", time.Now())
 
+	return r.settleReservations((*domain.ReservationAggregate).Complete, domain.MovementSell, sellStock, `
+		SELECT `+reservationColumns+`
+		FROM inventory_reservations
+		WHERE saga_id = $1 AND status = 'reserved'
+		FOR UPDATE
+	`, sagaID)
+}
+
+// Product updates applied by settleReservations, $1 is the product ID and $2 the
+// quantity of its settled reservations
+const (
+	releaseStock = `
+		UPDATE products
+		SET reserved_stock = GREATEST(reserved_stock - $2, 0), updated_at = NOW()
+		WHERE id = $1`
+	sellStock = `
+		UPDATE products
+		SET stock = stock - $2, reserved_stock = reserved_stock - $2,
+			sold_stock = sold_stock + $2, updated_at = NOW()
+		WHERE id = $1`
+)
+
+// settleReservations locks the reservations selected by query, applies
+// productUpdate to their products, records each as a movement of the given
+// type and moves them to the status set by end, all in one transaction
+func (r *InventoryRepository) settleReservations(end func(*domain.ReservationAggregate), movementType domain.MovementType, productUpdate string, query string, args ...interface{}) ([]*domain.ReservationAggregate, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reservations retrieval error: %v", err)
+	}
+	reservations := []*domain.ReservationAggregate{}
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("reservation scan error: %v", err)
+		}
 		reservations = append(reservations, reservation)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reservations retrieval error: %v", err)
+	}
+
+	// Products in lock order, see lockOrder
+	settled := make([]*domain.ReservationAggregate, len(reservations))
+	copy(settled, reservations)
+	sort.SliceStable(settled, func(i, j int) bool {
+		return settled[i].ProductID.String() < settled[j].ProductID.String()
+	})
 
+	for _, reservation := range settled {
+		movement := &domain.StockMovement{
+			ProductID:   reservation.ProductID,
+			WarehouseID: reservation.WarehouseID,
+			Type:        movementType,
+			SagaID:      reservation.SagaID,
+			OrderID:     reservation.OrderID,
+			ReferenceID: reservation.ID,
+		}
+		if err := moveStock(tx, movement, productUpdate, reservation.ProductID, reservation.Quantity); err != nil {
+			return nil, fmt.Errorf("product stock update error: %v", err)
+		}
+
+		end(reservation)
+		if _, err := tx.Exec(`
+			UPDATE inventory_reservations SET status = $2, updated_at = $3 WHERE id = $1
+		`, reservation.ID, reservation.Status, reservation.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("reservation update error: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit error: %v", err)
+	}
 	return reservations, nil
 }
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReservation(row rowScanner) (*domain.ReservationAggregate, error) {
+	reservation := &domain.ReservationAggregate{
+		InventoryReservation: &types.InventoryReservation{},
+	}
+	var correlationID uuid.NullUUID
+
+	err := row.Scan(
+		&reservation.ID,
+		&reservation.OrderID,
+		&reservation.ProductID,
+		&reservation.SagaID,
+		&reservation.Quantity,
+		&reservation.Status,
+		&reservation.ReservedAt,
+		&reservation.ExpiresAt,
+		&reservation.UpdatedAt,
+		&correlationID,
+		&reservation.WarehouseID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation.CorrelationID = correlationID.UUID
+	return reservation, nil
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/google/uuid"
+)
+
+// movementColumns selected by every movement query, in scanMovement order
+const movementColumns = `
+	id, sequence, product_id, warehouse_id, type, saga_id, order_id, reference_id,
+	stock_delta, reserved_delta, sold_delta,
+	stock_after, reserved_after, sold_after, created_at`
+
+// moveStock locks the movement's product, applies update to it and appends the
+// movement to the ledger in the same transaction. The deltas are taken from the
+// levels before and after the update, so the ledger always adds up to the
+// product; a movement with a warehouse applies the same deltas there. sql.ErrNoRows
+// is returned as is when the product is missing or update matched nothing, for
+// the caller to explain.
+func moveStock(tx *sql.Tx, movement *domain.StockMovement, update string, args ...interface{}) error {
+	var before domain.StockLevels
+	err := tx.QueryRow(`
+		SELECT stock, reserved_stock, sold_stock FROM products WHERE id = $1 FOR UPDATE
+	`, movement.ProductID).Scan(&before.Stock, &before.ReservedStock, &before.SoldStock)
+	if err != nil {
+		return err
+	}
+
+	after := &movement.After
+	err = tx.QueryRow(update+`
+		RETURNING stock, reserved_stock, sold_stock
+	`, args...).Scan(&after.Stock, &after.ReservedStock, &after.SoldStock)
+	if err != nil {
+		return err
+	}
+
+	movement.StockDelta = after.Stock - before.Stock
+	movement.ReservedDelta = after.ReservedStock - before.ReservedStock
+	movement.SoldDelta = after.SoldStock - before.SoldStock
+
+	if movement.WarehouseID != uuid.Nil {
+		if err := moveWarehouseStock(tx, movement); err != nil {
+			return err
+		}
+	}
+
+	if err := insertMovement(tx, movement); err != nil {
+		return fmt.Errorf("stock movement insert error: %v", err)
+	}
+	return nil
+}
+
+func insertMovement(db execer, movement *domain.StockMovement) error {
+	if movement.ID == uuid.Nil {
+		movement.ID = uuid.New()
+	}
+	if movement.CreatedAt.IsZero() {
+		movement.CreatedAt = time.Now()
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO stock_movements (
+			id, product_id, warehouse_id, type, saga_id, order_id, reference_id,
+			stock_delta, reserved_delta, sold_delta,
+			stock_after, reserved_after, sold_after, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`,
+		movement.ID,
+		movement.ProductID,
+		nullUUID(movement.WarehouseID),
+		movement.Type,
+		nullUUID(movement.SagaID),
+		nullUUID(movement.OrderID),
+		nullUUID(movement.ReferenceID),
+		movement.StockDelta,
+		movement.ReservedDelta,
+		movement.SoldDelta,
+		movement.After.Stock,
+		movement.After.ReservedStock,
+		movement.After.SoldStock,
+		movement.CreatedAt,
+	)
+	return err
+}
+
+// GetMovements returns one page of the ledger, newest first, and the number of
+// movements matching the filter
+func (r *InventoryRepository) GetMovements(filter domain.MovementFilter) ([]*domain.StockMovement, int, error) {
+	defer metrics.ObserveDBQuery("GetMovements", time.Now())
+	filter.Normalize()
+
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	addCondition := func(column string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s $%d", column, len(args)))
+	}
+	if filter.ProductID != uuid.Nil {
+		addCondition("product_id =", filter.ProductID)
+	}
+	if filter.WarehouseID != uuid.Nil {
+		addCondition("warehouse_id =", filter.WarehouseID)
+	}
+	if filter.SagaID != uuid.Nil {
+		addCondition("saga_id =", filter.SagaID)
+	}
+	if filter.OrderID != uuid.Nil {
+		addCondition("order_id =", filter.OrderID)
+	}
+	if filter.Type != "" {
+		addCondition("type =", filter.Type)
+	}
+	if !filter.Since.IsZero() {
+		addCondition("created_at >=", filter.Since)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM stock_movements WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("stock movements count error: %v", err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset())
+	rows, err := r.db.Query(`
+		SELECT `+movementColumns+`
+		FROM stock_movements
+		WHERE `+where+`
+		ORDER BY sequence DESC
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("stock movements retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	movements := []*domain.StockMovement{}
+	for rows.Next() {
+		movement, err := scanMovement(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("stock movement scan error: %v", err)
+		}
+		movements = append(movements, movement)
+	}
+
+	return movements, total, rows.Err()
+}
+
+// VerifyLedger compares every product with the sum of its movements and returns
+// the products that differ. It runs as one statement, so it sees a consistent
+// snapshot of products and ledger.
+func (r *InventoryRepository) VerifyLedger() ([]domain.LedgerDiscrepancy, error) {
+	defer metrics.ObserveDBQuery("VerifyLedger", time.Now())
+
+	rows, err := r.db.Query(`
+		SELECT p.id, p.sku, p.stock, p.reserved_stock, p.sold_stock,
+			COALESCE(l.stock, 0), COALESCE(l.reserved_stock, 0), COALESCE(l.sold_stock, 0)
+		FROM products p
+		LEFT JOIN (
+			SELECT product_id,
+				SUM(stock_delta) AS stock,
+				SUM(reserved_delta) AS reserved_stock,
+				SUM(sold_delta) AS sold_stock
+			FROM stock_movements
+			GROUP BY product_id
+		) l ON l.product_id = p.id
+		WHERE p.stock <> COALESCE(l.stock, 0)
+			OR p.reserved_stock <> COALESCE(l.reserved_stock, 0)
+			OR p.sold_stock <> COALESCE(l.sold_stock, 0)
+		ORDER BY p.sku
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("ledger verification error: %v", err)
+	}
+	defer rows.Close()
+
+	discrepancies := []domain.LedgerDiscrepancy{}
+	for rows.Next() {
+		var d domain.LedgerDiscrepancy
+		err := rows.Scan(
+			&d.ProductID,
+			&d.SKU,
+			&d.Product.Stock,
+			&d.Product.ReservedStock,
+			&d.Product.SoldStock,
+			&d.Ledger.Stock,
+			&d.Ledger.ReservedStock,
+			&d.Ledger.SoldStock,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ledger discrepancy scan error: %v", err)
+		}
+		discrepancies = append(discrepancies, d)
+	}
+
+	return discrepancies, rows.Err()
+}
+
+func scanMovement(row rowScanner) (*domain.StockMovement, error) {
+	movement := &domain.StockMovement{}
+	var warehouseID, sagaID, orderID, referenceID uuid.NullUUID
+
+	err := row.Scan(
+		&movement.ID,
+		&movement.Sequence,
+		&movement.ProductID,
+		&warehouseID,
+		&movement.Type,
+		&sagaID,
+		&orderID,
+		&referenceID,
+		&movement.StockDelta,
+		&movement.ReservedDelta,
+		&movement.SoldDelta,
+		&movement.After.Stock,
+		&movement.After.ReservedStock,
+		&movement.After.SoldStock,
+		&movement.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	movement.WarehouseID = warehouseID.UUID
+	movement.SagaID = sagaID.UUID
+	movement.OrderID = orderID.UUID
+	movement.ReferenceID = referenceID.UUID
+	return movement, nil
+}
+
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
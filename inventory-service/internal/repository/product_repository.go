@@ -0,0 +1,481 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// productColumns selected by every product query, in scanProduct order
+const productColumns = `
+	id, name, sku, price, currency, stock, reserved_stock, sold_stock,
+	low_stock_threshold, created_at, updated_at`
+
+// uniqueViolation PostgreSQL error code of a duplicate key
+const uniqueViolation = "23505"
+
+// CreateProduct adds the product with its initial stock kept at warehouseID, the
+// default warehouse for uuid.Nil
+func (r *InventoryRepository) CreateProduct(product *domain.InventoryAggregate, warehouseID uuid.UUID) error {
+	defer metrics.ObserveDBQuery("CreateProduct", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	warehouseID, err = resolveWarehouse(tx, warehouseID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO products (
+			id, name, sku, price, currency, stock, reserved_stock, sold_stock,
+			low_stock_threshold, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`,
+		product.ID,
+		product.Name,
+		product.SKU,
+		product.Price,
+		product.Price.Currency,
+		product.Stock,
+		product.ReservedStock,
+		product.SoldStock,
+		product.LowStockThreshold,
+		product.CreatedAt,
+		product.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %s", domain.ErrDuplicateSKU, product.SKU)
+	}
+	if err != nil {
+		return fmt.Errorf("product creation error: %v", err)
+	}
+
+	levels := domain.StockLevels{
+		Stock:         product.Stock,
+		ReservedStock: product.ReservedStock,
+		SoldStock:     product.SoldStock,
+	}
+	movement := &domain.StockMovement{
+		ProductID:     product.ID,
+		WarehouseID:   warehouseID,
+		Type:          domain.MovementCreate,
+		StockDelta:    levels.Stock,
+		ReservedDelta: levels.ReservedStock,
+		SoldDelta:     levels.SoldStock,
+		After:         levels,
+		CreatedAt:     product.CreatedAt,
+	}
+	if err := moveWarehouseStock(tx, movement); err != nil {
+		return fmt.Errorf("warehouse stock error: %v", err)
+	}
+	if err := insertMovement(tx, movement); err != nil {
+		return fmt.Errorf("stock movement insert error: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit error: %v", err)
+	}
+	return nil
+}
+
+// UpdateProduct stores the catalog fields of the product; stock levels are only
+// changed by reservations and stock adjustments
+func (r *InventoryRepository) UpdateProduct(product *domain.InventoryAggregate) error {
+	defer metrics.ObserveDBQuery("UpdateProduct", time.Now())
+
+	result, err := r.db.Exec(`
+		UPDATE products
+		SET name = $2, sku = $3, price = $4, currency = $5, low_stock_threshold = $6, updated_at = $7
+		WHERE id = $1 AND deleted_at IS NULL
+	`, product.ID, product.Name, product.SKU, product.Price, product.Price.Currency,
+		product.LowStockThreshold, product.UpdatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %s", domain.ErrDuplicateSKU, product.SKU)
+	}
+	if err != nil {
+		return fmt.Errorf("product update error: %v", err)
+	}
+
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrProductNotFound, product.ID)
+	}
+	return nil
+}
+
+// DeleteProduct removes the product from the catalog. It stays in the table for
+// the reservations and sales that refer to it, and its SKU is not reused.
+func (r *InventoryRepository) DeleteProduct(productID uuid.UUID) error {
+	defer metrics.ObserveDBQuery("DeleteProduct", time.Now())
+
+	result, err := r.db.Exec(`
+		UPDATE products
+		SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND reserved_stock = 0
+	`, productID)
+	if err != nil {
+		return fmt.Errorf("product delete error: %v", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 1 {
+		return err
+	}
+
+	// Either gone or still reserved by a running saga
+	if _, err := r.GetProductByID(productID); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", domain.ErrProductInUse, productID)
+}
+
+func (r *InventoryRepository) GetProductByID(productID uuid.UUID) (*domain.InventoryAggregate, error) {
+	defer metrics.ObserveDBQuery("GetProductByID", time.Now())
+
+	product, err := scanProduct(r.db.QueryRow(`
+		SELECT `+productColumns+`
+		FROM products
+		WHERE id = $1 AND deleted_at IS NULL
+	`, productID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", domain.ErrProductNotFound, productID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("product receive error: %v", err)
+	}
+
+	return product, nil
+}
+
+// GetProductsBySKUs returns the catalog products with the given SKUs, and
+// those of the SKUs that belong to deleted products
+func (r *InventoryRepository) GetProductsBySKUs(skus []string) ([]*domain.InventoryAggregate, []string, error) {
+	defer metrics.ObserveDBQuery("GetProductsBySKUs", time.Now())
+
+	rows, err := r.db.Query(`
+		SELECT `+productColumns+`
+		FROM products
+		WHERE sku = ANY($1) AND deleted_at IS NULL
+	`, pq.Array(skus))
+	if err != nil {
+		return nil, nil, fmt.Errorf("products retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	products := []*domain.InventoryAggregate{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("product scan error: %v", err)
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var deletedSKUs []string
+	if err := r.db.QueryRow(`
+		SELECT COALESCE(array_agg(sku), '{}') FROM products WHERE sku = ANY($1) AND deleted_at IS NOT NULL
+	`, pq.Array(skus)).Scan(pq.Array(&deletedSKUs)); err != nil {
+		return nil, nil, fmt.Errorf("deleted products retrieval error: %v", err)
+	}
+
+	return products, deletedSKUs, nil
+}
+
+// ListProducts returns one page of the catalog ordered by name, and the number
+// of products matching the filter
+func (r *InventoryRepository) ListProducts(filter domain.ProductFilter) ([]*domain.InventoryAggregate, int, error) {
+	defer metrics.ObserveDBQuery("ListProducts", time.Now())
+	filter.Normalize()
+
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	if filter.Search != "" {
+		args = append(args, "%"+escapeLike(filter.Search)+"%")
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%d OR sku ILIKE $%d)", len(args), len(args)))
+	}
+	if filter.SKU != "" {
+		args = append(args, filter.SKU)
+		conditions = append(conditions, fmt.Sprintf("sku = $%d", len(args)))
+	}
+	if len(filter.IDs) > 0 {
+		ids := make([]string, len(filter.IDs))
+		for i, id := range filter.IDs {
+			ids[i] = id.String()
+		}
+		args = append(args, pq.Array(ids))
+		conditions = append(conditions, fmt.Sprintf("id = ANY($%d::uuid[])", len(args)))
+	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			conditions = append(conditions, "stock - reserved_stock > 0")
+		} else {
+			conditions = append(conditions, "stock - reserved_stock <= 0")
+		}
+	}
+	if filter.LowStock {
+		conditions = append(conditions, "stock - reserved_stock < low_stock_threshold")
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM products WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("products count error: %v", err)
+	}
+
+	offset := filter.Offset()
+	args = append(args, filter.Limit, offset)
+	rows, err := r.db.Query(`
+		SELECT `+productColumns+`
+		FROM products
+		WHERE `+where+`
+		ORDER BY name, id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("products retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	products := []*domain.InventoryAggregate{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("product scan error: %v", err)
+		}
+		products = append(products, product)
+	}
+
+	return products, total, rows.Err()
+}
+
+// AdjustStock applies a manual stock change at the adjustment's warehouse, the
+// default warehouse if it names none, together with its record. Stock can never
+// drop below what running sagas have reserved, neither in total nor at the
+// warehouse.
+func (r *InventoryRepository) AdjustStock(adjustment *domain.StockAdjustment) error {
+	defer metrics.ObserveDBQuery("AdjustStock", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	adjustment.WarehouseID, err = resolveWarehouse(tx, adjustment.WarehouseID)
+	if err != nil {
+		return err
+	}
+
+	movement := &domain.StockMovement{
+		ProductID:   adjustment.ProductID,
+		WarehouseID: adjustment.WarehouseID,
+		Type:        domain.MovementAdjust,
+		ReferenceID: adjustment.ID,
+	}
+	err = moveStock(tx, movement, `
+		UPDATE products
+		SET stock = stock + $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND stock + $2 >= reserved_stock
+	`, adjustment.ProductID, adjustment.Quantity)
+	if err == sql.ErrNoRows {
+		var stock, reserved int
+		err = tx.QueryRow(`
+			SELECT stock, reserved_stock FROM products WHERE id = $1 AND deleted_at IS NULL
+		`, adjustment.ProductID).Scan(&stock, &reserved)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", domain.ErrProductNotFound, adjustment.ProductID)
+		}
+		if err != nil {
+			return fmt.Errorf("product receive error: %v", err)
+		}
+		return fmt.Errorf("%w: stock=%d, reserved=%d, adjustment=%d",
+			domain.ErrStockBelowReserved, stock, reserved, adjustment.Quantity)
+	}
+	if errors.Is(err, domain.ErrStockBelowReserved) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("stock adjustment error: %v", err)
+	}
+	adjustment.StockAfter = movement.After.Stock
+
+	_, err = tx.Exec(`
+		INSERT INTO stock_adjustments (id, product_id, warehouse_id, quantity, reason, note, stock_after, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		adjustment.ID,
+		adjustment.ProductID,
+		adjustment.WarehouseID,
+		adjustment.Quantity,
+		adjustment.Reason,
+		adjustment.Note,
+		adjustment.StockAfter,
+		adjustment.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("stock adjustment insert error: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit error: %v", err)
+	}
+	return nil
+}
+
+// GetStockAdjustments returns the latest manual stock changes of a product, newest first
+func (r *InventoryRepository) GetStockAdjustments(productID uuid.UUID, limit int) ([]*domain.StockAdjustment, error) {
+	defer metrics.ObserveDBQuery("GetStockAdjustments", time.Now())
+
+	rows, err := r.db.Query(`
+		SELECT id, product_id, warehouse_id, quantity, reason, note, stock_after, created_at
+		FROM stock_adjustments
+		WHERE product_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, productID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("stock adjustments retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	adjustments := []*domain.StockAdjustment{}
+	for rows.Next() {
+		adjustment := &domain.StockAdjustment{}
+		var warehouseID uuid.NullUUID
+		var note sql.NullString
+		err := rows.Scan(
+			&adjustment.ID,
+			&adjustment.ProductID,
+			&warehouseID,
+			&adjustment.Quantity,
+			&adjustment.Reason,
+			&note,
+			&adjustment.StockAfter,
+			&adjustment.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("stock adjustment scan error: %v", err)
+		}
+		adjustment.WarehouseID = warehouseID.UUID
+		adjustment.Note = note.String
+		adjustments = append(adjustments, adjustment)
+	}
+
+	return adjustments, rows.Err()
+}
+
+// GetReservationsByProductID returns one page of the product's reservations,
+// newest first, and the number matching the filter
+func (r *InventoryRepository) GetReservationsByProductID(productID uuid.UUID, filter domain.ReservationFilter) ([]*domain.ReservationAggregate, int, error) {
+	defer metrics.ObserveDBQuery("GetReservationsByProductID", time.Now())
+	filter.Normalize()
+
+	where := "product_id = $1"
+	args := []interface{}{productID}
+	if filter.Status != "" {
+		where += " AND status = $2"
+		args = append(args, filter.Status)
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM inventory_reservations WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("reservations count error: %v", err)
+	}
+
+	offset := filter.Offset()
+	args = append(args, filter.Limit, offset)
+	rows, err := r.db.Query(`
+		SELECT `+reservationColumns+`
+		FROM inventory_reservations
+		WHERE `+where+`
+		ORDER BY reserved_at DESC, id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reservations retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	reservations := []*domain.ReservationAggregate{}
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("reservation scan error: %v", err)
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, total, rows.Err()
+}
+
+// GetReservationsByOrderID returns every reservation made for the order, by
+// all of its sagas, oldest first
+func (r *InventoryRepository) GetReservationsByOrderID(orderID uuid.UUID) ([]*domain.ReservationAggregate, error) {
+	defer metrics.ObserveDBQuery("GetReservationsByOrderID", time.Now())
+
+	rows, err := r.db.Query(`
+		SELECT `+reservationColumns+`
+		FROM inventory_reservations
+		WHERE order_id = $1
+		ORDER BY reserved_at, id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("reservations retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	reservations := []*domain.ReservationAggregate{}
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("reservation scan error: %v", err)
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, rows.Err()
+}
+
+func scanProduct(row rowScanner) (*domain.InventoryAggregate, error) {
+	product := &domain.InventoryAggregate{Product: &types.Product{}}
+
+	err := row.Scan(
+		&product.ID,
+		&product.Name,
+		&product.SKU,
+		&product.Price,
+		&product.Price.Currency,
+		&product.Stock,
+		&product.ReservedStock,
+		&product.SoldStock,
+		&product.LowStockThreshold,
+		&product.CreatedAt,
+		&product.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == uniqueViolation
+}
+
+// escapeLike makes LIKE wildcards in user input match literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
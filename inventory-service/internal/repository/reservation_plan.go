@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/allocation"
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// reservationPlan what ReserveItems stores, decided before anything is written
+type reservationPlan struct {
+	// Items the outcome per product, in lock order; lenient policies only
+	Items []types.ItemOutcome
+	// Allocations in lock order, so stock rows are updated in the order they were locked
+	Allocations []domain.Allocation
+}
+
+// planReservation decides what to reserve of the requested quantities from the
+// locked stock of the request. Under all-or-nothing every product must be
+// covered in full, else the plan fails and nothing is reserved. The lenient
+// policies reserve what is available and report the rest as backordered or
+// unavailable; they fail only when nothing at all is available. missing holds
+// the products that no longer exist, which lenient policies report unavailable.
+func planReservation(policy types.FulfillmentPolicy, quantities map[uuid.UUID]int, missing map[uuid.UUID]bool, request allocation.Request, strategy allocation.Strategy) (*reservationPlan, error) {
+	policy = policy.OrDefault()
+	lenient := policy != types.FulfillAllOrNothing
+	requested := lockOrder(quantities)
+	if len(requested) == 0 {
+		return nil, domain.ErrNoItems
+	}
+
+	plan := &reservationPlan{}
+	request.Items = map[uuid.UUID]int{}
+	for _, productID := range requested {
+		if !lenient && missing[productID] {
+			return nil, &domain.ReservationError{ProductID: productID, Err: domain.ErrProductNotFound}
+		}
+
+		reserved := quantities[productID]
+		if lenient {
+			reserved = 0
+			if !missing[productID] {
+				reserved = min(quantities[productID], request.Available(productID))
+			}
+
+			item := types.ItemOutcome{
+				ProductID: productID,
+				Requested: quantities[productID],
+				Reserved:  reserved,
+			}
+			if short := item.Requested - item.Reserved; short > 0 {
+				if policy == types.FulfillAllowBackorder && !missing[productID] {
+					item.Backordered = short
+				} else {
+					item.Unavailable = short
+				}
+			}
+			plan.Items = append(plan.Items, item)
+		}
+		if reserved > 0 {
+			request.Items[productID] = reserved
+		}
+	}
+
+	if lenient && len(request.Items) == 0 {
+		// Nothing is available, which fails under every policy
+		productID := requested[0]
+		if missing[productID] {
+			return nil, &domain.ReservationError{ProductID: productID, Err: domain.ErrProductNotFound}
+		}
+		return nil, &domain.ReservationError{
+			ProductID: productID,
+			Err:       fmt.Errorf("%w: available=0, requested=%d", domain.ErrInsufficientStock, quantities[productID]),
+		}
+	}
+
+	allocations, err := strategy.Allocate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Products in lock order, see lockOrder
+	sort.SliceStable(allocations, func(i, j int) bool {
+		return allocations[i].ProductID.String() < allocations[j].ProductID.String()
+	})
+	plan.Allocations = allocations
+	return plan, nil
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/allocation"
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+var (
+	productA = uuid.MustParse("aaaaaaaa-0000-0000-0000-000000000000")
+	productB = uuid.MustParse("bbbbbbbb-0000-0000-0000-000000000000")
+	productC = uuid.MustParse("cccccccc-0000-0000-0000-000000000000")
+	central  = &domain.Warehouse{ID: uuid.MustParse("00000000-0000-0000-0000-0000000000c1"), Code: "CENTRAL"}
+)
+
+// stockOf one warehouse holding the given available units per product
+func stockOf(available map[uuid.UUID]int) allocation.Request {
+	request := allocation.Request{Warehouses: map[uuid.UUID]*domain.Warehouse{central.ID: central}}
+	for productID, units := range available {
+		request.Stock = append(request.Stock, domain.WarehouseStock{
+			WarehouseID: central.ID,
+			ProductID:   productID,
+			StockLevels: domain.StockLevels{Stock: units},
+		})
+	}
+	return request
+}
+
+func TestLockOrderIsIndependentOfRequestOrder(t *testing.T) {
+	want := []uuid.UUID{productA, productB, productC}
+	for i := 0; i < 20; i++ {
+		// Map iteration order differs from run to run
+		got := lockOrder(map[uuid.UUID]int{productC: 1, productA: 1, productB: 1})
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("lockOrder = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPlanReservationAllOrNothing(t *testing.T) {
+	quantities := map[uuid.UUID]int{productA: 5, productB: 2}
+
+	t.Run("everything available", func(t *testing.T) {
+		plan, err := planReservation(types.FulfillAllOrNothing, quantities, nil,
+			stockOf(map[uuid.UUID]int{productA: 10, productB: 2}), allocation.Nearest{})
+		if err != nil {
+			t.Fatalf("plan: %v", err)
+		}
+		want := []domain.Allocation{
+			{WarehouseID: central.ID, ProductID: productA, Quantity: 5},
+			{WarehouseID: central.ID, ProductID: productB, Quantity: 2},
+		}
+		if !reflect.DeepEqual(plan.Allocations, want) || plan.Items != nil {
+			t.Errorf("plan = %+v, want allocations %+v", plan, want)
+		}
+	})
+
+	t.Run("one product short", func(t *testing.T) {
+		plan, err := planReservation(types.FulfillAllOrNothing, quantities, nil,
+			stockOf(map[uuid.UUID]int{productA: 10, productB: 1}), allocation.Nearest{})
+
+		var itemErr *domain.ReservationError
+		if !errors.As(err, &itemErr) || itemErr.ProductID != productB || !errors.Is(err, domain.ErrInsufficientStock) {
+			t.Fatalf("got %+v, %v, want insufficient stock for product B", plan, err)
+		}
+	})
+
+	t.Run("one product deleted", func(t *testing.T) {
+		_, err := planReservation(types.FulfillAllOrNothing, quantities, map[uuid.UUID]bool{productB: true},
+			stockOf(map[uuid.UUID]int{productA: 10}), allocation.Nearest{})
+
+		var itemErr *domain.ReservationError
+		if !errors.As(err, &itemErr) || itemErr.ProductID != productB || !errors.Is(err, domain.ErrProductNotFound) {
+			t.Fatalf("got %v, want product B not found", err)
+		}
+	})
+
+	t.Run("no policy is all or nothing", func(t *testing.T) {
+		_, err := planReservation("", quantities, nil,
+			stockOf(map[uuid.UUID]int{productA: 10, productB: 1}), allocation.Nearest{})
+		if !errors.Is(err, domain.ErrInsufficientStock) {
+			t.Fatalf("got %v, want insufficient stock", err)
+		}
+	})
+}
+
+func TestPlanReservationLenientPolicies(t *testing.T) {
+	quantities := map[uuid.UUID]int{productA: 5, productB: 3, productC: 2}
+	missing := map[uuid.UUID]bool{productC: true}
+	request := func() allocation.Request {
+		return stockOf(map[uuid.UUID]int{productA: 10, productB: 1})
+	}
+
+	tests := []struct {
+		policy types.FulfillmentPolicy
+		want   []types.ItemOutcome
+	}{
+		{types.FulfillAllowPartial, []types.ItemOutcome{
+			{ProductID: productA, Requested: 5, Reserved: 5},
+			{ProductID: productB, Requested: 3, Reserved: 1, Unavailable: 2},
+			{ProductID: productC, Requested: 2, Unavailable: 2},
+		}},
+		// Deleted products are never backordered
+		{types.FulfillAllowBackorder, []types.ItemOutcome{
+			{ProductID: productA, Requested: 5, Reserved: 5},
+			{ProductID: productB, Requested: 3, Reserved: 1, Backordered: 2},
+			{ProductID: productC, Requested: 2, Unavailable: 2},
+		}},
+	}
+
+	for _, tt := range tests {
+		plan, err := planReservation(tt.policy, quantities, missing, request(), allocation.Nearest{})
+		if err != nil {
+			t.Fatalf("%s: plan: %v", tt.policy, err)
+		}
+		if !reflect.DeepEqual(plan.Items, tt.want) {
+			t.Errorf("%s: items = %+v, want %+v", tt.policy, plan.Items, tt.want)
+		}
+		wantAllocations := []domain.Allocation{
+			{WarehouseID: central.ID, ProductID: productA, Quantity: 5},
+			{WarehouseID: central.ID, ProductID: productB, Quantity: 1},
+		}
+		if !reflect.DeepEqual(plan.Allocations, wantAllocations) {
+			t.Errorf("%s: allocations = %+v, want %+v", tt.policy, plan.Allocations, wantAllocations)
+		}
+	}
+}
+
+func TestPlanReservationLenientNothingAvailable(t *testing.T) {
+	for _, policy := range []types.FulfillmentPolicy{types.FulfillAllowPartial, types.FulfillAllowBackorder} {
+		_, err := planReservation(policy, map[uuid.UUID]int{productA: 1, productB: 1}, nil,
+			stockOf(map[uuid.UUID]int{}), allocation.Nearest{})
+		if !errors.Is(err, domain.ErrInsufficientStock) {
+			t.Errorf("%s: got %v, want insufficient stock", policy, err)
+		}
+
+		_, err = planReservation(policy, map[uuid.UUID]int{productA: 1}, map[uuid.UUID]bool{productA: true},
+			stockOf(map[uuid.UUID]int{}), allocation.Nearest{})
+		if !errors.Is(err, domain.ErrProductNotFound) {
+			t.Errorf("%s: got %v, want product not found", policy, err)
+		}
+	}
+}
+
+func TestPlanReservationWithoutItems(t *testing.T) {
+	for _, policy := range []types.FulfillmentPolicy{types.FulfillAllOrNothing, types.FulfillAllowPartial, types.FulfillAllowBackorder} {
+		_, err := planReservation(policy, map[uuid.UUID]int{}, nil, stockOf(map[uuid.UUID]int{productA: 1}), allocation.Nearest{})
+		if !errors.Is(err, domain.ErrNoItems) {
+			t.Errorf("%s: got %v, want no items", policy, err)
+		}
+	}
+}
+
+func TestPlanReservationAllocatesInLockOrder(t *testing.T) {
+	east := &domain.Warehouse{ID: uuid.New(), Code: "EAST"}
+	west := &domain.Warehouse{ID: uuid.New(), Code: "WEST"}
+	request := allocation.Request{
+		Warehouses: map[uuid.UUID]*domain.Warehouse{east.ID: east, west.ID: west},
+		Stock: []domain.WarehouseStock{
+			{WarehouseID: west.ID, ProductID: productC, StockLevels: domain.StockLevels{Stock: 5}},
+			{WarehouseID: east.ID, ProductID: productA, StockLevels: domain.StockLevels{Stock: 1}},
+			{WarehouseID: west.ID, ProductID: productA, StockLevels: domain.StockLevels{Stock: 5}},
+			{WarehouseID: east.ID, ProductID: productC, StockLevels: domain.StockLevels{Stock: 5}},
+		},
+	}
+
+	// FewestSplits picks warehouses, not products, one after the other
+	plan, err := planReservation(types.FulfillAllOrNothing, map[uuid.UUID]int{productC: 4, productA: 3}, nil,
+		request, allocation.FewestSplits{})
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	for i := 1; i < len(plan.Allocations); i++ {
+		if plan.Allocations[i-1].ProductID.String() > plan.Allocations[i].ProductID.String() {
+			t.Fatalf("allocations not in lock order: %+v", plan.Allocations)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// warehouseColumns selected by every warehouse query, in scanWarehouse order
+const warehouseColumns = `
+	id, code, name, street, city, state, zip_code, country,
+	shipping_cost, currency, is_default, created_at`
+
+// checkViolation PostgreSQL error code of a failed CHECK constraint
+const checkViolation = "23514"
+
+// querier is satisfied by *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (r *InventoryRepository) CreateWarehouse(warehouse *domain.Warehouse) error {
+	defer metrics.ObserveDBQuery("CreateWarehouse", time.Now())
+
+	_, err := r.db.Exec(`
+		INSERT INTO warehouses (
+			id, code, name, street, city, state, zip_code, country,
+			shipping_cost, currency, is_default, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`,
+		warehouse.ID,
+		warehouse.Code,
+		warehouse.Name,
+		warehouse.Address.Street,
+		warehouse.Address.City,
+		warehouse.Address.State,
+		warehouse.Address.ZipCode,
+		warehouse.Address.Country,
+		warehouse.ShippingCost,
+		warehouse.ShippingCost.Currency,
+		warehouse.Default,
+		warehouse.CreatedAt,
+	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %s", domain.ErrDuplicateWarehouse, warehouse.Code)
+	}
+	if err != nil {
+		return fmt.Errorf("warehouse creation error: %v", err)
+	}
+	return nil
+}
+
+func (r *InventoryRepository) GetWarehouseByID(warehouseID uuid.UUID) (*domain.Warehouse, error) {
+	defer metrics.ObserveDBQuery("GetWarehouseByID", time.Now())
+
+	warehouse, err := scanWarehouse(r.db.QueryRow(`
+		SELECT `+warehouseColumns+` FROM warehouses WHERE id = $1
+	`, warehouseID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", domain.ErrWarehouseNotFound, warehouseID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("warehouse receive error: %v", err)
+	}
+	return warehouse, nil
+}
+
+// ListWarehouses returns every warehouse ordered by code
+func (r *InventoryRepository) ListWarehouses() ([]*domain.Warehouse, error) {
+	defer metrics.ObserveDBQuery("ListWarehouses", time.Now())
+
+	return listWarehouses(r.db)
+}
+
+func listWarehouses(db querier) ([]*domain.Warehouse, error) {
+	rows, err := db.Query(`SELECT ` + warehouseColumns + ` FROM warehouses ORDER BY code`)
+	if err != nil {
+		return nil, fmt.Errorf("warehouses retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	warehouses := []*domain.Warehouse{}
+	for rows.Next() {
+		warehouse, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("warehouse scan error: %v", err)
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+
+	return warehouses, rows.Err()
+}
+
+// GetProductWarehouseStock returns the levels of a product at every warehouse
+// that ever held it
+func (r *InventoryRepository) GetProductWarehouseStock(productID uuid.UUID) ([]domain.WarehouseStock, error) {
+	defer metrics.ObserveDBQuery("GetProductWarehouseStock", time.Now())
+
+	rows, err := r.db.Query(`
+		SELECT s.warehouse_id, s.product_id, s.stock, s.reserved_stock, s.sold_stock
+		FROM warehouse_stock s
+		JOIN warehouses w ON w.id = s.warehouse_id
+		WHERE s.product_id = $1
+		ORDER BY w.code
+	`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("warehouse stock retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	return scanWarehouseStock(rows)
+}
+
+// lockWarehouseStock locks the rows of a product at every warehouse; callers
+// lock the product row first, see lockOrder
+func lockWarehouseStock(tx *sql.Tx, productID uuid.UUID) ([]domain.WarehouseStock, error) {
+	rows, err := tx.Query(`
+		SELECT warehouse_id, product_id, stock, reserved_stock, sold_stock
+		FROM warehouse_stock
+		WHERE product_id = $1
+		ORDER BY warehouse_id
+		FOR UPDATE
+	`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("warehouse stock retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	return scanWarehouseStock(rows)
+}
+
+// moveWarehouseStock applies the deltas of a movement to the stock of its
+// product at its warehouse, so the warehouses keep adding up to the product.
+// The table's checks refuse stock dropping below what is reserved there.
+func moveWarehouseStock(tx *sql.Tx, movement *domain.StockMovement) error {
+	_, err := tx.Exec(`
+		INSERT INTO warehouse_stock (warehouse_id, product_id, stock, reserved_stock, sold_stock, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (warehouse_id, product_id) DO UPDATE
+		SET stock = warehouse_stock.stock + EXCLUDED.stock,
+			reserved_stock = warehouse_stock.reserved_stock + EXCLUDED.reserved_stock,
+			sold_stock = warehouse_stock.sold_stock + EXCLUDED.sold_stock,
+			updated_at = NOW()
+	`,
+		movement.WarehouseID,
+		movement.ProductID,
+		movement.StockDelta,
+		movement.ReservedDelta,
+		movement.SoldDelta,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == checkViolation {
+		return fmt.Errorf("%w at warehouse %s", domain.ErrStockBelowReserved, movement.WarehouseID)
+	}
+	return err
+}
+
+// resolveWarehouse returns the given warehouse if it exists, or the default
+// warehouse for uuid.Nil
+func resolveWarehouse(db querier, warehouseID uuid.UUID) (uuid.UUID, error) {
+	var err error
+	if warehouseID == uuid.Nil {
+		err = db.QueryRow(`SELECT id FROM warehouses WHERE is_default`).Scan(&warehouseID)
+	} else {
+		err = db.QueryRow(`SELECT id FROM warehouses WHERE id = $1`, warehouseID).Scan(&warehouseID)
+	}
+	if err == sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("%w: %s", domain.ErrWarehouseNotFound, warehouseID)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("warehouse receive error: %v", err)
+	}
+	return warehouseID, nil
+}
+
+func scanWarehouse(row rowScanner) (*domain.Warehouse, error) {
+	warehouse := &domain.Warehouse{}
+
+	err := row.Scan(
+		&warehouse.ID,
+		&warehouse.Code,
+		&warehouse.Name,
+		&warehouse.Address.Street,
+		&warehouse.Address.City,
+		&warehouse.Address.State,
+		&warehouse.Address.ZipCode,
+		&warehouse.Address.Country,
+		&warehouse.ShippingCost,
+		&warehouse.ShippingCost.Currency,
+		&warehouse.Default,
+		&warehouse.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return warehouse, nil
+}
+
+func scanWarehouseStock(rows *sql.Rows) ([]domain.WarehouseStock, error) {
+	stock := []domain.WarehouseStock{}
+	for rows.Next() {
+		var s domain.WarehouseStock
+		if err := rows.Scan(&s.WarehouseID, &s.ProductID, &s.Stock, &s.ReservedStock, &s.SoldStock); err != nil {
+			return nil, fmt.Errorf("warehouse stock scan error: %v", err)
+		}
+		stock = append(stock, s)
+	}
+
+	return stock, rows.Err()
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/google/uuid"
+)
+
+// fillBackorders hands waiting backorders of the products to follow-up sagas
+// after stock was freed or added. The stock change itself already happened,
+// so failures are logged rather than returned; the next change retries them.
+func (s *InventoryService) fillBackorders(ctx context.Context, productIDs ...uuid.UUID) {
+	seen := map[uuid.UUID]bool{}
+	for _, productID := range productIDs {
+		if seen[productID] {
+			continue
+		}
+		seen[productID] = true
+
+		backorders, err := s.inventoryRepo.ClaimBackorders(productID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Backorders claim error", "product_id", productID, "error", err)
+			continue
+		}
+
+		for _, backorder := range backorders {
+			if err := s.publishBackorderReadyEvent(ctx, backorder); err != nil {
+				slog.ErrorContext(ctx, "Backorder ready event error", "backorder_id", backorder.ID, "error", err)
+				if err := s.inventoryRepo.ReturnBackorder(backorder.ID, backorder.SagaID); err != nil {
+					slog.ErrorContext(ctx, "Backorder return error", "backorder_id", backorder.ID, "error", err)
+				}
+			}
+		}
+	}
+}
+
+func (s *InventoryService) GetProductBackorders(productID uuid.UUID, filter domain.BackorderFilter) ([]*domain.BackorderAggregate, int, error) {
+	if _, err := s.inventoryRepo.GetProductByID(productID); err != nil {
+		return nil, 0, err
+	}
+	return s.inventoryRepo.GetBackordersByProductID(productID, filter)
+}
+
+// publishBackorderReadyEvent starts the follow-up saga of a backorder. It is
+// not a reply to a command, so the checkout saga's correlation ID is taken
+// from the backorder and keeps the order's sagas in one trail.
+func (s *InventoryService) publishBackorderReadyEvent(ctx context.Context, backorder *domain.BackorderAggregate) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:            uuid.New(),
+		SagaID:        backorder.SagaID,
+		OrderID:       backorder.OrderID,
+		EventType:     events.InventoryBackorderReadyEvent,
+		Service:       "inventory-service",
+		CorrelationID: backorder.CorrelationID,
+		Payload: events.InventoryBackorderReadyPayload{
+			Backorder:   *backorder.Backorder,
+			OrderSagaID: backorder.OrderSagaID,
+		},
+	})
+
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
+		return fmt.Errorf("inventory backorder ready event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Inventory backorder ready event published", "backorder_id", backorder.ID,
+		"product_id", backorder.ProductID, "follow_up_saga_id", backorder.SagaID, "quantity", backorder.Quantity)
+	return nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/catalog"
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+)
+
+// importStockNote marks the stock adjustments of a catalog import
+const importStockNote = "Catalog import"
+
+// ImportProducts upserts the products of a catalog file by SKU and reports the
+// diff against the catalog. A dry run only reports it, and so does a file with
+// any invalid record. Records are stored one by one through the same paths as
+// the API: stock changes become recount adjustments at the default warehouse,
+// so they are in the ledger and feed backorders and low stock alerts. A record
+// that fails to store is reported without stopping the others.
+func (s *InventoryService) ImportProducts(ctx context.Context, format catalog.Format, r io.Reader, dryRun bool) (*catalog.Report, error) {
+	records, rowErrors, err := catalog.Decode(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	skus := make([]string, len(records))
+	for i, record := range records {
+		skus[i] = record.SKU
+	}
+	products, deletedSKUs, err := s.inventoryRepo.GetProductsBySKUs(skus)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*domain.InventoryAggregate, len(products))
+	for _, product := range products {
+		existing[product.SKU] = product
+	}
+	deleted := make(map[string]bool, len(deletedSKUs))
+	for _, sku := range deletedSKUs {
+		deleted[sku] = true
+	}
+
+	report := catalog.Plan(records, rowErrors, existing, deleted)
+	report.Format = format
+	report.DryRun = dryRun
+	if dryRun || report.Summary.Invalid > 0 {
+		slog.InfoContext(ctx, "Catalog import planned", "format", format, "dry_run", dryRun,
+			"records", len(report.Items), "invalid", report.Summary.Invalid)
+		return report, nil
+	}
+
+	for i := range report.Items {
+		s.applyImportItem(ctx, &report.Items[i])
+	}
+	report.Applied = true
+	report.Summarize()
+
+	slog.InfoContext(ctx, "Catalog imported", "format", format, "created", report.Summary.Created,
+		"updated", report.Summary.Updated, "unchanged", report.Summary.Unchanged, "failed", report.Summary.Failed)
+	return report, nil
+}
+
+func (s *InventoryService) applyImportItem(ctx context.Context, item *catalog.Item) {
+	switch item.Action {
+	case catalog.ActionCreate:
+		product, err := s.CreateProduct(ctx, *item.Create)
+		if err != nil {
+			item.Fail(err)
+			return
+		}
+		item.ProductID = product.ID
+
+	case catalog.ActionUpdate:
+		if item.Update != nil {
+			if _, err := s.UpdateProduct(ctx, item.ProductID, *item.Update); err != nil {
+				item.Fail(err)
+				return
+			}
+		}
+		if item.StockDelta != 0 {
+			if _, err := s.AdjustStock(ctx, item.ProductID, domain.StockAdjustmentRequest{
+				Quantity: item.StockDelta,
+				Reason:   domain.AdjustmentRecount,
+				Note:     importStockNote,
+			}); err != nil {
+				item.Fail(err)
+			}
+		}
+	}
+}
+
+// ExportProducts writes the whole catalog, ordered by name, as a file that
+// ImportProducts reads back. It returns the number of products written.
+func (s *InventoryService) ExportProducts(format catalog.Format, w io.Writer) (int, error) {
+	var records []catalog.Record
+	filter := domain.ProductFilter{Page: 1, Limit: maxPageLimit}
+	for {
+		products, total, err := s.inventoryRepo.ListProducts(filter)
+		if err != nil {
+			return 0, err
+		}
+		for _, product := range products {
+			records = append(records, catalog.RecordFromProduct(product))
+		}
+		if filter.Page*filter.Limit >= total {
+			break
+		}
+		filter.Page++
+	}
+
+	if records == nil {
+		records = []catalog.Record{}
+	}
+	return len(records), catalog.Encode(format, w, records)
+}
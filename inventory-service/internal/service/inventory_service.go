@@ -1,9 +1,12 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/allocation"
 	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
 	"github.com/distributed-ecommerce-saga/inventory-service/internal/repository"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
@@ -15,173 +18,289 @@ import (
 type InventoryService struct {
 	inventoryRepo *repository.InventoryRepository
 	publisher     *messaging.Publisher
+	allocator     allocation.Strategy // Splits reservations over warehouses
 }
 
-func NewInventoryService(inventoryRepo *repository.InventoryRepository, publisher *messaging.Publisher) *InventoryService {
+func NewInventoryService(inventoryRepo *repository.InventoryRepository, publisher *messaging.Publisher, allocator allocation.Strategy) *InventoryService {
 	return &InventoryService{
 		inventoryRepo: inventoryRepo,
 		publisher:     publisher,
+		allocator:     allocator,
 	}
 }
 
-func (s *InventoryService) ReserveInventory(request domain.InventoryReserveRequest) error {
-	log.Printf("Inventory reserve started: OrderID=%s", request.OrderID)
+// ReserveInventory reserves the items of the order as its fulfillment policy
+// allows, taken from the warehouses the allocation strategy picks. A follow-up
+// saga that cannot reserve its backorder puts it back in the queue. Products
+// left below their low stock threshold are reported after the reply.
+func (s *InventoryService) ReserveInventory(ctx context.Context, request domain.InventoryReserveRequest) error {
+	slog.InfoContext(ctx, "Inventory reserve started", "items", len(request.Items), "strategy", s.allocator.Name())
 
-	var reservations []*domain.ReservationAggregate
+	// Kept with the reservations, so the sweeper can address the saga when they expire
+	cause, _ := events.CauseFromContext(ctx)
 
-	for _, item := range request.Items {
-		product, err := s.inventoryRepo.GetProductByID(item.ProductID)
-		if err != nil {
-			return s.publishInventoryFailedEvent(request.SagaID, request.OrderID,
-				item.ProductID, fmt.Sprintf("Product not found: %v", err))
-		}
-
-		if !product.CanReserve(item.Quantity) {
-			return s.publishInventoryFailedEvent(request.SagaID, request.OrderID,
-				item.ProductID, "Insufficient stock")
+	result, err := s.inventoryRepo.ReserveItems(request, cause.CorrelationID, s.allocator)
+	if err != nil {
+		if request.BackorderID != uuid.Nil {
+			if err := s.inventoryRepo.ReturnBackorder(request.BackorderID, request.SagaID); err != nil {
+				slog.ErrorContext(ctx, "Backorder return error", "backorder_id", request.BackorderID, "error", err)
+			}
 		}
 
-		if err := product.Reserve(item.Quantity); err != nil {
-			return s.publishInventoryFailedEvent(request.SagaID, request.OrderID,
-				item.ProductID, err.Error())
+		var itemErr *domain.ReservationError
+		if !errors.As(err, &itemErr) {
+			return s.publishInventoryFailedEvent(ctx, request.SagaID, request.OrderID,
+				uuid.Nil, fmt.Sprintf("Failed to reserve inventory: %v", err))
 		}
 
-		if err := s.inventoryRepo.UpdateProduct(product); err != nil {
-			return s.publishInventoryFailedEvent(request.SagaID, request.OrderID,
-				item.ProductID, fmt.Sprintf("Failed to update product: %v", err))
+		reason := fmt.Sprintf("Product not found: %s", itemErr.ProductID)
+		if errors.Is(itemErr, domain.ErrInsufficientStock) {
+			reason = "Insufficient stock"
 		}
+		slog.InfoContext(ctx, "Inventory reserve rejected", "product_id", itemErr.ProductID, "error", itemErr.Err)
 
-		reservation := domain.NewReservationAggregate(request.OrderID, item.ProductID, request.SagaID, item.Quantity)
-		if err := s.inventoryRepo.CreateReservation(reservation); err != nil {
-			return s.publishInventoryFailedEvent(request.SagaID, request.OrderID,
-				item.ProductID, fmt.Sprintf("Failed to create reservation: %v", err))
-		}
+		return s.publishInventoryFailedEvent(ctx, request.SagaID, request.OrderID, itemErr.ProductID, reason)
+	}
 
-		reservations = append(reservations, reservation)
+	if err := s.publishInventoryReservedEvent(ctx, request.SagaID, request.OrderID, result); err != nil {
+		return err
 	}
 
-	return s.publishInventoryReservedEvent(request.SagaID, request.OrderID, reservations)
+	s.checkLowStock(ctx, request.SagaID, request.OrderID, reservationProducts(result.Reservations)...)
+	return nil
 }
 
-func (s *InventoryService) ReleaseInventory(request domain.InventoryReleaseRequest) error {
-	log.Printf("Inventory release started: OrderID=%s", request.OrderID)
+// ReleaseInventory frees the stock of the saga's reservations. Reservations the
+// sweeper already expired have nothing left to release. The freed stock goes
+// to waiting backorders first.
+func (s *InventoryService) ReleaseInventory(ctx context.Context, request domain.InventoryReleaseRequest) error {
+	slog.InfoContext(ctx, "Inventory release started")
 
-	reservations, err := s.inventoryRepo.GetReservationsBySagaID(request.SagaID)
+	reservations, err := s.inventoryRepo.ReleaseReservations(request.SagaID)
 	if err != nil {
-		return s.publishInventoryReleaseFailedEvent(request.SagaID, request.OrderID,
-			fmt.Sprintf("Failed to get reservations: %v", err))
+		return s.publishInventoryReleaseFailedEvent(ctx, request.SagaID, request.OrderID,
+			fmt.Sprintf("Failed to release reservations: %v", err))
+	}
+	if err := s.inventoryRepo.SettleBackorder(request.SagaID, types.BackorderStatusCancelled); err != nil {
+		slog.ErrorContext(ctx, "Backorder cancel error", "error", err)
 	}
+	s.fillBackorders(ctx, reservationProducts(reservations)...)
 
-	for _, reservation := range reservations {
-		product, err := s.inventoryRepo.GetProductByID(reservation.ProductID)
-		if err != nil {
-			log.Printf("Failed to get product for release: %v", err)
-			continue
+	return s.publishInventoryReleasedEvent(ctx, request.SagaID, request.OrderID, reservations)
+}
+
+// CommitInventory sells the reservations of a completed order: the reserved
+// units leave stock for good. Reservations already sold, released or expired
+// are left alone. Products left below their low stock threshold are reported.
+func (s *InventoryService) CommitInventory(ctx context.Context, sagaID, orderID uuid.UUID) error {
+	reservations, err := s.inventoryRepo.SellReservations(sagaID)
+	if err != nil {
+		return fmt.Errorf("reservations sell error: %v", err)
+	}
+	if err := s.inventoryRepo.SettleBackorder(sagaID, types.BackorderStatusFulfilled); err != nil {
+		return err
+	}
+
+	if len(reservations) == 0 {
+		slog.WarnContext(ctx, "No reserved stock left to sell for completed order", "order_id", orderID)
+		return nil
+	}
+
+	slog.InfoContext(ctx, "Reservations sold", "order_id", orderID, "reservations", len(reservations))
+	s.checkLowStock(ctx, sagaID, orderID, reservationProducts(reservations)...)
+	return nil
+}
+
+// RestockInventory puts the items of a refund back into stock, where waiting
+// backorders get it first. A redelivered command restocks nothing twice.
+func (s *InventoryService) RestockInventory(ctx context.Context, request domain.InventoryRestockRequest) error {
+	slog.InfoContext(ctx, "Inventory restock started", "items", len(request.Items))
+
+	for _, item := range request.Items {
+		if item.ProductID == uuid.Nil || item.Quantity <= 0 {
+			return s.publishInventoryRestockFailedEvent(ctx, request.SagaID, request.OrderID,
+				fmt.Sprintf("Invalid restock item: product=%s quantity=%d", item.ProductID, item.Quantity))
 		}
 
-		product.Release(reservation.Quantity)
-		s.inventoryRepo.UpdateProduct(product)
+		restocked, err := s.inventoryRepo.RestockProduct(request.SagaID, request.OrderID, item.ProductID, item.Quantity)
+		if err != nil {
+			return s.publishInventoryRestockFailedEvent(ctx, request.SagaID, request.OrderID,
+				fmt.Sprintf("Failed to restock product %s: %v", item.ProductID, err))
+		}
+		if !restocked {
+			slog.InfoContext(ctx, "Product already restocked for saga", "product_id", item.ProductID)
+		}
+	}
 
-		reservation.Release()
-		s.inventoryRepo.UpdateReservation(reservation)
+	productIDs := make([]uuid.UUID, len(request.Items))
+	for i, item := range request.Items {
+		productIDs[i] = item.ProductID
 	}
+	s.fillBackorders(ctx, productIDs...)
+
+	return s.publishInventoryRestockedEvent(ctx, request.SagaID, request.OrderID, request.Items)
+}
 
-	return s.publishInventoryReleasedEvent(request.SagaID, request.OrderID, reservations)
+// reservationProducts the products of the reservations, once each
+func reservationProducts(reservations []*domain.ReservationAggregate) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	var productIDs []uuid.UUID
+	for _, r := range reservations {
+		if !seen[r.ProductID] {
+			seen[r.ProductID] = true
+			productIDs = append(productIDs, r.ProductID)
+		}
+	}
+	return productIDs
 }
 
-func (s *InventoryService) publishInventoryReservedEvent(sagaID, orderID uuid.UUID, reservations []*domain.ReservationAggregate) error {
+func (s *InventoryService) publishInventoryReservedEvent(ctx context.Context, sagaID, orderID uuid.UUID, result *domain.ReservationResult) error {
+	reservations := result.Reservations
 	var reservationData []types.InventoryReservation
 	for _, r := range reservations {
 		reservationData = append(reservationData, *r.InventoryReservation)
 	}
+	backorders := make([]types.Backorder, len(result.Backorders))
+	for i, b := range result.Backorders {
+		backorders[i] = *b.Backorder
+	}
 
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        sagaID,
-		OrderID:       orderID,
-		EventType:     events.InventoryReservedEvent,
-		Service:       "inventory-service",
-		CorrelationID: uuid.New(),
+	// The stock is reserved already, so a failed lookup only leaves the origins
+	// without warehouse details rather than failing the reservation
+	warehouses, err := s.inventoryRepo.ListWarehouses()
+	if err != nil {
+		slog.WarnContext(ctx, "Warehouses of the reservation not loaded", "error", err)
+	}
+	origins := domain.ShipmentOrigins(reservations, domain.WarehousesByID(warehouses))
+
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.InventoryReservedEvent,
+		Service:   "inventory-service",
 		Payload: events.InventoryReservedPayload{
 			Reservations: reservationData,
+			Origins:      origins,
+			Items:        result.Items,
+			Backorders:   backorders,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("inventory reserved event publish error: %v", err)
 	}
 
-	log.Printf("Inventory reserved event published: OrderID=%s", orderID)
+	slog.InfoContext(ctx, "Inventory reserved event published",
+		"reservations", len(reservations), "warehouses", len(origins), "backorders", len(backorders))
 	return nil
 }
 
-func (s *InventoryService) publishInventoryFailedEvent(sagaID, orderID, productID uuid.UUID, reason string) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        sagaID,
-		OrderID:       orderID,
-		EventType:     events.InventoryFailedEvent,
-		Service:       "inventory-service",
-		CorrelationID: uuid.New(),
+func (s *InventoryService) publishInventoryFailedEvent(ctx context.Context, sagaID, orderID, productID uuid.UUID, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.InventoryFailedEvent,
+		Service:   "inventory-service",
 		Payload: events.InventoryFailedPayload{
 			OrderID:   orderID,
 			ProductID: productID,
 			Reason:    reason,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("inventory failed event publish error: %v", err)
 	}
 
-	log.Printf("Inventory failed event published: OrderID=%s, Reason=%s", orderID, reason)
+	slog.InfoContext(ctx, "Inventory failed event published", "product_id", productID, "reason", reason)
 	return nil
 }
 
-func (s *InventoryService) publishInventoryReleasedEvent(sagaID, orderID uuid.UUID, reservations []*domain.ReservationAggregate) error {
+func (s *InventoryService) publishInventoryReleasedEvent(ctx context.Context, sagaID, orderID uuid.UUID, reservations []*domain.ReservationAggregate) error {
 	var reservationIDs []uuid.UUID
 	for _, r := range reservations {
 		reservationIDs = append(reservationIDs, r.ID)
 	}
 
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        sagaID,
-		OrderID:       orderID,
-		EventType:     events.InventoryReleasedEvent,
-		Service:       "inventory-service",
-		CorrelationID: uuid.New(),
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.InventoryReleasedEvent,
+		Service:   "inventory-service",
 		Payload: map[string]interface{}{
 			"order_id":        orderID,
 			"reservation_ids": reservationIDs,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("inventory released event publish error: %v", err)
 	}
 
-	log.Printf("Inventory released event published: OrderID=%s", orderID)
+	slog.InfoContext(ctx, "Inventory released event published", "reservations", len(reservations))
 	return nil
 }
 
-func (s *InventoryService) publishInventoryReleaseFailedEvent(sagaID, orderID uuid.UUID, reason string) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        sagaID,
-		OrderID:       orderID,
-		EventType:     "inventory.release.failed",
-		Service:       "inventory-service",
-		CorrelationID: uuid.New(),
+func (s *InventoryService) publishInventoryReleaseFailedEvent(ctx context.Context, sagaID, orderID uuid.UUID, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: "inventory.release.failed",
+		Service:   "inventory-service",
 		Payload: map[string]interface{}{
 			"reason": reason,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("inventory release failed event publish error: %v", err)
 	}
 
-	log.Printf("Inventory release failed event published: OrderID=%s, Reason=%s", orderID, reason)
+	slog.InfoContext(ctx, "Inventory release failed event published", "reason", reason)
+	return nil
+}
+
+func (s *InventoryService) publishInventoryRestockedEvent(ctx context.Context, sagaID, orderID uuid.UUID, items []domain.ReservationItem) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.InventoryRestockedEvent,
+		Service:   "inventory-service",
+		Payload: map[string]interface{}{
+			"order_id": orderID,
+			"items":    items,
+		},
+	})
+
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
+		return fmt.Errorf("inventory restocked event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Inventory restocked event published", "items", len(items))
+	return nil
+}
+
+func (s *InventoryService) publishInventoryRestockFailedEvent(ctx context.Context, sagaID, orderID uuid.UUID, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.InventoryRestockFailedEvent,
+		Service:   "inventory-service",
+		Payload: map[string]interface{}{
+			"reason": reason,
+		},
+	})
+
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
+		return fmt.Errorf("inventory restock failed event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Inventory restock failed event published", "reason", reason)
 	return nil
 }
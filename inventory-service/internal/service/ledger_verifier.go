@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LedgerVerifier periodically checks that the stock ledger adds up to the
+// products' stock levels, see InventoryService.VerifyLedger.
+type LedgerVerifier struct {
+	inventory *InventoryService
+	interval  time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewLedgerVerifier(inventory *InventoryService, interval time.Duration) *LedgerVerifier {
+	return &LedgerVerifier{
+		inventory: inventory,
+		interval:  interval,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (v *LedgerVerifier) Start() {
+	go func() {
+		defer close(v.done)
+
+		ticker := time.NewTicker(v.interval)
+		defer ticker.Stop()
+
+		for {
+			v.verify()
+
+			select {
+			case <-ticker.C:
+			case <-v.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the verifier loop and waits for a running check to finish
+func (v *LedgerVerifier) Stop(ctx context.Context) error {
+	close(v.stop)
+
+	select {
+	case <-v.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (v *LedgerVerifier) verify() {
+	discrepancies, err := v.inventory.VerifyLedger(context.Background())
+	if err != nil {
+		slog.Error("Stock ledger verification error", "error", err)
+		return
+	}
+
+	if len(discrepancies) > 0 {
+		slog.Warn("Stock ledger verification found discrepancies", "products", len(discrepancies))
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/google/uuid"
+)
+
+// maxPageLimit largest page ProductFilter accepts
+const maxPageLimit = 100
+
+// checkLowStock publishes a low stock event for each of the products whose
+// available stock is below its threshold, after the stock change of the saga
+// (uuid.Nil for manual changes). The change itself already happened, so
+// failures are logged rather than returned. Every check of a low product
+// publishes again; notification-service debounces the alerts.
+func (s *InventoryService) checkLowStock(ctx context.Context, sagaID, orderID uuid.UUID, productIDs ...uuid.UUID) {
+	// One page of the product list holds at most maxPageLimit products
+	for start := 0; start < len(productIDs); start += maxPageLimit {
+		batch := productIDs[start:min(start+maxPageLimit, len(productIDs))]
+
+		products, _, err := s.inventoryRepo.ListProducts(domain.ProductFilter{
+			IDs:      batch,
+			LowStock: true,
+			Limit:    len(batch),
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Low stock check error", "error", err)
+			return
+		}
+
+		for _, product := range products {
+			if err := s.publishInventoryLowStockEvent(ctx, sagaID, orderID, product); err != nil {
+				slog.ErrorContext(ctx, "Inventory low stock event error", "product_id", product.ID, "error", err)
+			}
+		}
+	}
+}
+
+// GetLowStockProducts pages the products whose available stock is below their threshold
+func (s *InventoryService) GetLowStockProducts(filter domain.ProductFilter) ([]*domain.InventoryAggregate, int, error) {
+	filter.LowStock = true
+	return s.inventoryRepo.ListProducts(filter)
+}
+
+func (s *InventoryService) publishInventoryLowStockEvent(ctx context.Context, sagaID, orderID uuid.UUID, product *domain.InventoryAggregate) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.InventoryLowStockEvent,
+		Service:   "inventory-service",
+		Payload: events.InventoryLowStockPayload{
+			ProductID:     product.ID,
+			SKU:           product.SKU,
+			Name:          product.Name,
+			Stock:         product.Stock,
+			ReservedStock: product.ReservedStock,
+			Available:     product.Available(),
+			Threshold:     product.LowStockThreshold,
+		},
+	})
+
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
+		return fmt.Errorf("inventory low stock event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Inventory low stock event published", "product_id", product.ID,
+		"sku", product.SKU, "available", product.Available(), "threshold", product.LowStockThreshold)
+	return nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/google/uuid"
+)
+
+func (s *InventoryService) CreateProduct(ctx context.Context, request domain.CreateProductRequest) (*domain.InventoryAggregate, error) {
+	product, err := domain.NewProduct(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.inventoryRepo.CreateProduct(product, request.WarehouseID); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Product created", "product_id", product.ID, "sku", product.SKU, "stock", product.Stock)
+	return product, nil
+}
+
+func (s *InventoryService) UpdateProduct(ctx context.Context, productID uuid.UUID, request domain.UpdateProductRequest) (*domain.InventoryAggregate, error) {
+	product, err := s.inventoryRepo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := product.Update(request); err != nil {
+		return nil, err
+	}
+	if err := s.inventoryRepo.UpdateProduct(product); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Product updated", "product_id", product.ID, "sku", product.SKU)
+
+	// A raised threshold may already be above the available stock
+	if request.LowStockThreshold != nil {
+		s.checkLowStock(ctx, uuid.Nil, uuid.Nil, product.ID)
+	}
+	return product, nil
+}
+
+// DeleteProduct takes the product out of the catalog; products with reserved
+// stock are refused until their sagas finish
+func (s *InventoryService) DeleteProduct(ctx context.Context, productID uuid.UUID) error {
+	if err := s.inventoryRepo.DeleteProduct(productID); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Product deleted", "product_id", productID)
+	return nil
+}
+
+func (s *InventoryService) GetProduct(productID uuid.UUID) (*domain.InventoryAggregate, error) {
+	return s.inventoryRepo.GetProductByID(productID)
+}
+
+func (s *InventoryService) ListProducts(filter domain.ProductFilter) ([]*domain.InventoryAggregate, int, error) {
+	return s.inventoryRepo.ListProducts(filter)
+}
+
+// AdjustStock changes a product's stock by hand, e.g. for a delivery or a
+// stock take, and returns the stored adjustment with the resulting stock.
+// Added stock goes to waiting backorders first; removed stock may leave the
+// product low on stock.
+func (s *InventoryService) AdjustStock(ctx context.Context, productID uuid.UUID, request domain.StockAdjustmentRequest) (*domain.StockAdjustment, error) {
+	adjustment, err := domain.NewStockAdjustment(productID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.inventoryRepo.AdjustStock(adjustment); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Stock adjusted", "product_id", productID, "warehouse_id", adjustment.WarehouseID,
+		"quantity", adjustment.Quantity, "reason", adjustment.Reason, "stock", adjustment.StockAfter)
+
+	if adjustment.Quantity > 0 {
+		s.fillBackorders(ctx, productID)
+	} else {
+		s.checkLowStock(ctx, uuid.Nil, uuid.Nil, productID)
+	}
+	return adjustment, nil
+}
+
+func (s *InventoryService) GetStockAdjustments(productID uuid.UUID, limit int) ([]*domain.StockAdjustment, error) {
+	if _, err := s.inventoryRepo.GetProductByID(productID); err != nil {
+		return nil, err
+	}
+	return s.inventoryRepo.GetStockAdjustments(productID, limit)
+}
+
+func (s *InventoryService) GetProductReservations(productID uuid.UUID, filter domain.ReservationFilter) ([]*domain.ReservationAggregate, int, error) {
+	if _, err := s.inventoryRepo.GetProductByID(productID); err != nil {
+		return nil, 0, err
+	}
+	return s.inventoryRepo.GetReservationsByProductID(productID, filter)
+}
+
+func (s *InventoryService) GetOrderReservations(orderID uuid.UUID) ([]*domain.ReservationAggregate, error) {
+	return s.inventoryRepo.GetReservationsByOrderID(orderID)
+}
+
+func (s *InventoryService) GetMovements(filter domain.MovementFilter) ([]*domain.StockMovement, int, error) {
+	return s.inventoryRepo.GetMovements(filter)
+}
+
+// VerifyLedger checks that every product's levels equal the sum of its stock
+// movements and exports the number of products that do not
+func (s *InventoryService) VerifyLedger(ctx context.Context) ([]domain.LedgerDiscrepancy, error) {
+	discrepancies, err := s.inventoryRepo.VerifyLedger()
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.InventoryLedgerDiscrepancies.Set(float64(len(discrepancies)))
+	for _, d := range discrepancies {
+		slog.ErrorContext(ctx, "Stock ledger does not match product",
+			"product_id", d.ProductID, "sku", d.SKU,
+			"stock", d.Product.Stock, "ledger_stock", d.Ledger.Stock,
+			"reserved_stock", d.Product.ReservedStock, "ledger_reserved_stock", d.Ledger.ReservedStock,
+			"sold_stock", d.Product.SoldStock, "ledger_sold_stock", d.Ledger.SoldStock)
+	}
+	return discrepancies, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/google/uuid"
+)
+
+type ReservationSweeperConfig struct {
+	Interval  time.Duration
+	BatchSize int // Reservations expired per transaction
+}
+
+// ReservationSweeper periodically releases reservations held past ExpiresAt,
+// marks them expired and tells the orchestrator, which fails the owning saga
+// if it is still running.
+type ReservationSweeper struct {
+	inventory *InventoryService
+	config    ReservationSweeperConfig
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewReservationSweeper(inventory *InventoryService, config ReservationSweeperConfig) *ReservationSweeper {
+	return &ReservationSweeper{
+		inventory: inventory,
+		config:    config,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (w *ReservationSweeper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		<-w.stop
+		cancel()
+	}()
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(w.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := w.Sweep(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Reservation sweep error", "error", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the sweeper loop; a running sweep stops after the current batch
+func (w *ReservationSweeper) Stop(ctx context.Context) error {
+	close(w.stop)
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sweep expires batches of reservations until none is left and returns how many
+// were expired. The stock is released before the event is published; should
+// publishing fail, the saga is left to the orchestrator's stuck saga alerts.
+func (w *ReservationSweeper) Sweep(ctx context.Context) (int, error) {
+	expired := 0
+	for ctx.Err() == nil {
+		reservations, err := w.inventory.inventoryRepo.ExpireReservations(time.Now(), w.config.BatchSize)
+		if err != nil {
+			return expired, err
+		}
+		expired += len(reservations)
+
+		for _, sagaReservations := range groupBySaga(reservations) {
+			if err := w.inventory.publishReservationExpiredEvent(ctx, sagaReservations); err != nil {
+				slog.ErrorContext(ctx, "Reservation expired event error",
+					"saga_instance_id", sagaReservations[0].SagaID, "error", err)
+			}
+		}
+		w.inventory.fillBackorders(ctx, reservationProducts(reservations)...)
+
+		if len(reservations) < w.config.BatchSize {
+			break
+		}
+	}
+
+	if expired > 0 {
+		slog.InfoContext(ctx, "Expired reservations released", "reservations", expired)
+	}
+	return expired, nil
+}
+
+// groupBySaga reservations of the same saga, in the order they were expired
+func groupBySaga(reservations []*domain.ReservationAggregate) [][]*domain.ReservationAggregate {
+	index := map[uuid.UUID]int{}
+	var groups [][]*domain.ReservationAggregate
+	for _, reservation := range reservations {
+		i, ok := index[reservation.SagaID]
+		if !ok {
+			i = len(groups)
+			index[reservation.SagaID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], reservation)
+	}
+	return groups
+}
+
+// publishReservationExpiredEvent is not a reply to a command, so the saga's
+// correlation ID is taken from the reservations
+func (s *InventoryService) publishReservationExpiredEvent(ctx context.Context, reservations []*domain.ReservationAggregate) error {
+	first := reservations[0]
+
+	var reservationIDs []uuid.UUID
+	for _, r := range reservations {
+		reservationIDs = append(reservationIDs, r.ID)
+	}
+
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:            uuid.New(),
+		SagaID:        first.SagaID,
+		OrderID:       first.OrderID,
+		EventType:     events.InventoryReservationExpiredEvent,
+		Service:       "inventory-service",
+		CorrelationID: first.CorrelationID,
+		Payload: map[string]interface{}{
+			"order_id":        first.OrderID,
+			"reservation_ids": reservationIDs,
+			"expires_at":      first.ExpiresAt,
+			"reason":          "Inventory reservation expired",
+		},
+	})
+
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
+		return fmt.Errorf("inventory reservation expired event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Inventory reservation expired event published",
+		"saga_instance_id", first.SagaID, "reservations", len(reservations))
+	return nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/distributed-ecommerce-saga/inventory-service/internal/domain"
+	"github.com/google/uuid"
+)
+
+func (s *InventoryService) CreateWarehouse(ctx context.Context, request domain.CreateWarehouseRequest) (*domain.Warehouse, error) {
+	warehouse, err := domain.NewWarehouse(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.inventoryRepo.CreateWarehouse(warehouse); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Warehouse created", "warehouse_id", warehouse.ID, "code", warehouse.Code)
+	return warehouse, nil
+}
+
+func (s *InventoryService) GetWarehouse(warehouseID uuid.UUID) (*domain.Warehouse, error) {
+	return s.inventoryRepo.GetWarehouseByID(warehouseID)
+}
+
+func (s *InventoryService) ListWarehouses() ([]*domain.Warehouse, error) {
+	return s.inventoryRepo.ListWarehouses()
+}
+
+// GetProductWarehouseStock returns the product's stock levels per warehouse
+func (s *InventoryService) GetProductWarehouseStock(productID uuid.UUID) ([]domain.WarehouseStock, error) {
+	if _, err := s.inventoryRepo.GetProductByID(productID); err != nil {
+		return nil, err
+	}
+	return s.inventoryRepo.GetProductWarehouseStock(productID)
+}
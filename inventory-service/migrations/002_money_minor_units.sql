@@ -0,0 +1,4 @@
+-- Prices are stored as integer minor units (cents) with an ISO 4217 currency
+ALTER TABLE products ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE products ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100)::BIGINT;
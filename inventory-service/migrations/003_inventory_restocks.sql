@@ -0,0 +1,11 @@
+-- Items returned by a refund saga, restocked once per saga and product
+CREATE TABLE IF NOT EXISTS inventory_restocks (
+    id UUID PRIMARY KEY,
+    saga_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (saga_id, product_id)
+);
+CREATE INDEX IF NOT EXISTS idx_inventory_restocks_order_id ON inventory_restocks(order_id);
@@ -0,0 +1,10 @@
+-- Reservations held past expires_at are released by the reservation sweeper and
+-- marked expired; the correlation ID lets the orchestrator accept its event
+ALTER TABLE inventory_reservations DROP CONSTRAINT IF EXISTS inventory_reservations_status_check;
+ALTER TABLE inventory_reservations ADD CONSTRAINT inventory_reservations_status_check CHECK (status IN (
+    'available', 'reserved', 'released', 'sold', 'expired'
+));
+
+ALTER TABLE inventory_reservations ADD COLUMN IF NOT EXISTS correlation_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_reservations_expires ON inventory_reservations(expires_at) WHERE status = 'reserved';
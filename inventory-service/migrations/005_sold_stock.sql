@@ -0,0 +1,3 @@
+-- Reservations of completed orders are sold: the units leave stock and are
+-- counted in sold_stock
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sold_stock INTEGER NOT NULL DEFAULT 0 CHECK (sold_stock >= 0);
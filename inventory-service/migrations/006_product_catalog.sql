@@ -0,0 +1,25 @@
+-- Products are managed through the catalog API: every product has a unique SKU,
+-- deleted products are only hidden, and manual stock changes keep their reason
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+UPDATE products SET sku = 'SKU-' || UPPER(SUBSTRING(id::TEXT, 1, 8)) WHERE sku IS NULL;
+ALTER TABLE products ALTER COLUMN sku SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products(sku);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_products_name ON products(name) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS stock_adjustments (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity <> 0),
+    reason VARCHAR(20) NOT NULL CHECK (reason IN (
+        'received', 'returned', 'damaged', 'lost', 'recount', 'correction'
+    )),
+    note TEXT,
+    stock_after INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_stock_adjustments_product_id ON stock_adjustments(product_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_reservations_product_id ON inventory_reservations(product_id, reserved_at);
+CREATE INDEX IF NOT EXISTS idx_reservations_order_id ON inventory_reservations(order_id);
@@ -0,0 +1,45 @@
+-- Append-only ledger of every change to a product's stock levels, written in the
+-- same transaction as the product update. The deltas of a product sum up to its
+-- stock, reserved_stock and sold_stock.
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id UUID PRIMARY KEY,
+    sequence BIGSERIAL NOT NULL UNIQUE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    type VARCHAR(20) NOT NULL CHECK (type IN (
+        'opening', 'create', 'reserve', 'release', 'expire', 'sell', 'adjust', 'restock'
+    )),
+    saga_id UUID,
+    order_id UUID,
+    reference_id UUID, -- Reservation or stock adjustment
+    stock_delta INTEGER NOT NULL,
+    reserved_delta INTEGER NOT NULL,
+    sold_delta INTEGER NOT NULL,
+    stock_after INTEGER NOT NULL,
+    reserved_after INTEGER NOT NULL,
+    sold_after INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_id ON stock_movements(product_id, sequence);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_saga_id ON stock_movements(saga_id) WHERE saga_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_movements_order_id ON stock_movements(order_id) WHERE order_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION stock_movements_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_stock_movements_append_only ON stock_movements;
+CREATE TRIGGER trg_stock_movements_append_only
+    BEFORE UPDATE OR DELETE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION stock_movements_append_only();
+
+-- Levels of existing products open the ledger
+INSERT INTO stock_movements (
+    id, product_id, type, stock_delta, reserved_delta, sold_delta,
+    stock_after, reserved_after, sold_after
+)
+SELECT gen_random_uuid(), p.id, 'opening', p.stock, p.reserved_stock, p.sold_stock,
+    p.stock, p.reserved_stock, p.sold_stock
+FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.product_id = p.id);
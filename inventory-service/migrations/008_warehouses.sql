@@ -0,0 +1,51 @@
+-- Stock kept per warehouse. products keeps the totals; warehouse_stock splits
+-- them by location and every stock movement changes both in one transaction.
+CREATE TABLE IF NOT EXISTS warehouses (
+    id UUID PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    street VARCHAR(255) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    state VARCHAR(100) NOT NULL DEFAULT '',
+    zip_code VARCHAR(20) NOT NULL DEFAULT '',
+    country VARCHAR(100) NOT NULL,
+    shipping_cost BIGINT NOT NULL DEFAULT 0 CHECK (shipping_cost >= 0), -- Per shipment, minor units
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE, -- Receives stock that names no warehouse
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_default ON warehouses(is_default) WHERE is_default;
+
+-- Existing stock is kept at the default warehouse
+INSERT INTO warehouses (id, code, name, street, city, state, zip_code, country, shipping_cost, is_default)
+VALUES ('660e8400-e29b-41d4-a716-446655440001', 'MAIN', 'Main Warehouse',
+    '1 Warehouse Way', 'New York', 'NY', '10001', 'USA', 500, TRUE)
+ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS warehouse_stock (
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+    product_id UUID NOT NULL REFERENCES products(id),
+    stock INTEGER NOT NULL DEFAULT 0,
+    reserved_stock INTEGER NOT NULL DEFAULT 0,
+    sold_stock INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (warehouse_id, product_id),
+    CHECK (stock >= 0 AND sold_stock >= 0 AND reserved_stock >= 0 AND reserved_stock <= stock)
+);
+CREATE INDEX IF NOT EXISTS idx_warehouse_stock_product_id ON warehouse_stock(product_id);
+
+INSERT INTO warehouse_stock (warehouse_id, product_id, stock, reserved_stock, sold_stock)
+SELECT '660e8400-e29b-41d4-a716-446655440001', id, stock, reserved_stock, sold_stock
+FROM products
+ON CONFLICT (warehouse_id, product_id) DO NOTHING;
+
+ALTER TABLE inventory_reservations ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses(id);
+UPDATE inventory_reservations
+SET warehouse_id = '660e8400-e29b-41d4-a716-446655440001'
+WHERE warehouse_id IS NULL;
+ALTER TABLE inventory_reservations ALTER COLUMN warehouse_id SET NOT NULL;
+
+-- Left empty for adjustments and movements recorded before
+ALTER TABLE stock_adjustments ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses(id);
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses(id);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_warehouse_id ON stock_movements(warehouse_id, sequence) WHERE warehouse_id IS NOT NULL;
@@ -0,0 +1,19 @@
+-- Units of allow_backorder orders that were out of stock. Waiting backorders
+-- of a product are handed to follow-up sagas oldest first as stock arrives.
+CREATE TABLE IF NOT EXISTS backorders (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    order_saga_id UUID NOT NULL,
+    correlation_id UUID,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL CHECK (status IN (
+        'waiting', 'processing', 'reserved', 'fulfilled', 'cancelled'
+    )),
+    saga_id UUID, -- Follow-up saga, set once stock arrived
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_backorders_product_id ON backorders(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_backorders_waiting ON backorders(product_id, created_at) WHERE status = 'waiting';
+CREATE INDEX IF NOT EXISTS idx_backorders_saga_id ON backorders(saga_id) WHERE saga_id IS NOT NULL;
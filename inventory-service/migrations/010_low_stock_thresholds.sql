@@ -0,0 +1,4 @@
+-- Ops are alerted when a product's available stock falls below its threshold.
+-- 0 never alerts.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS low_stock_threshold INTEGER NOT NULL DEFAULT 0
+    CHECK (low_stock_threshold >= 0);
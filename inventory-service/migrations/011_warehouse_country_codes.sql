@@ -0,0 +1,3 @@
+-- Shipping addresses carry ISO 3166-1 alpha-2 countries, which the nearest
+-- strategy compares with the warehouse's country
+UPDATE warehouses SET country = 'US' WHERE country = 'USA';
@@ -1,78 +1,108 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"os/signal"
 	"strconv"
-	"syscall"
+	"time"
 
+	"github.com/distributed-ecommerce-saga/notification-service/internal/domain"
 	"github.com/distributed-ecommerce-saga/notification-service/internal/handlers"
+	"github.com/distributed-ecommerce-saga/notification-service/internal/provider"
 	"github.com/distributed-ecommerce-saga/notification-service/internal/repository"
 	"github.com/distributed-ecommerce-saga/notification-service/internal/service"
+	"github.com/distributed-ecommerce-saga/shared-domain/lifecycle"
+	"github.com/distributed-ecommerce-saga/shared-domain/logging"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/resilience"
+	"github.com/distributed-ecommerce-saga/shared-domain/tracing"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	log.Println("🚀 Starting Notification Service...")
+	logging.Init("notification-service")
+	slog.Info("Starting Notification Service")
+
+	shutdown := lifecycle.NewManager("Notification Service", lifecycle.TimeoutFromEnv(30*time.Second))
+
+	shutdownTracing, err := tracing.Init(context.Background(), "notification-service")
+	if err != nil {
+		logging.Fatal("Tracing init error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageTelemetry, "trace exporter", shutdownTracing)
+
+	metrics.Init("notification-service")
 
 	db, err := initDatabase()
 	if err != nil {
-		log.Fatalf("Database connection error: %v", err)
+		logging.Fatal("Database connection error", "error", err)
 	}
-	defer db.Close()
+	shutdown.Register(lifecycle.StageDatabase, "postgres", func(ctx context.Context) error {
+		return db.Close()
+	})
 
 	rabbitConfig := messaging.NewRabbitMQConfig()
 	rabbitClient := messaging.NewRabbitMQClient(rabbitConfig)
 
 	if err := rabbitClient.Connect(); err != nil {
-		log.Fatalf("RabbitMQ connection error: %v", err)
+		logging.Fatal("RabbitMQ connection error", "error", err)
 	}
-	defer rabbitClient.Close()
+	shutdown.Register(lifecycle.StageBroker, "rabbitmq", func(ctx context.Context) error {
+		return rabbitClient.Close()
+	})
 
 	failureRate := getEnvFloat("NOTIFICATION_FAILURE_RATE", 0.02)
+	providerPolicy := resilience.NewPolicy("notification-provider", resilience.ConfigFromEnv("NOTIFICATION_PROVIDER", resilience.Config{
+		Timeout:       5 * time.Second,
+		Retry:         resilience.RetryConfig{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+		Breaker:       resilience.BreakerConfig{FailureThreshold: 5, OpenTimeout: 30 * time.Second},
+		MaxConcurrent: 10,
+		MaxWait:       time.Second,
+	}))
+	notificationProvider := provider.NewResilientProvider(provider.NewMockNotificationProvider(failureRate), providerPolicy)
 
 	publisher := messaging.NewPublisher(rabbitClient)
+	shutdown.Register(lifecycle.StageOutbox, "event publisher", publisher.Flush)
 	consumer := messaging.NewConsumer(rabbitClient, "notification-service-queue", "notification-service")
 
 	notificationRepo := repository.NewNotificationRepository(db)
-	notificationService := service.NewNotificationService(notificationRepo, publisher, failureRate)
+	notificationService := service.NewNotificationService(notificationRepo, publisher, notificationProvider, domain.AlertConfig{
+		Recipient: getEnvOrDefault("OPS_ALERT_RECIPIENT", "ops@example.com"),
+		Debounce:  getEnvDuration("LOW_STOCK_ALERT_DEBOUNCE", time.Hour),
+	})
 	notificationHandler := handlers.NewNotificationHandler(notificationService)
 
 	app := setupFiberApp()
 	setupRoutes(app, notificationHandler)
 
-	go func() {
-		log.Println("🐰 Starting RabbitMQ event consumption...")
-		if err := notificationHandler.StartConsuming(consumer); err != nil {
-			log.Printf("RabbitMQ consumption error: %v", err)
-		}
-	}()
+	if err := notificationHandler.StartConsuming(consumer); err != nil {
+		slog.Error("RabbitMQ consumption error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageConsumers, "rabbitmq consumer", consumer.Stop)
+	shutdown.Register(lifecycle.StageDrain, "in-flight event handlers", consumer.Drain)
+	shutdown.Register(lifecycle.StageHTTP, "fiber", app.ShutdownWithContext)
 
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+	port := getEnvOrDefault("PORT", "8005")
+	slog.Info("Notification Service listening", "port", port)
+	slog.Info("Mock Notification Provider active", "failure_rate", failureRate)
 
-		log.Println("🛑 Shutting down Notification Service...")
-		if err := app.Shutdown(); err != nil {
-			log.Printf("Shutdown error: %v", err)
+	go func() {
+		if err := app.Listen(":" + port); err != nil {
+			slog.Error("Server startup error", "error", err)
+			shutdown.Trigger()
 		}
 	}()
 
-	port := getEnvOrDefault("PORT", "8005")
-	log.Printf("🌍 Notification Service running on: http://localhost:%s", port)
-	log.Printf("📧 Mock Notification Provider active - Failure Rate: %.1f%%", failureRate*100)
-
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatalf("Server startup error: %v", err)
+	if err := shutdown.Wait(); err != nil {
+		logging.Fatal("Shutdown error", "error", err)
 	}
 }
 
@@ -100,7 +130,7 @@ func initDatabase() (*sql.DB, error) {
 		return nil, fmt.Errorf("database ping error: %v", err)
 	}
 
-	log.Printf("✅ Database connection successful: %s", dbName)
+	slog.Info("Database connection successful", "database", dbName)
 	return db, nil
 }
 
@@ -111,9 +141,8 @@ func setupFiberApp() *fiber.App {
 	})
 
 	app.Use(recover.New())
-	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${method} ${path} - ${latency}\n",
-	}))
+	app.Use(tracing.FiberMiddleware("notification-service"))
+	app.Use(logging.FiberMiddleware())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
@@ -124,6 +153,9 @@ func setupFiberApp() *fiber.App {
 }
 
 func setupRoutes(app *fiber.App, notificationHandler *handlers.NotificationHandler) {
+	// Prometheus scrape endpoint
+	app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+
 	api := app.Group("/api/v1")
 	api.Get("/health", notificationHandler.HealthCheck)
 
@@ -144,7 +176,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 		message = e.Message
 	}
 
-	log.Printf("Error: %v", err)
+	slog.ErrorContext(c.UserContext(), "Request error", "path", c.Path(), "error", err)
 
 	return c.Status(code).JSON(fiber.Map{
 		"success":   false,
@@ -169,3 +201,12 @@ func getEnvFloat(key string, defaultValue float64) float64 {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
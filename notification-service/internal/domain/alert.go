@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/google/uuid"
+)
+
+// AlertKind what an ops alert is about
+type AlertKind string
+
+const (
+	AlertKindLowStock AlertKind = "low_stock"
+)
+
+type AlertStatus string
+
+const (
+	AlertStatusPending AlertStatus = "pending"
+	AlertStatusSent    AlertStatus = "sent"
+	AlertStatusFailed  AlertStatus = "failed"
+)
+
+// AlertConfig where ops alerts go and how long repeats of an alert are held back
+type AlertConfig struct {
+	Recipient string
+	Debounce  time.Duration
+}
+
+// OpsAlert a message to the operations team rather than a customer. Repeats
+// within the debounce window are counted on the alert instead of sent.
+type OpsAlert struct {
+	ID   uuid.UUID `json:"id" db:"id"`
+	Kind AlertKind `json:"kind" db:"kind"`
+	// Key what the alert is about, e.g. the product; alerts are debounced per kind and key
+	Key        string      `json:"key" db:"alert_key"`
+	Status     AlertStatus `json:"status" db:"status"`
+	Subject    string      `json:"subject" db:"subject"`
+	Message    string      `json:"message" db:"message"`
+	Recipient  string      `json:"recipient" db:"recipient"`
+	Suppressed int         `json:"suppressed" db:"suppressed"` // Repeats held back by the debounce
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+	SentAt     *time.Time  `json:"sent_at,omitempty" db:"sent_at"`
+}
+
+// NewLowStockAlert alerts ops that a product's available stock fell below its threshold
+func NewLowStockAlert(payload events.InventoryLowStockPayload, recipient string) *OpsAlert {
+	return &OpsAlert{
+		ID:      uuid.New(),
+		Kind:    AlertKindLowStock,
+		Key:     payload.ProductID.String(),
+		Status:  AlertStatusPending,
+		Subject: fmt.Sprintf("Low stock: %s (%s)", payload.Name, payload.SKU),
+		Message: fmt.Sprintf("%s (SKU %s) has %d units available, below its threshold of %d. Stock: %d, reserved: %d.",
+			payload.Name, payload.SKU, payload.Available, payload.Threshold, payload.Stock, payload.ReservedStock),
+		Recipient: recipient,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (a *OpsAlert) MarkAsSent() {
+	a.Status = AlertStatusSent
+	now := time.Now()
+	a.SentAt = &now
+}
+
+func (a *OpsAlert) MarkAsFailed() {
+	a.Status = AlertStatusFailed
+}
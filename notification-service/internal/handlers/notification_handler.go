@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/distributed-ecommerce-saga/notification-service/internal/domain"
 	"github.com/distributed-ecommerce-saga/notification-service/internal/service"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
 	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/resilience"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
@@ -24,27 +27,36 @@ func NewNotificationHandler(notificationService *service.NotificationService) *N
 }
 
 func (h *NotificationHandler) HealthCheck(c *fiber.Ctx) error {
-	return sharedHTTP.SuccessResponse(c, "Notification service is healthy", map[string]interface{}{
-		"service": "notification-service",
-		"status":  "healthy",
+	// An open provider circuit degrades the service but it still answers
+	status := "healthy"
+	if !resilience.Healthy() {
+		status = "degraded"
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Notification service is "+status, map[string]interface{}{
+		"service":          "notification-service",
+		"status":           status,
+		"circuit_breakers": resilience.BreakerStatuses(),
 	})
 }
 
-func (h *NotificationHandler) HandleSagaEvent(event events.SagaEvent) error {
-	log.Printf("Notification service saga event received: %s from %s",
-		event.EventType, event.Service)
+func (h *NotificationHandler) HandleSagaEvent(ctx context.Context, event events.SagaEvent) error {
+	slog.DebugContext(ctx, "Notification service saga event received", "from", event.Service)
 
 	switch event.EventType {
 	case "notification.send":
-		return h.handleNotificationSendCommand(event)
+		return h.handleNotificationSendCommand(ctx, event)
+
+	case events.InventoryLowStockEvent:
+		return h.handleLowStockEvent(ctx, event)
 
 	default:
-		log.Printf("Unhandled event type: %s", event.EventType)
+		slog.WarnContext(ctx, "Unhandled event type")
 		return nil
 	}
 }
 
-func (h *NotificationHandler) handleNotificationSendCommand(event events.SagaEvent) error {
+func (h *NotificationHandler) handleNotificationSendCommand(ctx context.Context, event events.SagaEvent) error {
 	payloadMap, ok := event.Payload.(map[string]interface{})
 	if !ok {
 		return h.logAndReturnError("Invalid payload format for notification.send", event)
@@ -55,8 +67,26 @@ func (h *NotificationHandler) handleNotificationSendCommand(event events.SagaEve
 		return h.logAndReturnError(fmt.Sprintf("Payload mapping error: %v", err), event)
 	}
 
-	if err := h.notificationService.SendNotification(request); err != nil {
-		log.Printf("Notification send error: %v", err)
+	if err := h.notificationService.SendNotification(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Notification send error", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (h *NotificationHandler) handleLowStockEvent(ctx context.Context, event events.SagaEvent) error {
+	data, err := json.Marshal(event.Payload)
+	if err != nil {
+		return h.logAndReturnError(fmt.Sprintf("Payload marshal error: %v", err), event)
+	}
+	var payload events.InventoryLowStockPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return h.logAndReturnError(fmt.Sprintf("Payload mapping error: %v", err), event)
+	}
+
+	if err := h.notificationService.SendLowStockAlert(ctx, payload); err != nil {
+		slog.ErrorContext(ctx, "Low stock alert error", "product_id", payload.ProductID, "error", err)
 		return err
 	}
 
@@ -104,13 +134,14 @@ func (h *NotificationHandler) mapToNotificationSendRequest(sagaID uuid.UUID, pay
 }
 
 func (h *NotificationHandler) logAndReturnError(message string, event events.SagaEvent) error {
-	log.Printf("%s - Event: %+v", message, event)
+	slog.Error(message, "event_id", event.ID, "event_type", event.EventType, "saga_id", event.SagaID)
 	return fmt.Errorf(message)
 }
 
 func (h *NotificationHandler) StartConsuming(consumer *messaging.Consumer) error {
 	routingKeys := []string{
 		"saga.saga-orchestrator.notification.send",
+		"saga.inventory-service.inventory.low_stock", // Ops alert
 	}
 
 	return consumer.ConsumeEvents(routingKeys, h.HandleSagaEvent)
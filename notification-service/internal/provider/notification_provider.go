@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/resilience"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+var ErrProviderUnavailable = errors.New("notification provider unavailable")
+
+// NotificationProvider external email, SMS and push delivery API
+type NotificationProvider interface {
+	Send(ctx context.Context, message Message) error
+}
+
+type Message struct {
+	Type      types.NotificationType `json:"type"`
+	Recipient string                 `json:"recipient"`
+	Subject   string                 `json:"subject"`
+	Body      string                 `json:"body"`
+}
+
+// MockNotificationProvider mock delivery provider for test
+type MockNotificationProvider struct {
+	FailureRate float64 // 0.0 - 1.0 arası hata oranı
+}
+
+func NewMockNotificationProvider(failureRate float64) *MockNotificationProvider {
+	return &MockNotificationProvider{FailureRate: failureRate}
+}
+
+func (m *MockNotificationProvider) Send(ctx context.Context, message Message) error {
+	timer := time.NewTimer(time.Millisecond * 200)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if rand.Float64() < m.FailureRate {
+		return ErrProviderUnavailable
+	}
+
+	slog.InfoContext(ctx, "Mock notification sent",
+		"type", message.Type, "recipient", message.Recipient, "subject", message.Subject)
+	return nil
+}
+
+// ResilientProvider runs delivery calls through a resilience policy
+type ResilientProvider struct {
+	next   NotificationProvider
+	policy *resilience.Policy
+}
+
+func NewResilientProvider(next NotificationProvider, policy *resilience.Policy) *ResilientProvider {
+	return &ResilientProvider{next: next, policy: policy}
+}
+
+func (p *ResilientProvider) Send(ctx context.Context, message Message) error {
+	return p.policy.Execute(ctx, func(ctx context.Context) error {
+		return p.next.Send(ctx, message)
+	})
+}
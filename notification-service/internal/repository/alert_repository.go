@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/notification-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+)
+
+// ClaimAlert stores the alert and opens its debounce window, unless an alert of
+// the same kind and key opened one that is still open. The repeat is then only
+// counted on that alert. It reports whether the alert is to be sent.
+func (r *NotificationRepository) ClaimAlert(alert *domain.OpsAlert, debounce time.Duration) (bool, error) {
+	defer metrics.ObserveDBQuery("ClaimAlert", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO ops_alerts (
+			id, kind, alert_key, status, subject, message, recipient, suppressed, created_at, sent_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`,
+		alert.ID,
+		alert.Kind,
+		alert.Key,
+		alert.Status,
+		alert.Subject,
+		alert.Message,
+		alert.Recipient,
+		alert.Suppressed,
+		alert.CreatedAt,
+		alert.SentAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("alert insert error: %v", err)
+	}
+
+	// Concurrent repeats conflict on the window row; only one of them takes it over
+	var windowAlertID string
+	err = tx.QueryRow(`
+		INSERT INTO ops_alert_windows (kind, alert_key, alert_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (kind, alert_key) DO UPDATE
+		SET alert_id = EXCLUDED.alert_id, expires_at = EXCLUDED.expires_at
+		WHERE ops_alert_windows.expires_at <= $5
+		RETURNING alert_id
+	`, alert.Kind, alert.Key, alert.ID, alert.CreatedAt.Add(debounce), alert.CreatedAt).Scan(&windowAlertID)
+	if err == sql.ErrNoRows {
+		// The repeat is not kept, only counted on the alert that opened the window
+		tx.Rollback()
+		if _, err := r.db.Exec(`
+			UPDATE ops_alerts SET suppressed = suppressed + 1
+			WHERE id = (SELECT alert_id FROM ops_alert_windows WHERE kind = $1 AND alert_key = $2)
+		`, alert.Kind, alert.Key); err != nil {
+			return false, fmt.Errorf("alert suppress error: %v", err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("alert window error: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("transaction commit error: %v", err)
+	}
+	return true, nil
+}
+
+// UpdateAlert stores the outcome of sending the alert. A failed alert closes
+// its debounce window, so the retry is not held back by it.
+func (r *NotificationRepository) UpdateAlert(alert *domain.OpsAlert) error {
+	defer metrics.ObserveDBQuery("UpdateAlert", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE ops_alerts SET status = $2, sent_at = $3 WHERE id = $1
+	`, alert.ID, alert.Status, alert.SentAt); err != nil {
+		return fmt.Errorf("alert update error: %v", err)
+	}
+
+	if alert.Status == domain.AlertStatusFailed {
+		if _, err := tx.Exec(`
+			DELETE FROM ops_alert_windows WHERE kind = $1 AND alert_key = $2 AND alert_id = $3
+		`, alert.Kind, alert.Key, alert.ID); err != nil {
+			return fmt.Errorf("alert window close error: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit error: %v", err)
+	}
+	return nil
+}
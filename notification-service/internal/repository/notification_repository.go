@@ -2,8 +2,10 @@ package repository
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/distributed-ecommerce-saga/notification-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
@@ -18,6 +20,8 @@ func NewNotificationRepository(db *sql.DB) *NotificationRepository {
 }
 
 func (r *NotificationRepository) CreateNotification(notification *domain.NotificationAggregate) error {
+	defer metrics.ObserveDBQuery("CreateNotification", time.Now())
+
 	query := `
 		INSERT INTO notifications (
 			id, order_id, customer_id, saga_id, type, status, 
@@ -44,6 +48,8 @@ func (r *NotificationRepository) CreateNotification(notification *domain.Notific
 }
 
 func (r *NotificationRepository) UpdateNotification(notification *domain.NotificationAggregate) error {
+	defer metrics.ObserveDBQuery("UpdateNotification", time.Now())
+
 	query := `
 		UPDATE notifications 
 		SET status = $2, sent_at = $3
@@ -55,6 +61,8 @@ func (r *NotificationRepository) UpdateNotification(notification *domain.Notific
 }
 
 func (r *NotificationRepository) GetNotificationsByOrderID(orderID uuid.UUID) ([]*domain.NotificationAggregate, error) {
+	defer metrics.ObserveDBQuery("GetNotificationsByOrderID", time.Now())
+
 	query := `
 		SELECT id, order_id, customer_id, saga_id, type, status,
 			   subject, message, recipient, created_at, sent_at
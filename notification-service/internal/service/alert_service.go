@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/distributed-ecommerce-saga/notification-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/notification-service/internal/provider"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+// SendLowStockAlert tells ops that a product runs low on stock. Inventory
+// reports a low product after every reservation or sale, so repeats for the
+// product within the debounce window are only counted on the first alert. A
+// failed send is returned for the event to be retried.
+func (s *NotificationService) SendLowStockAlert(ctx context.Context, payload events.InventoryLowStockPayload) error {
+	alert := domain.NewLowStockAlert(payload, s.alerts.Recipient)
+
+	send, err := s.notificationRepo.ClaimAlert(alert, s.alerts.Debounce)
+	if err != nil {
+		return err
+	}
+	if !send {
+		slog.DebugContext(ctx, "Low stock alert debounced", "product_id", payload.ProductID, "available", payload.Available)
+		return nil
+	}
+
+	if err := s.notificationProvider.Send(ctx, provider.Message{
+		Type:      types.NotificationTypeEmail,
+		Recipient: alert.Recipient,
+		Subject:   alert.Subject,
+		Body:      alert.Message,
+	}); err != nil {
+		alert.MarkAsFailed()
+		if err := s.notificationRepo.UpdateAlert(alert); err != nil {
+			slog.ErrorContext(ctx, "Alert status update error", "alert_id", alert.ID, "error", err)
+		}
+		return fmt.Errorf("low stock alert provider error: %v", err)
+	}
+
+	alert.MarkAsSent()
+	if err := s.notificationRepo.UpdateAlert(alert); err != nil {
+		slog.ErrorContext(ctx, "Alert status update error", "alert_id", alert.ID, "error", err)
+	}
+
+	slog.InfoContext(ctx, "Low stock alert sent", "product_id", payload.ProductID, "sku", payload.SKU,
+		"available", payload.Available, "threshold", payload.Threshold)
+	return nil
+}
@@ -1,12 +1,12 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"math/rand"
-	"time"
+	"log/slog"
 
 	"github.com/distributed-ecommerce-saga/notification-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/notification-service/internal/provider"
 	"github.com/distributed-ecommerce-saga/notification-service/internal/repository"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
@@ -15,21 +15,23 @@ import (
 )
 
 type NotificationService struct {
-	notificationRepo *repository.NotificationRepository
-	publisher        *messaging.Publisher
-	failureRate      float64
+	notificationRepo     *repository.NotificationRepository
+	publisher            *messaging.Publisher
+	notificationProvider provider.NotificationProvider
+	alerts               domain.AlertConfig // Ops alerts, e.g. low stock
 }
 
-func NewNotificationService(notificationRepo *repository.NotificationRepository, publisher *messaging.Publisher, failureRate float64) *NotificationService {
+func NewNotificationService(notificationRepo *repository.NotificationRepository, publisher *messaging.Publisher, notificationProvider provider.NotificationProvider, alerts domain.AlertConfig) *NotificationService {
 	return &NotificationService{
-		notificationRepo: notificationRepo,
-		publisher:        publisher,
-		failureRate:      failureRate,
+		notificationRepo:     notificationRepo,
+		publisher:            publisher,
+		notificationProvider: notificationProvider,
+		alerts:               alerts,
 	}
 }
 
-func (s *NotificationService) SendNotification(request domain.NotificationSendRequest) error {
-	log.Printf("Notification send started: OrderID=%s, Type=%s", request.OrderID, request.Type)
+func (s *NotificationService) SendNotification(ctx context.Context, request domain.NotificationSendRequest) error {
+	slog.InfoContext(ctx, "Notification send started", "type", request.Type)
 
 	notificationType := types.NotificationTypeEmail
 	switch request.Type {
@@ -52,76 +54,73 @@ func (s *NotificationService) SendNotification(request domain.NotificationSendRe
 	)
 
 	if err := s.notificationRepo.CreateNotification(notification); err != nil {
-		return s.publishNotificationFailedEvent(request.SagaID, request.OrderID,
+		return s.publishNotificationFailedEvent(ctx, request.SagaID, request.OrderID,
 			fmt.Sprintf("Failed to create notification: %v", err))
 	}
 
-	time.Sleep(time.Millisecond * 200)
-
-	if rand.Float64() < s.failureRate {
+	if err := s.notificationProvider.Send(ctx, provider.Message{
+		Type:      notificationType,
+		Recipient: request.Recipient,
+		Subject:   request.Subject,
+		Body:      request.Message,
+	}); err != nil {
 		notification.MarkAsFailed()
 		s.notificationRepo.UpdateNotification(notification)
 
-		return s.publishNotificationFailedEvent(request.SagaID, request.OrderID,
-			"Notification provider unavailable")
+		return s.publishNotificationFailedEvent(ctx, request.SagaID, request.OrderID,
+			fmt.Sprintf("Notification provider error: %v", err))
 	}
 
 	notification.MarkAsSent()
 
 	if err := s.notificationRepo.UpdateNotification(notification); err != nil {
-		log.Printf("Notification status update error: %v", err)
+		slog.ErrorContext(ctx, "Notification status update error", "error", err)
 	}
 
-	log.Printf("Mock notification sent: Type=%s, Recipient=%s, Subject=%s",
-		request.Type, request.Recipient, request.Subject)
-
-	return s.publishNotificationSentEvent(notification)
+	return s.publishNotificationSentEvent(ctx, notification)
 }
 
 func (s *NotificationService) GetNotificationsByOrderID(orderID uuid.UUID) ([]*domain.NotificationAggregate, error) {
 	return s.notificationRepo.GetNotificationsByOrderID(orderID)
 }
 
-func (s *NotificationService) publishNotificationSentEvent(notification *domain.NotificationAggregate) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        notification.SagaID,
-		OrderID:       notification.OrderID,
-		EventType:     events.NotificationSentEvent,
-		Service:       "notification-service",
-		CorrelationID: uuid.New(),
+func (s *NotificationService) publishNotificationSentEvent(ctx context.Context, notification *domain.NotificationAggregate) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    notification.SagaID,
+		OrderID:   notification.OrderID,
+		EventType: events.NotificationSentEvent,
+		Service:   "notification-service",
 		Payload: events.NotificationSentPayload{
 			Notification: *notification.Notification,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("notification sent event publish error: %v", err)
 	}
 
-	log.Printf("Notification sent event published: OrderID=%s, Type=%s",
-		notification.OrderID, notification.Type)
+	slog.InfoContext(ctx, "Notification sent event published", "type", notification.Type)
 	return nil
 }
 
-func (s *NotificationService) publishNotificationFailedEvent(sagaID, orderID uuid.UUID, reason string) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        sagaID,
-		OrderID:       orderID,
-		EventType:     events.NotificationFailedEvent,
-		Service:       "notification-service",
-		CorrelationID: uuid.New(),
+func (s *NotificationService) publishNotificationFailedEvent(ctx context.Context, sagaID, orderID uuid.UUID, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.NotificationFailedEvent,
+		Service:   "notification-service",
 		Payload: events.NotificationFailedPayload{
 			OrderID: orderID,
 			Reason:  reason,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("notification failed event publish error: %v", err)
 	}
 
-	log.Printf("Notification failed event published: OrderID=%s, Reason=%s", orderID, reason)
+	slog.InfoContext(ctx, "Notification failed event published", "reason", reason)
 	return nil
 }
@@ -0,0 +1,25 @@
+-- Alerts to the operations team, e.g. products running low on stock. Repeats
+-- within the debounce window are counted on the alert instead of sent.
+CREATE TABLE IF NOT EXISTS ops_alerts (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(30) NOT NULL CHECK (kind IN ('low_stock')),
+    alert_key VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'sent', 'failed')),
+    subject VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    suppressed INTEGER NOT NULL DEFAULT 0 CHECK (suppressed >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ops_alerts_kind_key ON ops_alerts(kind, alert_key, created_at);
+
+-- The debounce window of each kind and key, with the alert that opened it
+CREATE TABLE IF NOT EXISTS ops_alert_windows (
+    kind VARCHAR(30) NOT NULL,
+    alert_key VARCHAR(100) NOT NULL,
+    alert_id UUID NOT NULL REFERENCES ops_alerts(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (kind, alert_key)
+);
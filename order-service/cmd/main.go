@@ -1,49 +1,74 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
+	"github.com/distributed-ecommerce-saga/order-service/internal/catalog"
 	"github.com/distributed-ecommerce-saga/order-service/internal/handlers"
 	"github.com/distributed-ecommerce-saga/order-service/internal/repository"
 	"github.com/distributed-ecommerce-saga/order-service/internal/service"
+	"github.com/distributed-ecommerce-saga/shared-domain/lifecycle"
+	"github.com/distributed-ecommerce-saga/shared-domain/logging"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/tracing"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	log.Println("🚀 Order Service starting...")
+	logging.Init("order-service")
+	slog.Info("Order Service starting")
+
+	shutdown := lifecycle.NewManager("Order Service", lifecycle.TimeoutFromEnv(30*time.Second))
+
+	shutdownTracing, err := tracing.Init(context.Background(), "order-service")
+	if err != nil {
+		logging.Fatal("Tracing init error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageTelemetry, "trace exporter", shutdownTracing)
+
+	metrics.Init("order-service")
 
 	// Database connection
 	db, err := initDatabase()
 	if err != nil {
-		log.Fatalf("Database connection error: %v", err)
+		logging.Fatal("Database connection error", "error", err)
 	}
-	defer db.Close()
+	shutdown.Register(lifecycle.StageDatabase, "postgres", func(ctx context.Context) error {
+		return db.Close()
+	})
 
 	// RabbitMQ connection
 	rabbitConfig := messaging.NewRabbitMQConfig()
 	rabbitClient := messaging.NewRabbitMQClient(rabbitConfig)
 
 	if err := rabbitClient.Connect(); err != nil {
-		log.Fatalf("RabbitMQ connection error: %v", err)
+		logging.Fatal("RabbitMQ connection error", "error", err)
 	}
-	defer rabbitClient.Close()
+	shutdown.Register(lifecycle.StageBroker, "rabbitmq", func(ctx context.Context) error {
+		return rabbitClient.Close()
+	})
 
 	// Dependencies injection
 	publisher := messaging.NewPublisher(rabbitClient)
+	shutdown.Register(lifecycle.StageOutbox, "event publisher", publisher.Flush)
 	consumer := messaging.NewConsumer(rabbitClient, "order-service-queue", "order-service")
 
 	orderRepo := repository.NewOrderRepository(db)
-	orderService := service.NewOrderService(orderRepo, publisher)
+	catalogClient := catalog.NewClient(catalog.Config{
+		BaseURL: getEnvOrDefault("INVENTORY_SERVICE_URL", "http://localhost:8003"),
+		Timeout: getEnvDuration("CATALOG_TIMEOUT", 5*time.Second),
+	})
+	orderService := service.NewOrderService(orderRepo, publisher, catalogClient)
 	orderHandler := handlers.NewOrderHandler(orderService)
 
 	// Fiber app setup
@@ -53,29 +78,25 @@ func main() {
 	setupRoutes(app, orderHandler)
 
 	// RabbitMQ event consumption start
-	go func() {
-		if err := orderHandler.StartConsuming(consumer); err != nil {
-			log.Printf("RabbitMQ consumption error: %v", err)
-		}
-	}()
+	if err := orderHandler.StartConsuming(consumer); err != nil {
+		slog.Error("RabbitMQ consumption error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageConsumers, "rabbitmq consumer", consumer.Stop)
+	shutdown.Register(lifecycle.StageDrain, "in-flight event handlers", consumer.Drain)
+	shutdown.Register(lifecycle.StageHTTP, "fiber", app.ShutdownWithContext)
 
-	// Graceful shutdown setup
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+	port := getEnvOrDefault("PORT", "8001")
+	slog.Info("Order Service listening", "port", port)
 
-		log.Println("🛑 Order Service closing...")
-		if err := app.Shutdown(); err != nil {
-			log.Printf("Shutdown error: %v", err)
+	go func() {
+		if err := app.Listen(":" + port); err != nil {
+			slog.Error("Server startup error", "error", err)
+			shutdown.Trigger()
 		}
 	}()
 
-	port := getEnvOrDefault("PORT", "8001")
-	log.Printf("🌍 Order Service working: http://localhost:%s", port)
-
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatalf("Server startup error: %v", err)
+	if err := shutdown.Wait(); err != nil {
+		logging.Fatal("Shutdown error", "error", err)
 	}
 }
 
@@ -101,7 +122,7 @@ func initDatabase() (*sql.DB, error) {
 		return nil, fmt.Errorf("database ping error: %v", err)
 	}
 
-	log.Printf("✅ Database connection successful: %s", dbName)
+	slog.Info("Database connection successful", "database", dbName)
 	return db, nil
 }
 
@@ -113,9 +134,8 @@ func setupFiberApp() *fiber.App {
 
 	// Middlewares
 	app.Use(recover.New())
-	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${method} ${path} - ${latency}\n",
-	}))
+	app.Use(tracing.FiberMiddleware("order-service"))
+	app.Use(logging.FiberMiddleware())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
@@ -126,6 +146,9 @@ func setupFiberApp() *fiber.App {
 }
 
 func setupRoutes(app *fiber.App, orderHandler *handlers.OrderHandler) {
+	// Prometheus scrape endpoint
+	app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+
 	// API v1 routes
 	api := app.Group("/api/v1")
 
@@ -134,8 +157,10 @@ func setupRoutes(app *fiber.App, orderHandler *handlers.OrderHandler) {
 
 	// Order routes
 	orders := api.Group("/orders")
-	orders.Post("/", orderHandler.CreateOrder)    // POST /api/v1/orders
-	orders.Get("/:id", orderHandler.GetOrderByID) // GET /api/v1/orders/:id
+	orders.Post("/", orderHandler.CreateOrder)              // POST /api/v1/orders
+	orders.Get("/:id", orderHandler.GetOrderByID)           // GET /api/v1/orders/:id
+	orders.Post("/:id/refunds", orderHandler.RequestRefund) // POST /api/v1/orders/:id/refunds
+	orders.Get("/:id/refunds", orderHandler.GetRefunds)     // GET /api/v1/orders/:id/refunds
 
 	// Customer routes
 	customers := api.Group("/customers")
@@ -159,7 +184,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 		message = e.Message
 	}
 
-	log.Printf("Error: %v", err)
+	slog.ErrorContext(c.UserContext(), "Request error", "path", c.Path(), "error", err)
 
 	return c.Status(code).JSON(fiber.Map{
 		"success":   false,
@@ -174,3 +199,12 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,108 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/order-service/internal/domain"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTimeout   = 5 * time.Second
+	maxResponseBytes = 1 << 20
+	// pageLimit the most products inventory-service returns per page
+	pageLimit = 100
+)
+
+type Config struct {
+	BaseURL string        // e.g. http://inventory-service:8003
+	Timeout time.Duration // Per request, including reading the body
+}
+
+// Client reads product prices from inventory-service, the owner of the
+// catalog. Orders are priced synchronously so a price change applies to the
+// next order at once, rather than after a replicated copy catches up.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewClient(config Config) *Client {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Client{
+		baseURL: strings.TrimRight(config.BaseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// productsResponse the envelope of GET /api/v1/products
+type productsResponse struct {
+	Data struct {
+		Products []domain.CatalogProduct `json:"products"`
+	} `json:"data"`
+}
+
+// Products looks up the given products. Products the catalog does not list,
+// including deleted ones, are missing from the result. Failing to reach the
+// catalog is reported as domain.ErrCatalogUnavailable.
+func (c *Client) Products(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]domain.CatalogProduct, error) {
+	products := make(map[uuid.UUID]domain.CatalogProduct, len(productIDs))
+	for start := 0; start < len(productIDs); start += pageLimit {
+		page, err := c.fetch(ctx, productIDs[start:min(start+pageLimit, len(productIDs))])
+		if err != nil {
+			return nil, err
+		}
+		for _, product := range page {
+			products[product.ID] = product
+		}
+	}
+	return products, nil
+}
+
+func (c *Client) fetch(ctx context.Context, productIDs []uuid.UUID) ([]domain.CatalogProduct, error) {
+	ids := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = id.String()
+	}
+	query := url.Values{
+		"ids":   {strings.Join(ids, ",")},
+		"limit": {fmt.Sprint(pageLimit)},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/products?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("catalog request error: %v", err)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrCatalogUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrCatalogUnavailable, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: inventory-service answered %d", domain.ErrCatalogUnavailable, response.StatusCode)
+	}
+
+	var body productsResponse
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("catalog response decode error: %v", err)
+	}
+	return body.Data.Products, nil
+}
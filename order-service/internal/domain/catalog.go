@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnknownProduct the item names a product the catalog does not list
+	ErrUnknownProduct = errors.New("unknown product")
+	// ErrStalePrice the client sent a price the catalog no longer asks
+	ErrStalePrice = errors.New("price does not match the catalog")
+	// ErrCatalogUnavailable the catalog could not be asked, the order can be retried
+	ErrCatalogUnavailable = errors.New("catalog unavailable")
+)
+
+// CatalogProduct the catalog fields an order item is priced from
+type CatalogProduct struct {
+	ID    uuid.UUID   `json:"id"`
+	SKU   string      `json:"sku"`
+	Name  string      `json:"name"`
+	Price types.Money `json:"price"`
+}
+
+// PriceError an item that cannot be priced from the catalog
+type PriceError struct {
+	ItemIndex    int
+	ProductID    uuid.UUID
+	Price        types.Money // Sent by the client
+	CatalogPrice types.Money // Zero for unknown products
+	Err          error
+}
+
+func (e *PriceError) Error() string {
+	if errors.Is(e.Err, ErrStalePrice) {
+		return fmt.Sprintf("item %d: %v: product %s costs %s, not %s",
+			e.ItemIndex, e.Err, e.ProductID, e.CatalogPrice, e.Price)
+	}
+	return fmt.Sprintf("item %d: %v: %s", e.ItemIndex, e.Err, e.ProductID)
+}
+
+func (e *PriceError) Unwrap() error {
+	return e.Err
+}
+
+// ProductIDs of the requested items, each once
+func (r CreateOrderRequest) ProductIDs() []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(r.Items))
+	productIDs := make([]uuid.UUID, 0, len(r.Items))
+	for _, item := range r.Items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+	return productIDs
+}
+
+// PriceItems converts the items to the domain model at catalog prices. The
+// client price is optional; one that is sent must equal the catalog price, so
+// a customer never pays a price other than the one they were shown. A client
+// price without a currency is read in the order currency, see priceIn.
+func (r CreateOrderRequest) PriceItems(catalog map[uuid.UUID]CatalogProduct) ([]types.OrderItem, error) {
+	items := make([]types.OrderItem, len(r.Items))
+	for i, item := range r.Items {
+		product, ok := catalog[item.ProductID]
+		if !ok {
+			return nil, &PriceError{ItemIndex: i, ProductID: item.ProductID, Err: ErrUnknownProduct}
+		}
+		if item.HasPrice() {
+			price, err := r.priceIn(item, product)
+			if err != nil {
+				return nil, &PriceError{ItemIndex: i, ProductID: item.ProductID, Err: err}
+			}
+			if price != product.Price {
+				return nil, &PriceError{
+					ItemIndex:    i,
+					ProductID:    item.ProductID,
+					Price:        price,
+					CatalogPrice: product.Price,
+					Err:          ErrStalePrice,
+				}
+			}
+		}
+		items[i] = types.OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     product.Price,
+			SKU:       product.SKU,
+			Name:      product.Name,
+		}
+	}
+	return items, nil
+}
+
+// HasPrice whether the client sent a price for the item
+func (i OrderItemRequest) HasPrice() bool {
+	return i.Price != (types.Money{})
+}
+
+// priceIn reads the client price of the item in the order currency: the
+// request currency, else the catalog currency of the product, as OrderCurrency
+// resolves it. Bare amounts are scaled to the minor units of that currency.
+func (r CreateOrderRequest) priceIn(item OrderItemRequest, product CatalogProduct) (types.Money, error) {
+	currency := product.Price.Currency
+	if r.Currency != "" {
+		var err error
+		if currency, err = types.ParseCurrency(string(r.Currency)); err != nil {
+			return types.Money{}, err
+		}
+	}
+	return item.Price.InCurrency(currency)
+}
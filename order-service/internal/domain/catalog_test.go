@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+func jpy(amount int64) types.Money { return types.NewMoney(amount, types.CurrencyJPY) }
+
+// orderRequest decodes the request as the handler does, so bare prices stay
+// pending until the order currency is known
+func orderRequest(t *testing.T, body string) CreateOrderRequest {
+	t.Helper()
+
+	var request CreateOrderRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	return request
+}
+
+func TestPriceItems(t *testing.T) {
+	usdCatalog := map[uuid.UUID]CatalogProduct{
+		widget: {ID: widget, SKU: "WIDGET", Name: "Widget", Price: usd(1000)},
+	}
+	jpyCatalog := map[uuid.UUID]CatalogProduct{
+		widget: {ID: widget, SKU: "WIDGET", Name: "Widget", Price: jpy(1000)},
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		catalog map[uuid.UUID]CatalogProduct
+		want    types.Money
+		wantErr error
+	}{
+		{
+			name:    "no client price",
+			body:    `{"items": [{"product_id": "` + widget.String() + `", "quantity": 2}]}`,
+			catalog: usdCatalog,
+			want:    usd(1000),
+		},
+		{
+			name:    "price with currency",
+			body:    `{"items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": {"amount": "10.00", "currency": "USD"}}]}`,
+			catalog: usdCatalog,
+			want:    usd(1000),
+		},
+		{
+			name:    "bare price in the catalog currency",
+			body:    `{"items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": "10.00"}]}`,
+			catalog: usdCatalog,
+			want:    usd(1000),
+		},
+		{
+			name:    "bare price in a JPY order",
+			body:    `{"currency": "JPY", "items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": "1000"}]}`,
+			catalog: jpyCatalog,
+			want:    jpy(1000),
+		},
+		{
+			name:    "bare price in a lower case order currency",
+			body:    `{"currency": "jpy", "items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": "1000"}]}`,
+			catalog: jpyCatalog,
+			want:    jpy(1000),
+		},
+		{
+			name:    "bare price in the JPY catalog currency",
+			body:    `{"items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": "1000"}]}`,
+			catalog: jpyCatalog,
+			want:    jpy(1000),
+		},
+		{
+			name:    "stale price",
+			body:    `{"items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": "9.99"}]}`,
+			catalog: usdCatalog,
+			wantErr: ErrStalePrice,
+		},
+		{
+			name:    "stale JPY price",
+			body:    `{"currency": "JPY", "items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": "900"}]}`,
+			catalog: jpyCatalog,
+			wantErr: ErrStalePrice,
+		},
+		{
+			name:    "price in another currency",
+			body:    `{"items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": {"amount": "10.00", "currency": "EUR"}}]}`,
+			catalog: usdCatalog,
+			wantErr: ErrStalePrice,
+		},
+		{
+			name:    "bare price in an order currency the catalog does not use",
+			body:    `{"currency": "JPY", "items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": "10"}]}`,
+			catalog: usdCatalog,
+			wantErr: ErrStalePrice,
+		},
+		{
+			name:    "bare price with cents in a JPY order",
+			body:    `{"currency": "JPY", "items": [{"product_id": "` + widget.String() + `", "quantity": 2, "price": "1000.50"}]}`,
+			catalog: jpyCatalog,
+			wantErr: types.ErrInvalidAmount,
+		},
+		{
+			name:    "unknown product",
+			body:    `{"items": [{"product_id": "` + other.String() + `", "quantity": 1}]}`,
+			catalog: usdCatalog,
+			wantErr: ErrUnknownProduct,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := orderRequest(t, tt.body).PriceItems(tt.catalog)
+			if tt.wantErr != nil {
+				var priceErr *PriceError
+				if !errors.Is(err, tt.wantErr) || !errors.As(err, &priceErr) || priceErr.ItemIndex != 0 {
+					t.Fatalf("got %v, want item 0: %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("price items: %v", err)
+			}
+			if len(items) != 1 || items[0].Price != tt.want || items[0].SKU != "WIDGET" || items[0].Quantity != 2 {
+				t.Errorf("items = %+v, want 2 WIDGET at %s", items, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceItemsStalePriceReportsBothPrices(t *testing.T) {
+	request := orderRequest(t, `{"currency": "JPY", "items": [{"product_id": "`+widget.String()+`", "quantity": 1, "price": "900"}]}`)
+	catalog := map[uuid.UUID]CatalogProduct{widget: {ID: widget, Price: jpy(1000)}}
+
+	_, err := request.PriceItems(catalog)
+
+	var priceErr *PriceError
+	if !errors.As(err, &priceErr) {
+		t.Fatalf("got %v, want a PriceError", err)
+	}
+	if priceErr.Price != jpy(900) || priceErr.CatalogPrice != jpy(1000) {
+		t.Errorf("price %s, catalog price %s, want %s and %s", priceErr.Price, priceErr.CatalogPrice, jpy(900), jpy(1000))
+	}
+}
+
+func TestOrderCurrencyOfBarePricedJPYOrder(t *testing.T) {
+	request := orderRequest(t, `{"currency": "JPY", "items": [{"product_id": "`+widget.String()+`", "quantity": 3, "price": "1000"}]}`)
+	items, err := request.PriceItems(map[uuid.UUID]CatalogProduct{widget: {ID: widget, Price: jpy(1000)}})
+	if err != nil {
+		t.Fatalf("price items: %v", err)
+	}
+
+	currency, err := request.OrderCurrency(items)
+	if err != nil || currency != types.CurrencyJPY {
+		t.Fatalf("order currency = %q, %v, want JPY", currency, err)
+	}
+	order, err := NewOrderAggregate(uuid.New(), currency, items, &types.ShippingAddress{})
+	if err != nil {
+		t.Fatalf("new order: %v", err)
+	}
+	if order.TotalAmount != jpy(3000) {
+		t.Errorf("total = %s, want %s", order.TotalAmount, jpy(3000))
+	}
+}
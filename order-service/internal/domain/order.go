@@ -1,23 +1,44 @@
 package domain
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
-	"time"
 )
 
 type OrderAggregate struct {
 	*types.Order
 	SagaID        uuid.UUID `json:"saga_id,omitempty" db:"saga_id"`
 	FailureReason string    `json:"failure_reason,omitempty" db:"failure_reason"`
+	// CorrelationID of the checkout saga, continued by the later sagas of the
+	// order such as refunds; nil for orders placed before it was kept
+	CorrelationID uuid.UUID `json:"correlation_id,omitempty" db:"correlation_id"`
+	// PricedAt when the item prices were read from the catalog, nil for orders
+	// placed before prices were checked
+	PricedAt *time.Time `json:"priced_at,omitempty" db:"priced_at"`
+	// Fulfillment what was reserved of each product, empty when the order
+	// shipped complete
+	Fulfillment []types.ItemOutcome `json:"fulfillment,omitempty" db:"fulfillment"`
+	Backorders  []types.Backorder   `json:"backorders,omitempty" db:"backorders"`
+	// CapturedAmount charged at checkout when items were short, nil when the
+	// total was charged
+	CapturedAmount *types.Money `json:"captured_amount,omitempty" db:"captured_amount"`
 }
 
-func NewOrderAggregate(customerID uuid.UUID, items []types.OrderItem, shippingAddress *types.ShippingAddress) *OrderAggregate {
+func NewOrderAggregate(customerID uuid.UUID, currency types.Currency, items []types.OrderItem, shippingAddress *types.ShippingAddress) (*OrderAggregate, error) {
 	orderID := uuid.New()
 
-	var totalAmount float64
-	for _, item := range items {
-		totalAmount += item.Price * float64(item.Quantity)
+	totalAmount := types.NewMoney(0, currency)
+	for i, item := range items {
+		lineTotal, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		if totalAmount, err = totalAmount.Add(lineTotal); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
 	}
 
 	return &OrderAggregate{
@@ -31,7 +52,7 @@ func NewOrderAggregate(customerID uuid.UUID, items []types.OrderItem, shippingAd
 			CreatedAt:       time.Now(),
 			UpdatedAt:       time.Now(),
 		},
-	}
+	}, nil
 }
 
 func (o *OrderAggregate) UpdateStatus(status types.OrderStatus) {
@@ -39,8 +60,9 @@ func (o *OrderAggregate) UpdateStatus(status types.OrderStatus) {
 	o.UpdatedAt = time.Now()
 }
 
-func (o *OrderAggregate) AttachSaga(sagaID uuid.UUID) {
+func (o *OrderAggregate) AttachSaga(sagaID, correlationID uuid.UUID) {
 	o.SagaID = sagaID
+	o.CorrelationID = correlationID
 	o.UpdatedAt = time.Now()
 }
 
@@ -49,21 +71,71 @@ func (o *OrderAggregate) SetFailureReason(reason string) {
 	o.UpdatedAt = time.Now()
 }
 
+// RecordFulfillment keeps what the checkout saga reserved, backordered and
+// charged, as reported with its completion
+func (o *OrderAggregate) RecordFulfillment(items []types.ItemOutcome, backorders []types.Backorder, captured *types.Money) {
+	o.Fulfillment = items
+	o.Backorders = backorders
+	o.CapturedAmount = captured
+	o.UpdatedAt = time.Now()
+}
+
+// UpdateBackorder records the outcome of a backorder's saga. It reports false
+// for backorders the order does not know.
+func (o *OrderAggregate) UpdateBackorder(backorderID, sagaID uuid.UUID, status types.BackorderStatus) bool {
+	for i := range o.Backorders {
+		if o.Backorders[i].ID == backorderID {
+			o.Backorders[i].Status = status
+			o.Backorders[i].SagaID = sagaID
+			o.Backorders[i].UpdatedAt = time.Now()
+			o.UpdatedAt = time.Now()
+			return true
+		}
+	}
+	return false
+}
+
+// ChargedAmount what the checkout payment charged; backordered items are
+// charged by payments of their own
+func (o *OrderAggregate) ChargedAmount() types.Money {
+	if o.CapturedAmount != nil {
+		return *o.CapturedAmount
+	}
+	return o.TotalAmount
+}
+
+// ShippedQuantity units of the product the checkout shipped
+func (o *OrderAggregate) ShippedQuantity(item types.OrderItem) int {
+	if len(o.Fulfillment) == 0 {
+		return item.Quantity
+	}
+	for _, outcome := range o.Fulfillment {
+		if outcome.ProductID == item.ProductID {
+			return min(item.Quantity, outcome.Reserved)
+		}
+	}
+	return 0
+}
+
 // CanProcessSaga checks that saga can be started
 func (o *OrderAggregate) CanProcessSaga() bool {
-	return o.Status == types.OrderStatusPending && o.TotalAmount > 0
+	return o.Status == types.OrderStatusPending && o.TotalAmount.IsPositive()
 }
 
 type CreateOrderRequest struct {
 	CustomerID      uuid.UUID              `json:"customer_id" validate:"required"`
+	Currency        types.Currency         `json:"currency,omitempty"`
 	Items           []OrderItemRequest     `json:"items" validate:"required,min=1"`
 	ShippingAddress ShippingAddressRequest `json:"shipping_address" validate:"required"`
+	// FulfillmentPolicy for out of stock items, all_or_nothing if omitted
+	FulfillmentPolicy types.FulfillmentPolicy `json:"fulfillment_policy,omitempty"`
 }
 
 type OrderItemRequest struct {
 	ProductID uuid.UUID `json:"product_id" validate:"required"`
 	Quantity  int       `json:"quantity" validate:"required,min=1"`
-	Price     float64   `json:"price" validate:"required,min=0"`
+	// Price the customer was shown, optional; the catalog price is charged
+	Price types.Money `json:"price"`
 }
 
 type ShippingAddressRequest struct {
@@ -74,17 +146,30 @@ type ShippingAddressRequest struct {
 	Country string `json:"country" validate:"required"`
 }
 
-// converts to domain model
-func (r CreateOrderRequest) ToOrderItems() []types.OrderItem {
-	items := make([]types.OrderItem, len(r.Items))
-	for i, item := range r.Items {
-		items[i] = types.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Price,
+// OrderCurrency resolves the order currency: the request currency, else the
+// currency of the first priced item, else the default. Every item price must match it.
+func (r CreateOrderRequest) OrderCurrency(items []types.OrderItem) (types.Currency, error) {
+	currency := r.Currency
+	if currency == "" && len(items) > 0 {
+		currency = items[0].Price.Currency
+	}
+	if currency == "" {
+		currency = types.DefaultCurrency
+	}
+
+	currency, err := types.ParseCurrency(string(currency))
+	if err != nil {
+		return "", err
+	}
+
+	for i, item := range items {
+		if item.Price.Currency != currency {
+			return "", fmt.Errorf("item %d: %w: %s and %s",
+				i, types.ErrCurrencyMismatch, item.Price.Currency, currency)
 		}
 	}
-	return items
+
+	return currency, nil
 }
 
 // converts to domain model
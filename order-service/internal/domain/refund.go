@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrRefundNotAllowed the order is not in a state that can be refunded
+	ErrRefundNotAllowed = errors.New("order cannot be refunded")
+	// ErrInvalidRefund the requested items or amount do not fit the order
+	ErrInvalidRefund = errors.New("invalid refund")
+	// ErrBackorderedRefund the items were shipped by a backorder saga and
+	// charged by its own payment, which order refunds do not pay from
+	ErrBackorderedRefund = errors.New("backordered items cannot be refunded with the order")
+)
+
+type RefundStatus string
+
+const (
+	RefundStatusRequested RefundStatus = "requested" // Refund saga running
+	RefundStatusCompleted RefundStatus = "completed"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// OrderRefund a merchant initiated refund of part or all of a completed order.
+// Each refund runs its own refund saga in the orchestrator.
+type OrderRefund struct {
+	ID            uuid.UUID    `json:"id" db:"id"`
+	OrderID       uuid.UUID    `json:"order_id" db:"order_id"`
+	SagaID        uuid.UUID    `json:"saga_id" db:"saga_id"`
+	Amount        types.Money  `json:"amount" db:"amount"`
+	Items         []RefundItem `json:"items" db:"items"` // Returned items, restocked by the saga
+	Reason        string       `json:"reason,omitempty" db:"reason"`
+	Status        RefundStatus `json:"status" db:"status"`
+	FailureReason string       `json:"failure_reason,omitempty" db:"failure_reason"`
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at" db:"updated_at"`
+	CompletedAt   *time.Time   `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+type RefundItem struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+}
+
+type CreateRefundRequest struct {
+	Items  []RefundItem `json:"items"`
+	Amount *types.Money `json:"amount,omitempty"` // Defaults to the price of the items
+	Reason string       `json:"reason"`
+}
+
+// NewOrderRefund validates the request against the order and the refunds
+// already made for it. Running refunds count as made, so two requests cannot
+// refund the same item or amount twice.
+func NewOrderRefund(order *OrderAggregate, request CreateRefundRequest, previous []*OrderRefund) (*OrderRefund, error) {
+	if order.Status != types.OrderStatusCompleted {
+		return nil, fmt.Errorf("%w: status is %s, only completed orders can be refunded", ErrRefundNotAllowed, order.Status)
+	}
+	if len(request.Items) == 0 && request.Amount == nil {
+		return nil, fmt.Errorf("%w: items or amount is required", ErrInvalidRefund)
+	}
+
+	currency := order.TotalAmount.Currency
+	itemsAmount, err := refundItemsAmount(order, request.Items, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := itemsAmount
+	if request.Amount != nil {
+		if amount, err = request.Amount.InCurrency(currency); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRefund, err)
+		}
+		if !amount.SameCurrency(order.TotalAmount) {
+			return nil, fmt.Errorf("%w: %w: %s and %s", ErrInvalidRefund, types.ErrCurrencyMismatch, amount.Currency, currency)
+		}
+		// Less than the items is fine, e.g. a restocking fee; more is not
+		if len(request.Items) > 0 {
+			if exceeds, _ := amount.Cmp(itemsAmount); exceeds > 0 {
+				return nil, fmt.Errorf("%w: amount %s exceeds the price of the items %s", ErrInvalidRefund, amount, itemsAmount)
+			}
+		}
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidRefund)
+	}
+
+	refunded := types.NewMoney(0, currency)
+	for _, refund := range previous {
+		if refund.Status == RefundStatusFailed {
+			continue
+		}
+		if refunded, err = refunded.Add(refund.Amount); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRefund, err)
+		}
+	}
+	total, err := refunded.Add(amount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRefund, err)
+	}
+	charged := order.ChargedAmount()
+	if exceeds, _ := total.Cmp(charged); exceeds > 0 {
+		return nil, fmt.Errorf("%w: %s already refunded, %s more exceeds the charged amount %s",
+			ErrInvalidRefund, refunded, amount, charged)
+	}
+
+	items := request.Items
+	if items == nil {
+		items = []RefundItem{}
+	}
+
+	now := time.Now()
+	return &OrderRefund{
+		ID:        uuid.New(),
+		OrderID:   order.ID,
+		SagaID:    uuid.New(),
+		Amount:    amount,
+		Items:     items,
+		Reason:    request.Reason,
+		Status:    RefundStatusRequested,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// refundItemsAmount price of the refunded items; each item must be in the
+// order and not be refunded more often than the checkout shipped it. Units
+// the checkout backordered are rejected as such.
+func refundItemsAmount(order *OrderAggregate, items []RefundItem, previous []*OrderRefund) (types.Money, error) {
+	refundedQuantity := map[uuid.UUID]int{}
+	for _, refund := range previous {
+		if refund.Status == RefundStatusFailed {
+			continue
+		}
+		for _, item := range refund.Items {
+			refundedQuantity[item.ProductID] += item.Quantity
+		}
+	}
+
+	amount := types.NewMoney(0, order.TotalAmount.Currency)
+	for i, item := range items {
+		if item.Quantity <= 0 {
+			return types.Money{}, fmt.Errorf("%w: item %d: quantity must be positive", ErrInvalidRefund, i)
+		}
+
+		ordered, ok := order.findItem(item.ProductID)
+		if !ok {
+			return types.Money{}, fmt.Errorf("%w: item %d: product %s is not in the order", ErrInvalidRefund, i, item.ProductID)
+		}
+
+		shipped := order.ShippedQuantity(ordered)
+		refundedQuantity[item.ProductID] += item.Quantity
+		if refundedQuantity[item.ProductID] > shipped && shipped < ordered.Quantity &&
+			refundedQuantity[item.ProductID] <= ordered.Quantity {
+			return types.Money{}, fmt.Errorf("%w: %w: item %d: %d of product %s shipped at checkout, the other %d were backordered",
+				ErrInvalidRefund, ErrBackorderedRefund, i, shipped, item.ProductID, ordered.Quantity-shipped)
+		}
+		if refundedQuantity[item.ProductID] > shipped {
+			return types.Money{}, fmt.Errorf("%w: item %d: %d of product %s shipped, %d would be refunded",
+				ErrInvalidRefund, i, shipped, item.ProductID, refundedQuantity[item.ProductID])
+		}
+
+		lineTotal, err := ordered.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return types.Money{}, fmt.Errorf("%w: item %d: %v", ErrInvalidRefund, i, err)
+		}
+		if amount, err = amount.Add(lineTotal); err != nil {
+			return types.Money{}, fmt.Errorf("%w: item %d: %v", ErrInvalidRefund, i, err)
+		}
+	}
+
+	return amount, nil
+}
+
+func (o *OrderAggregate) findItem(productID uuid.UUID) (types.OrderItem, bool) {
+	for _, item := range o.Items {
+		if item.ProductID == productID {
+			return item, true
+		}
+	}
+	return types.OrderItem{}, false
+}
+
+func (r *OrderRefund) IsFinished() bool {
+	return r.Status == RefundStatusCompleted || r.Status == RefundStatusFailed
+}
+
+func (r *OrderRefund) Complete() {
+	now := time.Now()
+	r.Status = RefundStatusCompleted
+	r.UpdatedAt = now
+	r.CompletedAt = &now
+}
+
+func (r *OrderRefund) Fail(reason string) {
+	now := time.Now()
+	r.Status = RefundStatusFailed
+	r.FailureReason = reason
+	r.UpdatedAt = now
+	r.CompletedAt = &now
+}
@@ -0,0 +1,261 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+var (
+	widget = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	gadget = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	other  = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+)
+
+func usd(amount int64) types.Money { return types.NewMoney(amount, types.CurrencyUSD) }
+
+// completedOrder 3 widgets at 10.00 and 2 gadgets at 25.00, 80.00 in total
+func completedOrder(t *testing.T) *OrderAggregate {
+	t.Helper()
+
+	order, err := NewOrderAggregate(uuid.New(), types.CurrencyUSD, []types.OrderItem{
+		{ProductID: widget, Quantity: 3, Price: usd(1000)},
+		{ProductID: gadget, Quantity: 2, Price: usd(2500)},
+	}, &types.ShippingAddress{})
+	if err != nil {
+		t.Fatalf("new order: %v", err)
+	}
+	order.UpdateStatus(types.OrderStatusCompleted)
+	return order
+}
+
+// shortOrder the completed order where only 1 gadget shipped; the other was
+// backordered and 55.00 was charged
+func shortOrder(t *testing.T) *OrderAggregate {
+	order := completedOrder(t)
+	captured := usd(5500)
+	order.RecordFulfillment([]types.ItemOutcome{
+		{ProductID: widget, Requested: 3, Reserved: 3},
+		{ProductID: gadget, Requested: 2, Reserved: 1, Backordered: 1},
+	}, nil, &captured)
+	return order
+}
+
+func refundOf(status RefundStatus, amount int64, items ...RefundItem) *OrderRefund {
+	return &OrderRefund{Amount: usd(amount), Items: items, Status: status}
+}
+
+func amountOf(money types.Money) *types.Money { return &money }
+
+func TestNewOrderRefund(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    func(*testing.T) *OrderAggregate
+		request  CreateRefundRequest
+		previous []*OrderRefund
+		want     types.Money // Refunded amount when valid
+		wantErr  error
+	}{
+		{
+			name:    "items at their price",
+			order:   completedOrder,
+			request: CreateRefundRequest{Items: []RefundItem{{ProductID: widget, Quantity: 2}, {ProductID: gadget, Quantity: 1}}},
+			want:    usd(4500),
+		},
+		{
+			name:    "amount only",
+			order:   completedOrder,
+			request: CreateRefundRequest{Amount: amountOf(usd(1234))},
+			want:    usd(1234),
+		},
+		{
+			name:    "items for less, e.g. a restocking fee",
+			order:   completedOrder,
+			request: CreateRefundRequest{Items: []RefundItem{{ProductID: gadget, Quantity: 1}}, Amount: amountOf(usd(2000))},
+			want:    usd(2000),
+		},
+		{
+			name:    "items for more than their price",
+			order:   completedOrder,
+			request: CreateRefundRequest{Items: []RefundItem{{ProductID: gadget, Quantity: 1}}, Amount: amountOf(usd(2501))},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:    "the whole order",
+			order:   completedOrder,
+			request: CreateRefundRequest{Amount: amountOf(usd(8000))},
+			want:    usd(8000),
+		},
+		{
+			name:    "more than charged",
+			order:   completedOrder,
+			request: CreateRefundRequest{Amount: amountOf(usd(8001))},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:     "cumulative over-refund",
+			order:    completedOrder,
+			request:  CreateRefundRequest{Amount: amountOf(usd(3001))},
+			previous: []*OrderRefund{refundOf(RefundStatusCompleted, 3000), refundOf(RefundStatusRequested, 2000)},
+			wantErr:  ErrInvalidRefund,
+		},
+		{
+			name:     "up to the rest",
+			order:    completedOrder,
+			request:  CreateRefundRequest{Amount: amountOf(usd(3000))},
+			previous: []*OrderRefund{refundOf(RefundStatusCompleted, 3000), refundOf(RefundStatusRequested, 2000)},
+			want:     usd(3000),
+		},
+		{
+			name:     "failed refunds are not counted",
+			order:    completedOrder,
+			request:  CreateRefundRequest{Items: []RefundItem{{ProductID: widget, Quantity: 3}}},
+			previous: []*OrderRefund{refundOf(RefundStatusFailed, 3000, RefundItem{ProductID: widget, Quantity: 3})},
+			want:     usd(3000),
+		},
+		{
+			name:     "item already refunded",
+			order:    completedOrder,
+			request:  CreateRefundRequest{Items: []RefundItem{{ProductID: widget, Quantity: 2}}},
+			previous: []*OrderRefund{refundOf(RefundStatusRequested, 1000, RefundItem{ProductID: widget, Quantity: 2})},
+			wantErr:  ErrInvalidRefund,
+		},
+		{
+			name:     "rest of a partly refunded item",
+			order:    completedOrder,
+			request:  CreateRefundRequest{Items: []RefundItem{{ProductID: widget, Quantity: 1}}},
+			previous: []*OrderRefund{refundOf(RefundStatusCompleted, 2000, RefundItem{ProductID: widget, Quantity: 2})},
+			want:     usd(1000),
+		},
+		{
+			name:    "more of an item than ordered",
+			order:   completedOrder,
+			request: CreateRefundRequest{Items: []RefundItem{{ProductID: widget, Quantity: 4}}},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:    "shipped quantity of a short order",
+			order:   shortOrder,
+			request: CreateRefundRequest{Items: []RefundItem{{ProductID: gadget, Quantity: 1}}},
+			want:    usd(2500),
+		},
+		{
+			name:    "backordered quantity of a short order",
+			order:   shortOrder,
+			request: CreateRefundRequest{Items: []RefundItem{{ProductID: gadget, Quantity: 2}}},
+			wantErr: ErrBackorderedRefund,
+		},
+		{
+			name:     "backordered unit after the shipped one was refunded",
+			order:    shortOrder,
+			request:  CreateRefundRequest{Items: []RefundItem{{ProductID: gadget, Quantity: 1}}},
+			previous: []*OrderRefund{refundOf(RefundStatusCompleted, 2500, RefundItem{ProductID: gadget, Quantity: 1})},
+			wantErr:  ErrBackorderedRefund,
+		},
+		{
+			name:    "more than a short order's line",
+			order:   shortOrder,
+			request: CreateRefundRequest{Items: []RefundItem{{ProductID: gadget, Quantity: 3}}},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:    "more than the short order charged",
+			order:   shortOrder,
+			request: CreateRefundRequest{Amount: amountOf(usd(5501))},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:    "product not in the order",
+			order:   completedOrder,
+			request: CreateRefundRequest{Items: []RefundItem{{ProductID: other, Quantity: 1}}},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:    "zero quantity",
+			order:   completedOrder,
+			request: CreateRefundRequest{Items: []RefundItem{{ProductID: widget, Quantity: 0}}},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:    "zero amount",
+			order:   completedOrder,
+			request: CreateRefundRequest{Amount: amountOf(usd(0))},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:    "negative amount",
+			order:   completedOrder,
+			request: CreateRefundRequest{Amount: amountOf(usd(-100))},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:    "neither items nor amount",
+			order:   completedOrder,
+			request: CreateRefundRequest{},
+			wantErr: ErrInvalidRefund,
+		},
+		{
+			name:    "currency mismatch",
+			order:   completedOrder,
+			request: CreateRefundRequest{Amount: amountOf(types.NewMoney(1000, types.CurrencyEUR))},
+			wantErr: types.ErrCurrencyMismatch,
+		},
+		{
+			name: "order not completed",
+			order: func(t *testing.T) *OrderAggregate {
+				order := completedOrder(t)
+				order.UpdateStatus(types.OrderStatusPending)
+				return order
+			},
+			request: CreateRefundRequest{Amount: amountOf(usd(1000))},
+			wantErr: ErrRefundNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := tt.order(t)
+			refund, err := NewOrderRefund(order, tt.request, tt.previous)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, %v, want %v", refund, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if refund.Amount != tt.want {
+				t.Errorf("amount = %s, want %s", refund.Amount, tt.want)
+			}
+			if refund.OrderID != order.ID || refund.Status != RefundStatusRequested || refund.Items == nil {
+				t.Errorf("refund = %+v", refund)
+			}
+		})
+	}
+}
+
+func TestNewOrderRefundBareAmount(t *testing.T) {
+	order, err := NewOrderAggregate(uuid.New(), types.CurrencyJPY, []types.OrderItem{
+		{ProductID: widget, Quantity: 1, Price: types.NewMoney(5000, types.CurrencyJPY)},
+	}, &types.ShippingAddress{})
+	if err != nil {
+		t.Fatalf("new order: %v", err)
+	}
+	order.UpdateStatus(types.OrderStatusCompleted)
+
+	var request CreateRefundRequest
+	if err := json.Unmarshal([]byte(`{"amount": "1000"}`), &request); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+
+	// An amount without a currency is in the order currency
+	refund, err := NewOrderRefund(order, request, nil)
+	if err != nil || refund.Amount != types.NewMoney(1000, types.CurrencyJPY) {
+		t.Errorf("got %v, %v, want 1000 JPY", refund, err)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"time"
 
+	"github.com/distributed-ecommerce-saga/order-service/internal/domain"
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
 )
@@ -11,19 +12,32 @@ type OrderResponse struct {
 	ID              uuid.UUID               `json:"id"`
 	CustomerID      uuid.UUID               `json:"customer_id"`
 	Items           []OrderItemResponse     `json:"items"`
-	TotalAmount     float64                 `json:"total_amount"`
+	TotalAmount     types.Money             `json:"total_amount"`
 	Status          string                  `json:"status"`
 	ShippingAddress ShippingAddressResponse `json:"shipping_address"`
 	SagaID          uuid.UUID               `json:"saga_id,omitempty"`
 	FailureReason   string                  `json:"failure_reason,omitempty"`
+	PricedAt        *time.Time              `json:"priced_at,omitempty"`
+	Fulfillment     FulfillmentResponse     `json:"fulfillment"`
 	CreatedAt       time.Time               `json:"created_at"`
 	UpdatedAt       time.Time               `json:"updated_at"`
 }
 
 type OrderItemResponse struct {
-	ProductID uuid.UUID `json:"product_id"`
-	Quantity  int       `json:"quantity"`
-	Price     float64   `json:"price"`
+	ProductID uuid.UUID   `json:"product_id"`
+	SKU       string      `json:"sku,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Quantity  int         `json:"quantity"`
+	Price     types.Money `json:"price"`
+}
+
+// FulfillmentResponse how the order is fulfilled; Items and Backorders are
+// only filled when some items were short
+type FulfillmentResponse struct {
+	Policy         types.FulfillmentPolicy `json:"policy"`
+	Items          []types.ItemOutcome     `json:"items,omitempty"`
+	Backorders     []types.Backorder       `json:"backorders,omitempty"`
+	CapturedAmount *types.Money            `json:"captured_amount,omitempty"`
 }
 
 type ShippingAddressResponse struct {
@@ -39,6 +53,8 @@ func mapOrderItems(items []types.OrderItem) []OrderItemResponse {
 	for i, item := range items {
 		responses[i] = OrderItemResponse{
 			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Name:      item.Name,
 			Quantity:  item.Quantity,
 			Price:     item.Price,
 		}
@@ -46,6 +62,15 @@ func mapOrderItems(items []types.OrderItem) []OrderItemResponse {
 	return responses
 }
 
+func mapFulfillment(order *domain.OrderAggregate) FulfillmentResponse {
+	return FulfillmentResponse{
+		Policy:         order.FulfillmentPolicy.OrDefault(),
+		Items:          order.Fulfillment,
+		Backorders:     order.Backorders,
+		CapturedAmount: order.CapturedAmount,
+	}
+}
+
 func mapShippingAddress(address *types.ShippingAddress) ShippingAddressResponse {
 	if address == nil {
 		return ShippingAddressResponse{}
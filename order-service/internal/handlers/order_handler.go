@@ -1,15 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+
 	"github.com/distributed-ecommerce-saga/order-service/internal/domain"
 	"github.com/distributed-ecommerce-saga/order-service/internal/service"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
 	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"log"
-	"strconv"
 )
 
 type OrderHandler struct {
@@ -44,6 +48,8 @@ func (h *OrderHandler) GetOrderByID(c *fiber.Ctx) error {
 		Status:          string(order.Status),
 		ShippingAddress: mapShippingAddress(order.ShippingAddress),
 		SagaID:          order.SagaID,
+		PricedAt:        order.PricedAt,
+		Fulfillment:     mapFulfillment(order),
 		FailureReason:   order.FailureReason,
 		CreatedAt:       order.CreatedAt,
 		UpdatedAt:       order.UpdatedAt,
@@ -82,7 +88,7 @@ func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
 				"quantity":   item.Quantity,
 			})
 		}
-		if item.Price < 0 {
+		if item.Price.IsNegative() {
 			return sharedHTTP.BadRequestResponse(c, "Invalid price", map[string]interface{}{
 				"item_index": i,
 				"price":      item.Price,
@@ -90,14 +96,28 @@ func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
 		}
 	}
 
-	order, err := h.orderService.CreateOrder(request)
-	if err != nil {
-		log.Printf("Order creation error: %v", err)
-		return sharedHTTP.InternalServerErrorResponse(c, "Order creation failed", map[string]interface{}{
-			"error": err.Error(),
+	if request.FulfillmentPolicy != "" && !request.FulfillmentPolicy.Valid() {
+		return sharedHTTP.BadRequestResponse(c, "Invalid fulfillment policy", map[string]interface{}{
+			"fulfillment_policy": request.FulfillmentPolicy,
+			"allowed": []types.FulfillmentPolicy{
+				types.FulfillAllOrNothing, types.FulfillAllowPartial, types.FulfillAllowBackorder,
+			},
 		})
 	}
 
+	if request.Currency != "" {
+		if _, err := types.ParseCurrency(string(request.Currency)); err != nil {
+			return sharedHTTP.BadRequestResponse(c, "Invalid currency", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	order, err := h.orderService.CreateOrder(c.UserContext(), request)
+	if err != nil {
+		return createOrderErrorResponse(c, err)
+	}
+
 	// Response DTO oluştur
 	response := OrderResponse{
 		ID:              order.ID,
@@ -107,6 +127,8 @@ func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
 		Status:          string(order.Status),
 		ShippingAddress: mapShippingAddress(order.ShippingAddress),
 		SagaID:          order.SagaID,
+		PricedAt:        order.PricedAt,
+		Fulfillment:     mapFulfillment(order),
 		CreatedAt:       order.CreatedAt,
 		UpdatedAt:       order.UpdatedAt,
 	}
@@ -114,6 +136,47 @@ func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
 	return sharedHTTP.CreatedResponse(c, "Order created successfully", response)
 }
 
+// createOrderErrorResponse maps pricing failures to client errors: unknown
+// products and currency mismatches are bad requests, a stale price conflicts
+// with the catalog and carries the price to show the customer instead
+func createOrderErrorResponse(c *fiber.Ctx, err error) error {
+	var priceErr *domain.PriceError
+	switch {
+	case errors.Is(err, domain.ErrUnknownProduct) && errors.As(err, &priceErr):
+		return sharedHTTP.BadRequestResponse(c, "Unknown product", map[string]interface{}{
+			"item_index": priceErr.ItemIndex,
+			"product_id": priceErr.ProductID,
+		})
+	case errors.Is(err, domain.ErrStalePrice) && errors.As(err, &priceErr):
+		return sharedHTTP.ConflictResponse(c, "Price has changed", map[string]interface{}{
+			"item_index":    priceErr.ItemIndex,
+			"product_id":    priceErr.ProductID,
+			"price":         priceErr.Price,
+			"catalog_price": priceErr.CatalogPrice,
+		})
+	case errors.Is(err, types.ErrInvalidAmount) && errors.As(err, &priceErr):
+		return sharedHTTP.BadRequestResponse(c, "Invalid price", map[string]interface{}{
+			"item_index": priceErr.ItemIndex,
+			"product_id": priceErr.ProductID,
+			"error":      err.Error(),
+		})
+	case errors.Is(err, types.ErrCurrencyMismatch), errors.Is(err, types.ErrInvalidCurrency):
+		return sharedHTTP.BadRequestResponse(c, "Invalid currency", map[string]interface{}{
+			"error": err.Error(),
+		})
+	case errors.Is(err, domain.ErrCatalogUnavailable):
+		slog.WarnContext(c.UserContext(), "Catalog unavailable", "error", err)
+		return sharedHTTP.ServiceUnavailableResponse(c, "Catalog unavailable, try again", map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		slog.ErrorContext(c.UserContext(), "Order creation error", "error", err)
+		return sharedHTTP.InternalServerErrorResponse(c, "Order creation failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
 func (h *OrderHandler) GetOrdersByCustomerID(c *fiber.Ctx) error {
 	customerIDStr := c.Params("customer_id")
 	customerID, err := uuid.Parse(customerIDStr)
@@ -165,6 +228,8 @@ func (h *OrderHandler) GetOrdersByCustomerID(c *fiber.Ctx) error {
 			Status:          string(order.Status),
 			ShippingAddress: mapShippingAddress(order.ShippingAddress),
 			SagaID:          order.SagaID,
+			PricedAt:        order.PricedAt,
+			Fulfillment:     mapFulfillment(order),
 			FailureReason:   order.FailureReason,
 			CreatedAt:       order.CreatedAt,
 			UpdatedAt:       order.UpdatedAt,
@@ -182,6 +247,72 @@ func (h *OrderHandler) GetOrdersByCustomerID(c *fiber.Ctx) error {
 	})
 }
 
+// RequestRefund refunds part or all of a completed order, by line item or amount
+func (h *OrderHandler) RequestRefund(c *fiber.Ctx) error {
+	orderIDStr := c.Params("id")
+	orderID, err := uuid.Parse(orderIDStr)
+	if err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid order ID", map[string]interface{}{
+			"order_id": orderIDStr,
+		})
+	}
+
+	var request domain.CreateRefundRequest
+	if err := c.BodyParser(&request); err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid request body", map[string]interface{}{
+			"parse_error": err.Error(),
+		})
+	}
+
+	if _, err := h.orderService.GetOrderByID(orderID); err != nil {
+		return sharedHTTP.NotFoundResponse(c, "Order not found")
+	}
+
+	refund, err := h.orderService.RequestRefund(c.UserContext(), orderID, request)
+	switch {
+	case err == nil:
+		return sharedHTTP.CreatedResponse(c, "Refund requested", refund)
+	case errors.Is(err, domain.ErrInvalidRefund):
+		return sharedHTTP.BadRequestResponse(c, "Invalid refund", map[string]interface{}{
+			"error": err.Error(),
+		})
+	case errors.Is(err, domain.ErrRefundNotAllowed):
+		return sharedHTTP.ConflictResponse(c, "Order cannot be refunded", map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		slog.ErrorContext(c.UserContext(), "Refund request error", "error", err)
+		return sharedHTTP.InternalServerErrorResponse(c, "Refund request failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+func (h *OrderHandler) GetRefunds(c *fiber.Ctx) error {
+	orderIDStr := c.Params("id")
+	orderID, err := uuid.Parse(orderIDStr)
+	if err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid order ID", map[string]interface{}{
+			"order_id": orderIDStr,
+		})
+	}
+
+	if _, err := h.orderService.GetOrderByID(orderID); err != nil {
+		return sharedHTTP.NotFoundResponse(c, "Order not found")
+	}
+
+	refunds, err := h.orderService.GetRefunds(orderID)
+	if err != nil {
+		return sharedHTTP.InternalServerErrorResponse(c, "Refunds retrieval failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Refunds retrieved successfully", map[string]interface{}{
+		"refunds": refunds,
+	})
+}
+
 func (h *OrderHandler) HealthCheck(c *fiber.Ctx) error {
 	return sharedHTTP.SuccessResponse(c, "Order service is healthy", map[string]interface{}{
 		"service": "order-service",
@@ -195,13 +326,18 @@ func (h *OrderHandler) StartConsuming(consumer *messaging.Consumer) error {
 	routingKeys := []string{
 		"saga.saga-orchestrator.order.completed", // Saga completed successfully
 		"saga.saga-orchestrator.order.cancelled", // Saga rollback
+		"saga.saga-orchestrator.order.refund_completed",
+		"saga.saga-orchestrator.order.refund_failed",
+		"saga.saga-orchestrator.order.backorder_fulfilled",
+		"saga.saga-orchestrator.order.backorder_failed",
 	}
 
 	return consumer.ConsumeEvents(routingKeys, h.HandleSagaEvent)
 }
 
 // HandleSagaEvent process saga events received from Rabbitmq
-func (h *OrderHandler) HandleSagaEvent(event events.SagaEvent) error {
-	log.Printf("Order service saga event received: %s", event.EventType)
-	return h.orderService.ProcessSagaCompletionEvent(event)
+func (h *OrderHandler) HandleSagaEvent(ctx context.Context, event events.SagaEvent) error {
+	slog.DebugContext(ctx, "Order service saga event received")
+
+	return h.orderService.ProcessSagaCompletionEvent(ctx, event)
 }
@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/distributed-ecommerce-saga/order-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
 )
@@ -19,6 +21,8 @@ func NewOrderRepository(db *sql.DB) *OrderRepository {
 }
 
 func (r *OrderRepository) CreateOrder(order *domain.OrderAggregate) error {
+	defer metrics.ObserveDBQuery("CreateOrder", time.Now())
+
 	itemsJSON, err := json.Marshal(order.Items)
 	if err != nil {
 		return fmt.Errorf("items serialization error: %v", err)
@@ -31,9 +35,10 @@ func (r *OrderRepository) CreateOrder(order *domain.OrderAggregate) error {
 
 	query := `
 		INSERT INTO orders (
-			id, customer_id, items, total_amount, status, 
-			failure_reason, shipping_address, saga_id, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			id, customer_id, items, total_amount, currency, status, 
+			failure_reason, shipping_address, saga_id, correlation_id, priced_at, fulfillment_policy,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
 	_, err = r.db.Exec(
@@ -42,10 +47,14 @@ func (r *OrderRepository) CreateOrder(order *domain.OrderAggregate) error {
 		order.CustomerID,
 		itemsJSON,
 		order.TotalAmount,
+		order.TotalAmount.Currency,
 		order.Status,
 		order.FailureReason,
 		addressJSON,
 		order.SagaID,
+		order.CorrelationID,
+		order.PricedAt,
+		order.FulfillmentPolicy.OrDefault(),
 		order.CreatedAt,
 		order.UpdatedAt,
 	)
@@ -58,6 +67,8 @@ func (r *OrderRepository) CreateOrder(order *domain.OrderAggregate) error {
 }
 
 func (r *OrderRepository) UpdateOrder(order *domain.OrderAggregate) error {
+	defer metrics.ObserveDBQuery("UpdateOrder", time.Now())
+
 	itemsJSON, err := json.Marshal(order.Items)
 	if err != nil {
 		return fmt.Errorf("items serialization error: %v", err)
@@ -68,10 +79,16 @@ func (r *OrderRepository) UpdateOrder(order *domain.OrderAggregate) error {
 		return fmt.Errorf("shipping address serialization error: %v", err)
 	}
 
+	fulfillmentJSON, backordersJSON, err := fulfillmentJSON(order)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE orders 
-		SET status = $2, items = $3, total_amount = $4, 
-			failure_reason = $5, shipping_address = $6, saga_id = $7, updated_at = $8
+		SET status = $2, items = $3, total_amount = $4, currency = $5,
+			failure_reason = $6, shipping_address = $7, saga_id = $8, updated_at = $9,
+			fulfillment = $10, backorders = $11, captured_amount = $12, correlation_id = $13
 		WHERE id = $1
 	`
 
@@ -81,10 +98,15 @@ func (r *OrderRepository) UpdateOrder(order *domain.OrderAggregate) error {
 		order.Status,
 		itemsJSON,
 		order.TotalAmount,
+		order.TotalAmount.Currency,
 		order.FailureReason,
 		addressJSON,
 		order.SagaID,
 		order.UpdatedAt,
+		fulfillmentJSON,
+		backordersJSON,
+		order.CapturedAmount,
+		order.CorrelationID,
 	)
 
 	if err != nil {
@@ -104,26 +126,50 @@ func (r *OrderRepository) UpdateOrder(order *domain.OrderAggregate) error {
 }
 
 func (r *OrderRepository) GetOrderByID(orderID uuid.UUID) (*domain.OrderAggregate, error) {
+	defer metrics.ObserveDBQuery("GetOrderByID", time.Now())
+
+	return getOrderByID(r.db, orderID, "")
+}
+
+// querier runs queries on the database or in a transaction
+type querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// getOrderByID reads the order; lock is a locking clause such as FOR UPDATE
+func getOrderByID(db querier, orderID uuid.UUID, lock string) (*domain.OrderAggregate, error) {
 	query := `
-		SELECT id, customer_id, items, total_amount, status,
-			   failure_reason, shipping_address, saga_id, created_at, updated_at
+		SELECT id, customer_id, items, total_amount, currency, status,
+			   failure_reason, shipping_address, saga_id, correlation_id, priced_at, fulfillment_policy,
+			   fulfillment, backorders, captured_amount, created_at, updated_at
 		FROM orders 
 		WHERE id = $1
-	`
+	` + lock
 
 	order := &domain.OrderAggregate{Order: &types.Order{}}
 	var itemsJSON, addressJSON []byte
-	var sagaID sql.NullString
+	var sagaID, correlationID, policy sql.NullString
+	var pricedAt sql.NullTime
+	var fulfillment, backorders []byte
+	var captured sql.NullInt64
 
-	err := r.db.QueryRow(query, orderID).Scan(
+	err := db.QueryRow(query, orderID).Scan(
 		&order.ID,
 		&order.CustomerID,
 		&itemsJSON,
 		&order.TotalAmount,
+		&order.TotalAmount.Currency,
 		&order.Status,
 		&order.FailureReason,
 		&addressJSON,
 		&sagaID,
+		&correlationID,
+		&pricedAt,
+		&policy,
+		&fulfillment,
+		&backorders,
+		&captured,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 	)
@@ -138,6 +184,7 @@ func (r *OrderRepository) GetOrderByID(orderID uuid.UUID) (*domain.OrderAggregat
 	if err := json.Unmarshal(itemsJSON, &order.Items); err != nil {
 		return nil, fmt.Errorf("items deserialization error: %v", err)
 	}
+	fillItemCurrency(order)
 
 	if err := json.Unmarshal(addressJSON, &order.ShippingAddress); err != nil {
 		return nil, fmt.Errorf("shipping address deserialization error: %v", err)
@@ -149,14 +196,28 @@ func (r *OrderRepository) GetOrderByID(orderID uuid.UUID) (*domain.OrderAggregat
 			order.SagaID = parsedUUID
 		}
 	}
+	if correlationID.Valid {
+		if parsedUUID, err := uuid.Parse(correlationID.String); err == nil {
+			order.CorrelationID = parsedUUID
+		}
+	}
+	if pricedAt.Valid {
+		order.PricedAt = &pricedAt.Time
+	}
+	if err := scanFulfillment(order, policy, fulfillment, backorders, captured); err != nil {
+		return nil, err
+	}
 
 	return order, nil
 }
 
 func (r *OrderRepository) GetOrdersByCustomerID(customerID uuid.UUID) ([]*domain.OrderAggregate, error) {
+	defer metrics.ObserveDBQuery("GetOrdersByCustomerID", time.Now())
+
 	query := `
-		SELECT id, customer_id, items, total_amount, status,
-			   failure_reason, shipping_address, saga_id, created_at, updated_at
+		SELECT id, customer_id, items, total_amount, currency, status,
+			   failure_reason, shipping_address, saga_id, correlation_id, priced_at, fulfillment_policy,
+			   fulfillment, backorders, captured_amount, created_at, updated_at
 		FROM orders 
 		WHERE customer_id = $1
 		ORDER BY created_at DESC
@@ -173,17 +234,27 @@ func (r *OrderRepository) GetOrdersByCustomerID(customerID uuid.UUID) ([]*domain
 	for rows.Next() {
 		order := &domain.OrderAggregate{Order: &types.Order{}}
 		var itemsJSON, addressJSON []byte
-		var sagaID sql.NullString
+		var sagaID, correlationID, policy sql.NullString
+		var pricedAt sql.NullTime
+		var fulfillment, backorders []byte
+		var captured sql.NullInt64
 
 		err := rows.Scan(
 			&order.ID,
 			&order.CustomerID,
 			&itemsJSON,
 			&order.TotalAmount,
+			&order.TotalAmount.Currency,
 			&order.Status,
 			&order.FailureReason,
 			&addressJSON,
 			&sagaID,
+			&correlationID,
+			&pricedAt,
+			&policy,
+			&fulfillment,
+			&backorders,
+			&captured,
 			&order.CreatedAt,
 			&order.UpdatedAt,
 		)
@@ -195,6 +266,7 @@ func (r *OrderRepository) GetOrdersByCustomerID(customerID uuid.UUID) ([]*domain
 		if err := json.Unmarshal(itemsJSON, &order.Items); err != nil {
 			return nil, fmt.Errorf("items deserialization error: %v", err)
 		}
+		fillItemCurrency(order)
 
 		if err := json.Unmarshal(addressJSON, &order.ShippingAddress); err != nil {
 			return nil, fmt.Errorf("shipping address deserialization error: %v", err)
@@ -205,9 +277,66 @@ func (r *OrderRepository) GetOrdersByCustomerID(customerID uuid.UUID) ([]*domain
 				order.SagaID = parsedUUID
 			}
 		}
+		if correlationID.Valid {
+			if parsedUUID, err := uuid.Parse(correlationID.String); err == nil {
+				order.CorrelationID = parsedUUID
+			}
+		}
+		if pricedAt.Valid {
+			order.PricedAt = &pricedAt.Time
+		}
+		if err := scanFulfillment(order, policy, fulfillment, backorders, captured); err != nil {
+			return nil, err
+		}
 
 		orders = append(orders, order)
 	}
 
 	return orders, nil
 }
+
+// fillItemCurrency sets the order currency on item prices stored before
+// prices carried one
+func fillItemCurrency(order *domain.OrderAggregate) {
+	for i := range order.Items {
+		if order.Items[i].Price.Currency == "" {
+			order.Items[i].Price.Currency = order.TotalAmount.Currency
+		}
+	}
+}
+
+func fulfillmentJSON(order *domain.OrderAggregate) (fulfillment, backorders []byte, err error) {
+	if len(order.Fulfillment) > 0 {
+		if fulfillment, err = json.Marshal(order.Fulfillment); err != nil {
+			return nil, nil, fmt.Errorf("fulfillment serialization error: %v", err)
+		}
+	}
+	if len(order.Backorders) > 0 {
+		if backorders, err = json.Marshal(order.Backorders); err != nil {
+			return nil, nil, fmt.Errorf("backorders serialization error: %v", err)
+		}
+	}
+	return fulfillment, backorders, nil
+}
+
+// scanFulfillment fills the fulfillment columns, all empty for orders that
+// shipped complete or were placed before policies existed
+func scanFulfillment(order *domain.OrderAggregate, policy sql.NullString, fulfillment, backorders []byte, captured sql.NullInt64) error {
+	order.FulfillmentPolicy = types.FulfillmentPolicy(policy.String).OrDefault()
+
+	if len(fulfillment) > 0 {
+		if err := json.Unmarshal(fulfillment, &order.Fulfillment); err != nil {
+			return fmt.Errorf("fulfillment deserialization error: %v", err)
+		}
+	}
+	if len(backorders) > 0 {
+		if err := json.Unmarshal(backorders, &order.Backorders); err != nil {
+			return fmt.Errorf("backorders deserialization error: %v", err)
+		}
+	}
+	if captured.Valid {
+		amount := types.NewMoney(captured.Int64, order.TotalAmount.Currency)
+		order.CapturedAmount = &amount
+	}
+	return nil
+}
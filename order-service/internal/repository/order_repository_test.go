@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestOrderKeepsCorrelationID(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewOrderRepository(db)
+
+	order := createCompletedOrder(t, db, uuid.New(), 1)
+	order.AttachSaga(uuid.New(), uuid.New())
+	if err := repo.UpdateOrder(order); err != nil {
+		t.Fatalf("update order: %v", err)
+	}
+
+	stored, err := repo.GetOrderByID(order.ID)
+	if err != nil {
+		t.Fatalf("get order: %v", err)
+	}
+	if stored.SagaID != order.SagaID || stored.CorrelationID != order.CorrelationID {
+		t.Errorf("stored saga %s correlation %s, want %s and %s",
+			stored.SagaID, stored.CorrelationID, order.SagaID, order.CorrelationID)
+	}
+
+	orders, err := repo.GetOrdersByCustomerID(order.CustomerID)
+	if err != nil || len(orders) != 1 || orders[0].CorrelationID != order.CorrelationID {
+		t.Errorf("customer orders = %v, %v, want the order with correlation %s", orders, err, order.CorrelationID)
+	}
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/order-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/google/uuid"
+)
+
+// refundColumns selected by every refund query, in scanRefund order
+const refundColumns = `
+	id, order_id, saga_id, amount, currency, items, reason, status,
+	failure_reason, created_at, updated_at, completed_at`
+
+// CreateRefund builds a refund of the order with newRefund and stores it. The
+// order row stays locked from reading the previous refunds until the new one
+// is stored, so concurrent requests for the same order are validated one after
+// the other and cannot refund the same item or amount twice.
+func (r *OrderRepository) CreateRefund(orderID uuid.UUID, newRefund func(order *domain.OrderAggregate, previous []*domain.OrderRefund) (*domain.OrderRefund, error)) (*domain.OrderAggregate, *domain.OrderRefund, error) {
+	defer metrics.ObserveDBQuery("CreateRefund", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	order, err := getOrderByID(tx, orderID, "FOR UPDATE")
+	if err != nil {
+		return nil, nil, err
+	}
+	previous, err := getRefundsByOrderID(tx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refund, err := newRefund(order, previous)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := insertRefund(tx, refund); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("transaction commit error: %v", err)
+	}
+	return order, refund, nil
+}
+
+func insertRefund(tx *sql.Tx, refund *domain.OrderRefund) error {
+	itemsJSON, err := json.Marshal(refund.Items)
+	if err != nil {
+		return fmt.Errorf("refund items serialization error: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO order_refunds (
+			id, order_id, saga_id, amount, currency, items, reason, status,
+			failure_reason, created_at, updated_at, completed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`,
+		refund.ID,
+		refund.OrderID,
+		refund.SagaID,
+		refund.Amount,
+		refund.Amount.Currency,
+		itemsJSON,
+		refund.Reason,
+		refund.Status,
+		refund.FailureReason,
+		refund.CreatedAt,
+		refund.UpdatedAt,
+		refund.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("refund creation error: %v", err)
+	}
+
+	return nil
+}
+
+func (r *OrderRepository) UpdateRefund(refund *domain.OrderRefund) error {
+	defer metrics.ObserveDBQuery("UpdateRefund", time.Now())
+
+	result, err := r.db.Exec(`
+		UPDATE order_refunds
+		SET status = $2, failure_reason = $3, updated_at = $4, completed_at = $5
+		WHERE id = $1
+	`, refund.ID, refund.Status, refund.FailureReason, refund.UpdatedAt, refund.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("refund update error: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("refund not found: %s", refund.ID)
+	}
+
+	return nil
+}
+
+func (r *OrderRepository) GetRefundBySagaID(sagaID uuid.UUID) (*domain.OrderRefund, error) {
+	defer metrics.ObserveDBQuery("GetRefundBySagaID", time.Now())
+
+	refund, err := scanRefund(r.db.QueryRow(`
+		SELECT `+refundColumns+`
+		FROM order_refunds
+		WHERE saga_id = $1
+	`, sagaID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("refund not found for saga: %s", sagaID)
+		}
+		return nil, fmt.Errorf("refund receive error: %v", err)
+	}
+
+	return refund, nil
+}
+
+// GetRefundsByOrderID returns the refunds of the order, oldest first
+func (r *OrderRepository) GetRefundsByOrderID(orderID uuid.UUID) ([]*domain.OrderRefund, error) {
+	defer metrics.ObserveDBQuery("GetRefundsByOrderID", time.Now())
+
+	return getRefundsByOrderID(r.db, orderID)
+}
+
+func getRefundsByOrderID(db querier, orderID uuid.UUID) ([]*domain.OrderRefund, error) {
+	rows, err := db.Query(`
+		SELECT `+refundColumns+`
+		FROM order_refunds
+		WHERE order_id = $1
+		ORDER BY created_at
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("refunds retrieval error: %v", err)
+	}
+	defer rows.Close()
+
+	refunds := []*domain.OrderRefund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, fmt.Errorf("refund scan error: %v", err)
+		}
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRefund(row rowScanner) (*domain.OrderRefund, error) {
+	refund := &domain.OrderRefund{}
+	var itemsJSON []byte
+	var reason, failureReason sql.NullString
+	var completedAt sql.NullTime
+
+	err := row.Scan(
+		&refund.ID,
+		&refund.OrderID,
+		&refund.SagaID,
+		&refund.Amount,
+		&refund.Amount.Currency,
+		&itemsJSON,
+		&reason,
+		&refund.Status,
+		&failureReason,
+		&refund.CreatedAt,
+		&refund.UpdatedAt,
+		&completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(itemsJSON, &refund.Items); err != nil {
+		return nil, fmt.Errorf("refund items deserialization error: %v", err)
+	}
+
+	refund.Reason = reason.String
+	refund.FailureReason = failureReason.String
+	if completedAt.Valid {
+		refund.CompletedAt = &completedAt.Time
+	}
+
+	return refund, nil
+}
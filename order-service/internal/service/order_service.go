@@ -1,34 +1,66 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/order-service/internal/catalog"
 	"github.com/distributed-ecommerce-saga/order-service/internal/domain"
 	"github.com/distributed-ecommerce-saga/order-service/internal/repository"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/logging"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
-	"log"
 )
 
 type OrderService struct {
 	orderRepo *repository.OrderRepository
 	publisher *messaging.Publisher
+	catalog   *catalog.Client
 }
 
-func NewOrderService(orderRepo *repository.OrderRepository, publisher *messaging.Publisher) *OrderService {
+func NewOrderService(orderRepo *repository.OrderRepository, publisher *messaging.Publisher, catalogClient *catalog.Client) *OrderService {
 	return &OrderService{
 		orderRepo: orderRepo,
 		publisher: publisher,
+		catalog:   catalogClient,
 	}
 }
 
-func (s *OrderService) CreateOrder(request domain.CreateOrderRequest) (*domain.OrderAggregate, error) {
-	order := domain.NewOrderAggregate(
+// CreateOrder prices the items from the catalog, so the total is what the
+// catalog asks rather than what the client claims
+func (s *OrderService) CreateOrder(ctx context.Context, request domain.CreateOrderRequest) (*domain.OrderAggregate, error) {
+	pricedAt := time.Now()
+	products, err := s.catalog.Products(ctx, request.ProductIDs())
+	if err != nil {
+		return nil, fmt.Errorf("catalog lookup error: %w", err)
+	}
+
+	items, err := request.PriceItems(products)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := request.OrderCurrency(items)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := domain.NewOrderAggregate(
 		request.CustomerID,
-		request.ToOrderItems(),
+		currency,
+		items,
 		request.ToShippingAddress(),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("order total error: %w", err)
+	}
+	order.PricedAt = &pricedAt
+	order.FulfillmentPolicy = request.FulfillmentPolicy.OrDefault()
 
 	if !order.CanProcessSaga() {
 		return nil, fmt.Errorf("order is invalid for saga")
@@ -38,19 +70,19 @@ func (s *OrderService) CreateOrder(request domain.CreateOrderRequest) (*domain.O
 		return nil, fmt.Errorf("order creation error: %v", err)
 	}
 
-	log.Printf("Order created: OrderID=%s, CustomerID=%s, Amount=%.2f",
-		order.ID, order.CustomerID, order.TotalAmount)
+	ctx = logging.WithAttrs(ctx, logging.FieldOrderID, order.ID)
+	slog.InfoContext(ctx, "Order created", "customer_id", order.CustomerID, "amount", order.TotalAmount)
 
 	// publish event for saga
-	if err := s.publishOrderCreatedEvent(order); err != nil {
+	if err := s.publishOrderCreatedEvent(ctx, order); err != nil {
 		// Order created but saga not started
-		log.Printf("Saga creation error: %v", err)
+		slog.ErrorContext(ctx, "Saga creation error", "error", err)
 
 		order.UpdateStatus(types.OrderStatusFailed)
 		order.SetFailureReason(fmt.Sprintf("Saga creation error: %v", err))
 
 		if updateErr := s.orderRepo.UpdateOrder(order); updateErr != nil {
-			log.Printf("Order failed status update error: %v", updateErr)
+			slog.ErrorContext(ctx, "Order failed status update error", "error", updateErr)
 		}
 
 		return order, fmt.Errorf("saga creation error: %v", err)
@@ -59,7 +91,7 @@ func (s *OrderService) CreateOrder(request domain.CreateOrderRequest) (*domain.O
 	return order, nil
 }
 
-func (s *OrderService) publishOrderCreatedEvent(order *domain.OrderAggregate) error {
+func (s *OrderService) publishOrderCreatedEvent(ctx context.Context, order *domain.OrderAggregate) error {
 	event := events.SagaEvent{
 		ID:            uuid.New(),
 		SagaID:        uuid.New(),
@@ -72,18 +104,18 @@ func (s *OrderService) publishOrderCreatedEvent(order *domain.OrderAggregate) er
 		},
 	}
 
-	order.AttachSaga(event.SagaID)
+	order.AttachSaga(event.SagaID, event.CorrelationID)
 	order.UpdateStatus(types.OrderStatusProcessing)
 
 	if err := s.orderRepo.UpdateOrder(order); err != nil {
 		return fmt.Errorf("order saga ID update error: %v", err)
 	}
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("order created event publish error: %v", err)
 	}
 
-	log.Printf("Order created event published: SagaID=%s, OrderID=%s", event.SagaID, order.ID)
+	slog.InfoContext(ctx, "Order created event published", logging.FieldSagaID, event.SagaID)
 	return nil
 }
 
@@ -103,7 +135,14 @@ func (s *OrderService) GetOrdersByCustomerID(customerID uuid.UUID) ([]*domain.Or
 	return orders, nil
 }
 
-func (s *OrderService) ProcessSagaCompletionEvent(event events.SagaEvent) error {
+func (s *OrderService) ProcessSagaCompletionEvent(ctx context.Context, event events.SagaEvent) error {
+	switch event.EventType {
+	case events.OrderRefundCompletedEvent, events.OrderRefundFailedEvent:
+		return s.processRefundOutcome(ctx, event)
+	case events.OrderBackorderFulfilledEvent, events.OrderBackorderFailedEvent:
+		return s.processBackorderOutcome(ctx, event)
+	}
+
 	order, err := s.orderRepo.GetOrderByID(event.OrderID)
 	if err != nil {
 		return fmt.Errorf("order not found: %v", err)
@@ -111,8 +150,13 @@ func (s *OrderService) ProcessSagaCompletionEvent(event events.SagaEvent) error
 
 	switch event.EventType {
 	case events.OrderCompletedEvent:
+		var payload events.OrderCompletedPayload
+		if err := decodePayload(event.Payload, &payload); err != nil {
+			return fmt.Errorf("order completed payload error: %v", err)
+		}
+		order.RecordFulfillment(payload.Items, payload.Backorders, payload.CapturedAmount)
 		order.UpdateStatus(types.OrderStatusCompleted)
-		log.Printf("Order completed successfully: OrderID=%s", order.ID)
+		slog.InfoContext(ctx, "Order completed successfully", "backorders", len(payload.Backorders))
 
 	case events.OrderCancelledEvent:
 		order.UpdateStatus(types.OrderStatusCancelled)
@@ -123,7 +167,7 @@ func (s *OrderService) ProcessSagaCompletionEvent(event events.SagaEvent) error
 				}
 			}
 		}
-		log.Printf("Order is cancelled: OrderID=%s, Reason=%s", order.ID, order.FailureReason)
+		slog.InfoContext(ctx, "Order is cancelled", "reason", order.FailureReason)
 
 	default:
 		return fmt.Errorf("unknown saga completion event: %s", event.EventType)
@@ -135,3 +179,42 @@ func (s *OrderService) ProcessSagaCompletionEvent(event events.SagaEvent) error
 
 	return nil
 }
+
+// processBackorderOutcome records the result of a backorder's saga on the order
+func (s *OrderService) processBackorderOutcome(ctx context.Context, event events.SagaEvent) error {
+	var payload events.OrderBackorderPayload
+	if err := decodePayload(event.Payload, &payload); err != nil {
+		return fmt.Errorf("backorder payload error: %v", err)
+	}
+
+	order, err := s.orderRepo.GetOrderByID(event.OrderID)
+	if err != nil {
+		return fmt.Errorf("order not found: %v", err)
+	}
+
+	status := types.BackorderStatusFulfilled
+	if event.EventType == events.OrderBackorderFailedEvent {
+		status = types.BackorderStatusCancelled
+	}
+	if !order.UpdateBackorder(payload.BackorderID, event.SagaID, status) {
+		slog.WarnContext(ctx, "Unknown backorder of order", "backorder_id", payload.BackorderID)
+		return nil
+	}
+
+	slog.InfoContext(ctx, "Backorder finished", "backorder_id", payload.BackorderID,
+		"status", status, "amount", payload.Amount, "reason", payload.Reason)
+
+	if err := s.orderRepo.UpdateOrder(order); err != nil {
+		return fmt.Errorf("order backorder update error: %v", err)
+	}
+	return nil
+}
+
+// decodePayload converts an event payload, a JSON map once consumed, into out
+func decodePayload(payload interface{}, out interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/distributed-ecommerce-saga/order-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/logging"
+	"github.com/google/uuid"
+)
+
+// RequestRefund records a refund of the completed order and starts its refund
+// saga. The refund stays requested until the orchestrator reports the outcome.
+func (s *OrderService) RequestRefund(ctx context.Context, orderID uuid.UUID, request domain.CreateRefundRequest) (*domain.OrderRefund, error) {
+	order, refund, err := s.orderRepo.CreateRefund(orderID, func(order *domain.OrderAggregate, previous []*domain.OrderRefund) (*domain.OrderRefund, error) {
+		return domain.NewOrderRefund(order, request, previous)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = logging.WithAttrs(ctx, logging.FieldOrderID, order.ID)
+	slog.InfoContext(ctx, "Refund requested", "refund_id", refund.ID, "amount", refund.Amount, "items", len(refund.Items))
+
+	if err := s.publishRefundRequestedEvent(ctx, order, refund); err != nil {
+		slog.ErrorContext(ctx, "Refund saga creation error", "error", err)
+
+		refund.Fail(fmt.Sprintf("Refund saga creation error: %v", err))
+		if updateErr := s.orderRepo.UpdateRefund(refund); updateErr != nil {
+			slog.ErrorContext(ctx, "Refund failed status update error", "error", updateErr)
+		}
+
+		return refund, fmt.Errorf("refund saga creation error: %v", err)
+	}
+
+	return refund, nil
+}
+
+func (s *OrderService) GetRefunds(orderID uuid.UUID) ([]*domain.OrderRefund, error) {
+	return s.orderRepo.GetRefundsByOrderID(orderID)
+}
+
+// processRefundOutcome records the result of a refund saga
+func (s *OrderService) processRefundOutcome(ctx context.Context, event events.SagaEvent) error {
+	refund, err := s.orderRepo.GetRefundBySagaID(event.SagaID)
+	if err != nil {
+		return err
+	}
+
+	if refund.IsFinished() {
+		slog.InfoContext(ctx, "Refund outcome already recorded", "refund_id", refund.ID, "status", refund.Status)
+		return nil
+	}
+
+	if event.EventType == events.OrderRefundCompletedEvent {
+		refund.Complete()
+		slog.InfoContext(ctx, "Refund completed", "refund_id", refund.ID)
+	} else {
+		reason := "Refund failed"
+		if payload, ok := event.Payload.(map[string]interface{}); ok {
+			if r, ok := payload["reason"].(string); ok {
+				reason = r
+			}
+		}
+		refund.Fail(reason)
+		slog.WarnContext(ctx, "Refund failed", "refund_id", refund.ID, "reason", reason)
+	}
+
+	return s.orderRepo.UpdateRefund(refund)
+}
+
+// publishRefundRequestedEvent starts the refund saga in the correlation chain of
+// the order's checkout, so the refund can be traced back to the order
+func (s *OrderService) publishRefundRequestedEvent(ctx context.Context, order *domain.OrderAggregate, refund *domain.OrderRefund) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		SagaID:        refund.SagaID,
+		OrderID:       order.ID,
+		EventType:     events.OrderRefundRequestedEvent,
+		Service:       "order-service",
+		CorrelationID: order.CorrelationID,
+		Payload: map[string]interface{}{
+			"refund_id":   refund.ID,
+			"order_id":    order.ID,
+			"customer_id": order.CustomerID,
+			"amount":      refund.Amount,
+			"items":       refund.Items,
+			"reason":      refund.Reason,
+		},
+	})
+
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
+		return fmt.Errorf("refund requested event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Refund requested event published", logging.FieldSagaID, event.SagaID)
+	return nil
+}
@@ -0,0 +1,4 @@
+-- Amounts are stored as integer minor units (cents) with an ISO 4217 currency
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE orders ALTER COLUMN total_amount TYPE BIGINT USING ROUND(total_amount * 100)::BIGINT;
@@ -0,0 +1,16 @@
+-- Merchant initiated refunds of completed orders, each run by a refund saga
+CREATE TABLE IF NOT EXISTS order_refunds (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id),
+    saga_id UUID NOT NULL UNIQUE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    items JSONB NOT NULL DEFAULT '[]',
+    reason TEXT,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('requested', 'completed', 'failed')),
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_order_refunds_order_id ON order_refunds(order_id, created_at);
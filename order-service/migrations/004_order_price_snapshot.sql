@@ -0,0 +1,3 @@
+-- Items are priced from the inventory catalog; the items JSON keeps the price,
+-- SKU and name used, priced_at when they were read
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS priced_at TIMESTAMP WITH TIME ZONE;
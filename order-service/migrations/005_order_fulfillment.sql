@@ -0,0 +1,8 @@
+-- Orders choose what happens to out of stock items. Orders that did not ship
+-- complete keep what was reserved per product, their backorders and the amount
+-- charged at checkout.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fulfillment_policy VARCHAR(20) NOT NULL DEFAULT 'all_or_nothing'
+    CHECK (fulfillment_policy IN ('all_or_nothing', 'allow_partial', 'allow_backorder'));
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fulfillment JSONB;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS backorders JSONB;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS captured_amount BIGINT CHECK (captured_amount >= 0);
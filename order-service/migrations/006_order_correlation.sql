@@ -0,0 +1,2 @@
+-- The correlation ID of the checkout saga, which refund sagas of the order continue
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS correlation_id UUID;
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway/simulator"
+	"github.com/distributed-ecommerce-saga/shared-domain/lifecycle"
+	"github.com/distributed-ecommerce-saga/shared-domain/logging"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+)
+
+// Payment Gateway Simulator stands in for the external payment provider. Point
+// the payment service at it with PAYMENT_GATEWAY=http.
+func main() {
+	logging.Init("payment-gateway-simulator")
+	slog.Info("Payment Gateway Simulator starting")
+
+	shutdown := lifecycle.NewManager("Payment Gateway Simulator", lifecycle.TimeoutFromEnv(10*time.Second))
+
+	config := simulator.Config{
+		APIKey:           os.Getenv("SIMULATOR_API_KEY"),
+		AuthorizationTTL: getEnvDuration("SIMULATOR_AUTHORIZATION_TTL", 7*24*time.Hour),
+		WebhookURL:       os.Getenv("SIMULATOR_WEBHOOK_URL"),
+		WebhookSecret:    os.Getenv("SIMULATOR_WEBHOOK_SECRET"),
+		WebhookDelay:     getEnvDuration("SIMULATOR_WEBHOOK_DELAY", time.Second),
+	}
+	if path := os.Getenv("SIMULATOR_SCENARIOS_FILE"); path != "" {
+		scenarios, err := simulator.LoadScenarios(path)
+		if err != nil {
+			logging.Fatal("Scenario load error", "error", err)
+		}
+		config.Scenarios = scenarios
+		slog.Info("Scenarios loaded", "file", path, "count", len(scenarios))
+	}
+
+	app := fiber.New(fiber.Config{
+		AppName:               "Payment Gateway Simulator v1.0",
+		DisableStartupMessage: true,
+	})
+	app.Use(recover.New())
+	app.Use(logging.FiberMiddleware())
+
+	simulator.NewServer(config).Register(app)
+	shutdown.Register(lifecycle.StageHTTP, "fiber", app.ShutdownWithContext)
+
+	port := getEnvOrDefault("PORT", "8090")
+	slog.Info("Payment Gateway Simulator listening", "port", port)
+
+	go func() {
+		if err := app.Listen(":" + port); err != nil {
+			slog.Error("Server startup error", "error", err)
+			shutdown.Trigger()
+		}
+	}()
+
+	if err := shutdown.Wait(); err != nil {
+		logging.Fatal("Shutdown error", "error", err)
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
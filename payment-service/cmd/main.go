@@ -1,88 +1,175 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"os/signal"
 	"strconv"
-	"syscall"
+	"time"
 
+	"github.com/distributed-ecommerce-saga/payment-service/internal/fx"
 	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway"
 	"github.com/distributed-ecommerce-saga/payment-service/internal/handlers"
 	"github.com/distributed-ecommerce-saga/payment-service/internal/repository"
 	"github.com/distributed-ecommerce-saga/payment-service/internal/service"
+	"github.com/distributed-ecommerce-saga/shared-domain/lifecycle"
+	"github.com/distributed-ecommerce-saga/shared-domain/logging"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/resilience"
+	"github.com/distributed-ecommerce-saga/shared-domain/tracing"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	log.Println("🚀 Payment Service starting...")
+	logging.Init("payment-service")
+	slog.Info("Payment Service starting")
+
+	shutdown := lifecycle.NewManager("Payment Service", lifecycle.TimeoutFromEnv(30*time.Second))
+
+	shutdownTracing, err := tracing.Init(context.Background(), "payment-service")
+	if err != nil {
+		logging.Fatal("Tracing init error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageTelemetry, "trace exporter", shutdownTracing)
+
+	metrics.Init("payment-service")
 
 	// Database connection
 	db, err := initDatabase()
 	if err != nil {
-		log.Fatalf("Database connection error: %v", err)
+		logging.Fatal("Database connection error", "error", err)
 	}
-	defer db.Close()
+	shutdown.Register(lifecycle.StageDatabase, "postgres", func(ctx context.Context) error {
+		return db.Close()
+	})
 
 	// RabbitMQ connection
 	rabbitConfig := messaging.NewRabbitMQConfig()
 	rabbitClient := messaging.NewRabbitMQClient(rabbitConfig)
 
 	if err := rabbitClient.Connect(); err != nil {
-		log.Fatalf("RabbitMQ connection error: %v", err)
+		logging.Fatal("RabbitMQ connection error", "error", err)
 	}
-	defer rabbitClient.Close()
+	shutdown.Register(lifecycle.StageBroker, "rabbitmq", func(ctx context.Context) error {
+		return rabbitClient.Close()
+	})
+
+	gatewayPolicy := resilience.NewPolicy("payment-gateway", resilience.ConfigFromEnv("PAYMENT_GATEWAY", resilience.Config{
+		Timeout:       10 * time.Second,
+		Retry:         resilience.RetryConfig{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 2 * time.Second},
+		Breaker:       resilience.BreakerConfig{FailureThreshold: 5, OpenTimeout: 30 * time.Second},
+		MaxConcurrent: 10,
+		MaxWait:       time.Second,
+		Retryable:     gateway.IsRetryable,
+	}))
 
-	failureRate := getEnvFloat("PAYMENT_FAILURE_RATE", 0.1) // 10% failure rate
-	paymentGateway := gateway.NewMockPaymentGateway(failureRate)
+	provider, err := newPaymentGateway()
+	if err != nil {
+		logging.Fatal("Payment gateway config error", "error", err)
+	}
+	paymentGateway := gateway.NewResilientGateway(gateway.NewInstrumentedGateway(provider), gatewayPolicy)
 
 	// Dependencies injection
 	publisher := messaging.NewPublisher(rabbitClient)
+	shutdown.Register(lifecycle.StageOutbox, "event publisher", publisher.Flush)
 	consumer := messaging.NewConsumer(rabbitClient, "payment-service-queue", "payment-service")
 
+	settlementCurrency, err := types.ParseCurrency(getEnvOrDefault("SETTLEMENT_CURRENCY", string(types.DefaultCurrency)))
+	if err != nil {
+		logging.Fatal("Invalid SETTLEMENT_CURRENCY", "error", err)
+	}
+	rates, err := fx.ParseStaticRates(os.Getenv("FX_RATES"))
+	if err != nil {
+		logging.Fatal("Invalid FX_RATES", "error", err)
+	}
+
 	paymentRepo := repository.NewPaymentRepository(db)
-	paymentService := service.NewPaymentService(paymentRepo, paymentGateway, publisher)
+	paymentService := service.NewPaymentService(paymentRepo, paymentGateway, publisher, rates, settlementCurrency)
 	paymentHandler := handlers.NewPaymentHandler(paymentService)
 
+	// Resolves payments left pending or uncertain by crashes and gateway errors
+	reconciler := service.NewReconciler(paymentService, service.ReconciliationConfig{
+		Interval:         getEnvDuration("RECONCILIATION_INTERVAL", 5*time.Minute),
+		PendingAge:       getEnvDuration("RECONCILIATION_PENDING_AGE", 2*time.Minute),
+		Lookback:         getEnvDuration("RECONCILIATION_LOOKBACK", 24*time.Hour),
+		AuthorizationAge: getEnvDuration("RECONCILIATION_AUTHORIZATION_AGE", 7*24*time.Hour),
+		BatchSize:        100,
+	})
+	reconciliationHandler := handlers.NewReconciliationHandler(reconciler)
+
+	// Asynchronous payment results; without a secret the endpoint is not served
+	var webhookHandler *handlers.WebhookHandler
+	if secret := os.Getenv("PAYMENT_WEBHOOK_SECRET"); secret != "" {
+		webhookHandler = handlers.NewWebhookHandler(paymentService, secret,
+			getEnvDuration("PAYMENT_WEBHOOK_TOLERANCE", 5*time.Minute))
+	} else {
+		slog.Warn("PAYMENT_WEBHOOK_SECRET not set, provider webhooks disabled")
+	}
+
 	// Fiber app setup
 	app := setupFiberApp()
 
 	// Routes setup
-	setupRoutes(app, paymentHandler)
+	setupRoutes(app, paymentHandler, reconciliationHandler, webhookHandler)
 
 	// RabbitMQ event consumption başlat
-	go func() {
-		log.Println("🐰 RabbitMQ event consumption starting...")
-		if err := paymentHandler.StartConsuming(consumer); err != nil {
-			log.Printf("RabbitMQ consumption error: %v", err)
-		}
-	}()
+	if err := paymentHandler.StartConsuming(consumer); err != nil {
+		slog.Error("RabbitMQ consumption error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageConsumers, "rabbitmq consumer", consumer.Stop)
 
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+	reconciler.Start()
+	shutdown.Register(lifecycle.StageConsumers, "payment reconciler", reconciler.Stop)
+	shutdown.Register(lifecycle.StageDrain, "in-flight event handlers", consumer.Drain)
+	shutdown.Register(lifecycle.StageHTTP, "fiber", app.ShutdownWithContext)
+
+	// Server starting
+	port := getEnvOrDefault("PORT", "8002")
+	slog.Info("Payment Service listening", "port", port)
+	slog.Info("Settlement currency", "currency", settlementCurrency)
 
-		log.Println("🛑 Payment Service closing...")
-		if err := app.Shutdown(); err != nil {
-			log.Printf("Shutdown error: %v", err)
+	go func() {
+		if err := app.Listen(":" + port); err != nil {
+			slog.Error("Server startup error", "error", err)
+			shutdown.Trigger()
 		}
 	}()
 
-	// Server starting
-	port := getEnvOrDefault("PORT", "8002")
-	log.Printf("🌍 Payment Service running on: http://localhost:%s", port)
-	log.Printf("💳 Mock Payment Gateway active - Failure Rate: %.1f%%", failureRate*100)
+	if err := shutdown.Wait(); err != nil {
+		logging.Fatal("Shutdown error", "error", err)
+	}
+}
 
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatalf("Server startup error: %v", err)
+// newPaymentGateway selects the provider with PAYMENT_GATEWAY: the in-process
+// mock, or the HTTP adapter pointed at a real provider or the simulator
+func newPaymentGateway() (gateway.PaymentGateway, error) {
+	switch provider := getEnvOrDefault("PAYMENT_GATEWAY", "mock"); provider {
+	case "mock":
+		failureRate := getEnvFloat("PAYMENT_FAILURE_RATE", 0.1) // 10% failure rate
+		slog.Info("Mock Payment Gateway active", "failure_rate", failureRate)
+		return gateway.NewMockPaymentGateway(failureRate), nil
+	case "http":
+		baseURL := os.Getenv("PAYMENT_GATEWAY_URL")
+		if baseURL == "" {
+			return nil, fmt.Errorf("PAYMENT_GATEWAY_URL is required for the http gateway")
+		}
+		timeout := getEnvDuration("PAYMENT_GATEWAY_TIMEOUT", 10*time.Second) // Also the resilience policy timeout
+		slog.Info("HTTP Payment Gateway active", "url", baseURL, "timeout", timeout)
+		return gateway.NewHTTPPaymentGateway(gateway.HTTPGatewayConfig{
+			BaseURL: baseURL,
+			APIKey:  os.Getenv("PAYMENT_GATEWAY_API_KEY"),
+			Timeout: timeout,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_GATEWAY %q, expected mock or http", provider)
 	}
 }
 
@@ -110,7 +197,7 @@ func initDatabase() (*sql.DB, error) {
 		return nil, fmt.Errorf("database ping error: %v", err)
 	}
 
-	log.Printf("✅ Database connection success: %s", dbName)
+	slog.Info("Database connection successful", "database", dbName)
 	return db, nil
 }
 
@@ -121,9 +208,8 @@ func setupFiberApp() *fiber.App {
 	})
 
 	app.Use(recover.New())
-	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${method} ${path} - ${latency} | IP: ${ip}\n",
-	}))
+	app.Use(tracing.FiberMiddleware("payment-service"))
+	app.Use(logging.FiberMiddleware())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
@@ -133,7 +219,11 @@ func setupFiberApp() *fiber.App {
 	return app
 }
 
-func setupRoutes(app *fiber.App, paymentHandler *handlers.PaymentHandler) {
+func setupRoutes(app *fiber.App, paymentHandler *handlers.PaymentHandler,
+	reconciliationHandler *handlers.ReconciliationHandler, webhookHandler *handlers.WebhookHandler) {
+	// Prometheus scrape endpoint
+	app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+
 	// API v1 routes
 	api := app.Group("/api/v1")
 
@@ -142,7 +232,22 @@ func setupRoutes(app *fiber.App, paymentHandler *handlers.PaymentHandler) {
 
 	// Payment routes
 	orders := api.Group("/orders")
-	orders.Get("/:order_id/payment", paymentHandler.GetPaymentByOrderID) // GET /api/v1/orders/:order_id/payment
+	orders.Get("/:order_id/payment", paymentHandler.GetPaymentByOrderID)       // GET /api/v1/orders/:order_id/payment
+	orders.Get("/:order_id/payment/refunds", paymentHandler.GetPaymentRefunds) // GET /api/v1/orders/:order_id/payment/refunds
+
+	// Disputes are answered by the provider's dispute ID
+	api.Post("/disputes/:dispute_id/evidence", paymentHandler.SubmitDisputeEvidence) // POST /api/v1/disputes/:dispute_id/evidence
+
+	// Provider webhooks
+	if webhookHandler != nil {
+		api.Post("/webhooks/payment-provider", webhookHandler.HandleProviderWebhook) // POST /api/v1/webhooks/payment-provider
+	}
+
+	// Reconciliation reports for finance
+	reconciliation := api.Group("/reconciliation")
+	reconciliation.Get("/runs", reconciliationHandler.GetRuns)        // GET /api/v1/reconciliation/runs
+	reconciliation.Post("/runs", reconciliationHandler.TriggerRun)    // POST /api/v1/reconciliation/runs
+	reconciliation.Get("/runs/:run_id", reconciliationHandler.GetRun) // GET /api/v1/reconciliation/runs/:run_id[?format=csv]
 
 	app.Use("*", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -161,7 +266,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 		message = e.Message
 	}
 
-	log.Printf("Error: %v", err)
+	slog.ErrorContext(c.UserContext(), "Request error", "path", c.Path(), "error", err)
 
 	return c.Status(code).JSON(fiber.Map{
 		"success":   false,
@@ -186,3 +291,12 @@ func getEnvFloat(key string, defaultValue float64) float64 {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
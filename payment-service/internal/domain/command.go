@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/google/uuid"
+)
+
+// Saga commands handled by the payment service
+const (
+	CommandProcess   = "payment.process"
+	CommandAuthorize = "payment.authorize"
+	CommandCapture   = "payment.capture"
+	CommandVoid      = "payment.void"
+	CommandRefund    = "payment.refund"
+)
+
+type CommandStatus string
+
+const (
+	CommandStatusProcessing CommandStatus = "processing"
+	CommandStatusCompleted  CommandStatus = "completed"
+)
+
+// WebhookStatus of a provider event; each event is applied by one delivery
+type WebhookStatus string
+
+const (
+	WebhookStatusProcessing WebhookStatus = "processing"
+	WebhookStatusProcessed  WebhookStatus = "processed"
+)
+
+// PaymentCommand one saga step handled by the payment service. The idempotency
+// key is also sent to the gateway, so a retried provider call cannot charge twice.
+type PaymentCommand struct {
+	IdempotencyKey string            `json:"idempotency_key" db:"idempotency_key"`
+	SagaID         uuid.UUID         `json:"saga_id" db:"saga_id"`
+	Command        string            `json:"command" db:"command"`
+	PaymentID      uuid.UUID         `json:"payment_id,omitempty" db:"payment_id"` // Set once the payment row exists
+	Status         CommandStatus     `json:"status" db:"status"`
+	ResultEvent    *events.SagaEvent `json:"result_event,omitempty" db:"result_event"` // Reply replayed on redelivery
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	CompletedAt    *time.Time        `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// CommandKey idempotency key of a saga step
+func CommandKey(sagaID uuid.UUID, command string) string {
+	return sagaID.String() + ":" + command
+}
+
+func (c *PaymentCommand) IsCompleted() bool {
+	return c.Status == CommandStatusCompleted && c.ResultEvent != nil
+}
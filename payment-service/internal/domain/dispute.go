@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+type DisputeStatus string
+
+const (
+	DisputeStatusOpened            DisputeStatus = "opened"
+	DisputeStatusEvidenceSubmitted DisputeStatus = "evidence_submitted"
+	DisputeStatusWon               DisputeStatus = "won"
+	DisputeStatusLost              DisputeStatus = "lost" // The bank returned the money to the customer
+)
+
+// PaymentDispute a chargeback the customer raised with their bank after the
+// payment was charged
+type PaymentDispute struct {
+	ID                  string           `json:"id"` // Provider dispute ID
+	Status              DisputeStatus    `json:"status"`
+	Reason              string           `json:"reason,omitempty"`
+	Amount              types.Money      `json:"amount"`
+	Evidence            *DisputeEvidence `json:"evidence,omitempty"`
+	OpenedAt            time.Time        `json:"opened_at"`
+	EvidenceSubmittedAt *time.Time       `json:"evidence_submitted_at,omitempty"`
+	ClosedAt            *time.Time       `json:"closed_at,omitempty"`
+}
+
+// DisputeEvidence the merchant's side of a dispute, forwarded to the bank
+type DisputeEvidence struct {
+	ProductDescription     string `json:"product_description,omitempty"`
+	CustomerCommunication  string `json:"customer_communication,omitempty"`
+	ShippingCarrier        string `json:"shipping_carrier,omitempty"`
+	ShippingTrackingNumber string `json:"shipping_tracking_number,omitempty"`
+	RefundPolicy           string `json:"refund_policy,omitempty"`
+	UncategorizedText      string `json:"uncategorized_text,omitempty"`
+}
+
+func (e DisputeEvidence) IsEmpty() bool {
+	return e == DisputeEvidence{}
+}
+
+func (d *PaymentDispute) IsClosed() bool {
+	return d.Status == DisputeStatusWon || d.Status == DisputeStatusLost
+}
+
+// OpenDispute records a dispute the provider reported for a charged payment.
+// Reporting the same dispute again is a no-op.
+func (p *PaymentAggregate) OpenDispute(disputeID, reason string, amount types.Money) error {
+	if p.Dispute != nil && p.Dispute.ID == disputeID {
+		return nil
+	}
+	if p.Status != types.PaymentStatusCompleted && p.Status != types.PaymentStatusRefunded {
+		return fmt.Errorf("only charged payments can be disputed, current status: %s", p.Status)
+	}
+
+	now := time.Now()
+	p.Dispute = &PaymentDispute{
+		ID:       disputeID,
+		Status:   DisputeStatusOpened,
+		Reason:   reason,
+		Amount:   amount,
+		OpenedAt: now,
+	}
+	p.UpdatedAt = now
+	return nil
+}
+
+// CanSubmitDisputeEvidence evidence is accepted once, while the dispute is open
+func (p *PaymentAggregate) CanSubmitDisputeEvidence() bool {
+	return p.Dispute != nil && p.Dispute.Status == DisputeStatusOpened
+}
+
+// SubmitDisputeEvidence records the evidence the provider accepted
+func (p *PaymentAggregate) SubmitDisputeEvidence(evidence DisputeEvidence) error {
+	if !p.CanSubmitDisputeEvidence() {
+		return fmt.Errorf("evidence can only be submitted for an opened dispute, current dispute status: %s", p.disputeStatus())
+	}
+
+	now := time.Now()
+	p.Dispute.Evidence = &evidence
+	p.Dispute.Status = DisputeStatusEvidenceSubmitted
+	p.Dispute.EvidenceSubmittedAt = &now
+	p.UpdatedAt = now
+	return nil
+}
+
+// CloseDispute records the bank's decision. changed is false when the dispute
+// was already closed with the same outcome.
+func (p *PaymentAggregate) CloseDispute(disputeID string, status DisputeStatus) (changed bool, err error) {
+	if status != DisputeStatusWon && status != DisputeStatusLost {
+		return false, fmt.Errorf("invalid dispute outcome: %s", status)
+	}
+	if p.Dispute == nil || p.Dispute.ID != disputeID {
+		return false, fmt.Errorf("dispute not found on payment %s: %s", p.ID, disputeID)
+	}
+	if p.Dispute.Status == status {
+		return false, nil
+	}
+	if p.Dispute.IsClosed() {
+		return false, fmt.Errorf("dispute already closed as %s", p.Dispute.Status)
+	}
+
+	now := time.Now()
+	p.Dispute.Status = status
+	p.Dispute.ClosedAt = &now
+	p.UpdatedAt = now
+	return true, nil
+}
+
+func (p *PaymentAggregate) disputeStatus() DisputeStatus {
+	if p.Dispute == nil {
+		return "none"
+	}
+	return p.Dispute.Status
+}
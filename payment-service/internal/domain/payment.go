@@ -2,23 +2,31 @@ package domain
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
-	"time"
 )
 
 type PaymentAggregate struct {
 	*types.Payment
-	SagaID          uuid.UUID  `json:"saga_id" db:"saga_id"`
-	ExternalRef     string     `json:"external_ref,omitempty" db:"external_ref"` // Payment gateway reference
-	FailureReason   string     `json:"failure_reason,omitempty" db:"failure_reason"`
-	RefundedAmount  float64    `json:"refunded_amount" db:"refunded_amount"`
-	RefundReference string     `json:"refund_reference,omitempty" db:"refund_reference"`
-	ProcessedAt     *time.Time `json:"processed_at,omitempty" db:"processed_at"`
-	RefundedAt      *time.Time `json:"refunded_at,omitempty" db:"refunded_at"`
+	SagaID           uuid.UUID       `json:"saga_id" db:"saga_id"`
+	ExternalRef      string          `json:"external_ref,omitempty" db:"external_ref"` // Payment gateway reference
+	FailureReason    string          `json:"failure_reason,omitempty" db:"failure_reason"`
+	RefundedAmount   types.Money     `json:"refunded_amount" db:"refunded_amount"`
+	RefundReference  string          `json:"refund_reference,omitempty" db:"refund_reference"`
+	SettlementAmount types.Money     `json:"settlement_amount" db:"settlement_amount"`         // Amount in the settlement currency
+	ExchangeRate     string          `json:"exchange_rate,omitempty" db:"exchange_rate"`       // Capture to settlement rate
+	AuthorizationID  string          `json:"authorization_id,omitempty" db:"authorization_id"` // Gateway hold, set before capture
+	AuthorizedAt     *time.Time      `json:"authorized_at,omitempty" db:"authorized_at"`
+	ProcessedAt      *time.Time      `json:"processed_at,omitempty" db:"processed_at"` // Capture time
+	RefundedAt       *time.Time      `json:"refunded_at,omitempty" db:"refunded_at"`
+	VoidedAt         *time.Time      `json:"voided_at,omitempty" db:"voided_at"`
+	Dispute          *PaymentDispute `json:"dispute,omitempty"`
+	CorrelationID    uuid.UUID       `json:"correlation_id" db:"correlation_id"` // Of the saga, for events published outside a saga command
 }
 
-func NewPaymentAggregate(orderID, customerID, sagaID uuid.UUID, amount float64, paymentMethod string) *PaymentAggregate {
+func NewPaymentAggregate(orderID, customerID, sagaID uuid.UUID, amount types.Money, paymentMethod string) *PaymentAggregate {
 	return &PaymentAggregate{
 		Payment: &types.Payment{
 			ID:            uuid.New(),
@@ -31,8 +39,80 @@ func NewPaymentAggregate(orderID, customerID, sagaID uuid.UUID, amount float64,
 			UpdatedAt:     time.Now(),
 		},
 		SagaID:         sagaID,
-		RefundedAmount: 0.0,
+		RefundedAmount: types.NewMoney(0, amount.Currency),
+	}
+}
+
+// ApplyExchangeRate records the settlement amount and the rate it was converted with
+func (p *PaymentAggregate) ApplyExchangeRate(rate string, settlementAmount types.Money) {
+	p.ExchangeRate = rate
+	p.SettlementAmount = settlementAmount
+	p.UpdatedAt = time.Now()
+}
+
+// Authorize records a successful authorization; the funds are held but not charged
+func (p *PaymentAggregate) Authorize(authorizationID string) error {
+	if p.Status != types.PaymentStatusPending {
+		return fmt.Errorf("only pending payments can be authorized, current status: %s", p.Status)
 	}
+
+	p.Status = types.PaymentStatusAuthorized
+	p.AuthorizationID = authorizationID
+	now := time.Now()
+	p.AuthorizedAt = &now
+	p.UpdatedAt = now
+	return nil
+}
+
+// Capture charges the authorized amount
+func (p *PaymentAggregate) Capture(transactionID, externalRef string) error {
+	if !p.CanCapture() {
+		return fmt.Errorf("only authorized payments can be captured, current status: %s", p.Status)
+	}
+
+	p.ProcessPayment(transactionID, externalRef)
+	return nil
+}
+
+// CaptureLess lowers the amount to capture below the authorized amount, e.g.
+// when items of the order were out of stock. The rest of the hold is released
+// by the capture.
+func (p *PaymentAggregate) CaptureLess(amount, settlementAmount types.Money) error {
+	if !p.CanCapture() {
+		return fmt.Errorf("only authorized payments can be captured, current status: %s", p.Status)
+	}
+	if !amount.IsPositive() {
+		return fmt.Errorf("invalid capture amount: %s", amount)
+	}
+	if exceeds, err := amount.Cmp(p.Amount); err != nil || exceeds > 0 {
+		return fmt.Errorf("capture amount %s exceeds authorized amount %s", amount, p.Amount)
+	}
+
+	p.Amount = amount
+	p.SettlementAmount = settlementAmount
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// Void releases the authorization without charging the customer
+func (p *PaymentAggregate) Void() error {
+	if !p.CanVoid() {
+		return fmt.Errorf("only authorized payments can be voided, current status: %s", p.Status)
+	}
+
+	p.Status = types.PaymentStatusVoided
+	now := time.Now()
+	p.VoidedAt = &now
+	p.UpdatedAt = now
+	return nil
+}
+
+func (p *PaymentAggregate) CanCapture() bool {
+	return p.Status == types.PaymentStatusAuthorized
+}
+
+func (p *PaymentAggregate) CanVoid() bool {
+	return p.Status == types.PaymentStatusAuthorized
 }
 
 func (p *PaymentAggregate) ProcessPayment(transactionID, externalRef string) {
@@ -44,24 +124,29 @@ func (p *PaymentAggregate) ProcessPayment(transactionID, externalRef string) {
 	p.UpdatedAt = now
 }
 
-func (p *PaymentAggregate) RefundPayment(refundRef string, amount float64) error {
+func (p *PaymentAggregate) RefundPayment(refundRef string, amount types.Money) error {
 	// Business validation
 	if p.Status != types.PaymentStatusCompleted {
 		return fmt.Errorf("only completed payments can be refunded, current status: %s", p.Status)
 	}
 
-	if amount <= 0 || amount > p.Amount {
-		return fmt.Errorf("invalid refund amount: %.2f, max: %.2f", amount, p.Amount)
+	if !amount.IsPositive() {
+		return fmt.Errorf("invalid refund amount: %s, max: %s", amount, p.Amount)
+	}
+
+	refundedAmount, err := p.RefundedAmount.Add(amount)
+	if err != nil {
+		return fmt.Errorf("invalid refund amount: %w", err)
 	}
 
-	if p.RefundedAmount+amount > p.Amount {
-		return fmt.Errorf("total refund amount limit exceed: %.2f + %.2f > %.2f",
+	if exceeds, err := refundedAmount.Cmp(p.Amount); err != nil || exceeds > 0 {
+		return fmt.Errorf("total refund amount limit exceed: %s + %s > %s",
 			p.RefundedAmount, amount, p.Amount)
 	}
 
-	p.Status = types.PaymentStatusRefunded
-	p.RefundedAmount += amount
+	p.RefundedAmount = refundedAmount
 	p.RefundReference = refundRef
+	p.markRefundStatus()
 	now := time.Now()
 	p.RefundedAt = &now
 	p.UpdatedAt = now
@@ -69,16 +154,58 @@ func (p *PaymentAggregate) RefundPayment(refundRef string, amount float64) error
 	return nil
 }
 
+// AwaitProvider records the reference of a payment the provider accepted but
+// will confirm later by webhook; the payment stays pending until then
+func (p *PaymentAggregate) AwaitProvider(reference string) {
+	p.ExternalRef = reference
+	p.UpdatedAt = time.Now()
+}
+
+// RecordProviderRefund applies a refund total reported by the provider, for
+// refunds issued outside a saga. It returns the amount not yet recorded.
+func (p *PaymentAggregate) RecordProviderRefund(refundRef string, totalRefunded types.Money) (types.Money, error) {
+	if p.Status != types.PaymentStatusCompleted && p.Status != types.PaymentStatusRefunded {
+		return types.Money{}, fmt.Errorf("only charged payments can be refunded, current status: %s", p.Status)
+	}
+
+	added, err := totalRefunded.Sub(p.RefundedAmount)
+	if err != nil {
+		return types.Money{}, fmt.Errorf("invalid refund amount: %w", err)
+	}
+	if !added.IsPositive() {
+		return added, nil
+	}
+	if exceeds, err := totalRefunded.Cmp(p.Amount); err != nil || exceeds > 0 {
+		return types.Money{}, fmt.Errorf("total refund amount limit exceed: %s > %s", totalRefunded, p.Amount)
+	}
+
+	p.RefundedAmount = totalRefunded
+	p.RefundReference = refundRef
+	p.markRefundStatus()
+	now := time.Now()
+	p.RefundedAt = &now
+	p.UpdatedAt = now
+	return added, nil
+}
+
+// markRefundStatus a partially refunded payment stays completed so the rest
+// can still be refunded; only the last refund moves it to refunded
+func (p *PaymentAggregate) markRefundStatus() {
+	if p.IsFullyRefunded() {
+		p.Status = types.PaymentStatusRefunded
+	}
+}
+
 func (p *PaymentAggregate) CanRefund() bool {
-	return p.Status == types.PaymentStatusCompleted && p.RefundedAmount < p.Amount
+	return p.Status == types.PaymentStatusCompleted && p.GetRemainingRefundAmount().IsPositive()
 }
 
-func (p *PaymentAggregate) GetRemainingRefundAmount() float64 {
-	return p.Amount - p.RefundedAmount
+func (p *PaymentAggregate) GetRemainingRefundAmount() types.Money {
+	return types.NewMoney(p.Amount.Amount-p.RefundedAmount.Amount, p.Amount.Currency)
 }
 
 func (p *PaymentAggregate) IsFullyRefunded() bool {
-	return p.RefundedAmount >= p.Amount
+	return !p.GetRemainingRefundAmount().IsPositive()
 }
 
 func (p *PaymentAggregate) FailPayment(reason string) {
@@ -88,18 +215,33 @@ func (p *PaymentAggregate) FailPayment(reason string) {
 }
 
 type PaymentProcessRequest struct {
-	SagaID        uuid.UUID `json:"saga_id"`
-	OrderID       uuid.UUID `json:"order_id"`
-	CustomerID    uuid.UUID `json:"customer_id"`
-	Amount        float64   `json:"amount"`
-	PaymentMethod string    `json:"payment_method"`
+	SagaID        uuid.UUID   `json:"saga_id"`
+	OrderID       uuid.UUID   `json:"order_id"`
+	CustomerID    uuid.UUID   `json:"customer_id"`
+	Amount        types.Money `json:"amount"`
+	PaymentMethod string      `json:"payment_method"`
+}
+
+// PaymentCaptureRequest for saga
+type PaymentCaptureRequest struct {
+	SagaID    uuid.UUID `json:"saga_id"`
+	PaymentID uuid.UUID `json:"payment_id,omitempty"`
+	// Amount to capture when less than authorized, nil for all of it
+	Amount *types.Money `json:"amount,omitempty"`
+}
+
+// PaymentVoidRequest for saga
+type PaymentVoidRequest struct {
+	SagaID    uuid.UUID `json:"saga_id"`
+	PaymentID uuid.UUID `json:"payment_id,omitempty"`
+	Reason    string    `json:"reason"`
 }
 
 // PaymentRefundRequest for saga
 type PaymentRefundRequest struct {
-	SagaID        uuid.UUID `json:"saga_id"`
-	PaymentID     uuid.UUID `json:"payment_id,omitempty"`
-	TransactionID string    `json:"transaction_id,omitempty"`
-	Amount        float64   `json:"amount"`
-	Reason        string    `json:"reason"`
+	SagaID        uuid.UUID   `json:"saga_id"`
+	PaymentID     uuid.UUID   `json:"payment_id,omitempty"`
+	TransactionID string      `json:"transaction_id,omitempty"`
+	Amount        types.Money `json:"amount"`
+	Reason        string      `json:"reason"`
 }
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// GatewayErrorPrefix starts the failure reason of payments that failed because
+// the gateway call itself failed, not because the provider declined. The
+// provider may still have acted on such a request, so the reconciler checks them.
+const GatewayErrorPrefix = "Gateway error: "
+
+// ReconciliationAction what the reconciler did with one payment
+type ReconciliationAction string
+
+const (
+	// Local payment already matched the provider
+	ReconciliationConfirmed ReconciliationAction = "confirmed"
+	// Stuck pending payment resolved from the provider, missing saga reply emitted
+	ReconciliationAuthorized ReconciliationAction = "authorized"
+	ReconciliationCompleted  ReconciliationAction = "completed"
+	ReconciliationFailed     ReconciliationAction = "failed"
+	// Provider still processing a pending payment; its webhook settles it
+	ReconciliationAwaitingProvider ReconciliationAction = "awaiting_provider"
+	// Provider holds or charged money for a payment the saga saw fail
+	ReconciliationVoidedOrphan   ReconciliationAction = "voided_orphan_authorization"
+	ReconciliationRefundedOrphan ReconciliationAction = "refunded_orphan_charge"
+	// Old authorizations: released or captured at the provider, or still held
+	ReconciliationVoidedExpired      ReconciliationAction = "voided_expired_authorization"
+	ReconciliationCapturedAtProvider ReconciliationAction = "captured_at_provider"
+	ReconciliationStaleAuthorization ReconciliationAction = "stale_authorization"
+	ReconciliationUnresolved         ReconciliationAction = "unresolved"
+)
+
+// IsCorrection reports whether the action changed the local payment
+func (a ReconciliationAction) IsCorrection() bool {
+	switch a {
+	case ReconciliationAuthorized, ReconciliationCompleted, ReconciliationFailed,
+		ReconciliationVoidedOrphan, ReconciliationRefundedOrphan,
+		ReconciliationVoidedExpired, ReconciliationCapturedAtProvider:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsDiscrepancy reports whether finance should look at the entry: money moved
+// at the provider that the saga did not know about, or a hold is still open
+func (a ReconciliationAction) IsDiscrepancy() bool {
+	switch a {
+	case ReconciliationVoidedOrphan, ReconciliationRefundedOrphan,
+		ReconciliationCapturedAtProvider, ReconciliationStaleAuthorization:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReconciliationRun report of one reconciliation pass
+type ReconciliationRun struct {
+	ID            uuid.UUID             `json:"id" db:"id"`
+	StartedAt     time.Time             `json:"started_at" db:"started_at"`
+	FinishedAt    time.Time             `json:"finished_at" db:"finished_at"`
+	Checked       int                   `json:"checked" db:"checked"`
+	Corrected     int                   `json:"corrected" db:"corrected"`
+	Discrepancies int                   `json:"discrepancies" db:"discrepancies"`
+	Unresolved    int                   `json:"unresolved" db:"unresolved"`
+	Entries       []ReconciliationEntry `json:"entries,omitempty"`
+}
+
+type ReconciliationEntry struct {
+	PaymentID      uuid.UUID            `json:"payment_id" db:"payment_id"`
+	OrderID        uuid.UUID            `json:"order_id" db:"order_id"`
+	SagaID         uuid.UUID            `json:"saga_id" db:"saga_id"`
+	LocalStatus    types.PaymentStatus  `json:"local_status" db:"local_status"` // Before reconciliation
+	ProviderStatus string               `json:"provider_status,omitempty" db:"provider_status"`
+	Action         ReconciliationAction `json:"action" db:"action"`
+	Amount         types.Money          `json:"amount" db:"amount"`
+	Detail         string               `json:"detail,omitempty" db:"detail"`
+	CreatedAt      time.Time            `json:"created_at" db:"created_at"`
+}
+
+func NewReconciliationRun() *ReconciliationRun {
+	return &ReconciliationRun{ID: uuid.New(), StartedAt: time.Now()}
+}
+
+// Add records an entry and updates the run totals
+func (r *ReconciliationRun) Add(entry ReconciliationEntry) {
+	entry.CreatedAt = time.Now()
+	r.Entries = append(r.Entries, entry)
+
+	r.Checked++
+	if entry.Action.IsCorrection() {
+		r.Corrected++
+	}
+	if entry.Action.IsDiscrepancy() {
+		r.Discrepancies++
+	}
+	if entry.Action == ReconciliationUnresolved {
+		r.Unresolved++
+	}
+}
+
+func (r *ReconciliationRun) Finish() {
+	r.FinishedAt = time.Now()
+}
+
+// ReleaseOrphanedAuthorization records a hold the gateway placed for a payment
+// that was marked failed, after the reconciler voided it at the provider
+func (p *PaymentAggregate) ReleaseOrphanedAuthorization(authorizationID string) {
+	now := time.Now()
+	p.Status = types.PaymentStatusVoided
+	p.AuthorizationID = authorizationID
+	p.VoidedAt = &now
+	p.UpdatedAt = now
+}
+
+// RefundOrphanedCharge records a charge the gateway made for a payment that was
+// marked failed, after the reconciler refunded it in full at the provider
+func (p *PaymentAggregate) RefundOrphanedCharge(transactionID, externalRef, refundRef string) {
+	now := time.Now()
+	p.Status = types.PaymentStatusRefunded
+	p.TransactionID = transactionID
+	p.ExternalRef = externalRef
+	p.RefundedAmount = p.Amount
+	p.RefundReference = refundRef
+	p.ProcessedAt = &now
+	p.RefundedAt = &now
+	p.UpdatedAt = now
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+type RefundSource string
+
+const (
+	RefundSourceSaga     RefundSource = "saga"     // payment.refund command of a saga
+	RefundSourceProvider RefundSource = "provider" // Issued at the provider, learned from a webhook
+)
+
+// PaymentRefund one refund of a payment. A payment can be refunded in several
+// parts, the payment itself only keeps the running total.
+type PaymentRefund struct {
+	ID              uuid.UUID    `json:"id" db:"id"`
+	PaymentID       uuid.UUID    `json:"payment_id" db:"payment_id"`
+	SagaID          uuid.UUID    `json:"saga_id,omitempty" db:"saga_id"` // Nil for provider refunds
+	RefundReference string       `json:"refund_reference,omitempty" db:"refund_reference"`
+	Amount          types.Money  `json:"amount" db:"amount"`
+	Reason          string       `json:"reason,omitempty" db:"reason"`
+	Source          RefundSource `json:"source" db:"source"`
+	CreatedAt       time.Time    `json:"created_at" db:"created_at"`
+}
+
+func NewPaymentRefund(paymentID, sagaID uuid.UUID, reference string, amount types.Money, reason string, source RefundSource) *PaymentRefund {
+	return &PaymentRefund{
+		ID:              uuid.New(),
+		PaymentID:       paymentID,
+		SagaID:          sagaID,
+		RefundReference: reference,
+		Amount:          amount,
+		Reason:          reason,
+		Source:          source,
+		CreatedAt:       time.Now(),
+	}
+}
@@ -0,0 +1,100 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+// rateDigits number of fraction digits kept when a rate is stored or logged
+const rateDigits = 10
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// RateProvider source of exchange rates, e.g. a static table or an external FX API
+type RateProvider interface {
+	Rate(ctx context.Context, from, to types.Currency) (Rate, error)
+}
+
+// Rate converts amounts in From into To. Value is exact, so conversions never
+// go through float64.
+type Rate struct {
+	From  types.Currency
+	To    types.Currency
+	Value *big.Rat
+	AsOf  time.Time
+}
+
+// Identity rate for a currency converted into itself
+func Identity(currency types.Currency) Rate {
+	return Rate{From: currency, To: currency, Value: big.NewRat(1, 1), AsOf: time.Now()}
+}
+
+// ParseRate parses a decimal rate such as "1.0845"
+func ParseRate(from, to types.Currency, value string) (Rate, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || rat.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("invalid exchange rate %s/%s: %q", from, to, value)
+	}
+	return Rate{From: from, To: to, Value: rat, AsOf: time.Now()}, nil
+}
+
+// Inverse rate converting To back into From
+func (r Rate) Inverse() Rate {
+	return Rate{From: r.To, To: r.From, Value: new(big.Rat).Inv(r.Value), AsOf: r.AsOf}
+}
+
+// Convert converts money in From into To, rounding half away from zero to the
+// minor unit of the target currency
+func (r Rate) Convert(money types.Money) (types.Money, error) {
+	if money.Currency != r.From {
+		return types.Money{}, fmt.Errorf("%w: rate %s/%s applied to %s",
+			types.ErrCurrencyMismatch, r.From, r.To, money.Currency)
+	}
+
+	converted := new(big.Rat).Mul(big.NewRat(money.Amount, 1), r.Value)
+	converted.Mul(converted, minorUnitScale(r.From, r.To))
+
+	// Round half away from zero
+	num := new(big.Int).Set(converted.Num())
+	den := converted.Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(den) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(num.Sign())))
+	}
+	if !quotient.IsInt64() {
+		return types.Money{}, fmt.Errorf("%w: overflow converting %s", types.ErrInvalidAmount, money)
+	}
+
+	return types.NewMoney(quotient.Int64(), r.To), nil
+}
+
+// String decimal representation stored on the payment
+func (r Rate) String() string {
+	if r.Value == nil {
+		return ""
+	}
+	return r.Value.FloatString(rateDigits)
+}
+
+// minorUnitScale adjusts for currencies with a different number of minor unit digits
+func minorUnitScale(from, to types.Currency) *big.Rat {
+	diff := to.Exponent() - from.Exponent()
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(diff))), nil)
+	if diff < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), scale)
+	}
+	return new(big.Rat).SetInt(scale)
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
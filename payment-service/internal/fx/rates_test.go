@@ -0,0 +1,130 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+func mustRate(t *testing.T, from, to types.Currency, value string) Rate {
+	t.Helper()
+
+	rate, err := ParseRate(from, to, value)
+	if err != nil {
+		t.Fatalf("parse rate %s/%s=%s: %v", from, to, value, err)
+	}
+	return rate
+}
+
+func TestRateConvert(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  Rate
+		money types.Money
+		want  types.Money
+	}{
+		{"exact", mustRate(t, "EUR", "USD", "1.08"), types.NewMoney(10000, "EUR"), types.NewMoney(10800, "USD")},
+		{"rounds down", mustRate(t, "EUR", "USD", "1.0845"), types.NewMoney(100, "EUR"), types.NewMoney(108, "USD")},
+		{"rounds half up", mustRate(t, "EUR", "USD", "1.085"), types.NewMoney(100, "EUR"), types.NewMoney(109, "USD")},
+		{"rounds half away from zero", mustRate(t, "EUR", "USD", "1.085"), types.NewMoney(-100, "EUR"), types.NewMoney(-109, "USD")},
+		{"to fewer minor units", mustRate(t, "USD", "JPY", "150.25"), types.NewMoney(1999, "USD"), types.NewMoney(3003, "JPY")},
+		{"to more minor units", mustRate(t, "JPY", "KWD", "0.00205"), types.NewMoney(1000, "JPY"), types.NewMoney(2050, "KWD")},
+		{"identity", Identity("USD"), types.NewMoney(1234, "USD"), types.NewMoney(1234, "USD")},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.rate.Convert(tt.money)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: %s at %s = %v, %v, want %v", tt.name, tt.money, tt.rate, got, err, tt.want)
+		}
+	}
+}
+
+func TestRateConvertRoundTrip(t *testing.T) {
+	rate := mustRate(t, "EUR", "USD", "1.0845")
+	for _, amount := range []int64{1, 99, 1000, 123456, 99999999} {
+		money := types.NewMoney(amount, "EUR")
+
+		converted, err := rate.Convert(money)
+		if err != nil {
+			t.Fatalf("convert %s: %v", money, err)
+		}
+		back, err := rate.Inverse().Convert(converted)
+		if err != nil {
+			t.Fatalf("convert back %s: %v", converted, err)
+		}
+
+		// Rounding to a cent on the way out can only cost a cent on the way back
+		if diff := back.Amount - amount; diff < -1 || diff > 1 {
+			t.Errorf("%s -> %s -> %s, off by more than a minor unit", money, converted, back)
+		}
+	}
+}
+
+func TestRateConvertErrors(t *testing.T) {
+	rate := mustRate(t, "EUR", "USD", "1.08")
+	if _, err := rate.Convert(types.NewMoney(100, "GBP")); !errors.Is(err, types.ErrCurrencyMismatch) {
+		t.Errorf("converting GBP at EUR/USD error = %v, want ErrCurrencyMismatch", err)
+	}
+
+	large := mustRate(t, "USD", "JPY", "150")
+	if _, err := large.Convert(types.NewMoney(math.MaxInt64, "USD")); !errors.Is(err, types.ErrInvalidAmount) {
+		t.Errorf("converting an overflowing amount error = %v, want ErrInvalidAmount", err)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	if rate := mustRate(t, "EUR", "USD", " 1.0845 "); rate.String() != "1.0845000000" {
+		t.Errorf("String() = %q", rate.String())
+	}
+	if inverse := mustRate(t, "EUR", "USD", "2").Inverse(); inverse.From != "USD" || inverse.To != "EUR" || inverse.String() != "0.5000000000" {
+		t.Errorf("Inverse() = %s/%s %s", inverse.From, inverse.To, inverse)
+	}
+
+	for _, value := range []string{"", "abc", "0", "-1.08", "1,08"} {
+		if _, err := ParseRate("EUR", "USD", value); err == nil {
+			t.Errorf("ParseRate(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestParseStaticRates(t *testing.T) {
+	provider, err := ParseStaticRates("EUR/USD=1.08, gbp/usd=1.25,")
+	if err != nil {
+		t.Fatalf("parse rates: %v", err)
+	}
+
+	ctx := context.Background()
+	tests := []struct {
+		from, to types.Currency
+		money    types.Money
+		want     types.Money
+	}{
+		{"EUR", "USD", types.NewMoney(1000, "EUR"), types.NewMoney(1080, "USD")},
+		{"USD", "GBP", types.NewMoney(1000, "USD"), types.NewMoney(800, "GBP")},
+		{"JPY", "JPY", types.NewMoney(1000, "JPY"), types.NewMoney(1000, "JPY")},
+	}
+	for _, tt := range tests {
+		rate, err := provider.Rate(ctx, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("rate %s/%s: %v", tt.from, tt.to, err)
+			continue
+		}
+		if got, err := rate.Convert(tt.money); err != nil || got != tt.want {
+			t.Errorf("%s to %s = %v, %v, want %v", tt.money, tt.to, got, err, tt.want)
+		}
+	}
+
+	if _, err := provider.Rate(ctx, "EUR", "GBP"); !errors.Is(err, ErrRateNotFound) {
+		t.Errorf("EUR/GBP error = %v, want ErrRateNotFound", err)
+	}
+
+	for _, spec := range []string{"EUR/USD", "EURUSD=1.08", "EU/USD=1.08", "EUR/USD=0"} {
+		if _, err := ParseStaticRates(spec); err == nil {
+			t.Errorf("ParseStaticRates(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+// StaticRateProvider serves rates from a fixed table. Inverse pairs are derived,
+// so "EUR/USD" also answers USD to EUR.
+type StaticRateProvider struct {
+	rates map[string]Rate
+}
+
+func NewStaticRateProvider(rates ...Rate) *StaticRateProvider {
+	provider := &StaticRateProvider{rates: make(map[string]Rate, len(rates)*2)}
+	for _, rate := range rates {
+		provider.rates[pairKey(rate.From, rate.To)] = rate
+		if _, ok := provider.rates[pairKey(rate.To, rate.From)]; !ok {
+			provider.rates[pairKey(rate.To, rate.From)] = rate.Inverse()
+		}
+	}
+	return provider
+}
+
+// ParseStaticRates parses a table such as "EUR/USD=1.08,GBP/USD=1.27" (FX_RATES)
+func ParseStaticRates(spec string) (*StaticRateProvider, error) {
+	var rates []Rate
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pair, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate entry %q, expected FROM/TO=RATE", entry)
+		}
+		fromCode, toCode, ok := strings.Cut(pair, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid currency pair %q, expected FROM/TO", pair)
+		}
+
+		from, err := types.ParseCurrency(fromCode)
+		if err != nil {
+			return nil, err
+		}
+		to, err := types.ParseCurrency(toCode)
+		if err != nil {
+			return nil, err
+		}
+
+		rate, err := ParseRate(from, to, value)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return NewStaticRateProvider(rates...), nil
+}
+
+func (p *StaticRateProvider) Rate(_ context.Context, from, to types.Currency) (Rate, error) {
+	if from == to {
+		return Identity(from), nil
+	}
+
+	rate, ok := p.rates[pairKey(from, to)]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %s/%s", ErrRateNotFound, from, to)
+	}
+	return rate, nil
+}
+
+func pairKey(from, to types.Currency) string {
+	return string(from) + "/" + string(to)
+}
@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRetryable is matched by errors.Is for failures that may succeed when the
+// same request is sent again with the same idempotency key (timeouts, 5xx, 429)
+var ErrRetryable = errors.New("retryable payment gateway error")
+
+// GatewayError a provider call that failed without a business outcome. Declines
+// are not errors; they come back as a response with Success false.
+type GatewayError struct {
+	Operation  string
+	StatusCode int    // 0 when no response was received
+	Type       string // Provider error type, e.g. api_error
+	Code       string
+	Message    string
+	Retryable  bool
+	Err        error
+}
+
+func (e *GatewayError) Error() string {
+	message := e.Message
+	if message == "" && e.Err != nil {
+		message = e.Err.Error()
+	}
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("gateway %s failed: %s", e.Operation, message)
+	}
+	return fmt.Sprintf("gateway %s failed (status %d, %s): %s", e.Operation, e.StatusCode, e.Type, message)
+}
+
+func (e *GatewayError) Unwrap() error {
+	return e.Err
+}
+
+func (e *GatewayError) Is(target error) bool {
+	return target == ErrRetryable && e.Retryable
+}
+
+// IsRetryable reports whether err is a transient gateway failure
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRetryable)
+}
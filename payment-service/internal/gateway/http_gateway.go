@@ -0,0 +1,430 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway/providerapi"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+const (
+	defaultHTTPGatewayTimeout = 10 * time.Second
+	maxResponseBytes          = 1 << 20
+)
+
+type HTTPGatewayConfig struct {
+	BaseURL string        // e.g. http://payment-gateway-simulator:8090
+	APIKey  string        // Sent as a bearer token
+	Timeout time.Duration // Per request, including reading the body
+}
+
+// HTTPPaymentGateway talks to a Stripe-style payment provider over HTTP.
+// Authorizations are payment intents with manual capture; the intent ID is
+// used as both the authorization ID and the external reference.
+type HTTPPaymentGateway struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewHTTPPaymentGateway(config HTTPGatewayConfig) *HTTPPaymentGateway {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPGatewayTimeout
+	}
+
+	return &HTTPPaymentGateway{
+		baseURL: strings.TrimRight(config.BaseURL, "/"),
+		apiKey:  config.APIKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (g *HTTPPaymentGateway) ProcessPayment(ctx context.Context, request PaymentRequest) (*PaymentResponse, error) {
+	var intent providerapi.PaymentIntent
+	declined, err := g.do(ctx, "process_payment", http.MethodPost, "/v1/payment_intents",
+		idempotencyKey(request.IdempotencyKey, "payment", request.OrderID.String()),
+		intentRequest(request, providerapi.CaptureAutomatic), &intent)
+	if err != nil {
+		return nil, err
+	}
+
+	if declined != nil {
+		return &PaymentResponse{
+			Success:       false,
+			Status:        "failed",
+			Amount:        request.Amount,
+			ProcessedAt:   time.Now(),
+			FailureReason: declineReason(declined),
+		}, nil
+	}
+
+	if intent.Status == providerapi.StatusProcessing {
+		return &PaymentResponse{
+			Success:     true,
+			Pending:     true,
+			ExternalRef: intent.ID,
+			Status:      string(types.PaymentStatusPending),
+			Amount:      request.Amount,
+			ProcessedAt: time.Now(),
+		}, nil
+	}
+
+	return capturedResponse(intent), nil
+}
+
+func (g *HTTPPaymentGateway) Authorize(ctx context.Context, request PaymentRequest) (*AuthorizationResponse, error) {
+	var intent providerapi.PaymentIntent
+	declined, err := g.do(ctx, "authorize", http.MethodPost, "/v1/payment_intents",
+		idempotencyKey(request.IdempotencyKey, "authorize", request.OrderID.String()),
+		intentRequest(request, providerapi.CaptureManual), &intent)
+	if err != nil {
+		return nil, err
+	}
+
+	if declined != nil {
+		return &AuthorizationResponse{
+			Success:       false,
+			Amount:        request.Amount,
+			AuthorizedAt:  time.Now(),
+			FailureReason: declineReason(declined),
+		}, nil
+	}
+
+	if intent.Status == providerapi.StatusProcessing {
+		return &AuthorizationResponse{
+			Success:         true,
+			Pending:         true,
+			AuthorizationID: intent.ID,
+			Amount:          request.Amount,
+			AuthorizedAt:    time.Unix(intent.Created, 0),
+		}, nil
+	}
+
+	if intent.Status != providerapi.StatusRequiresCapture {
+		return nil, &GatewayError{
+			Operation: "authorize",
+			Message:   fmt.Sprintf("unexpected payment intent status %q", intent.Status),
+		}
+	}
+
+	response := &AuthorizationResponse{
+		Success:         true,
+		AuthorizationID: intent.ID,
+		Amount:          intentMoney(intent.AmountCapturable, intent.Currency),
+		AuthorizedAt:    time.Unix(intent.Created, 0),
+	}
+	if intent.CaptureBefore > 0 {
+		response.ExpiresAt = time.Unix(intent.CaptureBefore, 0)
+	}
+	return response, nil
+}
+
+func (g *HTTPPaymentGateway) Capture(ctx context.Context, request CaptureRequest) (*PaymentResponse, error) {
+	var intent providerapi.PaymentIntent
+	declined, err := g.do(ctx, "capture", http.MethodPost,
+		"/v1/payment_intents/"+url.PathEscape(request.AuthorizationID)+"/capture",
+		idempotencyKey(request.IdempotencyKey, "capture", request.AuthorizationID),
+		providerapi.CapturePaymentIntentRequest{AmountToCapture: request.Amount.Amount}, &intent)
+	if err != nil {
+		return nil, err
+	}
+
+	if declined != nil {
+		return &PaymentResponse{
+			Success:       false,
+			Status:        "failed",
+			Amount:        request.Amount,
+			ProcessedAt:   time.Now(),
+			FailureReason: declineReason(declined),
+		}, nil
+	}
+
+	return capturedResponse(intent), nil
+}
+
+func (g *HTTPPaymentGateway) Void(ctx context.Context, request VoidRequest) (*VoidResponse, error) {
+	var intent providerapi.PaymentIntent
+	declined, err := g.do(ctx, "void", http.MethodPost,
+		"/v1/payment_intents/"+url.PathEscape(request.AuthorizationID)+"/cancel",
+		idempotencyKey(request.IdempotencyKey, "void", request.AuthorizationID),
+		providerapi.CancelPaymentIntentRequest{CancellationReason: request.Reason}, &intent)
+	if err != nil {
+		return nil, err
+	}
+
+	if declined != nil {
+		return &VoidResponse{
+			Success:       false,
+			VoidedAt:      time.Now(),
+			FailureReason: declineReason(declined),
+		}, nil
+	}
+
+	return &VoidResponse{
+		Success:  true,
+		VoidedAt: time.Now(),
+	}, nil
+}
+
+func (g *HTTPPaymentGateway) RefundPayment(ctx context.Context, request RefundRequest) (*RefundResponse, error) {
+	reference := request.ExternalRef
+	if reference == "" {
+		reference = request.OriginalTransactionID
+	}
+
+	body := providerapi.CreateRefundRequest{
+		Amount: request.Amount.Amount,
+		Reason: request.Reason,
+	}
+	if request.ExternalRef != "" {
+		body.PaymentIntent = request.ExternalRef
+	} else {
+		body.Charge = request.OriginalTransactionID
+	}
+
+	var refund providerapi.Refund
+	declined, err := g.do(ctx, "refund_payment", http.MethodPost, "/v1/refunds",
+		idempotencyKey(request.IdempotencyKey, "refund", reference+"-"+strconv.FormatInt(request.Amount.Amount, 10)),
+		body, &refund)
+	if err != nil {
+		return nil, err
+	}
+
+	if declined != nil {
+		return &RefundResponse{
+			Success:       false,
+			Amount:        request.Amount,
+			RefundedAt:    time.Now(),
+			FailureReason: declineReason(declined),
+		}, nil
+	}
+
+	return &RefundResponse{
+		Success:         true,
+		RefundID:        refund.ID,
+		RefundReference: refund.ID,
+		Amount:          intentMoney(refund.Amount, refund.Currency),
+		RefundedAt:      time.Unix(refund.Created, 0),
+	}, nil
+}
+
+func (g *HTTPPaymentGateway) GetPaymentStatus(ctx context.Context, externalRef string) (*PaymentStatusResponse, error) {
+	var intent providerapi.PaymentIntent
+	if _, err := g.do(ctx, "get_payment_status", http.MethodGet,
+		"/v1/payment_intents/"+url.PathEscape(externalRef), "", nil, &intent); err != nil {
+		return nil, err
+	}
+
+	amount := intent.AmountReceived
+	if intent.Status == providerapi.StatusRequiresCapture {
+		amount = intent.AmountCapturable
+	}
+
+	return &PaymentStatusResponse{
+		Status:        intentPaymentStatus(intent.Status),
+		TransactionID: intent.LatestCharge,
+		Amount:        intentMoney(amount, intent.Currency),
+		ProcessedAt:   time.Unix(intent.Created, 0),
+	}, nil
+}
+
+func (g *HTTPPaymentGateway) SubmitDisputeEvidence(ctx context.Context, request DisputeEvidenceRequest) (*DisputeEvidenceResponse, error) {
+	var dispute providerapi.Dispute
+	declined, err := g.do(ctx, "submit_dispute_evidence", http.MethodPost,
+		"/v1/disputes/"+url.PathEscape(request.DisputeID),
+		idempotencyKey(request.IdempotencyKey, "dispute_evidence", request.DisputeID),
+		providerapi.UpdateDisputeRequest{
+			Evidence: providerapi.DisputeEvidence(request.Evidence),
+			Submit:   true,
+		}, &dispute)
+	if err != nil {
+		return nil, err
+	}
+
+	if declined != nil {
+		return &DisputeEvidenceResponse{
+			Success:       false,
+			SubmittedAt:   time.Now(),
+			FailureReason: declineReason(declined),
+		}, nil
+	}
+
+	return &DisputeEvidenceResponse{
+		Success:     true,
+		Status:      dispute.Status,
+		SubmittedAt: time.Now(),
+	}, nil
+}
+
+// do sends one request. A card decline is returned as the provider error with a
+// nil error; every other non-2xx response becomes a *GatewayError.
+func (g *HTTPPaymentGateway) do(ctx context.Context, operation, method, path, idempotencyKey string, body, out interface{}) (*providerapi.Error, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, &GatewayError{Operation: operation, Message: "request encode error", Err: err}
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, reader)
+	if err != nil {
+		return nil, &GatewayError{Operation: operation, Err: err}
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if g.apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+g.apiKey)
+	}
+	if idempotencyKey != "" {
+		request.Header.Set(providerapi.IdempotencyKeyHeader, idempotencyKey)
+	}
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		// Timeout or connection failure: the provider may or may not have acted
+		// on the request, resending it with the same idempotency key is safe.
+		// A cancelled caller context is not worth retrying.
+		return nil, &GatewayError{
+			Operation: operation,
+			Retryable: !errors.Is(err, context.Canceled),
+			Err:       err,
+		}
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxResponseBytes))
+	if err != nil {
+		return nil, &GatewayError{Operation: operation, StatusCode: response.StatusCode, Retryable: true, Err: err}
+	}
+
+	if response.Header.Get(providerapi.IdempotentReplayedHeader) == "true" {
+		slog.DebugContext(ctx, "Payment provider replayed idempotent response",
+			"operation", operation, "idempotency_key", idempotencyKey)
+	}
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, &GatewayError{
+				Operation:  operation,
+				StatusCode: response.StatusCode,
+				Message:    "response decode error",
+				Err:        err,
+			}
+		}
+		return nil, nil
+	}
+
+	var apiError providerapi.ErrorResponse
+	if err := json.Unmarshal(data, &apiError); err != nil || apiError.Error.Message == "" {
+		apiError.Error.Message = http.StatusText(response.StatusCode)
+	}
+
+	if response.StatusCode == http.StatusPaymentRequired && apiError.Error.Type == providerapi.ErrorTypeCard {
+		return &apiError.Error, nil
+	}
+
+	return nil, &GatewayError{
+		Operation:  operation,
+		StatusCode: response.StatusCode,
+		Type:       apiError.Error.Type,
+		Code:       apiError.Error.Code,
+		Message:    apiError.Error.Message,
+		Retryable:  retryableResponse(response),
+	}
+}
+
+// retryableResponse prefers the provider's own hint, then falls back to the
+// status code: rate limits, idempotency conflicts and server errors are transient
+func retryableResponse(response *http.Response) bool {
+	if hint := response.Header.Get(providerapi.ShouldRetryHeader); hint != "" {
+		if retry, err := strconv.ParseBool(hint); err == nil {
+			return retry
+		}
+	}
+
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusConflict:
+		return true
+	default:
+		return response.StatusCode >= 500
+	}
+}
+
+func intentRequest(request PaymentRequest, captureMethod string) providerapi.CreatePaymentIntentRequest {
+	return providerapi.CreatePaymentIntentRequest{
+		Amount:        request.Amount.Amount,
+		Currency:      strings.ToLower(string(request.Amount.Currency)),
+		PaymentMethod: request.PaymentMethod,
+		CaptureMethod: captureMethod,
+		Description:   request.Description,
+		Metadata: map[string]string{
+			"order_id":            request.OrderID.String(),
+			"customer_id":         request.CustomerID.String(),
+			"settlement_currency": string(request.SettlementCurrency),
+		},
+	}
+}
+
+func capturedResponse(intent providerapi.PaymentIntent) *PaymentResponse {
+	return &PaymentResponse{
+		Success:       true,
+		TransactionID: intent.LatestCharge,
+		ExternalRef:   intent.ID,
+		Status:        "completed",
+		Amount:        intentMoney(intent.AmountReceived, intent.Currency),
+		ProcessedAt:   time.Now(),
+	}
+}
+
+func intentMoney(amount int64, currency string) types.Money {
+	return types.NewMoney(amount, types.Currency(strings.ToUpper(currency)))
+}
+
+// intentPaymentStatus maps provider statuses onto payment statuses
+func intentPaymentStatus(status string) string {
+	switch status {
+	case providerapi.StatusProcessing:
+		return string(types.PaymentStatusPending)
+	case providerapi.StatusSucceeded:
+		return string(types.PaymentStatusCompleted)
+	case providerapi.StatusRequiresCapture:
+		return string(types.PaymentStatusAuthorized)
+	case providerapi.StatusCanceled:
+		return string(types.PaymentStatusVoided)
+	case providerapi.StatusFailed:
+		return string(types.PaymentStatusFailed)
+	default:
+		return status
+	}
+}
+
+func declineReason(apiError *providerapi.Error) string {
+	if apiError.DeclineCode != "" {
+		return fmt.Sprintf("%s (%s)", apiError.Message, apiError.DeclineCode)
+	}
+	return apiError.Message
+}
+
+// idempotencyKey uses the caller's key, or derives a stable one from the request
+func idempotencyKey(key, operation, reference string) string {
+	if key != "" {
+		return key
+	}
+	return operation + "-" + reference
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+)
+
+// InstrumentedGateway records the latency and outcome of every call to the wrapped gateway
+type InstrumentedGateway struct {
+	next PaymentGateway
+}
+
+func NewInstrumentedGateway(next PaymentGateway) *InstrumentedGateway {
+	return &InstrumentedGateway{next: next}
+}
+
+func (g *InstrumentedGateway) ProcessPayment(ctx context.Context, request PaymentRequest) (*PaymentResponse, error) {
+	started := time.Now()
+	response, err := g.next.ProcessPayment(ctx, request)
+	observe("process_payment", started, err, response != nil && !response.Success)
+	return response, err
+}
+
+func (g *InstrumentedGateway) Authorize(ctx context.Context, request PaymentRequest) (*AuthorizationResponse, error) {
+	started := time.Now()
+	response, err := g.next.Authorize(ctx, request)
+	observe("authorize", started, err, response != nil && !response.Success)
+	return response, err
+}
+
+func (g *InstrumentedGateway) Capture(ctx context.Context, request CaptureRequest) (*PaymentResponse, error) {
+	started := time.Now()
+	response, err := g.next.Capture(ctx, request)
+	observe("capture", started, err, response != nil && !response.Success)
+	return response, err
+}
+
+func (g *InstrumentedGateway) Void(ctx context.Context, request VoidRequest) (*VoidResponse, error) {
+	started := time.Now()
+	response, err := g.next.Void(ctx, request)
+	observe("void", started, err, response != nil && !response.Success)
+	return response, err
+}
+
+func (g *InstrumentedGateway) RefundPayment(ctx context.Context, request RefundRequest) (*RefundResponse, error) {
+	started := time.Now()
+	response, err := g.next.RefundPayment(ctx, request)
+	observe("refund_payment", started, err, response != nil && !response.Success)
+	return response, err
+}
+
+func (g *InstrumentedGateway) GetPaymentStatus(ctx context.Context, externalRef string) (*PaymentStatusResponse, error) {
+	started := time.Now()
+	response, err := g.next.GetPaymentStatus(ctx, externalRef)
+	observe("get_payment_status", started, err, false)
+	return response, err
+}
+
+func (g *InstrumentedGateway) SubmitDisputeEvidence(ctx context.Context, request DisputeEvidenceRequest) (*DisputeEvidenceResponse, error) {
+	started := time.Now()
+	response, err := g.next.SubmitDisputeEvidence(ctx, request)
+	observe("submit_dispute_evidence", started, err, response != nil && !response.Success)
+	return response, err
+}
+
+func observe(operation string, started time.Time, err error, declined bool) {
+	outcome := metrics.Outcome(err)
+	if err == nil && declined {
+		outcome = metrics.OutcomeDeclined
+	}
+	metrics.GatewayRequestDuration.WithLabelValues(operation, outcome).Observe(time.Since(started).Seconds())
+}
@@ -1,80 +1,161 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
+	"net/http"
+	"sync"
 	"time"
 
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway/providerapi"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
 )
 
 // PaymentGateway external payment provider interface
 type PaymentGateway interface {
-	ProcessPayment(request PaymentRequest) (*PaymentResponse, error)
-	RefundPayment(request RefundRequest) (*RefundResponse, error)
-	GetPaymentStatus(externalRef string) (*PaymentStatusResponse, error)
+	// ProcessPayment authorizes and captures in one call
+	ProcessPayment(ctx context.Context, request PaymentRequest) (*PaymentResponse, error)
+	// Authorize holds the amount on the customer's payment method without charging it
+	Authorize(ctx context.Context, request PaymentRequest) (*AuthorizationResponse, error)
+	// Capture charges a previously authorized amount
+	Capture(ctx context.Context, request CaptureRequest) (*PaymentResponse, error)
+	// Void releases an authorization that was not captured
+	Void(ctx context.Context, request VoidRequest) (*VoidResponse, error)
+	RefundPayment(ctx context.Context, request RefundRequest) (*RefundResponse, error)
+	GetPaymentStatus(ctx context.Context, externalRef string) (*PaymentStatusResponse, error)
+	// SubmitDisputeEvidence sends the merchant's side of a chargeback to the bank
+	SubmitDisputeEvidence(ctx context.Context, request DisputeEvidenceRequest) (*DisputeEvidenceResponse, error)
 }
 
 type PaymentRequest struct {
-	OrderID       uuid.UUID `json:"order_id"`
-	CustomerID    uuid.UUID `json:"customer_id"`
-	Amount        float64   `json:"amount"`
-	Currency      string    `json:"currency"`
-	PaymentMethod string    `json:"payment_method"`
-	Description   string    `json:"description"`
+	OrderID            uuid.UUID      `json:"order_id"`
+	CustomerID         uuid.UUID      `json:"customer_id"`
+	Amount             types.Money    `json:"amount"` // Charged in the order currency
+	SettlementCurrency types.Currency `json:"settlement_currency"`
+	PaymentMethod      string         `json:"payment_method"`
+	Description        string         `json:"description"`
+	IdempotencyKey     string         `json:"idempotency_key,omitempty"` // Derived from the request when empty
 }
 
+// PaymentResponse Success with Pending means the provider accepted the payment
+// but reports the outcome later by webhook; ExternalRef identifies it
 type PaymentResponse struct {
+	Success       bool        `json:"success"`
+	Pending       bool        `json:"pending,omitempty"`
+	TransactionID string      `json:"transaction_id"`
+	ExternalRef   string      `json:"external_ref"`
+	Status        string      `json:"status"`
+	Amount        types.Money `json:"amount"`
+	ProcessedAt   time.Time   `json:"processed_at"`
+	FailureReason string      `json:"failure_reason,omitempty"`
+}
+
+// AuthorizationResponse Success with Pending means the outcome follows by
+// webhook; AuthorizationID identifies the pending authorization
+type AuthorizationResponse struct {
+	Success         bool        `json:"success"`
+	Pending         bool        `json:"pending,omitempty"`
+	AuthorizationID string      `json:"authorization_id"`
+	Amount          types.Money `json:"amount"`
+	AuthorizedAt    time.Time   `json:"authorized_at"`
+	ExpiresAt       time.Time   `json:"expires_at"`
+	FailureReason   string      `json:"failure_reason,omitempty"`
+}
+
+type CaptureRequest struct {
+	AuthorizationID string      `json:"authorization_id"`
+	Amount          types.Money `json:"amount"`
+	IdempotencyKey  string      `json:"idempotency_key,omitempty"`
+}
+
+type VoidRequest struct {
+	AuthorizationID string `json:"authorization_id"`
+	Reason          string `json:"reason"`
+	IdempotencyKey  string `json:"idempotency_key,omitempty"`
+}
+
+type VoidResponse struct {
 	Success       bool      `json:"success"`
-	TransactionID string    `json:"transaction_id"`
-	ExternalRef   string    `json:"external_ref"`
-	Status        string    `json:"status"`
-	Amount        float64   `json:"amount"`
-	ProcessedAt   time.Time `json:"processed_at"`
+	VoidedAt      time.Time `json:"voided_at"`
 	FailureReason string    `json:"failure_reason,omitempty"`
 }
 
 type RefundRequest struct {
-	OriginalTransactionID string  `json:"original_transaction_id"`
-	ExternalRef           string  `json:"external_ref"`
-	Amount                float64 `json:"amount"`
-	Reason                string  `json:"reason"`
+	OriginalTransactionID string      `json:"original_transaction_id"`
+	ExternalRef           string      `json:"external_ref"`
+	Amount                types.Money `json:"amount"`
+	Reason                string      `json:"reason"`
+	IdempotencyKey        string      `json:"idempotency_key,omitempty"`
 }
 
 type RefundResponse struct {
-	Success         bool      `json:"success"`
-	RefundID        string    `json:"refund_id"`
-	RefundReference string    `json:"refund_reference"`
-	Amount          float64   `json:"amount"`
-	RefundedAt      time.Time `json:"refunded_at"`
-	FailureReason   string    `json:"failure_reason,omitempty"`
+	Success         bool        `json:"success"`
+	RefundID        string      `json:"refund_id"`
+	RefundReference string      `json:"refund_reference"`
+	Amount          types.Money `json:"amount"`
+	RefundedAt      time.Time   `json:"refunded_at"`
+	FailureReason   string      `json:"failure_reason,omitempty"`
 }
 
 type PaymentStatusResponse struct {
-	Status        string    `json:"status"`
-	TransactionID string    `json:"transaction_id"`
-	Amount        float64   `json:"amount"`
-	ProcessedAt   time.Time `json:"processed_at"`
+	Status        string      `json:"status"`
+	TransactionID string      `json:"transaction_id"`
+	Amount        types.Money `json:"amount"`
+	ProcessedAt   time.Time   `json:"processed_at"`
+}
+
+type DisputeEvidenceRequest struct {
+	DisputeID      string          `json:"dispute_id"`
+	Evidence       DisputeEvidence `json:"evidence"`
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+}
+
+type DisputeEvidence struct {
+	ProductDescription     string `json:"product_description,omitempty"`
+	CustomerCommunication  string `json:"customer_communication,omitempty"`
+	ShippingCarrier        string `json:"shipping_carrier,omitempty"`
+	ShippingTrackingNumber string `json:"shipping_tracking_number,omitempty"`
+	RefundPolicy           string `json:"refund_policy,omitempty"`
+	UncategorizedText      string `json:"uncategorized_text,omitempty"`
+}
+
+type DisputeEvidenceResponse struct {
+	Success       bool      `json:"success"`
+	Status        string    `json:"status"` // Provider dispute status after the submission
+	SubmittedAt   time.Time `json:"submitted_at"`
+	FailureReason string    `json:"failure_reason,omitempty"`
 }
 
+// mockAuthorizationTTL how long the mock provider holds authorized funds
+const mockAuthorizationTTL = 7 * 24 * time.Hour
+
 // MockPaymentGateway mock payment gateway for test
 type MockPaymentGateway struct {
 	FailureRate float64 // 0.0 - 1.0 arası hata oranı
+
+	mu             sync.Mutex
+	authorizations map[string]types.Money            // Open authorizations by ID
+	statuses       map[string]*PaymentStatusResponse // Last known state by authorization ID or external ref
 }
 
 func NewMockPaymentGateway(failureRate float64) *MockPaymentGateway {
 	return &MockPaymentGateway{
-		FailureRate: failureRate,
+		FailureRate:    failureRate,
+		authorizations: make(map[string]types.Money),
+		statuses:       make(map[string]*PaymentStatusResponse),
 	}
 }
 
-func (m *MockPaymentGateway) ProcessPayment(request PaymentRequest) (*PaymentResponse, error) {
-	log.Printf("Mock Payment Gateway: Processing payment for Order %s, Amount: %.2f",
-		request.OrderID, request.Amount)
+func (m *MockPaymentGateway) ProcessPayment(ctx context.Context, request PaymentRequest) (*PaymentResponse, error) {
+	slog.DebugContext(ctx, "Mock Payment Gateway: processing payment", "order_id", request.OrderID, "amount", request.Amount)
 
 	// Simulate processing delay
-	time.Sleep(time.Millisecond * 500)
+	if err := simulateLatency(ctx, time.Millisecond*500); err != nil {
+		return nil, err
+	}
 
 	// Random failure simulation
 	if rand.Float64() < m.FailureRate {
@@ -89,6 +170,82 @@ func (m *MockPaymentGateway) ProcessPayment(request PaymentRequest) (*PaymentRes
 
 	transactionID := fmt.Sprintf("TXN_%d", time.Now().Unix())
 	externalRef := fmt.Sprintf("REF_%s", uuid.New().String()[:8])
+	m.recordStatus("completed", transactionID, request.Amount, externalRef)
+
+	return &PaymentResponse{
+		Success:       true,
+		TransactionID: transactionID,
+		ExternalRef:   externalRef,
+		Status:        "completed",
+		Amount:        request.Amount,
+		ProcessedAt:   time.Now(),
+	}, nil
+}
+
+func (m *MockPaymentGateway) Authorize(ctx context.Context, request PaymentRequest) (*AuthorizationResponse, error) {
+	slog.DebugContext(ctx, "Mock Payment Gateway: authorizing payment", "order_id", request.OrderID, "amount", request.Amount)
+
+	if err := simulateLatency(ctx, time.Millisecond*300); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if rand.Float64() < m.FailureRate {
+		return &AuthorizationResponse{
+			Success:       false,
+			Amount:        request.Amount,
+			AuthorizedAt:  now,
+			FailureReason: "Insufficient funds", // Mock failure reason
+		}, nil
+	}
+
+	authorizationID := fmt.Sprintf("AUTH_%s", uuid.New().String()[:8])
+
+	m.mu.Lock()
+	m.authorizations[authorizationID] = request.Amount
+	m.mu.Unlock()
+	m.recordStatus(string(types.PaymentStatusAuthorized), "", request.Amount, authorizationID)
+
+	return &AuthorizationResponse{
+		Success:         true,
+		AuthorizationID: authorizationID,
+		Amount:          request.Amount,
+		AuthorizedAt:    now,
+		ExpiresAt:       now.Add(mockAuthorizationTTL),
+	}, nil
+}
+
+func (m *MockPaymentGateway) Capture(ctx context.Context, request CaptureRequest) (*PaymentResponse, error) {
+	slog.DebugContext(ctx, "Mock Payment Gateway: capturing authorization",
+		"authorization_id", request.AuthorizationID, "amount", request.Amount)
+
+	if err := simulateLatency(ctx, time.Millisecond*300); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	authorized, ok := m.authorizations[request.AuthorizationID]
+	if ok {
+		delete(m.authorizations, request.AuthorizationID)
+	}
+	m.mu.Unlock()
+
+	// Authorizations made before a restart are unknown to the mock, accept them
+	if ok {
+		if exceeds, err := request.Amount.Cmp(authorized); err != nil || exceeds > 0 {
+			return &PaymentResponse{
+				Success:       false,
+				Status:        "failed",
+				Amount:        request.Amount,
+				ProcessedAt:   time.Now(),
+				FailureReason: fmt.Sprintf("Capture amount %s exceeds authorized %s", request.Amount, authorized),
+			}, nil
+		}
+	}
+
+	transactionID := fmt.Sprintf("TXN_%d", time.Now().Unix())
+	externalRef := fmt.Sprintf("REF_%s", uuid.New().String()[:8])
+	m.recordStatus("completed", transactionID, request.Amount, request.AuthorizationID, externalRef)
 
 	return &PaymentResponse{
 		Success:       true,
@@ -100,11 +257,32 @@ func (m *MockPaymentGateway) ProcessPayment(request PaymentRequest) (*PaymentRes
 	}, nil
 }
 
-func (m *MockPaymentGateway) RefundPayment(request RefundRequest) (*RefundResponse, error) {
-	log.Printf("Mock Payment Gateway: Processing refund for Transaction %s, Amount: %.2f",
-		request.OriginalTransactionID, request.Amount)
+func (m *MockPaymentGateway) Void(ctx context.Context, request VoidRequest) (*VoidResponse, error) {
+	slog.DebugContext(ctx, "Mock Payment Gateway: voiding authorization", "authorization_id", request.AuthorizationID)
+
+	if err := simulateLatency(ctx, time.Millisecond*200); err != nil {
+		return nil, err
+	}
 
-	time.Sleep(time.Millisecond * 300)
+	m.mu.Lock()
+	amount := m.authorizations[request.AuthorizationID]
+	delete(m.authorizations, request.AuthorizationID)
+	m.mu.Unlock()
+	m.recordStatus(string(types.PaymentStatusVoided), "", amount, request.AuthorizationID)
+
+	return &VoidResponse{
+		Success:  true,
+		VoidedAt: time.Now(),
+	}, nil
+}
+
+func (m *MockPaymentGateway) RefundPayment(ctx context.Context, request RefundRequest) (*RefundResponse, error) {
+	slog.DebugContext(ctx, "Mock Payment Gateway: processing refund",
+		"transaction_id", request.OriginalTransactionID, "amount", request.Amount)
+
+	if err := simulateLatency(ctx, time.Millisecond*300); err != nil {
+		return nil, err
+	}
 
 	if rand.Float64() < (m.FailureRate * 0.5) {
 		return &RefundResponse{
@@ -127,15 +305,70 @@ func (m *MockPaymentGateway) RefundPayment(request RefundRequest) (*RefundRespon
 	}, nil
 }
 
-func (m *MockPaymentGateway) GetPaymentStatus(externalRef string) (*PaymentStatusResponse, error) {
-	log.Printf("Mock Payment Gateway: Checking status for Reference %s", externalRef)
+func (m *MockPaymentGateway) GetPaymentStatus(ctx context.Context, externalRef string) (*PaymentStatusResponse, error) {
+	slog.DebugContext(ctx, "Mock Payment Gateway: checking status", "external_ref", externalRef)
 
-	time.Sleep(time.Millisecond * 200)
+	if err := simulateLatency(ctx, time.Millisecond*200); err != nil {
+		return nil, err
+	}
 
-	return &PaymentStatusResponse{
-		Status:        "completed",
-		TransactionID: fmt.Sprintf("TXN_%s", externalRef),
-		Amount:        100.00, // Mock amount
-		ProcessedAt:   time.Now(),
+	m.mu.Lock()
+	status, ok := m.statuses[externalRef]
+	m.mu.Unlock()
+
+	// Payments made before a restart are unknown to the mock
+	if !ok {
+		return nil, &GatewayError{
+			Operation:  "get_payment_status",
+			StatusCode: http.StatusNotFound,
+			Type:       providerapi.ErrorTypeInvalidRequest,
+			Message:    fmt.Sprintf("No such payment: %s", externalRef),
+		}
+	}
+
+	response := *status
+	return &response, nil
+}
+
+func (m *MockPaymentGateway) SubmitDisputeEvidence(ctx context.Context, request DisputeEvidenceRequest) (*DisputeEvidenceResponse, error) {
+	slog.DebugContext(ctx, "Mock Payment Gateway: submitting dispute evidence", "dispute_id", request.DisputeID)
+
+	if err := simulateLatency(ctx, time.Millisecond*200); err != nil {
+		return nil, err
+	}
+
+	return &DisputeEvidenceResponse{
+		Success:     true,
+		Status:      providerapi.DisputeUnderReview,
+		SubmittedAt: time.Now(),
 	}, nil
 }
+
+// recordStatus remembers the state of a payment under each of its references
+func (m *MockPaymentGateway) recordStatus(status, transactionID string, amount types.Money, references ...string) {
+	response := &PaymentStatusResponse{
+		Status:        status,
+		TransactionID: transactionID,
+		Amount:        amount,
+		ProcessedAt:   time.Now(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, reference := range references {
+		m.statuses[reference] = response
+	}
+}
+
+// simulateLatency waits like a remote call would, giving up when ctx is done
+func simulateLatency(ctx context.Context, latency time.Duration) error {
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
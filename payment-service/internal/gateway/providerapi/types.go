@@ -0,0 +1,133 @@
+// Package providerapi wire format of the Stripe-style payment provider API,
+// shared by the HTTP gateway adapter and the provider simulator
+package providerapi
+
+const (
+	// IdempotencyKeyHeader makes a POST safe to retry; the provider replays the
+	// first response for a repeated key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed for a repeated key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// ShouldRetryHeader is the provider's own hint whether a failed request may be retried
+	ShouldRetryHeader = "Should-Retry"
+)
+
+// Payment intent statuses
+const (
+	StatusProcessing      = "processing" // Outcome follows asynchronously by webhook
+	StatusRequiresCapture = "requires_capture"
+	StatusSucceeded       = "succeeded"
+	StatusCanceled        = "canceled"
+	StatusFailed          = "failed"
+)
+
+// Dispute statuses
+const (
+	DisputeNeedsResponse = "needs_response"
+	DisputeUnderReview   = "under_review" // Evidence submitted, waiting for the bank
+	DisputeWon           = "won"
+	DisputeLost          = "lost"
+)
+
+// Capture methods
+const (
+	CaptureAutomatic = "automatic"
+	CaptureManual    = "manual"
+)
+
+// Error types
+const (
+	ErrorTypeCard           = "card_error"
+	ErrorTypeInvalidRequest = "invalid_request_error"
+	ErrorTypeIdempotency    = "idempotency_error"
+	ErrorTypeAuthentication = "authentication_error"
+	ErrorTypeRateLimit      = "rate_limit_error"
+	ErrorTypeAPI            = "api_error"
+)
+
+// CreatePaymentIntentRequest POST /v1/payment_intents. Amounts are in minor units.
+type CreatePaymentIntentRequest struct {
+	Amount        int64             `json:"amount"`
+	Currency      string            `json:"currency"`
+	PaymentMethod string            `json:"payment_method"`
+	CaptureMethod string            `json:"capture_method"`
+	Description   string            `json:"description,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// CapturePaymentIntentRequest POST /v1/payment_intents/{id}/capture
+type CapturePaymentIntentRequest struct {
+	AmountToCapture int64 `json:"amount_to_capture,omitempty"`
+}
+
+// CancelPaymentIntentRequest POST /v1/payment_intents/{id}/cancel
+type CancelPaymentIntentRequest struct {
+	CancellationReason string `json:"cancellation_reason,omitempty"`
+}
+
+// CreateRefundRequest POST /v1/refunds
+type CreateRefundRequest struct {
+	PaymentIntent string `json:"payment_intent,omitempty"`
+	Charge        string `json:"charge,omitempty"`
+	Amount        int64  `json:"amount"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// UpdateDisputeRequest POST /v1/disputes/{id}. Submit sends the evidence to the
+// bank; without it the evidence is only saved.
+type UpdateDisputeRequest struct {
+	Evidence DisputeEvidence `json:"evidence"`
+	Submit   bool            `json:"submit"`
+}
+
+type DisputeEvidence struct {
+	ProductDescription     string `json:"product_description,omitempty"`
+	CustomerCommunication  string `json:"customer_communication,omitempty"`
+	ShippingCarrier        string `json:"shipping_carrier,omitempty"`
+	ShippingTrackingNumber string `json:"shipping_tracking_number,omitempty"`
+	RefundPolicy           string `json:"refund_policy,omitempty"`
+	UncategorizedText      string `json:"uncategorized_text,omitempty"`
+}
+
+type PaymentIntent struct {
+	ID                 string            `json:"id"`
+	Object             string            `json:"object"`
+	Amount             int64             `json:"amount"`
+	AmountCapturable   int64             `json:"amount_capturable"`
+	AmountReceived     int64             `json:"amount_received"`
+	Currency           string            `json:"currency"`
+	Status             string            `json:"status"`
+	CaptureMethod      string            `json:"capture_method"`
+	PaymentMethod      string            `json:"payment_method"`
+	LatestCharge       string            `json:"latest_charge,omitempty"`
+	CancellationReason string            `json:"cancellation_reason,omitempty"`
+	Created            int64             `json:"created"`
+	CaptureBefore      int64             `json:"capture_before,omitempty"`
+	LastPaymentError   *Error            `json:"last_payment_error,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+}
+
+type Refund struct {
+	ID            string `json:"id"`
+	Object        string `json:"object"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	PaymentIntent string `json:"payment_intent"`
+	Charge        string `json:"charge,omitempty"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason,omitempty"`
+	Created       int64  `json:"created"`
+}
+
+// ErrorResponse body of every non-2xx response
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+type Error struct {
+	Type          string         `json:"type"`
+	Code          string         `json:"code,omitempty"`
+	DeclineCode   string         `json:"decline_code,omitempty"`
+	Message       string         `json:"message"`
+	PaymentIntent *PaymentIntent `json:"payment_intent,omitempty"`
+}
@@ -0,0 +1,122 @@
+package providerapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries "t=<unix time>,v1=<hex HMAC-SHA256>" on every webhook.
+// The HMAC covers "<t>.<raw body>" and is keyed with the endpoint secret.
+const SignatureHeader = "Provider-Signature"
+
+// Webhook event types
+const (
+	EventPaymentIntentSucceeded        = "payment_intent.succeeded"
+	EventPaymentIntentAmountCapturable = "payment_intent.amount_capturable_updated" // Authorized
+	EventPaymentIntentPaymentFailed    = "payment_intent.payment_failed"
+	EventChargeRefunded                = "charge.refunded"
+	EventChargeDisputeCreated          = "charge.dispute.created"
+	EventChargeDisputeClosed           = "charge.dispute.closed" // Won or lost
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// Event webhook body. Data.Object is a PaymentIntent, Charge or Dispute
+// depending on Type.
+type Event struct {
+	ID      string    `json:"id"`
+	Object  string    `json:"object"`
+	Type    string    `json:"type"`
+	Created int64     `json:"created"`
+	Data    EventData `json:"data"`
+}
+
+type EventData struct {
+	Object json.RawMessage `json:"object"`
+}
+
+type Charge struct {
+	ID             string `json:"id"`
+	Object         string `json:"object"`
+	Amount         int64  `json:"amount"`
+	AmountRefunded int64  `json:"amount_refunded"`
+	Currency       string `json:"currency"`
+	PaymentIntent  string `json:"payment_intent"`
+	Refunded       bool   `json:"refunded"`
+	Created        int64  `json:"created"`
+}
+
+type Dispute struct {
+	ID            string           `json:"id"`
+	Object        string           `json:"object"`
+	Amount        int64            `json:"amount"`
+	Currency      string           `json:"currency"`
+	Charge        string           `json:"charge"`
+	PaymentIntent string           `json:"payment_intent"`
+	Reason        string           `json:"reason"`
+	Status        string           `json:"status"`
+	Evidence      *DisputeEvidence `json:"evidence,omitempty"`
+	Created       int64            `json:"created"`
+}
+
+// SignPayload builds the SignatureHeader value for payload
+func SignPayload(payload []byte, secret string, timestamp time.Time) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, computeSignature(payload, secret, t))
+}
+
+// VerifySignature checks header against payload. Signatures older or newer
+// than tolerance are rejected so a captured request cannot be replayed later.
+func VerifySignature(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return ErrSignatureExpired
+	}
+
+	expected := computeSignature(payload, secret, timestamp)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func computeSignature(payload []byte, secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
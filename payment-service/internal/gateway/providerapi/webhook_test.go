@@ -0,0 +1,61 @@
+package providerapi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+var (
+	testPayload = []byte(`{"id":"evt_1","type":"payment_intent.succeeded"}`)
+	signedAt    = time.Unix(1700000000, 0)
+)
+
+func TestVerifySignature(t *testing.T) {
+	valid := SignPayload(testPayload, testSecret, signedAt)
+	_, validMAC, _ := strings.Cut(valid, ",v1=")
+
+	tests := []struct {
+		name    string
+		payload []byte
+		header  string
+		secret  string
+		now     time.Time
+		want    error
+	}{
+		{name: "valid", payload: testPayload, header: valid, secret: testSecret, now: signedAt},
+		{name: "valid within tolerance", payload: testPayload, header: valid, secret: testSecret, now: signedAt.Add(5 * time.Minute)},
+		{name: "valid with a rotated secret", payload: testPayload, header: valid + ",v1=" + strings.Repeat("0", 64), secret: testSecret, now: signedAt},
+		{name: "tampered body", payload: []byte(`{"id":"evt_1","type":"charge.refunded"}`), header: valid, secret: testSecret, now: signedAt, want: ErrInvalidSignature},
+		{name: "wrong secret", payload: testPayload, header: valid, secret: "whsec_other", now: signedAt, want: ErrInvalidSignature},
+		{name: "tampered timestamp", payload: testPayload, header: "t=1700000001,v1=" + validMAC, secret: testSecret, now: signedAt, want: ErrInvalidSignature},
+		{name: "expired", payload: testPayload, header: valid, secret: testSecret, now: signedAt.Add(5*time.Minute + time.Second), want: ErrSignatureExpired},
+		{name: "from the future", payload: testPayload, header: valid, secret: testSecret, now: signedAt.Add(-5*time.Minute - time.Second), want: ErrSignatureExpired},
+		{name: "empty header", payload: testPayload, header: "", secret: testSecret, now: signedAt, want: ErrInvalidSignature},
+		{name: "no timestamp", payload: testPayload, header: "v1=" + validMAC, secret: testSecret, now: signedAt, want: ErrInvalidSignature},
+		{name: "no signature", payload: testPayload, header: "t=1700000000", secret: testSecret, now: signedAt, want: ErrInvalidSignature},
+		{name: "non-numeric timestamp", payload: testPayload, header: "t=yesterday,v1=" + validMAC, secret: testSecret, now: signedAt, want: ErrInvalidSignature},
+		{name: "unknown scheme only", payload: testPayload, header: "t=1700000000,v0=" + validMAC, secret: testSecret, now: signedAt, want: ErrInvalidSignature},
+		{name: "garbage", payload: testPayload, header: "not a signature", secret: testSecret, now: signedAt, want: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		err := VerifySignature(tt.payload, tt.header, tt.secret, 5*time.Minute, tt.now)
+		if tt.want == nil && err != nil {
+			t.Errorf("%s: got %v, want a valid signature", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestVerifySignatureWithoutTolerance(t *testing.T) {
+	header := SignPayload(testPayload, testSecret, signedAt)
+	if err := VerifySignature(testPayload, header, testSecret, 0, signedAt.Add(24*time.Hour)); err != nil {
+		t.Errorf("a zero tolerance should not check the timestamp, got %v", err)
+	}
+}
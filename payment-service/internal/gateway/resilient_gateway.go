@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/resilience"
+)
+
+// ResilientGateway runs every call of the wrapped gateway through a resilience
+// policy. Retries are safe because each request carries an idempotency key.
+// Only transient errors are retried and count against the breaker; declines
+// are successful calls with Success false.
+type ResilientGateway struct {
+	next   PaymentGateway
+	policy *resilience.Policy
+}
+
+func NewResilientGateway(next PaymentGateway, policy *resilience.Policy) *ResilientGateway {
+	return &ResilientGateway{next: next, policy: policy}
+}
+
+func (g *ResilientGateway) ProcessPayment(ctx context.Context, request PaymentRequest) (*PaymentResponse, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (*PaymentResponse, error) {
+		return g.next.ProcessPayment(ctx, request)
+	})
+}
+
+func (g *ResilientGateway) Authorize(ctx context.Context, request PaymentRequest) (*AuthorizationResponse, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (*AuthorizationResponse, error) {
+		return g.next.Authorize(ctx, request)
+	})
+}
+
+func (g *ResilientGateway) Capture(ctx context.Context, request CaptureRequest) (*PaymentResponse, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (*PaymentResponse, error) {
+		return g.next.Capture(ctx, request)
+	})
+}
+
+func (g *ResilientGateway) Void(ctx context.Context, request VoidRequest) (*VoidResponse, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (*VoidResponse, error) {
+		return g.next.Void(ctx, request)
+	})
+}
+
+func (g *ResilientGateway) RefundPayment(ctx context.Context, request RefundRequest) (*RefundResponse, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (*RefundResponse, error) {
+		return g.next.RefundPayment(ctx, request)
+	})
+}
+
+func (g *ResilientGateway) GetPaymentStatus(ctx context.Context, externalRef string) (*PaymentStatusResponse, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (*PaymentStatusResponse, error) {
+		return g.next.GetPaymentStatus(ctx, externalRef)
+	})
+}
+
+func (g *ResilientGateway) SubmitDisputeEvidence(ctx context.Context, request DisputeEvidenceRequest) (*DisputeEvidenceResponse, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (*DisputeEvidenceResponse, error) {
+		return g.next.SubmitDisputeEvidence(ctx, request)
+	})
+}
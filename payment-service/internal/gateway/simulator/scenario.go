@@ -0,0 +1,113 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Operations a scenario can target
+const (
+	OperationCreate   = "create" // POST /v1/payment_intents (charge or authorize)
+	OperationCapture  = "capture"
+	OperationCancel   = "cancel"
+	OperationRefund   = "refund"
+	OperationRetrieve = "retrieve"
+)
+
+// builtinDeclines payment methods that always decline, like a provider's test cards
+var builtinDeclines = map[string]string{
+	"pm_card_chargeDeclined":                  "generic_decline",
+	"pm_card_chargeDeclinedInsufficientFunds": "insufficient_funds",
+	"pm_card_chargeDeclinedExpiredCard":       "expired_card",
+	"pm_card_chargeDeclinedFraudulent":        "fraudulent",
+}
+
+// Scenario scripted behaviour for matching requests. Empty match fields match
+// everything. A scenario with only a delay slows the request down and then lets
+// it succeed; a status of 402 declines it and any other status fails it. An
+// async create scenario answers "processing" and reports the outcome, success
+// or the scripted failure, by webhook.
+type Scenario struct {
+	Name          string   `json:"name"`
+	Operation     string   `json:"operation,omitempty"`
+	PaymentMethod string   `json:"payment_method,omitempty"`
+	OrderID       string   `json:"order_id,omitempty"`
+	Delay         Duration `json:"delay,omitempty"`
+	Status        int      `json:"status,omitempty"`
+	DeclineCode   string   `json:"decline_code,omitempty"`
+	Message       string   `json:"message,omitempty"`
+	Times         int      `json:"times,omitempty"` // Applies this many times, 0 means forever
+	Async         bool     `json:"async,omitempty"`
+
+	hits int
+}
+
+func (s *Scenario) matches(operation, paymentMethod, orderID string) bool {
+	if s.Times > 0 && s.hits >= s.Times {
+		return false
+	}
+	return (s.Operation == "" || s.Operation == operation) &&
+		(s.PaymentMethod == "" || s.PaymentMethod == paymentMethod) &&
+		(s.OrderID == "" || s.OrderID == orderID)
+}
+
+func (s *Scenario) validate() error {
+	switch s.Operation {
+	case "", OperationCreate, OperationCapture, OperationCancel, OperationRefund, OperationRetrieve:
+	default:
+		return fmt.Errorf("scenario %q: unknown operation %q", s.Name, s.Operation)
+	}
+	if s.Status != 0 && (s.Status < 400 || s.Status > 599) {
+		return fmt.Errorf("scenario %q: status must be 4xx or 5xx, got %d", s.Name, s.Status)
+	}
+	if s.Async && s.Operation != OperationCreate {
+		return fmt.Errorf("scenario %q: only create can be async", s.Name)
+	}
+	if s.Delay < 0 {
+		return fmt.Errorf("scenario %q: negative delay", s.Name)
+	}
+	return nil
+}
+
+// LoadScenarios reads a JSON array of scenarios (SIMULATOR_SCENARIOS_FILE)
+func LoadScenarios(path string) ([]Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("scenario file read error: %v", err)
+	}
+
+	var scenarios []Scenario
+	if err := json.Unmarshal(data, &scenarios); err != nil {
+		return nil, fmt.Errorf("scenario file parse error: %v", err)
+	}
+
+	for i := range scenarios {
+		if err := scenarios[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	return scenarios, nil
+}
+
+// Duration accepts "1.5s" style strings in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"2s\": %v", err)
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
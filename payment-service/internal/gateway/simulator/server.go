@@ -0,0 +1,742 @@
+// Package simulator an in-memory Stripe-style payment provider for local runs
+// and deterministic integration tests of the HTTP gateway adapter
+package simulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway/providerapi"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const defaultAuthorizationTTL = 7 * 24 * time.Hour
+
+type Config struct {
+	APIKey           string // Required as a bearer token when set
+	AuthorizationTTL time.Duration
+	Scenarios        []Scenario
+	WebhookURL       string        // Receives signed events; empty disables delivery
+	WebhookSecret    string        // HMAC key of the webhook signature
+	WebhookDelay     time.Duration // Time until an async payment is decided
+}
+
+type Server struct {
+	apiKey           string
+	authorizationTTL time.Duration
+	webhooks         *webhookSender
+	webhookDelay     time.Duration
+
+	mu          sync.Mutex
+	intents     map[string]*intentRecord
+	charges     map[string]string // Charge ID to payment intent ID
+	disputes    map[string]*providerapi.Dispute
+	idempotency map[string]*storedResponse
+	inFlight    map[string]bool
+	scenarios   []*Scenario
+}
+
+type intentRecord struct {
+	intent   providerapi.PaymentIntent
+	refunded int64
+}
+
+// storedResponse first response for an idempotency key, replayed on repeats
+type storedResponse struct {
+	fingerprint string
+	status      int
+	body        []byte
+}
+
+func NewServer(config Config) *Server {
+	ttl := config.AuthorizationTTL
+	if ttl <= 0 {
+		ttl = defaultAuthorizationTTL
+	}
+
+	webhookDelay := config.WebhookDelay
+	if webhookDelay <= 0 {
+		webhookDelay = defaultWebhookDelay
+	}
+
+	server := &Server{
+		apiKey:           config.APIKey,
+		authorizationTTL: ttl,
+		webhooks: &webhookSender{
+			url:    config.WebhookURL,
+			secret: config.WebhookSecret,
+			client: &http.Client{Timeout: 5 * time.Second},
+		},
+		webhookDelay: webhookDelay,
+	}
+	server.reset()
+	for i := range config.Scenarios {
+		scenario := config.Scenarios[i]
+		server.scenarios = append(server.scenarios, &scenario)
+	}
+	return server
+}
+
+// Register mounts the provider API, the admin API and the health check
+func (s *Server) Register(app *fiber.App) {
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "healthy"})
+	})
+
+	admin := app.Group("/__admin")
+	admin.Get("/scenarios", s.listScenarios)
+	admin.Post("/scenarios", s.addScenario)
+	admin.Delete("/scenarios", s.clearScenarios)
+	admin.Post("/reset", s.resetState)
+	admin.Post("/payment_intents/:id/dispute", s.openDispute)
+	admin.Post("/disputes/:id/close", s.closeDispute)
+
+	v1 := app.Group("/v1", s.authenticate)
+	v1.Post("/payment_intents", s.idempotent(s.createPaymentIntent))
+	v1.Get("/payment_intents/:id", s.retrievePaymentIntent)
+	v1.Post("/payment_intents/:id/capture", s.idempotent(s.capturePaymentIntent))
+	v1.Post("/payment_intents/:id/cancel", s.idempotent(s.cancelPaymentIntent))
+	v1.Post("/refunds", s.idempotent(s.createRefund))
+	v1.Post("/disputes/:id", s.idempotent(s.updateDispute))
+}
+
+func (s *Server) reset() {
+	s.intents = make(map[string]*intentRecord)
+	s.charges = make(map[string]string)
+	s.disputes = make(map[string]*providerapi.Dispute)
+	s.idempotency = make(map[string]*storedResponse)
+	s.inFlight = make(map[string]bool)
+}
+
+func (s *Server) authenticate(c *fiber.Ctx) error {
+	if s.apiKey != "" && c.Get(fiber.HeaderAuthorization) != "Bearer "+s.apiKey {
+		return apiError(c, fiber.StatusUnauthorized, providerapi.ErrorTypeAuthentication, "", "Invalid API key provided")
+	}
+	return c.Next()
+}
+
+// idempotent replays the stored response for a repeated Idempotency-Key. Rate
+// limits and server errors are not stored, so a retry gets a fresh attempt.
+func (s *Server) idempotent(handler fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := strings.Clone(c.Get(providerapi.IdempotencyKeyHeader))
+		if key == "" {
+			return handler(c)
+		}
+
+		sum := sha256.Sum256(append([]byte(c.Method()+" "+c.Path()+"\n"), c.Body()...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		s.mu.Lock()
+		if stored, ok := s.idempotency[key]; ok {
+			s.mu.Unlock()
+			if stored.fingerprint != fingerprint {
+				return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeIdempotency, "idempotency_key_in_use",
+					"Keys for idempotent requests can only be used with the same parameters they were first used with")
+			}
+			c.Set(providerapi.IdempotentReplayedHeader, "true")
+			c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			return c.Status(stored.status).Send(stored.body)
+		}
+		if s.inFlight[key] {
+			s.mu.Unlock()
+			c.Set(providerapi.ShouldRetryHeader, "true")
+			return apiError(c, fiber.StatusConflict, providerapi.ErrorTypeIdempotency, "idempotency_key_in_use",
+				"There is currently another in-progress request using this idempotency key")
+		}
+		s.inFlight[key] = true
+		s.mu.Unlock()
+
+		err := handler(c)
+
+		status := c.Response().StatusCode()
+		s.mu.Lock()
+		delete(s.inFlight, key)
+		if err == nil && status != fiber.StatusTooManyRequests && status < 500 {
+			s.idempotency[key] = &storedResponse{
+				fingerprint: fingerprint,
+				status:      status,
+				body:        bytes.Clone(c.Response().Body()),
+			}
+		}
+		s.mu.Unlock()
+
+		return err
+	}
+}
+
+func (s *Server) createPaymentIntent(c *fiber.Ctx) error {
+	var request providerapi.CreatePaymentIntentRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "parameter_invalid", "Invalid request body")
+	}
+
+	if request.Amount <= 0 {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "amount_too_small", "Amount must be positive")
+	}
+	if len(request.Currency) != 3 {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "parameter_invalid", "Invalid currency")
+	}
+	if request.PaymentMethod == "" {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "parameter_missing", "Missing payment_method")
+	}
+	if request.CaptureMethod == "" {
+		request.CaptureMethod = providerapi.CaptureAutomatic
+	}
+	if request.CaptureMethod != providerapi.CaptureAutomatic && request.CaptureMethod != providerapi.CaptureManual {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "parameter_invalid", "Invalid capture_method")
+	}
+
+	orderID := request.Metadata["order_id"]
+	scenario := s.matchScenario(c, OperationCreate, request.PaymentMethod, orderID)
+	async := scenario != nil && scenario.Async
+	if scenario != nil && !async {
+		if done, err := respondScenario(c, *scenario); done {
+			return err
+		}
+	}
+
+	// Async payments report the failure later instead of answering with it
+	var failure *providerapi.Error
+	if declineCode, ok := builtinDeclines[request.PaymentMethod]; ok {
+		failure = &providerapi.Error{Type: providerapi.ErrorTypeCard, Code: "card_declined",
+			DeclineCode: declineCode, Message: "Your card was declined."}
+		if !async {
+			return cardDeclined(c, declineCode, "")
+		}
+	}
+	if async {
+		if scenario.Delay > 0 {
+			time.Sleep(time.Duration(scenario.Delay))
+		}
+		if scenario.Status != 0 {
+			failure = scenarioFailure(*scenario)
+		}
+	}
+
+	now := time.Now()
+	intent := providerapi.PaymentIntent{
+		ID:            newID("pi"),
+		Object:        "payment_intent",
+		Amount:        request.Amount,
+		Currency:      strings.ToLower(request.Currency),
+		CaptureMethod: request.CaptureMethod,
+		PaymentMethod: request.PaymentMethod,
+		Created:       now.Unix(),
+		Metadata:      request.Metadata,
+	}
+
+	s.mu.Lock()
+	switch {
+	case async:
+		intent.Status = providerapi.StatusProcessing
+		time.AfterFunc(s.webhookDelay, func() { s.decide(intent.ID, failure) })
+	case request.CaptureMethod == providerapi.CaptureManual:
+		s.authorize(&intent)
+	default:
+		s.charge(&intent, request.Amount)
+	}
+	s.intents[intent.ID] = &intentRecord{intent: intent}
+	s.mu.Unlock()
+
+	slog.InfoContext(c.UserContext(), "Simulator payment intent created",
+		"payment_intent", intent.ID, "status", intent.Status, "amount", intent.Amount, "currency", intent.Currency)
+
+	return c.JSON(intent)
+}
+
+func (s *Server) retrievePaymentIntent(c *fiber.Ctx) error {
+	record, ok := s.lookupIntent(c.Params("id"))
+	if !ok {
+		return missingIntent(c)
+	}
+	if done, err := s.applyScenario(c, OperationRetrieve, record.PaymentMethod, record.Metadata["order_id"]); done {
+		return err
+	}
+	return c.JSON(record)
+}
+
+func (s *Server) capturePaymentIntent(c *fiber.Ctx) error {
+	var request providerapi.CapturePaymentIntentRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "parameter_invalid", "Invalid request body")
+		}
+	}
+
+	snapshot, ok := s.lookupIntent(c.Params("id"))
+	if !ok {
+		return missingIntent(c)
+	}
+	if done, err := s.applyScenario(c, OperationCapture, snapshot.PaymentMethod, snapshot.Metadata["order_id"]); done {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.intents[snapshot.ID]
+	if record.intent.Status != providerapi.StatusRequiresCapture {
+		return unexpectedState(c, record.intent, "capture")
+	}
+
+	amount := request.AmountToCapture
+	if amount == 0 {
+		amount = record.intent.AmountCapturable
+	}
+	if amount < 0 || amount > record.intent.AmountCapturable {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "amount_too_large",
+			fmt.Sprintf("Amount to capture %d exceeds capturable amount %d", amount, record.intent.AmountCapturable))
+	}
+
+	s.charge(&record.intent, amount)
+	return c.JSON(record.intent)
+}
+
+func (s *Server) cancelPaymentIntent(c *fiber.Ctx) error {
+	var request providerapi.CancelPaymentIntentRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "parameter_invalid", "Invalid request body")
+		}
+	}
+
+	snapshot, ok := s.lookupIntent(c.Params("id"))
+	if !ok {
+		return missingIntent(c)
+	}
+	if done, err := s.applyScenario(c, OperationCancel, snapshot.PaymentMethod, snapshot.Metadata["order_id"]); done {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.intents[snapshot.ID]
+	if record.intent.Status != providerapi.StatusRequiresCapture {
+		return unexpectedState(c, record.intent, "cancel")
+	}
+
+	record.intent.Status = providerapi.StatusCanceled
+	record.intent.AmountCapturable = 0
+	record.intent.CancellationReason = request.CancellationReason
+	return c.JSON(record.intent)
+}
+
+func (s *Server) createRefund(c *fiber.Ctx) error {
+	var request providerapi.CreateRefundRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "parameter_invalid", "Invalid request body")
+	}
+
+	intentID := request.PaymentIntent
+	if intentID == "" {
+		s.mu.Lock()
+		intentID = s.charges[request.Charge]
+		s.mu.Unlock()
+	}
+
+	snapshot, ok := s.lookupIntent(intentID)
+	if !ok {
+		return missingIntent(c)
+	}
+	if done, err := s.applyScenario(c, OperationRefund, snapshot.PaymentMethod, snapshot.Metadata["order_id"]); done {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.intents[snapshot.ID]
+	if record.intent.Status != providerapi.StatusSucceeded {
+		return unexpectedState(c, record.intent, "refund")
+	}
+
+	remaining := record.intent.AmountReceived - record.refunded
+	if remaining <= 0 {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "charge_already_refunded",
+			"Charge has already been refunded")
+	}
+
+	amount := request.Amount
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount < 0 || amount > remaining {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "amount_too_large",
+			fmt.Sprintf("Refund amount %d exceeds remaining %d", amount, remaining))
+	}
+
+	record.refunded += amount
+	s.webhooks.send(providerapi.EventChargeRefunded, providerapi.Charge{
+		ID:             record.intent.LatestCharge,
+		Object:         "charge",
+		Amount:         record.intent.AmountReceived,
+		AmountRefunded: record.refunded,
+		Currency:       record.intent.Currency,
+		PaymentIntent:  record.intent.ID,
+		Refunded:       record.refunded == record.intent.AmountReceived,
+		Created:        record.intent.Created,
+	})
+
+	return c.JSON(providerapi.Refund{
+		ID:            newID("re"),
+		Object:        "refund",
+		Amount:        amount,
+		Currency:      record.intent.Currency,
+		PaymentIntent: record.intent.ID,
+		Charge:        record.intent.LatestCharge,
+		Status:        providerapi.StatusSucceeded,
+		Reason:        request.Reason,
+		Created:       time.Now().Unix(),
+	})
+}
+
+// decide settles an async payment intent and reports the outcome by webhook
+func (s *Server) decide(intentID string, failure *providerapi.Error) {
+	s.mu.Lock()
+	record, ok := s.intents[intentID]
+	if !ok || record.intent.Status != providerapi.StatusProcessing {
+		s.mu.Unlock()
+		return
+	}
+
+	var eventType string
+	switch {
+	case failure != nil:
+		record.intent.Status = providerapi.StatusFailed
+		record.intent.LastPaymentError = failure
+		eventType = providerapi.EventPaymentIntentPaymentFailed
+	case record.intent.CaptureMethod == providerapi.CaptureManual:
+		s.authorize(&record.intent)
+		eventType = providerapi.EventPaymentIntentAmountCapturable
+	default:
+		s.charge(&record.intent, record.intent.Amount)
+		eventType = providerapi.EventPaymentIntentSucceeded
+	}
+	intent := record.intent
+	s.mu.Unlock()
+
+	slog.Info("Simulator async payment intent decided", "payment_intent", intent.ID, "status", intent.Status)
+	s.webhooks.send(eventType, intent)
+}
+
+// openDispute simulates a chargeback on a paid intent
+func (s *Server) openDispute(c *fiber.Ctx) error {
+	var request struct {
+		Reason string `json:"reason"`
+		Amount int64  `json:"amount"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if request.Reason == "" {
+		request.Reason = "fraudulent"
+	}
+
+	s.mu.Lock()
+	record, ok := s.intents[c.Params("id")]
+	if !ok {
+		s.mu.Unlock()
+		return missingIntent(c)
+	}
+	if record.intent.Status != providerapi.StatusSucceeded {
+		s.mu.Unlock()
+		return unexpectedState(c, record.intent, "dispute")
+	}
+
+	amount := request.Amount
+	if amount <= 0 || amount > record.intent.AmountReceived {
+		amount = record.intent.AmountReceived
+	}
+	dispute := &providerapi.Dispute{
+		ID:            newID("dp"),
+		Object:        "dispute",
+		Amount:        amount,
+		Currency:      record.intent.Currency,
+		Charge:        record.intent.LatestCharge,
+		PaymentIntent: record.intent.ID,
+		Reason:        request.Reason,
+		Status:        "needs_response",
+		Created:       time.Now().Unix(),
+	}
+	s.disputes[dispute.ID] = dispute
+	s.mu.Unlock()
+
+	s.webhooks.send(providerapi.EventChargeDisputeCreated, dispute)
+	return c.Status(fiber.StatusCreated).JSON(dispute)
+}
+
+// updateDispute saves the merchant's evidence and submits it when asked
+func (s *Server) updateDispute(c *fiber.Ctx) error {
+	var request providerapi.UpdateDisputeRequest
+	if err := c.BodyParser(&request); err != nil {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "", err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dispute, ok := s.disputes[c.Params("id")]
+	if !ok {
+		return missingDispute(c)
+	}
+	if dispute.Status != providerapi.DisputeNeedsResponse {
+		return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "dispute_already_submitted",
+			fmt.Sprintf("This dispute has a status of %s and can no longer be updated", dispute.Status))
+	}
+
+	evidence := request.Evidence
+	dispute.Evidence = &evidence
+	if request.Submit {
+		dispute.Status = providerapi.DisputeUnderReview
+	}
+
+	slog.InfoContext(c.UserContext(), "Simulator dispute updated", "dispute", dispute.ID, "status", dispute.Status)
+	return c.JSON(dispute)
+}
+
+// closeDispute decides a dispute as the bank would, won or lost
+func (s *Server) closeDispute(c *fiber.Ctx) error {
+	var request struct {
+		Status string `json:"status"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if request.Status != providerapi.DisputeWon && request.Status != providerapi.DisputeLost {
+		return fiber.NewError(fiber.StatusBadRequest, "status must be won or lost")
+	}
+
+	s.mu.Lock()
+	dispute, ok := s.disputes[c.Params("id")]
+	if !ok {
+		s.mu.Unlock()
+		return missingDispute(c)
+	}
+	if dispute.Status == providerapi.DisputeWon || dispute.Status == providerapi.DisputeLost {
+		s.mu.Unlock()
+		return fiber.NewError(fiber.StatusConflict, "dispute already closed as "+dispute.Status)
+	}
+	dispute.Status = request.Status
+	closed := *dispute
+	s.mu.Unlock()
+
+	s.webhooks.send(providerapi.EventChargeDisputeClosed, closed)
+	return c.JSON(closed)
+}
+
+// authorize holds the full amount until capture; the caller holds s.mu
+func (s *Server) authorize(intent *providerapi.PaymentIntent) {
+	intent.Status = providerapi.StatusRequiresCapture
+	intent.AmountCapturable = intent.Amount
+	intent.CaptureBefore = time.Now().Add(s.authorizationTTL).Unix()
+}
+
+// charge marks the intent as paid; the caller holds s.mu
+func (s *Server) charge(intent *providerapi.PaymentIntent, amount int64) {
+	intent.Status = providerapi.StatusSucceeded
+	intent.AmountReceived = amount
+	intent.AmountCapturable = 0
+	intent.LatestCharge = newID("ch")
+	s.charges[intent.LatestCharge] = intent.ID
+}
+
+func (s *Server) lookupIntent(id string) (providerapi.PaymentIntent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.intents[id]
+	if !ok {
+		return providerapi.PaymentIntent{}, false
+	}
+	return record.intent, true
+}
+
+// applyScenario runs the first matching scenario. done reports that it wrote
+// the response and the handler must stop.
+func (s *Server) applyScenario(c *fiber.Ctx, operation, paymentMethod, orderID string) (done bool, err error) {
+	matched := s.matchScenario(c, operation, paymentMethod, orderID)
+	if matched == nil {
+		return false, nil
+	}
+	return respondScenario(c, *matched)
+}
+
+// matchScenario returns a copy of the first matching scenario and counts the hit
+func (s *Server) matchScenario(c *fiber.Ctx, operation, paymentMethod, orderID string) *Scenario {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, scenario := range s.scenarios {
+		if scenario.matches(operation, paymentMethod, orderID) {
+			scenario.hits++
+			matched := *scenario
+
+			slog.InfoContext(c.UserContext(), "Simulator scenario applied",
+				"scenario", matched.Name, "operation", operation, "order_id", orderID)
+			return &matched
+		}
+	}
+	return nil
+}
+
+func respondScenario(c *fiber.Ctx, matched Scenario) (done bool, err error) {
+	if matched.Delay > 0 {
+		time.Sleep(time.Duration(matched.Delay))
+	}
+
+	switch {
+	case matched.Status == 0:
+		return false, nil
+	case matched.Status == fiber.StatusPaymentRequired:
+		declineCode := matched.DeclineCode
+		if declineCode == "" {
+			declineCode = "generic_decline"
+		}
+		return true, cardDeclined(c, declineCode, matched.Message)
+	default:
+		message := matched.Message
+		if message == "" {
+			message = fmt.Sprintf("Simulated failure: %s", http.StatusText(matched.Status))
+		}
+		return true, apiError(c, matched.Status, errorTypeForStatus(matched.Status), "", message)
+	}
+}
+
+func (s *Server) listScenarios(c *fiber.Ctx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scenarios := make([]Scenario, 0, len(s.scenarios))
+	for _, scenario := range s.scenarios {
+		scenarios = append(scenarios, *scenario)
+	}
+	return c.JSON(scenarios)
+}
+
+// addScenario accepts a single scenario or an array; new ones take precedence
+func (s *Server) addScenario(c *fiber.Ctx) error {
+	var scenarios []Scenario
+	body := bytes.TrimSpace(c.Body())
+	if len(body) > 0 && body[0] == '{' {
+		var scenario Scenario
+		if err := c.BodyParser(&scenario); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		scenarios = append(scenarios, scenario)
+	} else if err := c.BodyParser(&scenarios); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	added := make([]*Scenario, 0, len(scenarios))
+	for i := range scenarios {
+		if err := scenarios[i].validate(); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		added = append(added, &scenarios[i])
+	}
+
+	s.mu.Lock()
+	s.scenarios = append(added, s.scenarios...)
+	s.mu.Unlock()
+
+	return c.Status(fiber.StatusCreated).JSON(scenarios)
+}
+
+func (s *Server) clearScenarios(c *fiber.Ctx) error {
+	s.mu.Lock()
+	s.scenarios = nil
+	s.mu.Unlock()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// resetState drops payment intents, idempotency keys and scenarios
+func (s *Server) resetState(c *fiber.Ctx) error {
+	s.mu.Lock()
+	s.reset()
+	s.scenarios = nil
+	s.mu.Unlock()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// scenarioFailure the error an async scenario reports in its webhook
+func scenarioFailure(scenario Scenario) *providerapi.Error {
+	if scenario.Status == fiber.StatusPaymentRequired {
+		declineCode := scenario.DeclineCode
+		if declineCode == "" {
+			declineCode = "generic_decline"
+		}
+		message := scenario.Message
+		if message == "" {
+			message = "Your card was declined."
+		}
+		return &providerapi.Error{Type: providerapi.ErrorTypeCard, Code: "card_declined", DeclineCode: declineCode, Message: message}
+	}
+
+	message := scenario.Message
+	if message == "" {
+		message = fmt.Sprintf("Simulated failure: %s", http.StatusText(scenario.Status))
+	}
+	return &providerapi.Error{Type: errorTypeForStatus(scenario.Status), Message: message}
+}
+
+func apiError(c *fiber.Ctx, status int, errorType, code, message string) error {
+	return c.Status(status).JSON(providerapi.ErrorResponse{
+		Error: providerapi.Error{Type: errorType, Code: code, Message: message},
+	})
+}
+
+func cardDeclined(c *fiber.Ctx, declineCode, message string) error {
+	if message == "" {
+		message = "Your card was declined."
+	}
+	return c.Status(fiber.StatusPaymentRequired).JSON(providerapi.ErrorResponse{
+		Error: providerapi.Error{
+			Type:        providerapi.ErrorTypeCard,
+			Code:        "card_declined",
+			DeclineCode: declineCode,
+			Message:     message,
+		},
+	})
+}
+
+func missingIntent(c *fiber.Ctx) error {
+	return apiError(c, fiber.StatusNotFound, providerapi.ErrorTypeInvalidRequest, "resource_missing", "No such payment_intent")
+}
+
+func missingDispute(c *fiber.Ctx) error {
+	return apiError(c, fiber.StatusNotFound, providerapi.ErrorTypeInvalidRequest, "resource_missing", "No such dispute")
+}
+
+func unexpectedState(c *fiber.Ctx, intent providerapi.PaymentIntent, action string) error {
+	return apiError(c, fiber.StatusBadRequest, providerapi.ErrorTypeInvalidRequest, "payment_intent_unexpected_state",
+		fmt.Sprintf("You cannot %s this PaymentIntent because it has a status of %s", action, intent.Status))
+}
+
+func errorTypeForStatus(status int) string {
+	switch {
+	case status == fiber.StatusUnauthorized:
+		return providerapi.ErrorTypeAuthentication
+	case status == fiber.StatusTooManyRequests:
+		return providerapi.ErrorTypeRateLimit
+	case status >= 500:
+		return providerapi.ErrorTypeAPI
+	default:
+		return providerapi.ErrorTypeInvalidRequest
+	}
+}
+
+func newID(prefix string) string {
+	return prefix + "_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:24]
+}
@@ -0,0 +1,95 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway/providerapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultWebhookDelay = time.Second
+	webhookAttempts     = 5
+	webhookRetryDelay   = time.Second // Doubled after every failed delivery
+)
+
+// webhookSender delivers signed events like a provider does: in the background,
+// retrying non-2xx answers with backoff
+type webhookSender struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// send delivers the event asynchronously; without a URL it only logs it
+func (w *webhookSender) send(eventType string, object interface{}) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		slog.Error("Simulator webhook encode error", "type", eventType, "error", err)
+		return
+	}
+
+	event := providerapi.Event{
+		ID:      newID("evt"),
+		Object:  "event",
+		Type:    eventType,
+		Created: time.Now().Unix(),
+		Data:    providerapi.EventData{Object: data},
+	}
+	if w.url == "" {
+		slog.Info("Simulator webhook not sent, no URL configured", "event_id", event.ID, "type", eventType)
+		return
+	}
+
+	go w.deliver(event)
+}
+
+func (w *webhookSender) deliver(event providerapi.Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Simulator webhook encode error", "event_id", event.ID, "error", err)
+		return
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err := w.post(payload)
+		if err == nil {
+			slog.Info("Simulator webhook delivered", "event_id", event.ID, "type", event.Type, "attempt", attempt)
+			return
+		}
+
+		slog.Warn("Simulator webhook delivery failed",
+			"event_id", event.ID, "type", event.Type, "attempt", attempt, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	slog.Error("Simulator webhook dropped after retries", "event_id", event.ID, "type", event.Type)
+}
+
+func (w *webhookSender) post(payload []byte) error {
+	request, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	// Signed per attempt, so a retry carries a fresh timestamp
+	request.Header.Set(providerapi.SignatureHeader, providerapi.SignPayload(payload, w.secret, time.Now()))
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("endpoint answered %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway/providerapi"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+// WebhookSignatureHeader request header with the provider's webhook signature
+const WebhookSignatureHeader = providerapi.SignatureHeader
+
+// WebhookEventType provider-independent kind of an asynchronous payment result
+type WebhookEventType string
+
+const (
+	WebhookPaymentSucceeded  WebhookEventType = "succeeded"
+	WebhookPaymentAuthorized WebhookEventType = "authorized"
+	WebhookPaymentFailed     WebhookEventType = "failed"
+	WebhookPaymentRefunded   WebhookEventType = "refunded"
+	WebhookPaymentDisputed   WebhookEventType = "disputed"
+	WebhookDisputeClosed     WebhookEventType = "dispute_closed"
+)
+
+// WebhookEvent a payment result the provider reported asynchronously
+type WebhookEvent struct {
+	ID            string           // Provider event ID, used for deduplication
+	Type          WebhookEventType // Empty for provider events the service does not handle
+	ProviderType  string
+	Reference     string // Payment intent ID: the payment's external ref or authorization ID
+	TransactionID string
+	Amount        types.Money // Charged, authorized, refunded in total or disputed, by Type
+	FailureReason string
+	DisputeID     string
+	DisputeReason string
+	DisputeStatus string // Provider status; won or lost once closed
+	OccurredAt    time.Time
+}
+
+// ParseProviderWebhook verifies the signature of a Stripe-style webhook and
+// maps the event onto a WebhookEvent
+func ParseProviderWebhook(payload []byte, signature, secret string, tolerance time.Duration) (*WebhookEvent, error) {
+	if err := providerapi.VerifySignature(payload, signature, secret, tolerance, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var event providerapi.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("webhook decode error: %v", err)
+	}
+	if event.ID == "" {
+		return nil, fmt.Errorf("webhook event without id")
+	}
+
+	result := &WebhookEvent{
+		ID:           event.ID,
+		ProviderType: event.Type,
+		OccurredAt:   time.Unix(event.Created, 0),
+	}
+
+	switch event.Type {
+	case providerapi.EventPaymentIntentSucceeded,
+		providerapi.EventPaymentIntentAmountCapturable,
+		providerapi.EventPaymentIntentPaymentFailed:
+		var intent providerapi.PaymentIntent
+		if err := json.Unmarshal(event.Data.Object, &intent); err != nil {
+			return nil, fmt.Errorf("webhook payment intent decode error: %v", err)
+		}
+		result.Reference = intent.ID
+
+		switch event.Type {
+		case providerapi.EventPaymentIntentSucceeded:
+			result.Type = WebhookPaymentSucceeded
+			result.TransactionID = intent.LatestCharge
+			result.Amount = intentMoney(intent.AmountReceived, intent.Currency)
+		case providerapi.EventPaymentIntentAmountCapturable:
+			result.Type = WebhookPaymentAuthorized
+			result.Amount = intentMoney(intent.AmountCapturable, intent.Currency)
+		default:
+			result.Type = WebhookPaymentFailed
+			result.Amount = intentMoney(intent.Amount, intent.Currency)
+			result.FailureReason = "Payment failed at provider"
+			if intent.LastPaymentError != nil {
+				result.FailureReason = declineReason(intent.LastPaymentError)
+			}
+		}
+
+	case providerapi.EventChargeRefunded:
+		var charge providerapi.Charge
+		if err := json.Unmarshal(event.Data.Object, &charge); err != nil {
+			return nil, fmt.Errorf("webhook charge decode error: %v", err)
+		}
+		result.Type = WebhookPaymentRefunded
+		result.Reference = charge.PaymentIntent
+		result.TransactionID = charge.ID
+		result.Amount = intentMoney(charge.AmountRefunded, charge.Currency)
+
+	case providerapi.EventChargeDisputeCreated, providerapi.EventChargeDisputeClosed:
+		var dispute providerapi.Dispute
+		if err := json.Unmarshal(event.Data.Object, &dispute); err != nil {
+			return nil, fmt.Errorf("webhook dispute decode error: %v", err)
+		}
+		result.Type = WebhookPaymentDisputed
+		if event.Type == providerapi.EventChargeDisputeClosed {
+			result.Type = WebhookDisputeClosed
+		}
+		result.Reference = dispute.PaymentIntent
+		result.TransactionID = dispute.Charge
+		result.Amount = intentMoney(dispute.Amount, dispute.Currency)
+		result.DisputeID = dispute.ID
+		result.DisputeReason = dispute.Reason
+		result.DisputeStatus = dispute.Status
+	}
+
+	return result, nil
+}
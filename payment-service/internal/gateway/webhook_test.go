@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway/providerapi"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+const testSecret = "whsec_test"
+
+func TestParseProviderWebhook(t *testing.T) {
+	payload := []byte(`{
+		"id": "evt_1",
+		"object": "event",
+		"type": "payment_intent.succeeded",
+		"created": 1700000000,
+		"data": {"object": {"id": "pi_1", "object": "payment_intent", "amount": 1999,
+			"amount_received": 1999, "currency": "usd", "latest_charge": "ch_1", "status": "succeeded"}}
+	}`)
+	signature := providerapi.SignPayload(payload, testSecret, time.Now())
+
+	event, err := ParseProviderWebhook(payload, signature, testSecret, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("parse webhook: %v", err)
+	}
+	if event.ID != "evt_1" || event.Type != WebhookPaymentSucceeded || event.Reference != "pi_1" ||
+		event.TransactionID != "ch_1" || event.Amount != types.NewMoney(1999, types.CurrencyUSD) {
+		t.Errorf("parsed event = %+v", event)
+	}
+}
+
+func TestParseProviderWebhookRejectsBadSignatures(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"charge.refunded","data":{"object":{}}}`)
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+		want      error
+	}{
+		{"tampered body", []byte(`{"id":"evt_2","type":"charge.refunded","data":{"object":{}}}`),
+			providerapi.SignPayload(payload, testSecret, time.Now()), providerapi.ErrInvalidSignature},
+		{"expired", payload, providerapi.SignPayload(payload, testSecret, time.Now().Add(-time.Hour)), providerapi.ErrSignatureExpired},
+		{"malformed header", payload, "sha256=abc", providerapi.ErrInvalidSignature},
+		{"missing header", payload, "", providerapi.ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		if event, err := ParseProviderWebhook(tt.payload, tt.signature, testSecret, 5*time.Minute); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %+v, %v, want %v", tt.name, event, err, tt.want)
+		}
+	}
+}
@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
 	"github.com/distributed-ecommerce-saga/payment-service/internal/service"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
 	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/resilience"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
@@ -38,52 +42,139 @@ func (h *PaymentHandler) GetPaymentByOrderID(c *fiber.Ctx) error {
 	}
 
 	response := PaymentResponse{
-		ID:              payment.ID,
-		OrderID:         payment.OrderID,
-		CustomerID:      payment.CustomerID,
-		SagaID:          payment.SagaID,
-		Amount:          payment.Amount,
-		PaymentMethod:   payment.PaymentMethod,
-		Status:          string(payment.Status),
-		TransactionID:   payment.TransactionID,
-		ExternalRef:     payment.ExternalRef,
-		FailureReason:   payment.FailureReason,
-		RefundedAmount:  payment.RefundedAmount,
-		RefundReference: payment.RefundReference,
-		CreatedAt:       payment.CreatedAt,
-		UpdatedAt:       payment.UpdatedAt,
-		ProcessedAt:     payment.ProcessedAt,
-		RefundedAt:      payment.RefundedAt,
+		ID:               payment.ID,
+		OrderID:          payment.OrderID,
+		CustomerID:       payment.CustomerID,
+		SagaID:           payment.SagaID,
+		Amount:           payment.Amount,
+		PaymentMethod:    payment.PaymentMethod,
+		Status:           string(payment.Status),
+		TransactionID:    payment.TransactionID,
+		ExternalRef:      payment.ExternalRef,
+		FailureReason:    payment.FailureReason,
+		RefundedAmount:   payment.RefundedAmount,
+		RefundReference:  payment.RefundReference,
+		SettlementAmount: payment.SettlementAmount,
+		ExchangeRate:     payment.ExchangeRate,
+		CreatedAt:        payment.CreatedAt,
+		UpdatedAt:        payment.UpdatedAt,
+		AuthorizationID:  payment.AuthorizationID,
+		AuthorizedAt:     payment.AuthorizedAt,
+		ProcessedAt:      payment.ProcessedAt,
+		RefundedAt:       payment.RefundedAt,
+		VoidedAt:         payment.VoidedAt,
+		Dispute:          payment.Dispute,
 	}
 
 	return sharedHTTP.SuccessResponse(c, "Payment retrieved successfully", response)
 }
 
+// GetPaymentRefunds lists the partial and full refunds of the order's payment
+func (h *PaymentHandler) GetPaymentRefunds(c *fiber.Ctx) error {
+	orderIDStr := c.Params("order_id")
+	orderID, err := uuid.Parse(orderIDStr)
+	if err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid order ID", map[string]interface{}{
+			"order_id": orderIDStr,
+		})
+	}
+
+	payment, err := h.paymentService.GetPaymentByOrderID(orderID)
+	if err != nil {
+		return sharedHTTP.NotFoundResponse(c, "Payment not found")
+	}
+
+	refunds, err := h.paymentService.GetRefunds(payment.ID)
+	if err != nil {
+		return sharedHTTP.InternalServerErrorResponse(c, "Refunds could not be retrieved", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Refunds retrieved successfully", map[string]interface{}{
+		"payment_id":       payment.ID,
+		"refunded_amount":  payment.RefundedAmount,
+		"remaining_amount": payment.GetRemainingRefundAmount(),
+		"refunds":          refunds,
+	})
+}
+
+// SubmitDisputeEvidence answers the open dispute of the payment it was opened on
+func (h *PaymentHandler) SubmitDisputeEvidence(c *fiber.Ctx) error {
+	disputeID := c.Params("dispute_id")
+	if disputeID == "" {
+		return sharedHTTP.BadRequestResponse(c, "Invalid dispute ID", nil)
+	}
+
+	var evidence domain.DisputeEvidence
+	if err := c.BodyParser(&evidence); err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid request body", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if evidence.IsEmpty() {
+		return sharedHTTP.BadRequestResponse(c, "Evidence is required", nil)
+	}
+
+	if _, err := h.paymentService.GetPaymentByDisputeID(disputeID); err != nil {
+		return sharedHTTP.NotFoundResponse(c, "Dispute not found")
+	}
+
+	payment, err := h.paymentService.SubmitDisputeEvidence(c.UserContext(), disputeID, evidence)
+	switch {
+	case err == nil:
+		return sharedHTTP.SuccessResponse(c, "Dispute evidence submitted", payment.Dispute)
+	case errors.Is(err, service.ErrNoOpenDispute), errors.Is(err, service.ErrEvidenceRejected):
+		return sharedHTTP.ConflictResponse(c, "Dispute evidence not accepted", map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		return sharedHTTP.InternalServerErrorResponse(c, "Dispute evidence submission failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
 func (h *PaymentHandler) HealthCheck(c *fiber.Ctx) error {
-	return sharedHTTP.SuccessResponse(c, "Payment service is healthy", map[string]interface{}{
-		"service": "payment-service",
-		"status":  "healthy",
+	// An open provider circuit degrades the service but it still answers
+	status := "healthy"
+	if !resilience.Healthy() {
+		status = "degraded"
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Payment service is "+status, map[string]interface{}{
+		"service":          "payment-service",
+		"status":           status,
+		"circuit_breakers": resilience.BreakerStatuses(),
 	})
 }
 
-func (h *PaymentHandler) HandleSagaEvent(event events.SagaEvent) error {
-	log.Printf("Payment service saga event received: %s from %s",
-		event.EventType, event.Service)
+func (h *PaymentHandler) HandleSagaEvent(ctx context.Context, event events.SagaEvent) error {
+	slog.DebugContext(ctx, "Payment service saga event received", "from", event.Service)
 
 	switch event.EventType {
 	case "payment.process":
-		return h.handlePaymentProcessCommand(event)
+		return h.handlePaymentProcessCommand(ctx, event)
+
+	case "payment.authorize":
+		return h.handlePaymentAuthorizeCommand(ctx, event)
+
+	case "payment.capture":
+		return h.handlePaymentCaptureCommand(ctx, event)
+
+	case "payment.void":
+		return h.handlePaymentVoidCommand(ctx, event)
 
 	case "payment.refund":
-		return h.handlePaymentRefundCommand(event)
+		return h.handlePaymentRefundCommand(ctx, event)
 
 	default:
-		log.Printf("Unhandled event type: %s", event.EventType)
+		slog.WarnContext(ctx, "Unhandled event type")
 		return nil
 	}
 }
 
-func (h *PaymentHandler) handlePaymentProcessCommand(event events.SagaEvent) error {
+func (h *PaymentHandler) handlePaymentProcessCommand(ctx context.Context, event events.SagaEvent) error {
 	payloadMap, ok := event.Payload.(map[string]interface{})
 	if !ok {
 		return h.logAndReturnError("Invalid payload format for payment.process", event)
@@ -94,15 +185,82 @@ func (h *PaymentHandler) handlePaymentProcessCommand(event events.SagaEvent) err
 		return h.logAndReturnError(fmt.Sprintf("Payload mapping error: %v", err), event)
 	}
 
-	if err := h.paymentService.ProcessPayment(request); err != nil {
-		log.Printf("Payment processing error: %v", err)
+	if err := h.paymentService.ProcessPayment(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Payment processing error", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (h *PaymentHandler) handlePaymentAuthorizeCommand(ctx context.Context, event events.SagaEvent) error {
+	payloadMap, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return h.logAndReturnError("Invalid payload format for payment.authorize", event)
+	}
+
+	request, err := h.mapToPaymentProcessRequest(event.SagaID, payloadMap)
+	if err != nil {
+		return h.logAndReturnError(fmt.Sprintf("Payload mapping error: %v", err), event)
+	}
+
+	if err := h.paymentService.AuthorizePayment(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Payment authorization error", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (h *PaymentHandler) handlePaymentCaptureCommand(ctx context.Context, event events.SagaEvent) error {
+	payloadMap, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return h.logAndReturnError("Invalid payload format for payment.capture", event)
+	}
+
+	request := domain.PaymentCaptureRequest{
+		SagaID:    event.SagaID,
+		PaymentID: parseOptionalUUID(payloadMap["payment_id"]),
+	}
+	if value, ok := payloadMap["amount"]; ok && value != nil {
+		amount, err := types.MoneyFromValue(value)
+		if err != nil {
+			return h.logAndReturnError(fmt.Sprintf("Invalid capture amount: %v", err), event)
+		}
+		request.Amount = &amount
+	}
+
+	if err := h.paymentService.CapturePayment(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Payment capture error", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (h *PaymentHandler) handlePaymentVoidCommand(ctx context.Context, event events.SagaEvent) error {
+	payloadMap, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		return h.logAndReturnError("Invalid payload format for payment.void", event)
+	}
+
+	request := domain.PaymentVoidRequest{
+		SagaID:    event.SagaID,
+		PaymentID: parseOptionalUUID(payloadMap["payment_id"]),
+	}
+	if reason, ok := payloadMap["reason"].(string); ok {
+		request.Reason = reason
+	}
+
+	if err := h.paymentService.VoidPayment(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Payment void error", "error", err)
 		return err
 	}
 
 	return nil
 }
 
-func (h *PaymentHandler) handlePaymentRefundCommand(event events.SagaEvent) error {
+func (h *PaymentHandler) handlePaymentRefundCommand(ctx context.Context, event events.SagaEvent) error {
 	payloadMap, ok := event.Payload.(map[string]interface{})
 	if !ok {
 		return h.logAndReturnError("Invalid payload format for payment.refund", event)
@@ -113,8 +271,8 @@ func (h *PaymentHandler) handlePaymentRefundCommand(event events.SagaEvent) erro
 		return h.logAndReturnError(fmt.Sprintf("Refund payload mapping error: %v", err), event)
 	}
 
-	if err := h.paymentService.ProcessRefund(request); err != nil {
-		log.Printf("Payment refund error: %v", err)
+	if err := h.paymentService.ProcessRefund(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Payment refund error", "error", err)
 		return err
 	}
 
@@ -146,11 +304,14 @@ func (h *PaymentHandler) mapToPaymentProcessRequest(sagaID uuid.UUID, payload ma
 		return request, fmt.Errorf("missing or invalid customer_id")
 	}
 
-	if amount, ok := payload["amount"].(float64); ok {
-		request.Amount = amount
-	} else {
-		return request, fmt.Errorf("missing or invalid amount")
+	amount, err := types.MoneyFromValue(payload["amount"])
+	if err != nil {
+		return request, fmt.Errorf("missing or invalid amount: %v", err)
 	}
+	if amount, err = amount.InCurrency(types.DefaultCurrency); err != nil {
+		return request, fmt.Errorf("missing or invalid amount: %v", err)
+	}
+	request.Amount = amount
 
 	if method, ok := payload["payment_method"].(string); ok {
 		request.PaymentMethod = method
@@ -166,11 +327,11 @@ func (h *PaymentHandler) mapToPaymentRefundRequest(sagaID uuid.UUID, payload map
 		SagaID: sagaID,
 	}
 
-	if amount, ok := payload["amount"].(float64); ok {
-		request.Amount = amount
-	} else {
-		return request, fmt.Errorf("missing or invalid refund amount")
+	amount, err := types.MoneyFromValue(payload["amount"])
+	if err != nil {
+		return request, fmt.Errorf("missing or invalid refund amount: %v", err)
 	}
+	request.Amount = amount
 
 	if reason, ok := payload["reason"].(string); ok {
 		request.Reason = reason
@@ -189,15 +350,29 @@ func (h *PaymentHandler) mapToPaymentRefundRequest(sagaID uuid.UUID, payload map
 	return request, nil
 }
 
+// parseOptionalUUID returns uuid.Nil for missing or malformed IDs
+func parseOptionalUUID(value interface{}) uuid.UUID {
+	if str, ok := value.(string); ok {
+		if id, err := uuid.Parse(str); err == nil {
+			return id
+		}
+	}
+	return uuid.Nil
+}
+
 func (h *PaymentHandler) logAndReturnError(message string, event events.SagaEvent) error {
-	log.Printf("%s - Event: %+v", message, event)
+	slog.Error(message, "event_id", event.ID, "event_type", event.EventType, "saga_id", event.SagaID)
+
 	return fmt.Errorf(message)
 }
 
 func (h *PaymentHandler) StartConsuming(consumer *messaging.Consumer) error {
 	routingKeys := []string{
-		"saga.saga-orchestrator.payment.process", // Payment process command
-		"saga.saga-orchestrator.payment.refund",  // Refund command
+		"saga.saga-orchestrator.payment.process",   // Payment process command
+		"saga.saga-orchestrator.payment.authorize", // Authorize command
+		"saga.saga-orchestrator.payment.capture",   // Capture command
+		"saga.saga-orchestrator.payment.void",      // Void command
+		"saga.saga-orchestrator.payment.refund",    // Refund command
 	}
 
 	return consumer.ConsumeEvents(routingKeys, h.HandleSagaEvent)
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/payment-service/internal/service"
+	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ReconciliationHandler struct {
+	reconciler *service.Reconciler
+}
+
+func NewReconciliationHandler(reconciler *service.Reconciler) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		reconciler: reconciler,
+	}
+}
+
+// GetRuns lists the latest reconciliation runs without their entries
+func (h *ReconciliationHandler) GetRuns(c *fiber.Ctx) error {
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	runs, err := h.reconciler.GetRuns(limit)
+	if err != nil {
+		return sharedHTTP.InternalServerErrorResponse(c, "Reconciliation runs retrieval failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Reconciliation runs retrieved successfully", runs)
+}
+
+// GetRun returns one run with its entries, as CSV with ?format=csv
+func (h *ReconciliationHandler) GetRun(c *fiber.Ctx) error {
+	runIDStr := c.Params("run_id")
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid run ID", map[string]interface{}{
+			"run_id": runIDStr,
+		})
+	}
+
+	run, err := h.reconciler.GetRun(runID)
+	if err != nil {
+		return sharedHTTP.NotFoundResponse(c, "Reconciliation run not found")
+	}
+
+	if c.Query("format") == "csv" {
+		data, err := reconciliationCSV(run)
+		if err != nil {
+			return sharedHTTP.InternalServerErrorResponse(c, "Reconciliation report export failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+
+		c.Attachment(fmt.Sprintf("reconciliation-%s.csv", run.ID))
+		c.Set(fiber.HeaderContentType, "text/csv")
+		return c.Send(data)
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Reconciliation run retrieved successfully", run)
+}
+
+// TriggerRun reconciles now instead of waiting for the next scheduled run
+func (h *ReconciliationHandler) TriggerRun(c *fiber.Ctx) error {
+	run, err := h.reconciler.Run(c.UserContext())
+	if err != nil {
+		return sharedHTTP.InternalServerErrorResponse(c, "Reconciliation run failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Reconciliation run finished", run)
+}
+
+func reconciliationCSV(run *domain.ReconciliationRun) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	writer.Write([]string{
+		"run_id", "checked_at", "payment_id", "order_id", "saga_id",
+		"local_status", "provider_status", "action", "amount", "currency", "detail",
+	})
+	for _, entry := range run.Entries {
+		writer.Write([]string{
+			run.ID.String(),
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			entry.PaymentID.String(),
+			entry.OrderID.String(),
+			entry.SagaID.String(),
+			string(entry.LocalStatus),
+			entry.ProviderStatus,
+			string(entry.Action),
+			entry.Amount.Decimal(),
+			string(entry.Amount.Currency),
+			entry.Detail,
+		})
+	}
+
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway"
+	"github.com/distributed-ecommerce-saga/payment-service/internal/service"
+	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookHandler struct {
+	paymentService *service.PaymentService
+	secret         string
+	tolerance      time.Duration // Accepted signature age
+}
+
+func NewWebhookHandler(paymentService *service.PaymentService, secret string, tolerance time.Duration) *WebhookHandler {
+	return &WebhookHandler{
+		paymentService: paymentService,
+		secret:         secret,
+		tolerance:      tolerance,
+	}
+}
+
+// HandleProviderWebhook receives asynchronous payment results. Any non-2xx
+// answer makes the provider redeliver the event later.
+func (h *WebhookHandler) HandleProviderWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	event, err := gateway.ParseProviderWebhook(c.Body(), c.Get(gateway.WebhookSignatureHeader), h.secret, h.tolerance)
+	if err != nil {
+		slog.WarnContext(ctx, "Provider webhook rejected", "error", err)
+		return sharedHTTP.BadRequestResponse(c, "Invalid webhook", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.paymentService.HandleWebhook(ctx, event); err != nil {
+		if errors.Is(err, service.ErrWebhookRetryLater) {
+			slog.InfoContext(ctx, "Provider webhook deferred", "event_id", event.ID, "error", err)
+			return sharedHTTP.ConflictResponse(c, "Webhook cannot be applied yet", map[string]interface{}{
+				"event_id": event.ID,
+			})
+		}
+
+		slog.ErrorContext(ctx, "Provider webhook processing error", "event_id", event.ID, "error", err)
+		return sharedHTTP.InternalServerErrorResponse(c, "Webhook processing failed", map[string]interface{}{
+			"event_id": event.ID,
+		})
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Webhook received", map[string]interface{}{
+		"event_id": event.ID,
+	})
+}
@@ -0,0 +1,16 @@
+-- Amounts are stored as integer minor units (cents) with an ISO 4217 currency
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+DROP INDEX IF EXISTS idx_payments_refundable;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS chk_refunded_amount_limit;
+
+ALTER TABLE payments ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100)::BIGINT;
+ALTER TABLE payments ALTER COLUMN refunded_amount DROP DEFAULT;
+ALTER TABLE payments ALTER COLUMN refunded_amount TYPE BIGINT USING ROUND(refunded_amount * 100)::BIGINT;
+ALTER TABLE payments ALTER COLUMN refunded_amount SET DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_payments_refundable ON payments(order_id, status, refunded_amount)
+    WHERE status = 'completed' AND refunded_amount < amount;
+
+ALTER TABLE payments ADD CONSTRAINT chk_refunded_amount_limit
+    CHECK (refunded_amount <= amount);
@@ -0,0 +1,4 @@
+-- Settlement amount and the capture to settlement exchange rate applied at capture time
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS settlement_amount BIGINT;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS settlement_currency CHAR(3);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC(20,10);
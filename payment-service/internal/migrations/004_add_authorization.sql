@@ -0,0 +1,12 @@
+-- Authorize/capture split: payments are authorized first and captured or voided later
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS authorization_id VARCHAR(255);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS authorized_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS voided_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (status IN (
+    'pending', 'authorized', 'completed', 'failed', 'refunded', 'voided'
+));
+
+CREATE INDEX IF NOT EXISTS idx_payments_authorized ON payments(authorized_at)
+    WHERE status = 'authorized';
@@ -0,0 +1,14 @@
+-- Saga commands handled by the payment service, keyed by saga and step. A
+-- redelivered command replays result_event instead of charging again.
+CREATE TABLE IF NOT EXISTS payment_commands (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    saga_id UUID NOT NULL,
+    command VARCHAR(50) NOT NULL,
+    payment_id UUID,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'completed')),
+    result_event JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_commands_saga_id ON payment_commands(saga_id);
@@ -0,0 +1,37 @@
+-- Reconciliation of local payments against the gateway. reconciled_at marks
+-- payments the reconciler already checked, so they are reported only once.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS reconciled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_payments_unreconciled ON payments(status, updated_at)
+    WHERE reconciled_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id UUID PRIMARY KEY,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    checked INTEGER NOT NULL DEFAULT 0,
+    corrected INTEGER NOT NULL DEFAULT 0,
+    discrepancies INTEGER NOT NULL DEFAULT 0,
+    unresolved INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_runs_started_at ON reconciliation_runs(started_at);
+
+-- One row per payment checked in a run
+CREATE TABLE IF NOT EXISTS reconciliation_entries (
+    id BIGSERIAL PRIMARY KEY,
+    run_id UUID NOT NULL REFERENCES reconciliation_runs(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    saga_id UUID NOT NULL,
+    local_status VARCHAR(20) NOT NULL,
+    provider_status VARCHAR(50),
+    action VARCHAR(50) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    detail TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_entries_run_id ON reconciliation_entries(run_id);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_entries_payment_id ON reconciliation_entries(payment_id);
@@ -0,0 +1,20 @@
+-- Provider webhooks: asynchronous payment results, refunds and disputes.
+-- Each provider event is applied once; redeliveries of a processed event are acknowledged only.
+CREATE TABLE IF NOT EXISTS provider_webhook_events (
+    event_id VARCHAR(255) PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    payment_id UUID,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'processed')),
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Webhooks find the payment by the provider reference
+CREATE INDEX IF NOT EXISTS idx_payments_external_ref ON payments(external_ref) WHERE external_ref IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_payments_authorization_id ON payments(authorization_id) WHERE authorization_id IS NOT NULL;
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS dispute_id VARCHAR(255);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS dispute_status VARCHAR(20);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS dispute_reason VARCHAR(100);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS dispute_amount BIGINT;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS disputed_at TIMESTAMP WITH TIME ZONE;
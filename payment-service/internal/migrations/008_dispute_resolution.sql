@@ -0,0 +1,7 @@
+-- Dispute evidence and outcome; a lost dispute starts the orchestrator's chargeback flow
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS dispute_evidence JSONB;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS dispute_evidence_submitted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS dispute_closed_at TIMESTAMP WITH TIME ZONE;
+
+-- Correlation ID of the saga, for events published outside a saga command (webhooks, reconciliation)
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS correlation_id UUID;
@@ -0,0 +1,17 @@
+-- Refund history: a payment can now be refunded in several parts, by refund sagas
+-- or at the provider. payments.refunded_amount stays the running total.
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id UUID PRIMARY KEY,
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    saga_id UUID,
+    refund_reference VARCHAR(255),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    reason TEXT,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('saga', 'provider')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_payment_id ON payment_refunds(payment_id, created_at);
+
+-- Refund commands of refund sagas are found by payment, not by the checkout saga
+CREATE INDEX IF NOT EXISTS idx_payment_commands_payment_id ON payment_commands(payment_id);
@@ -0,0 +1,4 @@
+-- Backordered items are charged by their own sagas, so an order can now have a
+-- payment per saga: the checkout payment and one per backorder
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_order_id_key;
+DROP INDEX IF EXISTS idx_payments_unique_order;
@@ -0,0 +1,2 @@
+-- Dispute evidence is submitted by dispute ID, which names exactly one payment
+CREATE INDEX IF NOT EXISTS idx_payments_dispute_id ON payments(dispute_id) WHERE dispute_id IS NOT NULL;
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/google/uuid"
+)
+
+// BeginCommand claims a saga command. The first delivery inserts a processing
+// record; a redelivery gets the existing record back, completed or not.
+func (r *PaymentRepository) BeginCommand(key string, sagaID uuid.UUID, command string) (*domain.PaymentCommand, error) {
+	defer metrics.ObserveDBQuery("BeginCommand", time.Now())
+
+	_, err := r.db.Exec(`
+		INSERT INTO payment_commands (idempotency_key, saga_id, command, status, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (idempotency_key) DO NOTHING
+	`, key, sagaID, command, domain.CommandStatusProcessing, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("payment command insert error: %v", err)
+	}
+
+	record, err := scanCommand(r.db.QueryRow(`
+		SELECT `+commandColumns+`
+		FROM payment_commands
+		WHERE idempotency_key = $1
+	`, key))
+	if err != nil {
+		return nil, fmt.Errorf("payment command receive error: %v", err)
+	}
+
+	return record, nil
+}
+
+// GetCommand returns the command with the given idempotency key
+func (r *PaymentRepository) GetCommand(key string) (*domain.PaymentCommand, error) {
+	defer metrics.ObserveDBQuery("GetCommand", time.Now())
+
+	record, err := scanCommand(r.db.QueryRow(`
+		SELECT `+commandColumns+`
+		FROM payment_commands
+		WHERE idempotency_key = $1
+	`, key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment command not found: %s", key)
+		}
+		return nil, fmt.Errorf("payment command receive error: %v", err)
+	}
+
+	return record, nil
+}
+
+// GetCommandByPaymentID returns the saga command that created the payment
+func (r *PaymentRepository) GetCommandByPaymentID(paymentID uuid.UUID) (*domain.PaymentCommand, error) {
+	defer metrics.ObserveDBQuery("GetCommandByPaymentID", time.Now())
+
+	record, err := scanCommand(r.db.QueryRow(`
+		SELECT `+commandColumns+`
+		FROM payment_commands
+		WHERE payment_id = $1
+		ORDER BY created_at
+		LIMIT 1
+	`, paymentID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment command not found for payment: %s", paymentID)
+		}
+		return nil, fmt.Errorf("payment command receive error: %v", err)
+	}
+
+	return record, nil
+}
+
+// LinkCommandPayment records which payment a command works on, for commands
+// that act on an existing payment
+func (r *PaymentRepository) LinkCommandPayment(key string, paymentID uuid.UUID) error {
+	defer metrics.ObserveDBQuery("LinkCommandPayment", time.Now())
+
+	_, err := r.db.Exec(`
+		UPDATE payment_commands SET payment_id = $2 WHERE idempotency_key = $1
+	`, key, paymentID)
+	if err != nil {
+		return fmt.Errorf("payment command update error: %v", err)
+	}
+	return nil
+}
+
+// ReleaseCommand forgets a command that failed without an outcome. A command
+// that already created its payment is kept, so the retry resumes that payment.
+func (r *PaymentRepository) ReleaseCommand(key string) error {
+	defer metrics.ObserveDBQuery("ReleaseCommand", time.Now())
+
+	_, err := r.db.Exec(`
+		DELETE FROM payment_commands
+		WHERE idempotency_key = $1 AND status = $2 AND payment_id IS NULL
+	`, key, domain.CommandStatusProcessing)
+	if err != nil {
+		return fmt.Errorf("payment command release error: %v", err)
+	}
+	return nil
+}
+
+// HasCommandInProgress reports whether a command of the given kind is still
+// running against the payment, from any saga
+func (r *PaymentRepository) HasCommandInProgress(paymentID uuid.UUID, command string) (bool, error) {
+	defer metrics.ObserveDBQuery("HasCommandInProgress", time.Now())
+
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM payment_commands
+			WHERE payment_id = $1 AND command = $2 AND status = $3
+		)
+	`, paymentID, command, domain.CommandStatusProcessing).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("payment command receive error: %v", err)
+	}
+	return exists, nil
+}
+
+// commandColumns selected by every command query, in scanCommand order
+const commandColumns = `idempotency_key, saga_id, command, payment_id, status, result_event, created_at, completed_at`
+
+func scanCommand(row rowScanner) (*domain.PaymentCommand, error) {
+	record := &domain.PaymentCommand{}
+	var paymentID uuid.NullUUID
+	var resultEvent []byte
+	var completedAt sql.NullTime
+
+	err := row.Scan(
+		&record.IdempotencyKey,
+		&record.SagaID,
+		&record.Command,
+		&paymentID,
+		&record.Status,
+		&resultEvent,
+		&record.CreatedAt,
+		&completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	record.PaymentID = paymentID.UUID
+	if completedAt.Valid {
+		record.CompletedAt = &completedAt.Time
+	}
+	if resultEvent != nil {
+		record.ResultEvent = &events.SagaEvent{}
+		if err := json.Unmarshal(resultEvent, record.ResultEvent); err != nil {
+			return nil, fmt.Errorf("payment command result unmarshal error: %v", err)
+		}
+	}
+
+	return record, nil
+}
+
+// CreateCommandPayment stores the payment and links it to its command in one
+// transaction, so a redelivery never creates a second payment for the order
+func (r *PaymentRepository) CreateCommandPayment(payment *domain.PaymentAggregate, key string) error {
+	defer metrics.ObserveDBQuery("CreateCommandPayment", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertPayment(tx, payment); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE payment_commands SET payment_id = $2 WHERE idempotency_key = $1
+	`, key, payment.ID); err != nil {
+		return fmt.Errorf("payment command update error: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit error: %v", err)
+	}
+	return nil
+}
+
+// CompleteCommand records the reply event as the outcome of the command
+func (r *PaymentRepository) CompleteCommand(key string, event events.SagaEvent) error {
+	defer metrics.ObserveDBQuery("CompleteCommand", time.Now())
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("payment command result marshal error: %v", err)
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE payment_commands
+		SET status = $2, result_event = $3, completed_at = $4
+		WHERE idempotency_key = $1
+	`, key, domain.CommandStatusCompleted, data, time.Now())
+	if err != nil {
+		return fmt.Errorf("payment command complete error: %v", err)
+	}
+	return nil
+}
@@ -2,14 +2,27 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"time"
 
 	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 )
 
+// paymentColumns selected by every query, in scanPayment order
+const paymentColumns = `
+	id, order_id, customer_id, saga_id, amount, currency, payment_method,
+	status, transaction_id, external_ref, failure_reason,
+	refunded_amount, refund_reference, created_at, updated_at,
+	processed_at, refunded_at, settlement_amount, settlement_currency, exchange_rate,
+	authorization_id, authorized_at, voided_at,
+	dispute_id, dispute_status, dispute_reason, dispute_amount, disputed_at,
+	dispute_evidence, dispute_evidence_submitted_at, dispute_closed_at, correlation_id`
+
 type PaymentRepository struct {
 	db *sql.DB
 }
@@ -19,22 +32,36 @@ func NewPaymentRepository(db *sql.DB) *PaymentRepository {
 }
 
 func (r *PaymentRepository) CreatePayment(payment *domain.PaymentAggregate) error {
+	defer metrics.ObserveDBQuery("CreatePayment", time.Now())
+
+	return insertPayment(r.db, payment)
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertPayment(db execer, payment *domain.PaymentAggregate) error {
+	dispute := disputeOrEmpty(payment.Dispute)
+	evidence, err := disputeEvidence(payment.Dispute)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO payments (
-			id, order_id, customer_id, saga_id, amount, payment_method, 
-			status, transaction_id, external_ref, failure_reason, 
-			refunded_amount, refund_reference, created_at, updated_at, 
-			processed_at, refunded_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		INSERT INTO payments (` + paymentColumns + `
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32)
 	`
 
-	_, err := r.db.Exec(
+	_, err = db.Exec(
 		query,
 		payment.ID,
 		payment.OrderID,
 		payment.CustomerID,
 		payment.SagaID,
 		payment.Amount,
+		payment.Amount.Currency,
 		payment.PaymentMethod,
 		payment.Status,
 		payment.TransactionID,
@@ -46,6 +73,21 @@ func (r *PaymentRepository) CreatePayment(payment *domain.PaymentAggregate) erro
 		payment.UpdatedAt,
 		payment.ProcessedAt,
 		payment.RefundedAt,
+		payment.SettlementAmount,
+		nullString(string(payment.SettlementAmount.Currency)),
+		nullString(payment.ExchangeRate),
+		nullString(payment.AuthorizationID),
+		payment.AuthorizedAt,
+		payment.VoidedAt,
+		nullString(dispute.ID),
+		nullString(string(dispute.Status)),
+		nullString(dispute.Reason),
+		disputeAmount(payment.Dispute),
+		disputedAt(payment.Dispute),
+		evidence,
+		dispute.EvidenceSubmittedAt,
+		dispute.ClosedAt,
+		uuid.NullUUID{UUID: payment.CorrelationID, Valid: payment.CorrelationID != uuid.Nil},
 	)
 
 	if err != nil {
@@ -57,15 +99,32 @@ func (r *PaymentRepository) CreatePayment(payment *domain.PaymentAggregate) erro
 
 // UpdatePayment mevcut payment'i günceller
 func (r *PaymentRepository) UpdatePayment(payment *domain.PaymentAggregate) error {
+	defer metrics.ObserveDBQuery("UpdatePayment", time.Now())
+
+	return updatePayment(r.db, payment)
+}
+
+func updatePayment(db execer, payment *domain.PaymentAggregate) error {
 	query := `
-		UPDATE payments 
-		SET status = $2, transaction_id = $3, external_ref = $4, 
+		UPDATE payments
+		SET status = $2, transaction_id = $3, external_ref = $4,
 			failure_reason = $5, refunded_amount = $6, refund_reference = $7,
-			updated_at = $8, processed_at = $9, refunded_at = $10
+			updated_at = $8, processed_at = $9, refunded_at = $10,
+			authorization_id = $11, authorized_at = $12, voided_at = $13,
+			dispute_id = $14, dispute_status = $15, dispute_reason = $16,
+			dispute_amount = $17, disputed_at = $18, dispute_evidence = $19,
+			dispute_evidence_submitted_at = $20, dispute_closed_at = $21,
+			amount = $22, settlement_amount = $23
 		WHERE id = $1
 	`
 
-	result, err := r.db.Exec(
+	dispute := disputeOrEmpty(payment.Dispute)
+	evidence, err := disputeEvidence(payment.Dispute)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec(
 		query,
 		payment.ID,
 		payment.Status,
@@ -77,6 +136,19 @@ func (r *PaymentRepository) UpdatePayment(payment *domain.PaymentAggregate) erro
 		payment.UpdatedAt,
 		payment.ProcessedAt,
 		payment.RefundedAt,
+		nullString(payment.AuthorizationID),
+		payment.AuthorizedAt,
+		payment.VoidedAt,
+		nullString(dispute.ID),
+		nullString(string(dispute.Status)),
+		nullString(dispute.Reason),
+		disputeAmount(payment.Dispute),
+		disputedAt(payment.Dispute),
+		evidence,
+		dispute.EvidenceSubmittedAt,
+		dispute.ClosedAt,
+		payment.Amount,
+		payment.SettlementAmount,
 	)
 
 	if err != nil {
@@ -96,38 +168,15 @@ func (r *PaymentRepository) UpdatePayment(payment *domain.PaymentAggregate) erro
 }
 
 func (r *PaymentRepository) GetPaymentByID(paymentID uuid.UUID) (*domain.PaymentAggregate, error) {
+	defer metrics.ObserveDBQuery("GetPaymentByID", time.Now())
+
 	query := `
-		SELECT id, order_id, customer_id, saga_id, amount, payment_method,
-			   status, transaction_id, external_ref, failure_reason,
-			   refunded_amount, refund_reference, created_at, updated_at,
-			   processed_at, refunded_at
-		FROM payments 
+		SELECT ` + paymentColumns + `
+		FROM payments
 		WHERE id = $1
 	`
 
-	payment := &domain.PaymentAggregate{Payment: &types.Payment{}}
-	var transactionID, externalRef, failureReason, refundRef sql.NullString
-	var processedAt, refundedAt sql.NullTime
-
-	err := r.db.QueryRow(query, paymentID).Scan(
-		&payment.ID,
-		&payment.OrderID,
-		&payment.CustomerID,
-		&payment.SagaID,
-		&payment.Amount,
-		&payment.PaymentMethod,
-		&payment.Status,
-		&transactionID,
-		&externalRef,
-		&failureReason,
-		&payment.RefundedAmount,
-		&refundRef,
-		&payment.CreatedAt,
-		&payment.UpdatedAt,
-		&processedAt,
-		&refundedAt,
-	)
-
+	payment, err := scanPayment(r.db.QueryRow(query, paymentID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("payment not found: %s", paymentID)
@@ -135,63 +184,23 @@ func (r *PaymentRepository) GetPaymentByID(paymentID uuid.UUID) (*domain.Payment
 		return nil, fmt.Errorf("payment receive error: %v", err)
 	}
 
-	if transactionID.Valid {
-		payment.TransactionID = transactionID.String
-	}
-	if externalRef.Valid {
-		payment.ExternalRef = externalRef.String
-	}
-	if failureReason.Valid {
-		payment.FailureReason = failureReason.String
-	}
-	if refundRef.Valid {
-		payment.RefundReference = refundRef.String
-	}
-	if processedAt.Valid {
-		payment.ProcessedAt = &processedAt.Time
-	}
-	if refundedAt.Valid {
-		payment.RefundedAt = &refundedAt.Time
-	}
-
 	return payment, nil
 }
 
+// GetPaymentByOrderID returns the payment of the order's checkout; payments of
+// backordered items are made later
 func (r *PaymentRepository) GetPaymentByOrderID(orderID uuid.UUID) (*domain.PaymentAggregate, error) {
+	defer metrics.ObserveDBQuery("GetPaymentByOrderID", time.Now())
+
 	query := `
-		SELECT id, order_id, customer_id, saga_id, amount, payment_method,
-			   status, transaction_id, external_ref, failure_reason,
-			   refunded_amount, refund_reference, created_at, updated_at,
-			   processed_at, refunded_at
-		FROM payments 
+		SELECT ` + paymentColumns + `
+		FROM payments
 		WHERE order_id = $1
-		ORDER BY created_at DESC
+		ORDER BY created_at
 		LIMIT 1
 	`
 
-	payment := &domain.PaymentAggregate{Payment: &types.Payment{}}
-	var transactionID, externalRef, failureReason, refundRef sql.NullString
-	var processedAt, refundedAt sql.NullTime
-
-	err := r.db.QueryRow(query, orderID).Scan(
-		&payment.ID,
-		&payment.OrderID,
-		&payment.CustomerID,
-		&payment.SagaID,
-		&payment.Amount,
-		&payment.PaymentMethod,
-		&payment.Status,
-		&transactionID,
-		&externalRef,
-		&failureReason,
-		&payment.RefundedAmount,
-		&refundRef,
-		&payment.CreatedAt,
-		&payment.UpdatedAt,
-		&processedAt,
-		&refundedAt,
-	)
-
+	payment, err := scanPayment(r.db.QueryRow(query, orderID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("payment not found for order: %s", orderID)
@@ -199,24 +208,25 @@ func (r *PaymentRepository) GetPaymentByOrderID(orderID uuid.UUID) (*domain.Paym
 		return nil, fmt.Errorf("payment receive hatası: %v", err)
 	}
 
-	// Nullable fields handling (aynı logic)
-	if transactionID.Valid {
-		payment.TransactionID = transactionID.String
-	}
-	if externalRef.Valid {
-		payment.ExternalRef = externalRef.String
-	}
-	if failureReason.Valid {
-		payment.FailureReason = failureReason.String
-	}
-	if refundRef.Valid {
-		payment.RefundReference = refundRef.String
-	}
-	if processedAt.Valid {
-		payment.ProcessedAt = &processedAt.Time
-	}
-	if refundedAt.Valid {
-		payment.RefundedAt = &refundedAt.Time
+	return payment, nil
+}
+
+// GetPaymentByDisputeID returns the payment the provider's dispute was opened on
+func (r *PaymentRepository) GetPaymentByDisputeID(disputeID string) (*domain.PaymentAggregate, error) {
+	defer metrics.ObserveDBQuery("GetPaymentByDisputeID", time.Now())
+
+	query := `
+		SELECT ` + paymentColumns + `
+		FROM payments
+		WHERE dispute_id = $1
+	`
+
+	payment, err := scanPayment(r.db.QueryRow(query, disputeID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment not found for dispute: %s", disputeID)
+		}
+		return nil, fmt.Errorf("payment receive error: %v", err)
 	}
 
 	return payment, nil
@@ -224,12 +234,11 @@ func (r *PaymentRepository) GetPaymentByOrderID(orderID uuid.UUID) (*domain.Paym
 
 // GetPaymentsBySagaID Saga ID'ye göre tüm payment'ları getirir
 func (r *PaymentRepository) GetPaymentsBySagaID(sagaID uuid.UUID) ([]*domain.PaymentAggregate, error) {
+	defer metrics.ObserveDBQuery("GetPaymentsBySagaID", time.Now())
+
 	query := `
-		SELECT id, order_id, customer_id, saga_id, amount, payment_method,
-			   status, transaction_id, external_ref, failure_reason,
-			   refunded_amount, refund_reference, created_at, updated_at,
-			   processed_at, refunded_at
-		FROM payments 
+		SELECT ` + paymentColumns + `
+		FROM payments
 		WHERE saga_id = $1
 		ORDER BY created_at DESC
 	`
@@ -243,55 +252,156 @@ func (r *PaymentRepository) GetPaymentsBySagaID(sagaID uuid.UUID) ([]*domain.Pay
 	var payments []*domain.PaymentAggregate
 
 	for rows.Next() {
-		payment := &domain.PaymentAggregate{Payment: &types.Payment{}}
-		var transactionID, externalRef, failureReason, refundRef sql.NullString
-		var processedAt, refundedAt sql.NullTime
-
-		err := rows.Scan(
-			&payment.ID,
-			&payment.OrderID,
-			&payment.CustomerID,
-			&payment.SagaID,
-			&payment.Amount,
-			&payment.PaymentMethod,
-			&payment.Status,
-			&transactionID,
-			&externalRef,
-			&failureReason,
-			&payment.RefundedAmount,
-			&refundRef,
-			&payment.CreatedAt,
-			&payment.UpdatedAt,
-			&processedAt,
-			&refundedAt,
-		)
-
+		payment, err := scanPayment(rows)
 		if err != nil {
 			return nil, fmt.Errorf("payment scan error: %v", err)
 		}
 
-		// Nullable fields handling
-		if transactionID.Valid {
-			payment.TransactionID = transactionID.String
-		}
-		if externalRef.Valid {
-			payment.ExternalRef = externalRef.String
-		}
-		if failureReason.Valid {
-			payment.FailureReason = failureReason.String
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPayment(row rowScanner) (*domain.PaymentAggregate, error) {
+	payment := &domain.PaymentAggregate{Payment: &types.Payment{}}
+	var transactionID, externalRef, failureReason, refundRef sql.NullString
+	var settlementCurrency, exchangeRate, authorizationID sql.NullString
+	var processedAt, refundedAt, authorizedAt, voidedAt sql.NullTime
+	var disputeID, disputeStatus, disputeReason sql.NullString
+	var disputeAmount sql.NullInt64
+	var disputedAt, evidenceSubmittedAt, disputeClosedAt sql.NullTime
+	var evidenceJSON []byte
+	var correlationID uuid.NullUUID
+
+	err := row.Scan(
+		&payment.ID,
+		&payment.OrderID,
+		&payment.CustomerID,
+		&payment.SagaID,
+		&payment.Amount,
+		&payment.Amount.Currency,
+		&payment.PaymentMethod,
+		&payment.Status,
+		&transactionID,
+		&externalRef,
+		&failureReason,
+		&payment.RefundedAmount,
+		&refundRef,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+		&processedAt,
+		&refundedAt,
+		&payment.SettlementAmount,
+		&settlementCurrency,
+		&exchangeRate,
+		&authorizationID,
+		&authorizedAt,
+		&voidedAt,
+		&disputeID,
+		&disputeStatus,
+		&disputeReason,
+		&disputeAmount,
+		&disputedAt,
+		&evidenceJSON,
+		&evidenceSubmittedAt,
+		&disputeClosedAt,
+		&correlationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	payment.RefundedAmount.Currency = payment.Amount.Currency
+
+	// Nullable fields handling
+	payment.TransactionID = transactionID.String
+	payment.ExternalRef = externalRef.String
+	payment.FailureReason = failureReason.String
+	payment.RefundReference = refundRef.String
+	payment.SettlementAmount.Currency = types.Currency(settlementCurrency.String)
+	payment.ExchangeRate = exchangeRate.String
+	payment.AuthorizationID = authorizationID.String
+	if processedAt.Valid {
+		payment.ProcessedAt = &processedAt.Time
+	}
+	if refundedAt.Valid {
+		payment.RefundedAt = &refundedAt.Time
+	}
+	if authorizedAt.Valid {
+		payment.AuthorizedAt = &authorizedAt.Time
+	}
+	if voidedAt.Valid {
+		payment.VoidedAt = &voidedAt.Time
+	}
+	if disputeID.Valid {
+		payment.Dispute = &domain.PaymentDispute{
+			ID:       disputeID.String,
+			Status:   domain.DisputeStatus(disputeStatus.String),
+			Reason:   disputeReason.String,
+			Amount:   types.NewMoney(disputeAmount.Int64, payment.Amount.Currency),
+			OpenedAt: disputedAt.Time,
 		}
-		if refundRef.Valid {
-			payment.RefundReference = refundRef.String
+		if evidenceJSON != nil {
+			payment.Dispute.Evidence = &domain.DisputeEvidence{}
+			if err := json.Unmarshal(evidenceJSON, payment.Dispute.Evidence); err != nil {
+				return nil, fmt.Errorf("dispute evidence deserialization error: %v", err)
+			}
 		}
-		if processedAt.Valid {
-			payment.ProcessedAt = &processedAt.Time
+		if evidenceSubmittedAt.Valid {
+			payment.Dispute.EvidenceSubmittedAt = &evidenceSubmittedAt.Time
 		}
-		if refundedAt.Valid {
-			payment.RefundedAt = &refundedAt.Time
+		if disputeClosedAt.Valid {
+			payment.Dispute.ClosedAt = &disputeClosedAt.Time
 		}
+	}
 
-		payments = append(payments, payment)
+	// Payments created before correlation tracking have none
+	if correlationID.Valid {
+		payment.CorrelationID = correlationID.UUID
 	}
 
-	return payments, nil
+	return payment, nil
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func disputeOrEmpty(dispute *domain.PaymentDispute) domain.PaymentDispute {
+	if dispute == nil {
+		return domain.PaymentDispute{}
+	}
+	return *dispute
+}
+
+func disputeAmount(dispute *domain.PaymentDispute) sql.NullInt64 {
+	if dispute == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: dispute.Amount.Amount, Valid: true}
+}
+
+func disputedAt(dispute *domain.PaymentDispute) *time.Time {
+	if dispute == nil {
+		return nil
+	}
+	return &dispute.OpenedAt
+}
+
+// disputeEvidence JSONB value of the dispute evidence, NULL when none was submitted
+func disputeEvidence(dispute *domain.PaymentDispute) ([]byte, error) {
+	if dispute == nil || dispute.Evidence == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(dispute.Evidence)
+	if err != nil {
+		return nil, fmt.Errorf("dispute evidence serialization error: %v", err)
+	}
+	return data, nil
 }
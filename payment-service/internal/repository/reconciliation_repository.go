@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// GetReconciliationCandidates returns payments whose local state may not match
+// the gateway: pending payments nobody finished, payments failed by a gateway
+// error since failedSince and authorizations older than authorizedBefore.
+// Failed and authorized payments are returned until they are marked reconciled.
+func (r *PaymentRepository) GetReconciliationCandidates(pendingBefore, failedSince, authorizedBefore time.Time, limit int) ([]*domain.PaymentAggregate, error) {
+	defer metrics.ObserveDBQuery("GetReconciliationCandidates", time.Now())
+
+	query := `
+		SELECT ` + paymentColumns + `
+		FROM payments
+		WHERE (status = $1 AND updated_at < $2)
+		   OR (reconciled_at IS NULL AND (
+				(status = $3 AND failure_reason LIKE $4 AND updated_at >= $5)
+			 OR (status = $6 AND authorized_at < $7)
+		   ))
+		ORDER BY updated_at
+		LIMIT $8
+	`
+
+	rows, err := r.db.Query(query,
+		types.PaymentStatusPending, pendingBefore,
+		types.PaymentStatusFailed, domain.GatewayErrorPrefix+"%", failedSince,
+		types.PaymentStatusAuthorized, authorizedBefore,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reconciliation candidates receive error: %v", err)
+	}
+	defer rows.Close()
+
+	var payments []*domain.PaymentAggregate
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("payment scan error: %v", err)
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+// MarkReconciled excludes a failed or authorized payment from later runs
+func (r *PaymentRepository) MarkReconciled(paymentID uuid.UUID) error {
+	defer metrics.ObserveDBQuery("MarkReconciled", time.Now())
+
+	if _, err := r.db.Exec(`UPDATE payments SET reconciled_at = $2 WHERE id = $1`, paymentID, time.Now()); err != nil {
+		return fmt.Errorf("payment reconcile mark error: %v", err)
+	}
+	return nil
+}
+
+// SaveReconciliationRun stores the run report with its entries
+func (r *PaymentRepository) SaveReconciliationRun(run *domain.ReconciliationRun) error {
+	defer metrics.ObserveDBQuery("SaveReconciliationRun", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO reconciliation_runs (id, started_at, finished_at, checked, corrected, discrepancies, unresolved)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, run.ID, run.StartedAt, run.FinishedAt, run.Checked, run.Corrected, run.Discrepancies, run.Unresolved)
+	if err != nil {
+		return fmt.Errorf("reconciliation run insert error: %v", err)
+	}
+
+	for _, entry := range run.Entries {
+		_, err := tx.Exec(`
+			INSERT INTO reconciliation_entries (
+				run_id, payment_id, order_id, saga_id, local_status, provider_status,
+				action, amount, currency, detail, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`,
+			run.ID,
+			entry.PaymentID,
+			entry.OrderID,
+			entry.SagaID,
+			entry.LocalStatus,
+			nullString(entry.ProviderStatus),
+			entry.Action,
+			entry.Amount,
+			entry.Amount.Currency,
+			nullString(entry.Detail),
+			entry.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("reconciliation entry insert error: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit error: %v", err)
+	}
+	return nil
+}
+
+// GetReconciliationRuns returns the latest runs without their entries
+func (r *PaymentRepository) GetReconciliationRuns(limit int) ([]*domain.ReconciliationRun, error) {
+	defer metrics.ObserveDBQuery("GetReconciliationRuns", time.Now())
+
+	rows, err := r.db.Query(`
+		SELECT id, started_at, finished_at, checked, corrected, discrepancies, unresolved
+		FROM reconciliation_runs
+		ORDER BY started_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("reconciliation runs receive error: %v", err)
+	}
+	defer rows.Close()
+
+	var runs []*domain.ReconciliationRun
+	for rows.Next() {
+		run, err := scanReconciliationRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("reconciliation run scan error: %v", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// GetReconciliationRun returns one run with all of its entries
+func (r *PaymentRepository) GetReconciliationRun(runID uuid.UUID) (*domain.ReconciliationRun, error) {
+	defer metrics.ObserveDBQuery("GetReconciliationRun", time.Now())
+
+	run, err := scanReconciliationRun(r.db.QueryRow(`
+		SELECT id, started_at, finished_at, checked, corrected, discrepancies, unresolved
+		FROM reconciliation_runs
+		WHERE id = $1
+	`, runID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("reconciliation run not found: %s", runID)
+		}
+		return nil, fmt.Errorf("reconciliation run receive error: %v", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT payment_id, order_id, saga_id, local_status, provider_status,
+			action, amount, currency, detail, created_at
+		FROM reconciliation_entries
+		WHERE run_id = $1
+		ORDER BY id
+	`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("reconciliation entries receive error: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry domain.ReconciliationEntry
+		var providerStatus, detail sql.NullString
+
+		err := rows.Scan(
+			&entry.PaymentID,
+			&entry.OrderID,
+			&entry.SagaID,
+			&entry.LocalStatus,
+			&providerStatus,
+			&entry.Action,
+			&entry.Amount,
+			&entry.Amount.Currency,
+			&detail,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("reconciliation entry scan error: %v", err)
+		}
+
+		entry.ProviderStatus = providerStatus.String
+		entry.Detail = detail.String
+		run.Entries = append(run.Entries, entry)
+	}
+
+	return run, rows.Err()
+}
+
+func scanReconciliationRun(row rowScanner) (*domain.ReconciliationRun, error) {
+	run := &domain.ReconciliationRun{}
+	err := row.Scan(
+		&run.ID,
+		&run.StartedAt,
+		&run.FinishedAt,
+		&run.Checked,
+		&run.Corrected,
+		&run.Discrepancies,
+		&run.Unresolved,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
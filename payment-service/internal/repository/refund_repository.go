@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/google/uuid"
+)
+
+// RecordRefund stores the refunded payment together with the refund, so the
+// refund history always adds up to the payment's refunded amount
+func (r *PaymentRepository) RecordRefund(payment *domain.PaymentAggregate, refund *domain.PaymentRefund) error {
+	defer metrics.ObserveDBQuery("RecordRefund", time.Now())
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("transaction begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := updatePayment(tx, payment); err != nil {
+		return err
+	}
+
+	var sagaID uuid.NullUUID
+	if refund.SagaID != uuid.Nil {
+		sagaID = uuid.NullUUID{UUID: refund.SagaID, Valid: true}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO payment_refunds (
+			id, payment_id, saga_id, refund_reference, amount, currency, reason, source, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		refund.ID,
+		refund.PaymentID,
+		sagaID,
+		nullString(refund.RefundReference),
+		refund.Amount,
+		refund.Amount.Currency,
+		nullString(refund.Reason),
+		refund.Source,
+		refund.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("payment refund insert error: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit error: %v", err)
+	}
+	return nil
+}
+
+// GetRefundsByPaymentID returns the refunds of the payment, oldest first
+func (r *PaymentRepository) GetRefundsByPaymentID(paymentID uuid.UUID) ([]*domain.PaymentRefund, error) {
+	defer metrics.ObserveDBQuery("GetRefundsByPaymentID", time.Now())
+
+	rows, err := r.db.Query(`
+		SELECT id, payment_id, saga_id, refund_reference, amount, currency, reason, source, created_at
+		FROM payment_refunds
+		WHERE payment_id = $1
+		ORDER BY created_at
+	`, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("payment refunds receive error: %v", err)
+	}
+	defer rows.Close()
+
+	refunds := []*domain.PaymentRefund{}
+	for rows.Next() {
+		refund := &domain.PaymentRefund{}
+		var sagaID uuid.NullUUID
+		var reference, reason sql.NullString
+
+		if err := rows.Scan(
+			&refund.ID,
+			&refund.PaymentID,
+			&sagaID,
+			&reference,
+			&refund.Amount,
+			&refund.Amount.Currency,
+			&reason,
+			&refund.Source,
+			&refund.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("payment refund scan error: %v", err)
+		}
+
+		refund.SagaID = sagaID.UUID
+		refund.RefundReference = reference.String
+		refund.Reason = reason.String
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, rows.Err()
+}
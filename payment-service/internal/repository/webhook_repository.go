@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/google/uuid"
+)
+
+// BeginWebhookEvent claims a provider event for one delivery. When another
+// delivery holds it, claimed is false and status tells whether that delivery
+// applied the event or is still applying it. A claim still processing after
+// staleAfter is taken over, so a crashed handler does not hold the event forever.
+func (r *PaymentRepository) BeginWebhookEvent(eventID, eventType string, staleAfter time.Duration) (claimed bool, status domain.WebhookStatus, err error) {
+	defer metrics.ObserveDBQuery("BeginWebhookEvent", time.Now())
+
+	now := time.Now()
+	err = r.db.QueryRow(`
+		INSERT INTO provider_webhook_events (event_id, event_type, status, received_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id) DO UPDATE SET received_at = EXCLUDED.received_at
+		WHERE provider_webhook_events.status = $3 AND provider_webhook_events.received_at < $5
+		RETURNING event_id
+	`, eventID, eventType, domain.WebhookStatusProcessing, now, now.Add(-staleAfter)).Scan(&eventID)
+	if err == nil {
+		return true, domain.WebhookStatusProcessing, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, "", fmt.Errorf("webhook event insert error: %v", err)
+	}
+
+	err = r.db.QueryRow(`SELECT status FROM provider_webhook_events WHERE event_id = $1`, eventID).Scan(&status)
+	if err != nil {
+		return false, "", fmt.Errorf("webhook event receive error: %v", err)
+	}
+	return false, status, nil
+}
+
+// ReleaseWebhookEvent gives up the claim of an event that could not be
+// applied, so the next delivery applies it without waiting for the claim to
+// go stale
+func (r *PaymentRepository) ReleaseWebhookEvent(eventID string) error {
+	defer metrics.ObserveDBQuery("ReleaseWebhookEvent", time.Now())
+
+	_, err := r.db.Exec(`
+		DELETE FROM provider_webhook_events WHERE event_id = $1 AND status = $2
+	`, eventID, domain.WebhookStatusProcessing)
+	if err != nil {
+		return fmt.Errorf("webhook event release error: %v", err)
+	}
+	return nil
+}
+
+// CompleteWebhookEvent marks the event applied; paymentID is uuid.Nil for
+// events that matched no payment
+func (r *PaymentRepository) CompleteWebhookEvent(eventID string, paymentID uuid.UUID) error {
+	defer metrics.ObserveDBQuery("CompleteWebhookEvent", time.Now())
+
+	_, err := r.db.Exec(`
+		UPDATE provider_webhook_events
+		SET status = $4, payment_id = $2, processed_at = $3
+		WHERE event_id = $1
+	`, eventID, uuid.NullUUID{UUID: paymentID, Valid: paymentID != uuid.Nil}, time.Now(), domain.WebhookStatusProcessed)
+	if err != nil {
+		return fmt.Errorf("webhook event complete error: %v", err)
+	}
+	return nil
+}
+
+// GetPaymentByGatewayRef finds a payment by its external ref or authorization ID
+func (r *PaymentRepository) GetPaymentByGatewayRef(reference string) (*domain.PaymentAggregate, error) {
+	defer metrics.ObserveDBQuery("GetPaymentByGatewayRef", time.Now())
+
+	query := `
+		SELECT ` + paymentColumns + `
+		FROM payments
+		WHERE external_ref = $1 OR authorization_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	payment, err := scanPayment(r.db.QueryRow(query, reference))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment not found for gateway reference: %s", reference)
+		}
+		return nil, fmt.Errorf("payment receive error: %v", err)
+	}
+
+	return payment, nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/google/uuid"
+)
+
+type commandContextKey struct{}
+
+// runCommand handles a saga command at most once per saga and step. A command
+// that already has an outcome re-publishes the recorded reply instead of
+// touching the gateway again. A handler error that left no outcome releases the
+// command, so the redelivered message runs it again.
+func (s *PaymentService) runCommand(ctx context.Context, sagaID uuid.UUID, name string,
+	handle func(ctx context.Context, command *domain.PaymentCommand) error) error {
+	command, err := s.paymentRepo.BeginCommand(domain.CommandKey(sagaID, name), sagaID, name)
+	if err != nil {
+		return err
+	}
+
+	if command.IsCompleted() {
+		slog.InfoContext(ctx, "Duplicate payment command, replaying recorded outcome",
+			"command", name, "event_type", command.ResultEvent.EventType)
+
+		if err := s.publisher.PublishSagaEvent(ctx, *command.ResultEvent); err != nil {
+			return fmt.Errorf("recorded outcome publish error: %v", err)
+		}
+		return nil
+	}
+
+	if err := handle(context.WithValue(ctx, commandContextKey{}, command), command); err != nil {
+		if releaseErr := s.paymentRepo.ReleaseCommand(command.IdempotencyKey); releaseErr != nil {
+			slog.ErrorContext(ctx, "Payment command release error", "command", name, "error", releaseErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// publish records the event as the outcome of the command being handled, then
+// publishes it. The outcome is stored first so that a failed publish is retried
+// by replaying it rather than by running the command again.
+func (s *PaymentService) publish(ctx context.Context, event events.SagaEvent) error {
+	if command, ok := ctx.Value(commandContextKey{}).(*domain.PaymentCommand); ok {
+		if err := s.paymentRepo.CompleteCommand(command.IdempotencyKey, event); err != nil {
+			return err
+		}
+	}
+
+	return s.publisher.PublishSagaEvent(ctx, event)
+}
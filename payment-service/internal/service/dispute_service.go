@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoOpenDispute the payment has no dispute waiting for evidence
+	ErrNoOpenDispute = errors.New("no open dispute on payment")
+	// ErrEvidenceRejected the provider did not accept the evidence
+	ErrEvidenceRejected = errors.New("dispute evidence rejected by provider")
+)
+
+// SubmitDisputeEvidence sends the merchant's evidence for the open dispute to
+// the provider and records it once accepted. An order can have a payment per
+// saga, so the dispute names the payment.
+func (s *PaymentService) SubmitDisputeEvidence(ctx context.Context, disputeID string, evidence domain.DisputeEvidence) (*domain.PaymentAggregate, error) {
+	payment, err := s.paymentRepo.GetPaymentByDisputeID(disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if !payment.CanSubmitDisputeEvidence() {
+		return nil, fmt.Errorf("%w %s", ErrNoOpenDispute, payment.ID)
+	}
+
+	response, err := s.paymentGateway.SubmitDisputeEvidence(ctx, gateway.DisputeEvidenceRequest{
+		DisputeID:      payment.Dispute.ID,
+		Evidence:       gateway.DisputeEvidence(evidence),
+		IdempotencyKey: "dispute-evidence-" + payment.Dispute.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s%v", domain.GatewayErrorPrefix, err)
+	}
+	if !response.Success {
+		return nil, fmt.Errorf("%w: %s", ErrEvidenceRejected, response.FailureReason)
+	}
+
+	if err := payment.SubmitDisputeEvidence(evidence); err != nil {
+		return nil, err
+	}
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Dispute evidence submitted",
+		"payment_id", payment.ID, "dispute_id", payment.Dispute.ID, "provider_status", response.Status)
+
+	if err := s.publishPaymentDisputedEvent(correlated(ctx, payment), payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// applyDisputeClosed records the bank's decision. A dispute whose creation
+// event never arrived is opened first.
+func (s *PaymentService) applyDisputeClosed(ctx context.Context, payment *domain.PaymentAggregate, event *gateway.WebhookEvent) error {
+	status := domain.DisputeStatus(event.DisputeStatus)
+	if status != domain.DisputeStatusWon && status != domain.DisputeStatusLost {
+		slog.WarnContext(ctx, "Dispute closed with unknown outcome", "dispute_id", event.DisputeID, "status", event.DisputeStatus)
+		return nil
+	}
+
+	if payment.Dispute == nil || payment.Dispute.ID != event.DisputeID {
+		if err := payment.OpenDispute(event.DisputeID, event.DisputeReason, event.Amount); err != nil {
+			return err
+		}
+	}
+
+	changed, err := payment.CloseDispute(event.DisputeID, status)
+	if err != nil || !changed {
+		return err
+	}
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Dispute closed", "payment_id", payment.ID, "dispute_id", event.DisputeID, "status", status)
+	return s.publishPaymentDisputedEvent(ctx, payment)
+}
+
+// correlated carries the saga's correlation ID into events published outside a
+// saga command, such as webhooks, reconciliation and the dispute API, so the
+// orchestrator accepts them
+func correlated(ctx context.Context, payment *domain.PaymentAggregate) context.Context {
+	if _, ok := events.CauseFromContext(ctx); ok || payment.CorrelationID == uuid.Nil {
+		return ctx
+	}
+	return events.WithCause(ctx, events.SagaEvent{
+		SagaID:        payment.SagaID,
+		OrderID:       payment.OrderID,
+		CorrelationID: payment.CorrelationID,
+	})
+}
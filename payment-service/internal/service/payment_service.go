@@ -1,103 +1,372 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/payment-service/internal/fx"
 	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway"
 	"github.com/distributed-ecommerce-saga/payment-service/internal/repository"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
 )
 
 type PaymentService struct {
-	paymentRepo    *repository.PaymentRepository
-	paymentGateway gateway.PaymentGateway
-	publisher      *messaging.Publisher
+	paymentRepo        *repository.PaymentRepository
+	paymentGateway     gateway.PaymentGateway
+	publisher          *messaging.Publisher
+	rates              fx.RateProvider
+	settlementCurrency types.Currency
 }
 
 func NewPaymentService(
 	paymentRepo *repository.PaymentRepository,
 	paymentGateway gateway.PaymentGateway,
 	publisher *messaging.Publisher,
+	rates fx.RateProvider,
+	settlementCurrency types.Currency,
 ) *PaymentService {
 	return &PaymentService{
-		paymentRepo:    paymentRepo,
-		paymentGateway: paymentGateway,
-		publisher:      publisher,
+		paymentRepo:        paymentRepo,
+		paymentGateway:     paymentGateway,
+		publisher:          publisher,
+		rates:              rates,
+		settlementCurrency: settlementCurrency,
 	}
 }
 
 // ProcessPayment Process payment.process command which receives from saga
-func (s *PaymentService) ProcessPayment(request domain.PaymentProcessRequest) error {
-	log.Printf("Payment process started: OrderID=%s, Amount=%.2f",
-		request.OrderID, request.Amount)
+func (s *PaymentService) ProcessPayment(ctx context.Context, request domain.PaymentProcessRequest) error {
+	return s.runCommand(ctx, request.SagaID, domain.CommandProcess, func(ctx context.Context, command *domain.PaymentCommand) error {
+		return s.processPayment(ctx, request, command)
+	})
+}
 
-	// Business validation
-	if request.Amount <= 0 {
-		return s.publishPaymentFailedEvent(request.SagaID, request.OrderID,
-			"Invalid payment amount", request.Amount)
-	}
+func (s *PaymentService) processPayment(ctx context.Context, request domain.PaymentProcessRequest, command *domain.PaymentCommand) error {
+	slog.InfoContext(ctx, "Payment process started", "amount", request.Amount)
 
-	payment := domain.NewPaymentAggregate(
-		request.OrderID,
-		request.CustomerID,
-		request.SagaID,
-		request.Amount,
-		request.PaymentMethod,
-	)
-
-	if err := s.paymentRepo.CreatePayment(payment); err != nil {
-		return s.publishPaymentFailedEvent(request.SagaID, request.OrderID,
-			fmt.Sprintf("Database error: %v", err), request.Amount)
+	payment, reason, err := s.preparePayment(ctx, request, command)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return s.publishPaymentFailedEvent(ctx, request.SagaID, request.OrderID, reason, request.Amount)
 	}
 
-	gatewayRequest := gateway.PaymentRequest{
-		OrderID:       request.OrderID,
-		CustomerID:    request.CustomerID,
-		Amount:        request.Amount,
-		Currency:      "USD", // Default currency
-		PaymentMethod: request.PaymentMethod,
-		Description:   fmt.Sprintf("Order payment for %s", request.OrderID),
+	// Resumed after a crash: the earlier attempt already has an outcome
+	switch payment.Status {
+	case types.PaymentStatusCompleted:
+		return s.publishPaymentProcessedEvent(ctx, payment)
+	case types.PaymentStatusFailed:
+		return s.publishPaymentFailedEvent(ctx, request.SagaID, request.OrderID, payment.FailureReason, request.Amount)
 	}
 
-	gatewayResponse, err := s.paymentGateway.ProcessPayment(gatewayRequest)
+	gatewayResponse, err := s.paymentGateway.ProcessPayment(ctx, s.gatewayPaymentRequest(request, command.IdempotencyKey))
 	if err != nil {
 		// Gateway error - payment'i failed olarak işaretle
-		payment.FailPayment(fmt.Sprintf("Gateway error: %v", err))
+		payment.FailPayment(domain.GatewayErrorPrefix + err.Error())
 		s.paymentRepo.UpdatePayment(payment)
 
-		return s.publishPaymentFailedEvent(request.SagaID, request.OrderID,
+		return s.publishPaymentFailedEvent(ctx, request.SagaID, request.OrderID,
 			fmt.Sprintf("Payment gateway error: %v", err), request.Amount)
 	}
 
+	// Accepted, the outcome arrives by webhook and the saga waits for it
+	if gatewayResponse.Pending {
+		return s.awaitProvider(ctx, payment, gatewayResponse.ExternalRef)
+	}
+
 	// Gateway response'una göre işle
 	if !gatewayResponse.Success {
 		// Payment failed
 		payment.FailPayment(gatewayResponse.FailureReason)
 		s.paymentRepo.UpdatePayment(payment)
 
-		return s.publishPaymentFailedEvent(request.SagaID, request.OrderID,
+		return s.publishPaymentFailedEvent(ctx, request.SagaID, request.OrderID,
 			gatewayResponse.FailureReason, request.Amount)
 	}
 
 	// Payment successful
 	payment.ProcessPayment(gatewayResponse.TransactionID, gatewayResponse.ExternalRef)
 	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
-		log.Printf("Payment success update error: %v", err)
+		slog.ErrorContext(ctx, "Payment success update error", "error", err)
 		// Burada compensating action yapılabilir
 	}
 
 	// Success event publish et
-	return s.publishPaymentProcessedEvent(payment)
+	return s.publishPaymentProcessedEvent(ctx, payment)
+}
+
+// AuthorizePayment Process payment.authorize command which receives from saga.
+// The amount is only held; it is charged by CapturePayment once the order ships.
+func (s *PaymentService) AuthorizePayment(ctx context.Context, request domain.PaymentProcessRequest) error {
+	return s.runCommand(ctx, request.SagaID, domain.CommandAuthorize, func(ctx context.Context, command *domain.PaymentCommand) error {
+		return s.authorizePayment(ctx, request, command)
+	})
+}
+
+func (s *PaymentService) authorizePayment(ctx context.Context, request domain.PaymentProcessRequest, command *domain.PaymentCommand) error {
+	slog.InfoContext(ctx, "Payment authorization started", "amount", request.Amount)
+
+	payment, reason, err := s.preparePayment(ctx, request, command)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return s.publishPaymentFailedEvent(ctx, request.SagaID, request.OrderID, reason, request.Amount)
+	}
+
+	// Resumed after a crash: the earlier attempt already has an outcome
+	switch payment.Status {
+	case types.PaymentStatusAuthorized:
+		return s.publishPaymentAuthorizedEvent(ctx, payment)
+	case types.PaymentStatusFailed:
+		return s.publishPaymentFailedEvent(ctx, request.SagaID, request.OrderID, payment.FailureReason, request.Amount)
+	}
+
+	gatewayResponse, err := s.paymentGateway.Authorize(ctx, s.gatewayPaymentRequest(request, command.IdempotencyKey))
+	if err != nil {
+		payment.FailPayment(domain.GatewayErrorPrefix + err.Error())
+		s.paymentRepo.UpdatePayment(payment)
+
+		return s.publishPaymentFailedEvent(ctx, request.SagaID, request.OrderID,
+			fmt.Sprintf("Payment gateway error: %v", err), request.Amount)
+	}
+
+	if gatewayResponse.Pending {
+		return s.awaitProvider(ctx, payment, gatewayResponse.AuthorizationID)
+	}
+
+	if !gatewayResponse.Success {
+		payment.FailPayment(gatewayResponse.FailureReason)
+		s.paymentRepo.UpdatePayment(payment)
+
+		return s.publishPaymentFailedEvent(ctx, request.SagaID, request.OrderID,
+			gatewayResponse.FailureReason, request.Amount)
+	}
+
+	if err := payment.Authorize(gatewayResponse.AuthorizationID); err != nil {
+		return s.publishPaymentFailedEvent(ctx, request.SagaID, request.OrderID, err.Error(), request.Amount)
+	}
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		slog.ErrorContext(ctx, "Payment authorization update error", "error", err)
+	}
+
+	return s.publishPaymentAuthorizedEvent(ctx, payment)
+}
+
+// CapturePayment Process payment.capture command which receives from saga
+func (s *PaymentService) CapturePayment(ctx context.Context, request domain.PaymentCaptureRequest) error {
+	return s.runCommand(ctx, request.SagaID, domain.CommandCapture, func(ctx context.Context, command *domain.PaymentCommand) error {
+		return s.capturePayment(ctx, request, command)
+	})
+}
+
+func (s *PaymentService) capturePayment(ctx context.Context, request domain.PaymentCaptureRequest, command *domain.PaymentCommand) error {
+	payment, err := s.findSagaPayment(request.SagaID, request.PaymentID)
+	if err != nil {
+		return s.publishCaptureFailedEvent(ctx, request.SagaID, uuid.Nil,
+			fmt.Sprintf("Payment bulunamadı: %v", err))
+	}
+
+	slog.InfoContext(ctx, "Payment capture started", "payment_id", payment.ID, "amount", payment.Amount)
+
+	// Redelivered command, the capture already happened
+	if payment.Status == types.PaymentStatusCompleted {
+		return s.publishPaymentCapturedEvent(ctx, payment)
+	}
+
+	if !payment.CanCapture() {
+		return s.publishCaptureFailedEvent(ctx, payment.SagaID, payment.OrderID,
+			fmt.Sprintf("Payment capture edilemez, status: %s", payment.Status))
+	}
+
+	if request.Amount != nil {
+		if err := captureLess(payment, *request.Amount); err != nil {
+			return s.publishCaptureFailedEvent(ctx, payment.SagaID, payment.OrderID, err.Error())
+		}
+	}
+
+	gatewayResponse, err := s.paymentGateway.Capture(ctx, gateway.CaptureRequest{
+		AuthorizationID: payment.AuthorizationID,
+		Amount:          payment.Amount,
+		IdempotencyKey:  command.IdempotencyKey,
+	})
+	if err != nil {
+		return s.publishCaptureFailedEvent(ctx, payment.SagaID, payment.OrderID,
+			fmt.Sprintf("Gateway capture error: %v", err))
+	}
+
+	if !gatewayResponse.Success {
+		return s.publishCaptureFailedEvent(ctx, payment.SagaID, payment.OrderID,
+			gatewayResponse.FailureReason)
+	}
+
+	if err := payment.Capture(gatewayResponse.TransactionID, gatewayResponse.ExternalRef); err != nil {
+		return s.publishCaptureFailedEvent(ctx, payment.SagaID, payment.OrderID, err.Error())
+	}
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		slog.ErrorContext(ctx, "Payment capture update error", "error", err)
+	}
+
+	return s.publishPaymentCapturedEvent(ctx, payment)
+}
+
+// VoidPayment Process payment.void command which receives from saga during
+// compensation. Voiding releases the hold, so no refund is needed.
+func (s *PaymentService) VoidPayment(ctx context.Context, request domain.PaymentVoidRequest) error {
+	return s.runCommand(ctx, request.SagaID, domain.CommandVoid, func(ctx context.Context, command *domain.PaymentCommand) error {
+		return s.voidPayment(ctx, request, command)
+	})
+}
+
+func (s *PaymentService) voidPayment(ctx context.Context, request domain.PaymentVoidRequest, command *domain.PaymentCommand) error {
+	payment, err := s.findSagaPayment(request.SagaID, request.PaymentID)
+	if err != nil {
+		return s.publishVoidFailedEvent(ctx, request.SagaID,
+			fmt.Sprintf("Payment bulunamadı: %v", err))
+	}
+
+	slog.InfoContext(ctx, "Payment void started", "payment_id", payment.ID)
+
+	// Redelivered command, the authorization is already released
+	if payment.Status == types.PaymentStatusVoided {
+		return s.publishPaymentVoidedEvent(ctx, payment, request.Reason)
+	}
+
+	if !payment.CanVoid() {
+		return s.publishVoidFailedEvent(ctx, request.SagaID,
+			fmt.Sprintf("Payment void edilemez, status: %s", payment.Status))
+	}
+
+	gatewayResponse, err := s.paymentGateway.Void(ctx, gateway.VoidRequest{
+		AuthorizationID: payment.AuthorizationID,
+		Reason:          request.Reason,
+		IdempotencyKey:  command.IdempotencyKey,
+	})
+	if err != nil {
+		return s.publishVoidFailedEvent(ctx, request.SagaID,
+			fmt.Sprintf("Gateway void error: %v", err))
+	}
+
+	if !gatewayResponse.Success {
+		return s.publishVoidFailedEvent(ctx, request.SagaID, gatewayResponse.FailureReason)
+	}
+
+	if err := payment.Void(); err != nil {
+		return s.publishVoidFailedEvent(ctx, request.SagaID, err.Error())
+	}
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		slog.ErrorContext(ctx, "Payment void update error", "error", err)
+	}
+
+	return s.publishPaymentVoidedEvent(ctx, payment, request.Reason)
+}
+
+// awaitProvider stores the provider reference of a payment whose outcome comes
+// by webhook. No reply is published; the command completes when the webhook
+// settles the payment. A failed update is returned so the command is redelivered.
+func (s *PaymentService) awaitProvider(ctx context.Context, payment *domain.PaymentAggregate, reference string) error {
+	payment.AwaitProvider(reference)
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		return fmt.Errorf("pending payment update error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Payment pending at provider, waiting for webhook",
+		"payment_id", payment.ID, "external_ref", reference)
+	return nil
+}
+
+// preparePayment validates the request, converts the amount into the settlement
+// currency and stores a pending payment. A non-empty reason means it was rejected;
+// an error means the database failed and the command should be retried.
+func (s *PaymentService) preparePayment(ctx context.Context, request domain.PaymentProcessRequest, command *domain.PaymentCommand) (*domain.PaymentAggregate, string, error) {
+	// Redelivered after a crash: keep using the payment the first attempt created
+	if command.PaymentID != uuid.Nil {
+		payment, err := s.paymentRepo.GetPaymentByID(command.PaymentID)
+		if err != nil {
+			return nil, "", err
+		}
+		slog.InfoContext(ctx, "Resuming interrupted payment command",
+			"payment_id", payment.ID, "status", payment.Status)
+		return payment, "", nil
+	}
+
+	// Business validation
+	if !request.Amount.IsPositive() || !request.Amount.Currency.Valid() {
+		return nil, "Invalid payment amount", nil
+	}
+
+	rate, err := s.rates.Rate(ctx, request.Amount.Currency, s.settlementCurrency)
+	if err != nil {
+		return nil, fmt.Sprintf("Exchange rate error: %v", err), nil
+	}
+
+	settlementAmount, err := rate.Convert(request.Amount)
+	if err != nil {
+		return nil, fmt.Sprintf("Currency conversion error: %v", err), nil
+	}
+
+	payment := domain.NewPaymentAggregate(
+		request.OrderID,
+		request.CustomerID,
+		request.SagaID,
+		request.Amount,
+		request.PaymentMethod,
+	)
+	payment.ApplyExchangeRate(rate.String(), settlementAmount)
+	if cause, ok := events.CauseFromContext(ctx); ok {
+		payment.CorrelationID = cause.CorrelationID
+	}
+
+	if err := s.paymentRepo.CreateCommandPayment(payment, command.IdempotencyKey); err != nil {
+		return nil, "", err
+	}
+
+	return payment, "", nil
+}
+
+func (s *PaymentService) gatewayPaymentRequest(request domain.PaymentProcessRequest, idempotencyKey string) gateway.PaymentRequest {
+	return gateway.PaymentRequest{
+		OrderID:            request.OrderID,
+		CustomerID:         request.CustomerID,
+		Amount:             request.Amount,
+		SettlementCurrency: s.settlementCurrency,
+		PaymentMethod:      request.PaymentMethod,
+		Description:        fmt.Sprintf("Order payment for %s", request.OrderID),
+		IdempotencyKey:     idempotencyKey,
+	}
+}
+
+// findSagaPayment loads the payment by ID, or the latest payment of the saga
+func (s *PaymentService) findSagaPayment(sagaID, paymentID uuid.UUID) (*domain.PaymentAggregate, error) {
+	if paymentID != uuid.Nil {
+		return s.paymentRepo.GetPaymentByID(paymentID)
+	}
+
+	payments, err := s.paymentRepo.GetPaymentsBySagaID(sagaID)
+	if err != nil {
+		return nil, err
+	}
+	if len(payments) == 0 {
+		return nil, fmt.Errorf("no payment for saga %s", sagaID)
+	}
+	return payments[0], nil
 }
 
 // ProcessRefund Process payment.refund command which receives from saga
-func (s *PaymentService) ProcessRefund(request domain.PaymentRefundRequest) error {
-	log.Printf("Payment refund başlatıldı: SagaID=%s, Amount=%.2f",
-		request.SagaID, request.Amount)
+func (s *PaymentService) ProcessRefund(ctx context.Context, request domain.PaymentRefundRequest) error {
+	return s.runCommand(ctx, request.SagaID, domain.CommandRefund, func(ctx context.Context, command *domain.PaymentCommand) error {
+		return s.processRefund(ctx, request, command)
+	})
+}
+
+func (s *PaymentService) processRefund(ctx context.Context, request domain.PaymentRefundRequest, command *domain.PaymentCommand) error {
+	slog.InfoContext(ctx, "Payment refund started", "amount", request.Amount)
 
 	var payment *domain.PaymentAggregate
 	var err error
@@ -106,26 +375,40 @@ func (s *PaymentService) ProcessRefund(request domain.PaymentRefundRequest) erro
 		payment, err = s.paymentRepo.GetPaymentByID(request.PaymentID)
 	} else if request.TransactionID != "" {
 		// Transaction ID ile arama için ek metod gerekli, şimdilik saga ID kullan
-		payments, err := s.paymentRepo.GetPaymentsBySagaID(request.SagaID)
+		var payments []*domain.PaymentAggregate
+		payments, err = s.paymentRepo.GetPaymentsBySagaID(request.SagaID)
 		if err == nil && len(payments) > 0 {
 			payment = payments[0] // İlk completed payment'i al
 		}
 	}
 
 	if err != nil {
-		return s.publishRefundFailedEvent(request.SagaID,
+		return s.publishRefundFailedEvent(ctx, request.SagaID,
 			fmt.Sprintf("Payment bulunamadı: %v", err))
 	}
+	if payment == nil {
+		return s.publishRefundFailedEvent(ctx, request.SagaID,
+			fmt.Sprintf("Payment bulunamadı: no payment for saga %s", request.SagaID))
+	}
+
+	// Refund sagas of the order refund the same payment under their own saga ID
+	if err := s.paymentRepo.LinkCommandPayment(command.IdempotencyKey, payment.ID); err != nil {
+		slog.ErrorContext(ctx, "Refund command link error", "error", err)
+	}
 
 	if !payment.CanRefund() {
-		return s.publishRefundFailedEvent(request.SagaID,
+		return s.publishRefundFailedEvent(ctx, request.SagaID,
 			fmt.Sprintf("Payment refund edilemez, status: %s", payment.Status))
 	}
 
-	refundAmount := request.Amount
-	if refundAmount <= 0 || refundAmount > payment.GetRemainingRefundAmount() {
-		return s.publishRefundFailedEvent(request.SagaID,
-			fmt.Sprintf("Geçersiz refund amount: %.2f", refundAmount))
+	refundAmount, err := captureCurrencyAmount(payment, request.Amount)
+	if err != nil {
+		return s.publishRefundFailedEvent(ctx, request.SagaID,
+			fmt.Sprintf("Refund currency error: %v", err))
+	}
+	if exceeds, err := refundAmount.Cmp(payment.GetRemainingRefundAmount()); err != nil || exceeds > 0 || !refundAmount.IsPositive() {
+		return s.publishRefundFailedEvent(ctx, request.SagaID,
+			fmt.Sprintf("Geçersiz refund amount: %s", refundAmount))
 	}
 
 	gatewayRequest := gateway.RefundRequest{
@@ -133,127 +416,340 @@ func (s *PaymentService) ProcessRefund(request domain.PaymentRefundRequest) erro
 		ExternalRef:           payment.ExternalRef,
 		Amount:                refundAmount,
 		Reason:                request.Reason,
+		IdempotencyKey:        command.IdempotencyKey,
 	}
 
-	gatewayResponse, err := s.paymentGateway.RefundPayment(gatewayRequest)
+	gatewayResponse, err := s.paymentGateway.RefundPayment(ctx, gatewayRequest)
 	if err != nil {
-		return s.publishRefundFailedEvent(request.SagaID,
+		return s.publishRefundFailedEvent(ctx, request.SagaID,
 			fmt.Sprintf("Gateway refund error: %v", err))
 	}
 
 	if !gatewayResponse.Success {
-		return s.publishRefundFailedEvent(request.SagaID,
+		return s.publishRefundFailedEvent(ctx, request.SagaID,
 			gatewayResponse.FailureReason)
 	}
 
 	if err := payment.RefundPayment(gatewayResponse.RefundReference, refundAmount); err != nil {
-		return s.publishRefundFailedEvent(request.SagaID,
+		return s.publishRefundFailedEvent(ctx, request.SagaID,
 			fmt.Sprintf("Refund processing error: %v", err))
 	}
 
-	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
-		log.Printf("Refund database update hatası: %v", err)
+	refund := domain.NewPaymentRefund(payment.ID, request.SagaID, gatewayResponse.RefundReference,
+		refundAmount, request.Reason, domain.RefundSourceSaga)
+	if err := s.paymentRepo.RecordRefund(payment, refund); err != nil {
+		slog.ErrorContext(ctx, "Refund database update error", "error", err)
+	}
+
+	return s.publishPaymentRefundedEvent(ctx, request.SagaID, payment, refundAmount)
+}
+
+// captureLess lowers the payment to a partial capture. The settlement amount is
+// converted with the rate recorded at authorization, so both amounts stay in
+// proportion.
+func captureLess(payment *domain.PaymentAggregate, amount types.Money) error {
+	amount, err := amount.InCurrency(payment.Amount.Currency)
+	if err != nil {
+		return err
+	}
+	if same, err := amount.Cmp(payment.Amount); err == nil && same == 0 {
+		return nil
 	}
 
-	return s.publishPaymentRefundedEvent(payment, refundAmount)
+	settlementAmount := amount
+	if payment.ExchangeRate != "" {
+		rate, err := fx.ParseRate(payment.Amount.Currency, payment.SettlementAmount.Currency, payment.ExchangeRate)
+		if err != nil {
+			return fmt.Errorf("exchange rate error: %v", err)
+		}
+		if settlementAmount, err = rate.Convert(amount); err != nil {
+			return fmt.Errorf("currency conversion error: %v", err)
+		}
+	}
+
+	return payment.CaptureLess(amount, settlementAmount)
+}
+
+// captureCurrencyAmount refunds are always issued in the currency the payment
+// was captured in. An amount in the settlement currency is converted back with
+// the rate recorded at capture, not today's rate.
+func captureCurrencyAmount(payment *domain.PaymentAggregate, amount types.Money) (types.Money, error) {
+	captureCurrency := payment.Amount.Currency
+
+	switch {
+	case amount.Currency == "" || amount.Currency == captureCurrency:
+		return amount.InCurrency(captureCurrency)
+
+	case amount.Currency == payment.SettlementAmount.Currency && payment.ExchangeRate != "":
+		rate, err := fx.ParseRate(captureCurrency, payment.SettlementAmount.Currency, payment.ExchangeRate)
+		if err != nil {
+			return types.Money{}, err
+		}
+		return rate.Inverse().Convert(amount)
+
+	default:
+		return types.Money{}, fmt.Errorf("%w: refund in %s for payment captured in %s",
+			types.ErrCurrencyMismatch, amount.Currency, captureCurrency)
+	}
 }
 
 func (s *PaymentService) GetPaymentByOrderID(orderID uuid.UUID) (*domain.PaymentAggregate, error) {
 	return s.paymentRepo.GetPaymentByOrderID(orderID)
 }
 
+// GetPaymentByDisputeID returns the payment the dispute was opened on
+func (s *PaymentService) GetPaymentByDisputeID(disputeID string) (*domain.PaymentAggregate, error) {
+	return s.paymentRepo.GetPaymentByDisputeID(disputeID)
+}
+
+// GetRefunds returns the refund history of the payment
+func (s *PaymentService) GetRefunds(paymentID uuid.UUID) ([]*domain.PaymentRefund, error) {
+	return s.paymentRepo.GetRefundsByPaymentID(paymentID)
+}
+
 // publishPaymentProcessedEvent publish event of successfully payment
 
-func (s *PaymentService) publishPaymentProcessedEvent(payment *domain.PaymentAggregate) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        payment.SagaID,
-		OrderID:       payment.OrderID,
-		EventType:     events.PaymentProcessedEvent,
-		Service:       "payment-service",
-		CorrelationID: uuid.New(),
+func (s *PaymentService) publishPaymentProcessedEvent(ctx context.Context, payment *domain.PaymentAggregate) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    payment.SagaID,
+		OrderID:   payment.OrderID,
+		EventType: events.PaymentProcessedEvent,
+		Service:   "payment-service",
 		Payload: events.PaymentProcessedPayload{
 			Payment: *payment.Payment,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publish(ctx, event); err != nil {
 		return fmt.Errorf("payment processed event publish error: %v", err)
 	}
 
-	log.Printf("Payment processed event published: PaymentID=%s, OrderID=%s",
-		payment.ID, payment.OrderID)
+	slog.InfoContext(ctx, "Payment processed event published", "payment_id", payment.ID)
+	return nil
+}
+
+func (s *PaymentService) publishPaymentAuthorizedEvent(ctx context.Context, payment *domain.PaymentAggregate) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    payment.SagaID,
+		OrderID:   payment.OrderID,
+		EventType: events.PaymentAuthorizedEvent,
+		Service:   "payment-service",
+		Payload: events.PaymentAuthorizedPayload{
+			PaymentID:       payment.ID,
+			AuthorizationID: payment.AuthorizationID,
+			Amount:          payment.Amount,
+		},
+	})
+
+	if err := s.publish(ctx, event); err != nil {
+		return fmt.Errorf("payment authorized event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Payment authorized event published", "payment_id", payment.ID)
 	return nil
 }
 
-func (s *PaymentService) publishPaymentFailedEvent(sagaID, orderID uuid.UUID, reason string, amount float64) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        sagaID,
-		OrderID:       orderID,
-		EventType:     events.PaymentFailedEvent,
-		Service:       "payment-service",
-		CorrelationID: uuid.New(),
+func (s *PaymentService) publishPaymentCapturedEvent(ctx context.Context, payment *domain.PaymentAggregate) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    payment.SagaID,
+		OrderID:   payment.OrderID,
+		EventType: events.PaymentCapturedEvent,
+		Service:   "payment-service",
+		Payload: events.PaymentCapturedPayload{
+			PaymentID:     payment.ID,
+			TransactionID: payment.TransactionID,
+			Amount:        payment.Amount,
+		},
+	})
+
+	if err := s.publish(ctx, event); err != nil {
+		return fmt.Errorf("payment captured event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Payment captured event published", "payment_id", payment.ID)
+	return nil
+}
+
+func (s *PaymentService) publishCaptureFailedEvent(ctx context.Context, sagaID, orderID uuid.UUID, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.PaymentCaptureFailedEvent,
+		Service:   "payment-service",
+		Payload: map[string]interface{}{
+			"order_id": orderID,
+			"reason":   reason,
+		},
+	})
+
+	if err := s.publish(ctx, event); err != nil {
+		return fmt.Errorf("payment capture failed event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Payment capture failed event published", "reason", reason)
+	return nil
+}
+
+func (s *PaymentService) publishPaymentVoidedEvent(ctx context.Context, payment *domain.PaymentAggregate, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    payment.SagaID,
+		OrderID:   payment.OrderID,
+		EventType: events.PaymentVoidedEvent,
+		Service:   "payment-service",
+		Payload: events.PaymentVoidedPayload{
+			PaymentID:       payment.ID,
+			AuthorizationID: payment.AuthorizationID,
+			Reason:          reason,
+		},
+	})
+
+	if err := s.publish(ctx, event); err != nil {
+		return fmt.Errorf("payment voided event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Payment voided event published", "payment_id", payment.ID)
+	return nil
+}
+
+func (s *PaymentService) publishVoidFailedEvent(ctx context.Context, sagaID uuid.UUID, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   uuid.Nil, // OrderID may not be known
+		EventType: events.PaymentVoidFailedEvent,
+		Service:   "payment-service",
+		Payload: map[string]interface{}{
+			"reason": reason,
+		},
+	})
+
+	if err := s.publish(ctx, event); err != nil {
+		return fmt.Errorf("void failed event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Void failed event published", "reason", reason)
+	return nil
+}
+
+func (s *PaymentService) publishPaymentFailedEvent(ctx context.Context, sagaID, orderID uuid.UUID, reason string, amount types.Money) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.PaymentFailedEvent,
+		Service:   "payment-service",
 		Payload: events.PaymentFailedPayload{
 			OrderID: orderID,
 			Reason:  reason,
 			Amount:  amount,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publish(ctx, event); err != nil {
 		return fmt.Errorf("payment failed event publish error: %v", err)
 	}
 
-	log.Printf("Payment failed event published: OrderID=%s, Reason=%s",
-		orderID, reason)
+	slog.InfoContext(ctx, "Payment failed event published", "reason", reason)
 	return nil
 }
 
-func (s *PaymentService) publishPaymentRefundedEvent(payment *domain.PaymentAggregate, refundAmount float64) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        payment.SagaID,
-		OrderID:       payment.OrderID,
-		EventType:     events.PaymentRefundedEvent,
-		Service:       "payment-service",
-		CorrelationID: uuid.New(),
+// publishPaymentRefundedEvent replies to the saga that asked for the refund,
+// which is not the payment's own saga for a refund saga
+func (s *PaymentService) publishPaymentRefundedEvent(ctx context.Context, sagaID uuid.UUID, payment *domain.PaymentAggregate, refundAmount types.Money) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   payment.OrderID,
+		EventType: events.PaymentRefundedEvent,
+		Service:   "payment-service",
 		Payload: map[string]interface{}{
 			"payment_id":       payment.ID,
 			"transaction_id":   payment.TransactionID,
 			"refund_reference": payment.RefundReference,
 			"refunded_amount":  refundAmount,
 			"total_refunded":   payment.RefundedAmount,
+			"fully_refunded":   payment.IsFullyRefunded(),
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publish(ctx, event); err != nil {
 		return fmt.Errorf("payment refunded event publish error: %v", err)
 	}
 
-	log.Printf("Payment refunded event published: PaymentID=%s, Amount=%.2f",
-		payment.ID, refundAmount)
+	slog.InfoContext(ctx, "Payment refunded event published", "payment_id", payment.ID, "amount", refundAmount)
 	return nil
 }
 
-func (s *PaymentService) publishRefundFailedEvent(sagaID uuid.UUID, reason string) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        sagaID,
-		OrderID:       uuid.Nil, // OrderID moy not known
-		EventType:     "payment.refund.failed",
-		Service:       "payment-service",
-		CorrelationID: uuid.New(),
+func (s *PaymentService) publishRefundFailedEvent(ctx context.Context, sagaID uuid.UUID, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   uuid.Nil, // OrderID moy not known
+		EventType: events.PaymentRefundFailedEvent,
+		Service:   "payment-service",
 		Payload: map[string]interface{}{
 			"reason": reason,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publish(ctx, event); err != nil {
 		return fmt.Errorf("refund failed event publish error: %v", err)
 	}
 
-	log.Printf("Refund failed event published: SagaID=%s, Reason=%s",
-		sagaID, reason)
+	slog.InfoContext(ctx, "Refund failed event published", "reason", reason)
+
+	return nil
+}
+
+func (s *PaymentService) publishPaymentDisputedEvent(ctx context.Context, payment *domain.PaymentAggregate) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    payment.SagaID,
+		OrderID:   payment.OrderID,
+		EventType: events.PaymentDisputedEvent,
+		Service:   "payment-service",
+		Payload: events.PaymentDisputedPayload{
+			PaymentID: payment.ID,
+			DisputeID: payment.Dispute.ID,
+			Status:    string(payment.Dispute.Status),
+			Reason:    payment.Dispute.Reason,
+			Amount:    payment.Dispute.Amount,
+		},
+	})
+
+	if err := s.publish(ctx, event); err != nil {
+		return fmt.Errorf("payment disputed event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Payment disputed event published", "payment_id", payment.ID, "dispute_id", payment.Dispute.ID)
+	return nil
+}
+
+func (s *PaymentService) publishPaymentProviderRefundedEvent(ctx context.Context, payment *domain.PaymentAggregate, refundAmount types.Money) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    payment.SagaID,
+		OrderID:   payment.OrderID,
+		EventType: events.PaymentProviderRefundedEvent,
+		Service:   "payment-service",
+		Payload: map[string]interface{}{
+			"payment_id":       payment.ID,
+			"transaction_id":   payment.TransactionID,
+			"refund_reference": payment.RefundReference,
+			"refunded_amount":  refundAmount,
+			"total_refunded":   payment.RefundedAmount,
+		},
+	})
+
+	if err := s.publish(ctx, event); err != nil {
+		return fmt.Errorf("payment provider refunded event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Payment provider refunded event published", "payment_id", payment.ID, "amount", refundAmount)
 	return nil
 }
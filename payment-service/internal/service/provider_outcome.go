@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+)
+
+// providerState what the gateway knows about a payment
+type providerState struct {
+	status          types.PaymentStatus
+	authorizationID string
+	transactionID   string
+	externalRef     string
+	failureReason   string
+	replayed        bool // Learned by replaying the original request
+}
+
+// settlePendingPayment applies the provider outcome to a payment whose command
+// is still waiting for it, then sends the saga the reply. Used when the outcome
+// arrives late: by webhook, or found by the reconciler after a crash. replied is
+// false when the command already had a recorded reply.
+func (s *PaymentService) settlePendingPayment(ctx context.Context, payment *domain.PaymentAggregate, state *providerState) (replied bool, err error) {
+	if payment.Status != types.PaymentStatusPending {
+		return false, fmt.Errorf("payment is not pending, current status: %s", payment.Status)
+	}
+
+	switch state.status {
+	case types.PaymentStatusAuthorized:
+		if err := payment.Authorize(state.authorizationID); err != nil {
+			return false, err
+		}
+
+	case types.PaymentStatusCompleted:
+		payment.ProcessPayment(state.transactionID, state.externalRef)
+
+	case types.PaymentStatusFailed, types.PaymentStatusVoided:
+		reason := state.failureReason
+		if reason == "" {
+			reason = fmt.Sprintf("Payment %s at provider", state.status)
+		}
+		payment.FailPayment(reason)
+
+	default:
+		return false, fmt.Errorf("unexpected provider status: %s", state.status)
+	}
+
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		return false, err
+	}
+
+	ctx = correlated(ctx, payment)
+
+	// Payments created before command tracking have no recorded outcome to check
+	if command, err := s.paymentRepo.GetCommandByPaymentID(payment.ID); err == nil {
+		if command.IsCompleted() {
+			return false, nil
+		}
+		ctx = context.WithValue(ctx, commandContextKey{}, command)
+	}
+
+	switch payment.Status {
+	case types.PaymentStatusAuthorized:
+		err = s.publishPaymentAuthorizedEvent(ctx, payment)
+	case types.PaymentStatusCompleted:
+		err = s.publishPaymentProcessedEvent(ctx, payment)
+	default:
+		err = s.publishPaymentFailedEvent(ctx, payment.SagaID, payment.OrderID, payment.FailureReason, payment.Amount)
+	}
+	return err == nil, err
+}
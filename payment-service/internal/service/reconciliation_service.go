@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+type ReconciliationConfig struct {
+	Interval time.Duration
+	// PendingAge a pending payment untouched for this long is stuck; keep it
+	// above the longest gateway call including retries
+	PendingAge time.Duration
+	// Lookback how far back payments failed by a gateway error are checked
+	Lookback time.Duration
+	// AuthorizationAge authorizations older than this are checked for expiry
+	AuthorizationAge time.Duration
+	BatchSize        int // Payments checked per run
+}
+
+// Reconciler periodically compares payments whose outcome is uncertain with the
+// gateway, corrects the local payment, emits saga replies that were never sent
+// and stores a report of every run for finance.
+type Reconciler struct {
+	payments *PaymentService
+	config   ReconciliationConfig
+
+	mu   sync.Mutex // One run at a time, scheduled or manual
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewReconciler(payments *PaymentService, config ReconciliationConfig) *Reconciler {
+	return &Reconciler{
+		payments: payments,
+		config:   config,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (r *Reconciler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		<-r.stop
+		cancel()
+	}()
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := r.Run(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Payment reconciliation error", "error", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the worker loop; a running reconciliation stops after the payment
+// it is checking
+func (r *Reconciler) Stop(ctx context.Context) error {
+	close(r.stop)
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run reconciles one batch of candidates. Runs that found nothing are not stored.
+func (r *Reconciler) Run(ctx context.Context) (*domain.ReconciliationRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	candidates, err := r.payments.paymentRepo.GetReconciliationCandidates(
+		now.Add(-r.config.PendingAge),
+		now.Add(-r.config.Lookback),
+		now.Add(-r.config.AuthorizationAge),
+		r.config.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	run := domain.NewReconciliationRun()
+	for _, payment := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		run.Add(r.reconcile(ctx, payment))
+	}
+	run.Finish()
+
+	if run.Checked == 0 {
+		return run, nil
+	}
+
+	if err := r.payments.paymentRepo.SaveReconciliationRun(run); err != nil {
+		return run, err
+	}
+
+	slog.InfoContext(ctx, "Payment reconciliation finished",
+		"run_id", run.ID, "checked", run.Checked, "corrected", run.Corrected,
+		"discrepancies", run.Discrepancies, "unresolved", run.Unresolved)
+	return run, nil
+}
+
+func (r *Reconciler) GetRuns(limit int) ([]*domain.ReconciliationRun, error) {
+	return r.payments.paymentRepo.GetReconciliationRuns(limit)
+}
+
+func (r *Reconciler) GetRun(runID uuid.UUID) (*domain.ReconciliationRun, error) {
+	return r.payments.paymentRepo.GetReconciliationRun(runID)
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, payment *domain.PaymentAggregate) domain.ReconciliationEntry {
+	entry := domain.ReconciliationEntry{
+		PaymentID:   payment.ID,
+		OrderID:     payment.OrderID,
+		SagaID:      payment.SagaID,
+		LocalStatus: payment.Status,
+		Amount:      payment.Amount,
+	}
+
+	// Payments created before command tracking have no command
+	command, _ := r.payments.paymentRepo.GetCommandByPaymentID(payment.ID)
+
+	state, err := r.queryProvider(ctx, payment, command)
+	if err != nil {
+		return unresolved(ctx, entry, err)
+	}
+	entry.ProviderStatus = string(state.status)
+
+	var action domain.ReconciliationAction
+	var detail string
+
+	switch payment.Status {
+	case types.PaymentStatusPending:
+		action, detail, err = r.resolvePending(ctx, payment, state)
+	case types.PaymentStatusFailed:
+		action, detail, err = r.resolveFailed(ctx, payment, state)
+	case types.PaymentStatusAuthorized:
+		action, detail, err = r.resolveAuthorized(ctx, payment, state)
+	default:
+		err = fmt.Errorf("payment status %s is not reconciled", payment.Status)
+	}
+	if err != nil {
+		return unresolved(ctx, entry, err)
+	}
+
+	if entry.LocalStatus != types.PaymentStatusPending {
+		if err := r.payments.paymentRepo.MarkReconciled(payment.ID); err != nil {
+			slog.ErrorContext(ctx, "Payment reconcile mark error", "payment_id", payment.ID, "error", err)
+		}
+	}
+
+	entry.Action = action
+	entry.Detail = detail
+	if state.replayed {
+		entry.Detail += " (provider state from idempotent replay)"
+	}
+
+	slog.InfoContext(ctx, "Payment reconciled",
+		"payment_id", payment.ID, "local_status", entry.LocalStatus,
+		"provider_status", entry.ProviderStatus, "action", action)
+	return entry
+}
+
+func unresolved(ctx context.Context, entry domain.ReconciliationEntry, err error) domain.ReconciliationEntry {
+	slog.WarnContext(ctx, "Payment reconciliation unresolved", "payment_id", entry.PaymentID, "error", err)
+
+	entry.Action = domain.ReconciliationUnresolved
+	entry.Detail = err.Error()
+	return entry
+}
+
+// queryProvider looks the payment up by its gateway reference. A payment without
+// one never got an answer from the gateway; replaying the original request with
+// its idempotency key returns the outcome the provider recorded, or processes it
+// now if the provider never received it.
+func (r *Reconciler) queryProvider(ctx context.Context, payment *domain.PaymentAggregate, command *domain.PaymentCommand) (*providerState, error) {
+	reference := payment.ExternalRef
+	if reference == "" {
+		reference = payment.AuthorizationID
+	}
+
+	if reference != "" {
+		response, err := r.payments.paymentGateway.GetPaymentStatus(ctx, reference)
+		if err != nil {
+			return nil, fmt.Errorf("gateway status error: %v", err)
+		}
+
+		state := &providerState{
+			status:          types.PaymentStatus(response.Status),
+			authorizationID: payment.AuthorizationID,
+			transactionID:   response.TransactionID,
+			externalRef:     payment.ExternalRef,
+		}
+		if state.authorizationID == "" {
+			state.authorizationID = reference
+		}
+		if state.externalRef == "" {
+			state.externalRef = reference
+		}
+		return state, nil
+	}
+
+	if command == nil {
+		return nil, errors.New("payment has no gateway reference and no command to replay")
+	}
+
+	request := r.payments.gatewayPaymentRequest(domain.PaymentProcessRequest{
+		SagaID:        payment.SagaID,
+		OrderID:       payment.OrderID,
+		CustomerID:    payment.CustomerID,
+		Amount:        payment.Amount,
+		PaymentMethod: payment.PaymentMethod,
+	}, command.IdempotencyKey)
+
+	switch command.Command {
+	case domain.CommandAuthorize:
+		response, err := r.payments.paymentGateway.Authorize(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("gateway authorize replay error: %v", err)
+		}
+		if !response.Success {
+			return &providerState{status: types.PaymentStatusFailed, failureReason: response.FailureReason, replayed: true}, nil
+		}
+		return &providerState{
+			status:          types.PaymentStatusAuthorized,
+			authorizationID: response.AuthorizationID,
+			replayed:        true,
+		}, nil
+
+	case domain.CommandProcess:
+		response, err := r.payments.paymentGateway.ProcessPayment(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("gateway payment replay error: %v", err)
+		}
+		if !response.Success {
+			return &providerState{status: types.PaymentStatusFailed, failureReason: response.FailureReason, replayed: true}, nil
+		}
+		return &providerState{
+			status:        types.PaymentStatusCompleted,
+			transactionID: response.TransactionID,
+			externalRef:   response.ExternalRef,
+			replayed:      true,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("payment command %s cannot be replayed", command.Command)
+	}
+}
+
+// resolvePending applies the provider outcome to a payment whose handler never
+// finished, and sends the saga the reply it is still waiting for
+func (r *Reconciler) resolvePending(ctx context.Context, payment *domain.PaymentAggregate, state *providerState) (domain.ReconciliationAction, string, error) {
+	var action domain.ReconciliationAction
+
+	switch state.status {
+	case types.PaymentStatusPending:
+		return domain.ReconciliationAwaitingProvider, "Provider has not decided yet, outcome follows by webhook", nil
+	case types.PaymentStatusAuthorized:
+		action = domain.ReconciliationAuthorized
+	case types.PaymentStatusCompleted:
+		action = domain.ReconciliationCompleted
+	case types.PaymentStatusFailed, types.PaymentStatusVoided:
+		action = domain.ReconciliationFailed
+	default:
+		return "", "", fmt.Errorf("unexpected provider status: %s", state.status)
+	}
+
+	replied, err := r.payments.settlePendingPayment(ctx, payment, state)
+	if err != nil {
+		return "", "", err
+	}
+	if !replied {
+		return action, "Payment corrected, saga already had a reply", nil
+	}
+	return action, "Payment corrected, missing saga reply emitted", nil
+}
+
+// resolveFailed releases money the provider took for a payment the saga has
+// already seen fail; the saga compensated, so nothing is published
+func (r *Reconciler) resolveFailed(ctx context.Context, payment *domain.PaymentAggregate, state *providerState) (domain.ReconciliationAction, string, error) {
+	switch state.status {
+	case types.PaymentStatusFailed, types.PaymentStatusVoided:
+		return domain.ReconciliationConfirmed, "", nil
+
+	case types.PaymentStatusAuthorized:
+		response, err := r.payments.paymentGateway.Void(ctx, gateway.VoidRequest{
+			AuthorizationID: state.authorizationID,
+			Reason:          "Payment failed locally, released by reconciliation",
+			IdempotencyKey:  "reconcile-void-" + payment.ID.String(),
+		})
+		if err != nil {
+			return "", "", fmt.Errorf("gateway void error: %v", err)
+		}
+		if !response.Success {
+			return "", "", fmt.Errorf("gateway void failed: %s", response.FailureReason)
+		}
+
+		payment.ReleaseOrphanedAuthorization(state.authorizationID)
+		if err := r.payments.paymentRepo.UpdatePayment(payment); err != nil {
+			return "", "", err
+		}
+		return domain.ReconciliationVoidedOrphan,
+			fmt.Sprintf("Authorization %s held for a failed payment, voided", state.authorizationID), nil
+
+	case types.PaymentStatusCompleted:
+		response, err := r.payments.paymentGateway.RefundPayment(ctx, gateway.RefundRequest{
+			OriginalTransactionID: state.transactionID,
+			ExternalRef:           state.externalRef,
+			Amount:                payment.Amount,
+			Reason:                "Payment failed locally, refunded by reconciliation",
+			IdempotencyKey:        "reconcile-refund-" + payment.ID.String(),
+		})
+		if err != nil {
+			return "", "", fmt.Errorf("gateway refund error: %v", err)
+		}
+		if !response.Success {
+			return "", "", fmt.Errorf("gateway refund failed: %s", response.FailureReason)
+		}
+
+		payment.RefundOrphanedCharge(state.transactionID, state.externalRef, response.RefundReference)
+		if err := r.payments.paymentRepo.UpdatePayment(payment); err != nil {
+			return "", "", err
+		}
+		return domain.ReconciliationRefundedOrphan,
+			fmt.Sprintf("Transaction %s charged for a failed payment, refunded", state.transactionID), nil
+
+	default:
+		return "", "", fmt.Errorf("unexpected provider status: %s", state.status)
+	}
+}
+
+// resolveAuthorized checks an old authorization: the provider may have let it
+// expire or captured it, otherwise the hold is reported as still open
+func (r *Reconciler) resolveAuthorized(ctx context.Context, payment *domain.PaymentAggregate, state *providerState) (domain.ReconciliationAction, string, error) {
+	switch state.status {
+	case types.PaymentStatusAuthorized:
+		return domain.ReconciliationStaleAuthorization,
+			fmt.Sprintf("Authorization %s still held since %s", payment.AuthorizationID, payment.AuthorizedAt.Format(time.RFC3339)), nil
+
+	case types.PaymentStatusVoided, types.PaymentStatusFailed:
+		if err := payment.Void(); err != nil {
+			return "", "", err
+		}
+		if err := r.payments.paymentRepo.UpdatePayment(payment); err != nil {
+			return "", "", err
+		}
+		return domain.ReconciliationVoidedExpired, "Authorization released by the provider", nil
+
+	case types.PaymentStatusCompleted:
+		if err := payment.Capture(state.transactionID, state.externalRef); err != nil {
+			return "", "", err
+		}
+		if err := r.payments.paymentRepo.UpdatePayment(payment); err != nil {
+			return "", "", err
+		}
+		return domain.ReconciliationCapturedAtProvider,
+			fmt.Sprintf("Provider captured transaction %s without a saga capture", state.transactionID), nil
+
+	default:
+		return "", "", fmt.Errorf("unexpected provider status: %s", state.status)
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/payment-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/payment-service/internal/gateway"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// ErrWebhookRetryLater the event cannot be applied yet: the payment it refers to
+// is not stored or a saga command on it is still running. Answering with an
+// error makes the provider deliver it again later.
+var ErrWebhookRetryLater = errors.New("webhook cannot be applied yet")
+
+// webhookClaimTimeout after which a delivery still applying an event is
+// presumed dead and a redelivery takes the event over
+const webhookClaimTimeout = 5 * time.Minute
+
+// HandleWebhook applies an asynchronous provider result once per provider event
+func (s *PaymentService) HandleWebhook(ctx context.Context, event *gateway.WebhookEvent) error {
+	if event.Type == "" {
+		slog.DebugContext(ctx, "Provider webhook ignored", "event_id", event.ID, "provider_type", event.ProviderType)
+		return nil
+	}
+
+	claimed, status, err := s.paymentRepo.BeginWebhookEvent(event.ID, event.ProviderType, webhookClaimTimeout)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		if status == domain.WebhookStatusProcessed {
+			slog.InfoContext(ctx, "Duplicate provider webhook, already applied", "event_id", event.ID)
+			return nil
+		}
+		return fmt.Errorf("%w: event %s is being applied by another delivery", ErrWebhookRetryLater, event.ID)
+	}
+
+	if err := s.applyClaimedWebhook(ctx, event); err != nil {
+		if releaseErr := s.paymentRepo.ReleaseWebhookEvent(event.ID); releaseErr != nil {
+			slog.ErrorContext(ctx, "Provider webhook release error", "event_id", event.ID, "error", releaseErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *PaymentService) applyClaimedWebhook(ctx context.Context, event *gateway.WebhookEvent) error {
+	payment, err := s.paymentRepo.GetPaymentByGatewayRef(event.Reference)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookRetryLater, err)
+	}
+
+	ctx = correlated(ctx, payment)
+	slog.InfoContext(ctx, "Provider webhook received",
+		"event_id", event.ID, "type", event.Type, "payment_id", payment.ID, "status", payment.Status)
+
+	if err := s.applyWebhook(ctx, payment, event); err != nil {
+		return err
+	}
+
+	return s.paymentRepo.CompleteWebhookEvent(event.ID, payment.ID)
+}
+
+func (s *PaymentService) applyWebhook(ctx context.Context, payment *domain.PaymentAggregate, event *gateway.WebhookEvent) error {
+	switch event.Type {
+	case gateway.WebhookPaymentAuthorized, gateway.WebhookPaymentSucceeded, gateway.WebhookPaymentFailed:
+		// Only a payment waiting for the provider changes; any other status was
+		// set by the synchronous response or an earlier event
+		if payment.Status != types.PaymentStatusPending {
+			slog.InfoContext(ctx, "Provider outcome already applied", "payment_id", payment.ID, "status", payment.Status)
+			return nil
+		}
+
+		state := &providerState{
+			authorizationID: event.Reference,
+			transactionID:   event.TransactionID,
+			externalRef:     event.Reference,
+			failureReason:   event.FailureReason,
+		}
+		switch event.Type {
+		case gateway.WebhookPaymentAuthorized:
+			state.status = types.PaymentStatusAuthorized
+		case gateway.WebhookPaymentSucceeded:
+			state.status = types.PaymentStatusCompleted
+		default:
+			state.status = types.PaymentStatusFailed
+		}
+
+		_, err := s.settlePendingPayment(ctx, payment, state)
+		return err
+
+	case gateway.WebhookPaymentRefunded:
+		return s.applyProviderRefund(ctx, payment, event)
+
+	case gateway.WebhookPaymentDisputed:
+		if err := payment.OpenDispute(event.DisputeID, event.DisputeReason, event.Amount); err != nil {
+			return err
+		}
+		if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+			return err
+		}
+		return s.publishPaymentDisputedEvent(ctx, payment)
+
+	case gateway.WebhookDisputeClosed:
+		return s.applyDisputeClosed(ctx, payment, event)
+
+	default:
+		return nil
+	}
+}
+
+// applyProviderRefund records refunds made at the provider. Refunds a saga
+// issued are already recorded and change nothing; while a saga's refund is
+// still running the event waits, so the saga records its own refund first.
+func (s *PaymentService) applyProviderRefund(ctx context.Context, payment *domain.PaymentAggregate, event *gateway.WebhookEvent) error {
+	running, err := s.paymentRepo.HasCommandInProgress(payment.ID, domain.CommandRefund)
+	if err != nil {
+		return err
+	}
+	if running {
+		return fmt.Errorf("%w: saga refund in progress for payment %s", ErrWebhookRetryLater, payment.ID)
+	}
+
+	added, err := payment.RecordProviderRefund(event.ID, event.Amount)
+	if err != nil {
+		return err
+	}
+	if !added.IsPositive() {
+		slog.InfoContext(ctx, "Provider refund already recorded", "payment_id", payment.ID, "total_refunded", event.Amount)
+		return nil
+	}
+
+	refund := domain.NewPaymentRefund(payment.ID, uuid.Nil, event.ID, added, "", domain.RefundSourceProvider)
+	if err := s.paymentRepo.RecordRefund(payment, refund); err != nil {
+		return err
+	}
+	return s.publishPaymentProviderRefundedEvent(ctx, payment, added)
+}
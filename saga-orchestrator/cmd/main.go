@@ -1,41 +1,62 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
 	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/handlers"
 	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/repository"
 	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/service"
+	"github.com/distributed-ecommerce-saga/shared-domain/lifecycle"
+	"github.com/distributed-ecommerce-saga/shared-domain/logging"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/tracing"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	log.Println("🚀 Starting Saga Orchestrator...")
+	logging.Init("saga-orchestrator")
+	slog.Info("Starting Saga Orchestrator")
+
+	shutdown := lifecycle.NewManager("Saga Orchestrator", lifecycle.TimeoutFromEnv(30*time.Second))
+
+	shutdownTracing, err := tracing.Init(context.Background(), "saga-orchestrator")
+	if err != nil {
+		logging.Fatal("Tracing init error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageTelemetry, "trace exporter", shutdownTracing)
+
+	metrics.Init("saga-orchestrator")
 
 	// Database connection
 	db, err := initDatabase()
 	if err != nil {
-		log.Fatalf("Database connection error: %v", err)
+		logging.Fatal("Database connection error", "error", err)
 	}
-	defer db.Close()
+	shutdown.Register(lifecycle.StageDatabase, "postgres", func(ctx context.Context) error {
+		return db.Close()
+	})
 
 	// RabbitMQ connection
 	rabbitConfig := messaging.NewRabbitMQConfig()
 	rabbitClient := messaging.NewRabbitMQClient(rabbitConfig)
 
 	if err := rabbitClient.Connect(); err != nil {
-		log.Fatalf("RabbitMQ connection error: %v", err)
+		logging.Fatal("RabbitMQ connection error", "error", err)
 	}
-	defer rabbitClient.Close()
+	shutdown.Register(lifecycle.StageBroker, "rabbitmq", func(ctx context.Context) error {
+		return rabbitClient.Close()
+	})
 
 	// Dependencies injection
 	publisher := messaging.NewPublisher(rabbitClient)
+	shutdown.Register(lifecycle.StageOutbox, "event publisher", publisher.Flush)
 	consumer := messaging.NewConsumer(rabbitClient, "saga-orchestrator-queue", "saga-orchestrator")
 
 	sagaRepo := repository.NewSagaRepository(db)
@@ -43,26 +64,36 @@ func main() {
 	eventHandler := handlers.NewEventHandler(orchestrator)
 
 	// Start RabbitMQ event consumption
-	go func() {
-		log.Println("🐰 Starting RabbitMQ event consumption...")
-		if err := eventHandler.StartConsuming(consumer); err != nil {
-			log.Printf("RabbitMQ consumption error: %v", err)
-		}
-	}()
+	if err := eventHandler.StartConsuming(consumer); err != nil {
+		slog.Error("RabbitMQ consumption error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageConsumers, "rabbitmq consumer", consumer.Stop)
+	shutdown.Register(lifecycle.StageDrain, "in-flight event handlers", consumer.Drain)
 
-	// Graceful shutdown
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+	stuckMonitor := service.NewStuckSagaMonitor(sagaRepo,
+		getEnvDuration("SAGA_STUCK_THRESHOLD", 5*time.Minute), 30*time.Second)
+	stuckMonitor.Start()
+	shutdown.Register(lifecycle.StageConsumers, "stuck saga monitor", stuckMonitor.Stop)
 
-		log.Println("🛑 Shutting down Saga Orchestrator...")
+	// Metrics endpoint (the orchestrator has no HTTP API of its own)
+	metricsPort := getEnvOrDefault("METRICS_PORT", "9090")
+	metricsServer := metrics.NewServer(":" + metricsPort)
+	shutdown.Register(lifecycle.StageHTTP, "metrics server", metricsServer.Shutdown)
+
+	go func() {
+		slog.Info("Metrics server listening", "port", metricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Metrics server error", "error", err)
+			shutdown.Trigger()
+		}
 	}()
 
-	log.Println("✅ Saga Orchestrator is ready and listening for events")
+	slog.Info("Saga Orchestrator is ready and listening for events")
 
-	// Keep the application running
-	select {}
+	// Keep the application running until a shutdown signal is received
+	if err := shutdown.Wait(); err != nil {
+		logging.Fatal("Shutdown error", "error", err)
+	}
 }
 
 func initDatabase() (*sql.DB, error) {
@@ -91,7 +122,7 @@ func initDatabase() (*sql.DB, error) {
 		return nil, fmt.Errorf("database ping error: %v", err)
 	}
 
-	log.Printf("✅ Database connection successful: %s", dbName)
+	slog.Info("Database connection successful", "database", dbName)
 	return db, nil
 }
 
@@ -101,3 +132,12 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -1,8 +1,10 @@
 package domain
 
 import (
-	"github.com/google/uuid"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type SagaStatus string
@@ -14,6 +16,21 @@ const (
 	SagaStatusFailed       SagaStatus = "failed"
 	SagaStatusCompensating SagaStatus = "compensating"
 	SagaStatusCompensated  SagaStatus = "compensated"
+
+	// Chargeback flow of a completed saga whose payment dispute was lost
+	SagaStatusDisputeHandling SagaStatus = "dispute_handling"
+	SagaStatusChargedBack     SagaStatus = "charged_back"
+)
+
+// SagaType distinguishes an order's checkout saga from the refund and
+// backorder sagas started for it later
+type SagaType string
+
+const (
+	SagaTypeOrder  SagaType = "order"
+	SagaTypeRefund SagaType = "refund"
+	// SagaTypeBackorder ships and charges a backordered item once stock arrived
+	SagaTypeBackorder SagaType = "backorder"
 )
 
 type SagaStep string
@@ -21,20 +38,48 @@ type SagaStep string
 const (
 	// Forward steps
 	StepOrderCreated      SagaStep = "order_created"
-	StepPaymentProcessed  SagaStep = "payment_processed"
+	StepPaymentAuthorized SagaStep = "payment_authorized"
 	StepInventoryReserved SagaStep = "inventory_reserved"
 	StepShippingCreated   SagaStep = "shipping_created"
+	StepPaymentCaptured   SagaStep = "payment_captured"
 	StepNotificationSent  SagaStep = "notification_sent"
 
+	// StepPaymentProcessed single step charge of sagas started before the
+	// authorize/capture split, kept so they can finish or be refunded
+	StepPaymentProcessed SagaStep = "payment_processed"
+
 	// Compensation steps
 	StepOrderCancelled    SagaStep = "order_cancelled"
 	StepPaymentRefunded   SagaStep = "payment_refunded"
+	StepPaymentVoided     SagaStep = "payment_voided"
 	StepInventoryReleased SagaStep = "inventory_released"
 	StepShippingCancelled SagaStep = "shipping_cancelled"
+	// StepPaymentVoidFailed the authorization could not be voided and is still
+	// held; compensation goes on and the saga ends failed, for ops to release it
+	StepPaymentVoidFailed SagaStep = "payment_void_failed"
+
+	// Chargeback steps, run after completion when the bank decides a dispute
+	// for the customer. The money is already gone, so nothing is refunded.
+	StepDisputeLost              SagaStep = "dispute_lost"
+	StepDisputeShippingCancelled SagaStep = "dispute_shipping_cancelled"
+	StepDisputeShippingKept      SagaStep = "dispute_shipping_kept" // Already shipped, could not be cancelled
+	StepDisputeCustomerNotified  SagaStep = "dispute_customer_notified"
+
+	// Refund saga steps, a merchant refunding part of a completed order
+	StepRefundRequested        SagaStep = "refund_requested"
+	StepRefundPaymentRefunded  SagaStep = "refund_payment_refunded"
+	StepRefundItemsRestocked   SagaStep = "refund_items_restocked"
+	StepRefundRestockFailed    SagaStep = "refund_restock_failed" // Money is back, stock is fixed by hand
+	StepRefundCustomerNotified SagaStep = "refund_customer_notified"
+
+	// StepBackorderReady first step of a backorder saga, stock for it arrived.
+	// The forward steps above follow, starting with the reservation.
+	StepBackorderReady SagaStep = "backorder_ready"
 )
 
 type SagaInstance struct {
 	ID               uuid.UUID  `json:"id" db:"id"`
+	Type             SagaType   `json:"saga_type" db:"saga_type"`
 	OrderID          uuid.UUID  `json:"order_id" db:"order_id"`
 	CustomerID       uuid.UUID  `json:"customer_id" db:"customer_id"`
 	Status           SagaStatus `json:"status" db:"status"`
@@ -45,11 +90,20 @@ type SagaInstance struct {
 	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
 	CompletedAt      *time.Time `json:"completed_at,omitempty" db:"completed_at"`
 	CompensatedSteps []SagaStep `json:"compensated_steps" db:"compensated_steps"`
+	CorrelationID    uuid.UUID  `json:"correlation_id" db:"correlation_id"` // From OrderCreatedEvent, carried by every saga event
 
 	// All data during saga
 	Context map[string]interface{} `json:"context" db:"context"`
 }
 
+// TypeOrDefault sagas created before refunds existed are checkout sagas
+func (s *SagaInstance) TypeOrDefault() SagaType {
+	if s.Type == "" {
+		return SagaTypeOrder
+	}
+	return s.Type
+}
+
 func (s *SagaInstance) IsStepCompleted(step SagaStep) bool {
 	for _, completedStep := range s.CompletedSteps {
 		if completedStep == step {
@@ -67,21 +121,65 @@ func (s *SagaInstance) MarkStepCompleted(step SagaStep) {
 	s.UpdatedAt = time.Now()
 }
 
+// DecodeContext reads a context value into out. Values loaded from the database
+// are plain JSON maps and slices, so they are converted by a JSON round trip.
+func (s *SagaInstance) DecodeContext(key string, out interface{}) error {
+	value, ok := s.Context[key]
+	if !ok || value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
 func (s *SagaInstance) GetNextStep() SagaStep {
+	if s.TypeOrDefault() == SagaTypeBackorder {
+		return s.GetNextBackorderStep()
+	}
+
 	switch s.CurrentStep {
 	case StepOrderCreated:
-		return StepPaymentProcessed
-	case StepPaymentProcessed:
+		return StepPaymentAuthorized
+	case StepPaymentAuthorized, StepPaymentProcessed:
 		return StepInventoryReserved
 	case StepInventoryReserved:
 		return StepShippingCreated
 	case StepShippingCreated:
+		// Capture only once the order is on its way
+		if s.IsStepCompleted(StepPaymentProcessed) {
+			return StepNotificationSent
+		}
+		return StepPaymentCaptured
+	case StepPaymentCaptured:
 		return StepNotificationSent
 	default:
 		return "" // Last step
 	}
 }
 
+// GetNextBackorderStep the next backorder step: the stock is reserved before
+// the customer is charged, so a backorder whose stock was taken in the meantime
+// goes back to waiting without touching the payment
+func (s *SagaInstance) GetNextBackorderStep() SagaStep {
+	switch s.CurrentStep {
+	case StepBackorderReady:
+		return StepInventoryReserved
+	case StepInventoryReserved:
+		return StepPaymentAuthorized
+	case StepPaymentAuthorized:
+		return StepShippingCreated
+	case StepShippingCreated:
+		return StepPaymentCaptured
+	case StepPaymentCaptured:
+		return StepNotificationSent
+	default:
+		return ""
+	}
+}
+
 func (s *SagaInstance) GetCompensationStep() SagaStep {
 	// Shipping completed but compensation not worked
 	if s.IsStepCompleted(StepShippingCreated) && !s.IsCompensationCompleted(StepShippingCancelled) {
@@ -90,15 +188,53 @@ func (s *SagaInstance) GetCompensationStep() SagaStep {
 	if s.IsStepCompleted(StepInventoryReserved) && !s.IsCompensationCompleted(StepInventoryReleased) {
 		return StepInventoryReleased
 	}
-	if s.IsStepCompleted(StepPaymentProcessed) && !s.IsCompensationCompleted(StepPaymentRefunded) {
+	// Captured money is refunded, an authorization that was never captured is voided
+	if s.IsPaymentCaptured() && !s.IsCompensationCompleted(StepPaymentRefunded) {
 		return StepPaymentRefunded
 	}
+	if s.IsStepCompleted(StepPaymentAuthorized) && !s.IsPaymentCaptured() &&
+		!s.IsCompensationCompleted(StepPaymentVoided) && !s.IsCompensationCompleted(StepPaymentVoidFailed) {
+		return StepPaymentVoided
+	}
 	if s.IsStepCompleted(StepOrderCreated) && !s.IsCompensationCompleted(StepOrderCancelled) {
 		return StepOrderCancelled
 	}
 	return ""
 }
 
+// GetNextDisputeStep the next chargeback step: the shipment is cancelled while
+// it still can be, then the customer is told either way
+func (s *SagaInstance) GetNextDisputeStep() SagaStep {
+	if s.IsStepCompleted(StepShippingCreated) &&
+		!s.IsStepCompleted(StepDisputeShippingCancelled) && !s.IsStepCompleted(StepDisputeShippingKept) {
+		return StepDisputeShippingCancelled
+	}
+	if !s.IsStepCompleted(StepDisputeCustomerNotified) {
+		return StepDisputeCustomerNotified
+	}
+	return ""
+}
+
+// GetNextRefundStep the next refund step: money first, then stock for the
+// returned items if there are any, then the customer
+func (s *SagaInstance) GetNextRefundStep(hasItems bool) SagaStep {
+	if !s.IsStepCompleted(StepRefundPaymentRefunded) {
+		return StepRefundPaymentRefunded
+	}
+	if hasItems && !s.IsStepCompleted(StepRefundItemsRestocked) && !s.IsStepCompleted(StepRefundRestockFailed) {
+		return StepRefundItemsRestocked
+	}
+	if !s.IsStepCompleted(StepRefundCustomerNotified) {
+		return StepRefundCustomerNotified
+	}
+	return ""
+}
+
+// IsPaymentCaptured reports whether the customer has actually been charged
+func (s *SagaInstance) IsPaymentCaptured() bool {
+	return s.IsStepCompleted(StepPaymentCaptured) || s.IsStepCompleted(StepPaymentProcessed)
+}
+
 func (s *SagaInstance) IsCompensationCompleted(compensationStep SagaStep) bool {
 	for _, compensated := range s.CompensatedSteps {
 		if compensated == compensationStep {
@@ -0,0 +1,30 @@
+package domain
+
+import "testing"
+
+func TestGetCompensationStep(t *testing.T) {
+	authorized := []SagaStep{StepOrderCreated, StepPaymentAuthorized, StepInventoryReserved}
+	captured := []SagaStep{StepOrderCreated, StepPaymentAuthorized, StepInventoryReserved, StepShippingCreated, StepPaymentCaptured}
+
+	tests := []struct {
+		name        string
+		completed   []SagaStep
+		compensated []SagaStep
+		want        SagaStep
+	}{
+		{"release before the payment", authorized, nil, StepInventoryReleased},
+		{"void an authorization", authorized, []SagaStep{StepInventoryReleased}, StepPaymentVoided},
+		{"cancel after the void", authorized, []SagaStep{StepInventoryReleased, StepPaymentVoided}, StepOrderCancelled},
+		// The hold is left for ops, the order is cancelled all the same
+		{"cancel after a failed void", authorized, []SagaStep{StepInventoryReleased, StepPaymentVoidFailed}, StepOrderCancelled},
+		{"refund a capture", captured, []SagaStep{StepShippingCancelled, StepInventoryReleased}, StepPaymentRefunded},
+		{"done", authorized, []SagaStep{StepInventoryReleased, StepPaymentVoidFailed, StepOrderCancelled}, ""},
+	}
+
+	for _, tt := range tests {
+		saga := &SagaInstance{CompletedSteps: tt.completed, CompensatedSteps: tt.compensated}
+		if got := saga.GetCompensationStep(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,8 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"log/slog"
 
 	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/service"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
@@ -16,9 +17,9 @@ func NewEventHandler(orchestrator *service.SagaOrchestrator) *EventHandler {
 	return &EventHandler{orchestrator: orchestrator}
 }
 
-func (h *EventHandler) HandleSagaEvent(event events.SagaEvent) error {
-	log.Printf("Saga orchestrator event handler: %s from %s", event.EventType, event.Service)
-	return h.orchestrator.ProcessIncomingEvent(event)
+func (h *EventHandler) HandleSagaEvent(ctx context.Context, event events.SagaEvent) error {
+	slog.DebugContext(ctx, "Saga orchestrator event received", "from", event.Service)
+	return h.orchestrator.ProcessIncomingEvent(ctx, event)
 }
 
 func (h *EventHandler) StartConsuming(consumer *messaging.Consumer) error {
@@ -4,11 +4,20 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
 	"github.com/google/uuid"
 )
 
+// sagaColumns selected by every query, in scanSaga order
+const sagaColumns = `
+	id, order_id, customer_id, status, current_step, completed_steps,
+	failure_reason, context, created_at, updated_at, completed_at, correlation_id,
+	compensated_steps, saga_type`
+
 type SagaRepository struct {
 	db *sql.DB
 }
@@ -20,6 +29,7 @@ func NewSagaRepository(db *sql.DB) *SagaRepository {
 }
 
 func (r *SagaRepository) CreateSaga(saga *domain.SagaInstance) error {
+	defer metrics.ObserveDBQuery("CreateSaga", time.Now())
 
 	contextJson, err := json.Marshal(saga.Context)
 	if err != nil {
@@ -31,11 +41,17 @@ func (r *SagaRepository) CreateSaga(saga *domain.SagaInstance) error {
 		return fmt.Errorf("steps serialization error: %v", err)
 	}
 
+	compensatedJSON, err := json.Marshal(saga.CompensatedSteps)
+	if err != nil {
+		return fmt.Errorf("compensated steps serialization error: %v", err)
+	}
+
 	query := `
 		INSERT INTO saga_instances (
 			id, order_id, customer_id, status, current_step, 
-			completed_steps, failure_reason, context, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			completed_steps, failure_reason, context, created_at, updated_at, correlation_id,
+			compensated_steps, saga_type
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
 	_, err = r.db.Exec(
@@ -50,6 +66,9 @@ func (r *SagaRepository) CreateSaga(saga *domain.SagaInstance) error {
 		contextJson,
 		saga.CreatedAt,
 		saga.UpdatedAt,
+		saga.CorrelationID,
+		compensatedJSON,
+		saga.TypeOrDefault(),
 	)
 
 	if err != nil {
@@ -60,31 +79,15 @@ func (r *SagaRepository) CreateSaga(saga *domain.SagaInstance) error {
 }
 
 func (r *SagaRepository) GetSagaByID(sagaID uuid.UUID) (*domain.SagaInstance, error) {
+	defer metrics.ObserveDBQuery("GetSagaByID", time.Now())
+
 	query := `
-		SELECT id, order_id, customer_id, status, current_step, completed_steps,
-			   failure_reason, context, created_at, updated_at, completed_at
+		SELECT ` + sagaColumns + `
 		FROM saga_instances 
 		WHERE id = $1
 	`
 
-	saga := &domain.SagaInstance{}
-	var contextJSON, stepsJSON []byte
-	var completedAt sql.NullTime
-
-	err := r.db.QueryRow(query, sagaID).Scan(
-		&saga.ID,
-		&saga.OrderID,
-		&saga.CustomerID,
-		&saga.Status,
-		&saga.CurrentStep,
-		&stepsJSON,
-		&saga.FailureReason,
-		&contextJSON,
-		&saga.CreatedAt,
-		&saga.UpdatedAt,
-		&completedAt,
-	)
-
+	saga, err := scanSaga(r.db.QueryRow(query, sagaID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("saga not found: %s", sagaID)
@@ -92,23 +95,12 @@ func (r *SagaRepository) GetSagaByID(sagaID uuid.UUID) (*domain.SagaInstance, er
 		return nil, fmt.Errorf("saga receive error: %v", err)
 	}
 
-	// JSON'ları deserialize et
-	if err := json.Unmarshal(contextJSON, &saga.Context); err != nil {
-		return nil, fmt.Errorf("context deserialization error: %v", err)
-	}
-
-	if err := json.Unmarshal(stepsJSON, &saga.CompletedSteps); err != nil {
-		return nil, fmt.Errorf("steps deserialization error: %v", err)
-	}
-
-	if completedAt.Valid {
-		saga.CompletedAt = &completedAt.Time
-	}
-
 	return saga, nil
 }
 
 func (r *SagaRepository) UpdateSaga(saga *domain.SagaInstance) error {
+	defer metrics.ObserveDBQuery("UpdateSaga", time.Now())
+
 	contextJSON, err := json.Marshal(saga.Context)
 	if err != nil {
 		return fmt.Errorf("context serialization error: %v", err)
@@ -119,10 +111,16 @@ func (r *SagaRepository) UpdateSaga(saga *domain.SagaInstance) error {
 		return fmt.Errorf("steps serialization error: %v", err)
 	}
 
+	compensatedJSON, err := json.Marshal(saga.CompensatedSteps)
+	if err != nil {
+		return fmt.Errorf("compensated steps serialization error: %v", err)
+	}
+
 	query := `
 		UPDATE saga_instances 
 		SET status = $2, current_step = $3, completed_steps = $4, 
-			failure_reason = $5, context = $6, updated_at = $7, completed_at = $8
+			failure_reason = $5, context = $6, updated_at = $7, completed_at = $8,
+			compensated_steps = $9
 		WHERE id = $1
 	`
 
@@ -136,6 +134,7 @@ func (r *SagaRepository) UpdateSaga(saga *domain.SagaInstance) error {
 		contextJSON,
 		saga.UpdatedAt,
 		saga.CompletedAt,
+		compensatedJSON,
 	)
 
 	if err != nil {
@@ -154,19 +153,70 @@ func (r *SagaRepository) UpdateSaga(saga *domain.SagaInstance) error {
 	return nil
 }
 
+// GetSagaByOrderID returns the order's checkout saga; refund sagas of the order are not included
 func (r *SagaRepository) GetSagaByOrderID(orderID uuid.UUID) (*domain.SagaInstance, error) {
+	defer metrics.ObserveDBQuery("GetSagaByOrderID", time.Now())
+
+	query := `
+		SELECT ` + sagaColumns + `
+		FROM saga_instances 
+		WHERE order_id = $1 AND saga_type = 'order'
+	`
+
+	saga, err := scanSaga(r.db.QueryRow(query, orderID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("saga not found order: %s", orderID)
+		}
+		return nil, fmt.Errorf("saga receive error: %v", err)
+	}
+
+	return saga, nil
+}
+
+// for recovery
+func (r *SagaRepository) GetInProgressSagas() ([]*domain.SagaInstance, error) {
+	defer metrics.ObserveDBQuery("GetInProgressSagas", time.Now())
+
 	query := `
-		SELECT id, order_id, customer_id, status, current_step, completed_steps,
-			   failure_reason, context, created_at, updated_at, completed_at
+		SELECT ` + sagaColumns + `
 		FROM saga_instances 
-		WHERE order_id = $1
+		WHERE status IN ('started', 'in_progress', 'compensating', 'dispute_handling')
+		ORDER BY created_at ASC
 	`
 
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("sagas received error: %v", err)
+	}
+	defer rows.Close()
+
+	var sagas []*domain.SagaInstance
+
+	for rows.Next() {
+		saga, err := scanSaga(rows)
+		if err != nil {
+			return nil, fmt.Errorf("saga scan error: %v", err)
+		}
+
+		sagas = append(sagas, saga)
+	}
+
+	return sagas, nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSaga(row rowScanner) (*domain.SagaInstance, error) {
 	saga := &domain.SagaInstance{}
-	var contextJSON, stepsJSON []byte
+	var contextJSON, stepsJSON, compensatedJSON []byte
 	var completedAt sql.NullTime
+	var correlationID uuid.NullUUID
 
-	err := r.db.QueryRow(query, orderID).Scan(
+	err := row.Scan(
 		&saga.ID,
 		&saga.OrderID,
 		&saga.CustomerID,
@@ -178,16 +228,15 @@ func (r *SagaRepository) GetSagaByOrderID(orderID uuid.UUID) (*domain.SagaInstan
 		&saga.CreatedAt,
 		&saga.UpdatedAt,
 		&completedAt,
+		&correlationID,
+		&compensatedJSON,
+		&saga.Type,
 	)
-
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("saga not found order: %s", orderID)
-		}
-		return nil, fmt.Errorf("saga receive error: %v", err)
+		return nil, err
 	}
 
-	// Deserialization
+	// JSON'ları deserialize et
 	if err := json.Unmarshal(contextJSON, &saga.Context); err != nil {
 		return nil, fmt.Errorf("context deserialization error: %v", err)
 	}
@@ -196,69 +245,82 @@ func (r *SagaRepository) GetSagaByOrderID(orderID uuid.UUID) (*domain.SagaInstan
 		return nil, fmt.Errorf("steps deserialization error: %v", err)
 	}
 
+	if err := json.Unmarshal(compensatedJSON, &saga.CompensatedSteps); err != nil {
+		return nil, fmt.Errorf("compensated steps deserialization error: %v", err)
+	}
+
 	if completedAt.Valid {
 		saga.CompletedAt = &completedAt.Time
 	}
 
+	// Sagas started before correlation tracking have no correlation ID
+	if correlationID.Valid {
+		saga.CorrelationID = correlationID.UUID
+	}
+
 	return saga, nil
 }
 
-// for recovery
-func (r *SagaRepository) GetInProgressSagas() ([]*domain.SagaInstance, error) {
+// LogEvent records an event of the saga in saga_event_log.
+// rejectionReason is empty for events that were processed.
+func (r *SagaRepository) LogEvent(event events.SagaEvent, rejectionReason string) error {
+	defer metrics.ObserveDBQuery("LogEvent", time.Now())
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("event serialization error: %v", err)
+	}
+
 	query := `
-		SELECT id, order_id, customer_id, status, current_step, completed_steps,
-			   failure_reason, context, created_at, updated_at, completed_at
-		FROM saga_instances 
-		WHERE status IN ('started', 'in_progress', 'compensating')
-		ORDER BY created_at ASC
+		INSERT INTO saga_event_log (
+			saga_id, event_type, event_data, service_name, timestamp,
+			correlation_id, causation_id, rejection_reason
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 
-	rows, err := r.db.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf("sagas received error: %v", err)
+	var causationID uuid.NullUUID
+	if event.CausationID != uuid.Nil {
+		causationID = uuid.NullUUID{UUID: event.CausationID, Valid: true}
 	}
-	defer rows.Close()
 
-	var sagas []*domain.SagaInstance
+	var reason sql.NullString
+	if rejectionReason != "" {
+		reason = sql.NullString{String: rejectionReason, Valid: true}
+	}
 
-	for rows.Next() {
-		saga := &domain.SagaInstance{}
-		var contextJSON, stepsJSON []byte
-		var completedAt sql.NullTime
-
-		err := rows.Scan(
-			&saga.ID,
-			&saga.OrderID,
-			&saga.CustomerID,
-			&saga.Status,
-			&saga.CurrentStep,
-			&stepsJSON,
-			&saga.FailureReason,
-			&contextJSON,
-			&saga.CreatedAt,
-			&saga.UpdatedAt,
-			&completedAt,
-		)
+	_, err = r.db.Exec(
+		query,
+		event.SagaID,
+		event.EventType,
+		eventJSON,
+		event.Service,
+		event.Timestamp,
+		event.CorrelationID,
+		causationID,
+		reason,
+	)
 
-		if err != nil {
-			return nil, fmt.Errorf("saga scan error: %v", err)
-		}
+	if err != nil {
+		return fmt.Errorf("saga event log error: %v", err)
+	}
 
-		// Deserialization
-		if err := json.Unmarshal(contextJSON, &saga.Context); err != nil {
-			return nil, fmt.Errorf("context deserialization error: %v", err)
-		}
+	return nil
+}
 
-		if err := json.Unmarshal(stepsJSON, &saga.CompletedSteps); err != nil {
-			return nil, fmt.Errorf("steps deserialization error: %v", err)
-		}
+// CountStuckSagas returns the number of unfinished sagas that have not been updated since before
+func (r *SagaRepository) CountStuckSagas(before time.Time) (int, error) {
+	defer metrics.ObserveDBQuery("CountStuckSagas", time.Now())
 
-		if completedAt.Valid {
-			saga.CompletedAt = &completedAt.Time
-		}
+	query := `
+		SELECT COUNT(*)
+		FROM saga_instances
+		WHERE status IN ('started', 'in_progress', 'compensating', 'dispute_handling') AND updated_at < $1
+	`
 
-		sagas = append(sagas, saga)
+	var count int
+	if err := r.db.QueryRow(query, before).Scan(&count); err != nil {
+		return 0, fmt.Errorf("stuck saga count error: %v", err)
 	}
 
-	return sagas, nil
+	return count, nil
 }
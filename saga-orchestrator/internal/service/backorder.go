@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// StartBackorderSaga starts the saga shipping a backordered item once inventory
+// reports its stock arrived: the stock is reserved, the item is charged at the
+// price of the original order, shipped and the customer notified. The saga
+// uses the saga ID inventory chose, so inventory can match the reservation to
+// the backorder.
+func (s *SagaOrchestrator) StartBackorderSaga(ctx context.Context, event events.SagaEvent) error {
+	if _, err := s.sagaRepo.GetSagaByID(event.SagaID); err == nil {
+		slog.InfoContext(ctx, "Duplicate backorder ready event ignored", "saga_instance_id", event.SagaID)
+		return nil
+	}
+
+	data, err := json.Marshal(event.Payload)
+	if err != nil {
+		return fmt.Errorf("backorder payload marshal error: %v", err)
+	}
+	var payload events.InventoryBackorderReadyPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return fmt.Errorf("backorder payload conversion error: %v", err)
+	}
+	backorder := payload.Backorder
+
+	// Price and address are those the customer checked out with
+	orderSaga, err := s.sagaRepo.GetSagaByOrderID(event.OrderID)
+	if err != nil {
+		return fmt.Errorf("order saga of backorder %s not found: %v", backorder.ID, err)
+	}
+	var orderItems []types.OrderItem
+	if err := orderSaga.DecodeContext("items", &orderItems); err != nil {
+		return fmt.Errorf("order saga items decode error: %v", err)
+	}
+
+	var item *types.OrderItem
+	for i := range orderItems {
+		if orderItems[i].ProductID == backorder.ProductID {
+			item = &orderItems[i]
+			break
+		}
+	}
+	if item == nil {
+		return fmt.Errorf("backordered product %s is not part of order %s", backorder.ProductID, event.OrderID)
+	}
+
+	backorderItem := *item
+	backorderItem.Quantity = backorder.Quantity
+	amount, err := backorderItem.Price.Mul(int64(backorder.Quantity))
+	if err != nil {
+		return fmt.Errorf("backorder amount error: %v", err)
+	}
+
+	saga := &domain.SagaInstance{
+		ID:             event.SagaID,
+		Type:           domain.SagaTypeBackorder,
+		OrderID:        event.OrderID,
+		CustomerID:     orderSaga.CustomerID,
+		Status:         domain.SagaStatusStarted,
+		CurrentStep:    domain.StepBackorderReady,
+		CompletedSteps: []domain.SagaStep{domain.StepBackorderReady},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		CorrelationID:  event.CorrelationID,
+		Context: map[string]interface{}{
+			"backorder_id":     backorder.ID,
+			"product_id":       backorder.ProductID,
+			"quantity":         backorder.Quantity,
+			"order_saga_id":    orderSaga.ID,
+			"total_amount":     amount,
+			"items":            []types.OrderItem{backorderItem},
+			"shipping_address": orderSaga.Context["shipping_address"],
+			"retry_counts":     map[string]int{},
+		},
+	}
+
+	if err := s.sagaRepo.CreateSaga(saga); err != nil {
+		return err
+	}
+
+	metrics.SagasStarted.Inc()
+	slog.InfoContext(ctx, "Backorder saga started", "saga_instance_id", saga.ID,
+		"backorder_id", backorder.ID, "amount", amount)
+
+	return s.processNextStep(ctx, saga)
+}
+
+// recordItemOutcomes keeps what inventory reserved of each product. When items
+// came up short only the reserved units are shipped and captured; backordered
+// units are charged by their own sagas later.
+func recordItemOutcomes(saga *domain.SagaInstance, eventData map[string]interface{}) error {
+	saga.Context["item_outcomes"] = eventData["items"]
+	saga.Context["backorders"] = eventData["backorders"]
+
+	var outcomes []types.ItemOutcome
+	if err := saga.DecodeContext("item_outcomes", &outcomes); err != nil {
+		return fmt.Errorf("item outcomes decode error: %v", err)
+	}
+	reserved := map[uuid.UUID]int{}
+	short := false
+	for _, outcome := range outcomes {
+		reserved[outcome.ProductID] = outcome.Reserved
+		short = short || outcome.Short()
+	}
+	if !short {
+		return nil
+	}
+
+	var items []types.OrderItem
+	if err := saga.DecodeContext("items", &items); err != nil {
+		return fmt.Errorf("order items decode error: %v", err)
+	}
+	total, err := types.MoneyFromValue(saga.Context["total_amount"])
+	if err != nil {
+		return fmt.Errorf("order total decode error: %v", err)
+	}
+
+	// Lines of one product share its reserved units, in order
+	captureAmount := types.NewMoney(0, total.Currency)
+	var shippingItems []types.OrderItem
+	for _, item := range items {
+		quantity := min(item.Quantity, reserved[item.ProductID])
+		if quantity == 0 {
+			continue
+		}
+		reserved[item.ProductID] -= quantity
+
+		lineAmount, err := item.Price.Mul(int64(quantity))
+		if err != nil {
+			return fmt.Errorf("capture amount error: %v", err)
+		}
+		if captureAmount, err = captureAmount.Add(lineAmount); err != nil {
+			return fmt.Errorf("capture amount error: %v", err)
+		}
+
+		item.Quantity = quantity
+		shippingItems = append(shippingItems, item)
+	}
+
+	saga.Context["capture_amount"] = captureAmount
+	saga.Context["shipping_items"] = shippingItems
+	return nil
+}
+
+// shippingItems the items that actually ship, fewer than ordered when some
+// were short
+func shippingItems(saga *domain.SagaInstance) interface{} {
+	if items, ok := saga.Context["shipping_items"]; ok && items != nil {
+		return items
+	}
+	return saga.Context["items"]
+}
+
+// confirmationMessage tells the customer which items are not part of the
+// shipment and why
+func confirmationMessage(saga *domain.SagaInstance) string {
+	if saga.TypeOrDefault() == domain.SagaTypeBackorder {
+		return "Your backordered item is back in stock and on its way!"
+	}
+
+	var outcomes []types.ItemOutcome
+	if err := saga.DecodeContext("item_outcomes", &outcomes); err != nil {
+		slog.Warn("Item outcomes decode error", "saga_instance_id", saga.ID, "error", err)
+	}
+	backordered, unavailable := 0, 0
+	for _, outcome := range outcomes {
+		backordered += outcome.Backordered
+		unavailable += outcome.Unavailable
+	}
+
+	message := "Your order has been created successfully!"
+	if backordered > 0 {
+		message += fmt.Sprintf(" %d backordered item(s) will ship and be charged once back in stock.", backordered)
+	}
+	if unavailable > 0 {
+		message += fmt.Sprintf(" %d item(s) were out of stock and removed from your order.", unavailable)
+	}
+	return message
+}
+
+// orderCompletedPayload reports what the checkout saga reserved, backordered
+// and charged when items were short
+func orderCompletedPayload(saga *domain.SagaInstance) events.OrderCompletedPayload {
+	payload := events.OrderCompletedPayload{
+		OrderID: saga.OrderID,
+		Status:  "completed",
+	}
+
+	if err := saga.DecodeContext("item_outcomes", &payload.Items); err != nil {
+		slog.Warn("Item outcomes decode error", "saga_instance_id", saga.ID, "error", err)
+	}
+	if err := saga.DecodeContext("backorders", &payload.Backorders); err != nil {
+		slog.Warn("Backorders decode error", "saga_instance_id", saga.ID, "error", err)
+	}
+	if value, ok := saga.Context["capture_amount"]; ok && value != nil {
+		if captured, err := types.MoneyFromValue(value); err == nil {
+			payload.CapturedAmount = &captured
+		}
+	}
+	return payload
+}
+
+// backorderPayload the backorder a backorder saga shipped or gave up on
+func backorderPayload(saga *domain.SagaInstance) events.OrderBackorderPayload {
+	payload := events.OrderBackorderPayload{OrderID: saga.OrderID}
+	payload.BackorderID, _ = uuid.Parse(fmt.Sprint(saga.Context["backorder_id"]))
+	payload.ProductID, _ = uuid.Parse(fmt.Sprint(saga.Context["product_id"]))
+	if err := saga.DecodeContext("quantity", &payload.Quantity); err != nil {
+		slog.Warn("Backorder quantity decode error", "saga_instance_id", saga.ID, "error", err)
+	}
+	return payload
+}
+
+func (s *SagaOrchestrator) completeBackorder(ctx context.Context, saga *domain.SagaInstance) error {
+	payload := backorderPayload(saga)
+	if amount, err := types.MoneyFromValue(saga.Context["total_amount"]); err == nil {
+		payload.Amount = &amount
+	}
+
+	return s.publishBackorderOutcome(ctx, saga, events.OrderBackorderFulfilledEvent, payload)
+}
+
+// backorderCompensated reports a backorder saga that failed after reserving
+// its stock; the backorder is cancelled with the release. A saga that never
+// reserved left the backorder waiting for the next stock, so the order has
+// nothing to learn.
+func (s *SagaOrchestrator) backorderCompensated(ctx context.Context, saga *domain.SagaInstance) error {
+	if !saga.IsStepCompleted(domain.StepInventoryReserved) {
+		slog.InfoContext(ctx, "Backorder not reserved, waiting for more stock", "reason", saga.FailureReason)
+		return nil
+	}
+
+	payload := backorderPayload(saga)
+	payload.Reason = saga.FailureReason
+
+	return s.publishBackorderOutcome(ctx, saga, events.OrderBackorderFailedEvent, payload)
+}
+
+func (s *SagaOrchestrator) publishBackorderOutcome(ctx context.Context, saga *domain.SagaInstance, eventType events.SagaEventType, payload events.OrderBackorderPayload) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:            uuid.New(),
+		SagaID:        saga.ID,
+		OrderID:       saga.OrderID,
+		EventType:     eventType,
+		Service:       "saga-orchestrator",
+		Timestamp:     time.Now(),
+		CorrelationID: saga.CorrelationID,
+		Payload:       payload,
+	})
+
+	return s.publisher.PublishSagaEvent(ctx, event)
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/google/uuid"
+)
+
+// disputeStatusLost dispute status the payment service reports once the bank
+// decided for the customer
+const disputeStatusLost = "lost"
+
+// HandlePaymentDisputed tracks the dispute on the saga. A lost dispute of a
+// completed order starts the chargeback flow: the shipment is cancelled if it
+// has not left yet and the customer is notified.
+func (s *SagaOrchestrator) HandlePaymentDisputed(ctx context.Context, sagaID uuid.UUID, eventData map[string]interface{}) error {
+	saga, err := s.sagaRepo.GetSagaByID(sagaID)
+	if err != nil {
+		return fmt.Errorf("saga not found: %v", err)
+	}
+
+	status, _ := eventData["status"].(string)
+	saga.Context["dispute_id"] = eventData["dispute_id"]
+	saga.Context["dispute_status"] = status
+	saga.Context["dispute_reason"] = eventData["reason"]
+	saga.UpdatedAt = time.Now()
+
+	slog.InfoContext(ctx, "Payment dispute updated", "dispute_id", eventData["dispute_id"], "status", status)
+
+	if status != disputeStatusLost || saga.IsStepCompleted(domain.StepDisputeLost) {
+		return s.sagaRepo.UpdateSaga(saga)
+	}
+	if saga.Status != domain.SagaStatusCompleted {
+		slog.WarnContext(ctx, "Lost dispute on unfinished saga, no chargeback flow", "status", saga.Status)
+		return s.sagaRepo.UpdateSaga(saga)
+	}
+
+	saga.Status = domain.SagaStatusDisputeHandling
+	saga.FailureReason = "Payment dispute lost"
+	saga.MarkStepCompleted(domain.StepDisputeLost)
+	metrics.Compensations.WithLabelValues(string(domain.StepDisputeLost)).Inc()
+
+	slog.WarnContext(ctx, "Payment dispute lost, starting chargeback flow")
+
+	return s.processNextDisputeStep(ctx, saga)
+}
+
+// disputeReplySteps chargeback step completed by each reply
+var disputeReplySteps = map[events.SagaEventType]domain.SagaStep{
+	events.ShippingCancelledEvent:    domain.StepDisputeShippingCancelled,
+	events.ShippingCancelFailedEvent: domain.StepDisputeShippingKept,
+	events.NotificationSentEvent:     domain.StepDisputeCustomerNotified,
+}
+
+// handleDisputeReply applies a shipping or notification reply to the chargeback
+// flow. handled is false for replies the flow does not wait for.
+func (s *SagaOrchestrator) handleDisputeReply(ctx context.Context, saga *domain.SagaInstance, event events.SagaEvent) (handled bool, err error) {
+	step, ok := disputeReplySteps[event.EventType]
+	if !ok {
+		return false, nil
+	}
+
+	if saga.IsStepCompleted(step) {
+		slog.InfoContext(ctx, "Duplicate chargeback reply ignored", "step", step)
+		return true, nil
+	}
+
+	metrics.StepDuration.WithLabelValues(string(step), metrics.OutcomeSuccess).
+		Observe(time.Since(saga.UpdatedAt).Seconds())
+
+	if step == domain.StepDisputeShippingKept {
+		if payload, ok := event.Payload.(map[string]interface{}); ok {
+			saga.Context["dispute_shipping_error"] = payload["reason"]
+		}
+	}
+	saga.MarkStepCompleted(step)
+
+	slog.InfoContext(ctx, "Chargeback step completed", "step", step)
+
+	return true, s.processNextDisputeStep(ctx, saga)
+}
+
+func (s *SagaOrchestrator) processNextDisputeStep(ctx context.Context, saga *domain.SagaInstance) error {
+	nextStep := saga.GetNextDisputeStep()
+	if nextStep == "" {
+		return s.completeChargeback(ctx, saga)
+	}
+
+	saga.UpdatedAt = time.Now()
+	if err := s.sagaRepo.UpdateSaga(saga); err != nil {
+		return fmt.Errorf("saga chargeback update error: %v", err)
+	}
+
+	return s.sendDisputeStepEvent(ctx, saga, nextStep)
+}
+
+func (s *SagaOrchestrator) completeChargeback(ctx context.Context, saga *domain.SagaInstance) error {
+	saga.Status = domain.SagaStatusChargedBack
+	saga.UpdatedAt = time.Now()
+
+	if err := s.sagaRepo.UpdateSaga(saga); err != nil {
+		return fmt.Errorf("saga chargeback complete error: %v", err)
+	}
+	metrics.SagasFinished.WithLabelValues(string(domain.SagaStatusChargedBack)).Inc()
+
+	slog.InfoContext(ctx, "Chargeback flow completed",
+		"shipment_cancelled", saga.IsStepCompleted(domain.StepDisputeShippingCancelled))
+	return nil
+}
+
+func (s *SagaOrchestrator) sendDisputeStepEvent(ctx context.Context, saga *domain.SagaInstance, step domain.SagaStep) error {
+	var event events.SagaEvent
+
+	switch step {
+	case domain.StepDisputeShippingCancelled:
+		event = events.ReplyTo(ctx, events.SagaEvent{
+			ID:            uuid.New(),
+			SagaID:        saga.ID,
+			OrderID:       saga.OrderID,
+			EventType:     "shipping.cancel",
+			Service:       "saga-orchestrator",
+			Timestamp:     time.Now(),
+			CorrelationID: saga.CorrelationID,
+			Payload: map[string]interface{}{
+				"shipment_id": saga.Context["shipment_id"],
+				"reason":      saga.FailureReason,
+			},
+		})
+
+	case domain.StepDisputeCustomerNotified:
+		message := "Your bank decided your payment dispute in your favour and returned the payment. Your order has been cancelled."
+		if saga.IsStepCompleted(domain.StepDisputeShippingKept) {
+			message = "Your bank decided your payment dispute in your favour and returned the payment. " +
+				"Your order had already shipped, please contact support to arrange its return."
+		}
+
+		event = events.ReplyTo(ctx, events.SagaEvent{
+			ID:            uuid.New(),
+			SagaID:        saga.ID,
+			OrderID:       saga.OrderID,
+			EventType:     "notification.send",
+			Service:       "saga-orchestrator",
+			Timestamp:     time.Now(),
+			CorrelationID: saga.CorrelationID,
+			Payload: map[string]interface{}{
+				"order_id":    saga.OrderID,
+				"customer_id": saga.CustomerID,
+				"type":        "email",
+				"subject":     "Your order was cancelled after a payment dispute",
+				"message":     message,
+			},
+		})
+
+	default:
+		return fmt.Errorf("unknown chargeback step: %s", step)
+	}
+
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
+		return fmt.Errorf("chargeback event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Chargeback command sent", "step", step, "command", event.EventType)
+	return nil
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/domain"
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+// refundReplySteps refund step completed by each successful reply
+var refundReplySteps = map[events.SagaEventType]domain.SagaStep{
+	events.PaymentRefundedEvent:        domain.StepRefundPaymentRefunded,
+	events.InventoryRestockedEvent:     domain.StepRefundItemsRestocked,
+	events.InventoryRestockFailedEvent: domain.StepRefundRestockFailed,
+	events.NotificationSentEvent:       domain.StepRefundCustomerNotified,
+}
+
+// StartRefundSaga starts the refund saga of a completed order: the amount is
+// refunded to the customer, returned items are restocked and the customer is
+// notified. The saga uses the saga ID the order service chose, so the order
+// service can match the outcome to its refund.
+func (s *SagaOrchestrator) StartRefundSaga(ctx context.Context, event events.SagaEvent, payload map[string]interface{}) error {
+	if _, err := s.sagaRepo.GetSagaByID(event.SagaID); err == nil {
+		slog.InfoContext(ctx, "Duplicate refund request ignored", "saga_instance_id", event.SagaID)
+		return nil
+	}
+
+	customerID, _ := uuid.Parse(fmt.Sprint(payload["customer_id"]))
+	items, _ := payload["items"].([]interface{})
+
+	saga := &domain.SagaInstance{
+		ID:             event.SagaID,
+		Type:           domain.SagaTypeRefund,
+		OrderID:        event.OrderID,
+		CustomerID:     customerID,
+		Status:         domain.SagaStatusStarted,
+		CurrentStep:    domain.StepRefundRequested,
+		CompletedSteps: []domain.SagaStep{domain.StepRefundRequested},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		CorrelationID:  event.CorrelationID,
+		Context: map[string]interface{}{
+			"refund_id": payload["refund_id"],
+			"amount":    payload["amount"],
+			"items":     items,
+			"reason":    payload["reason"],
+		},
+	}
+
+	// The payment to refund is known to the order's checkout saga
+	orderSaga, err := s.sagaRepo.GetSagaByOrderID(event.OrderID)
+	failure := ""
+	switch {
+	case err != nil:
+		failure = fmt.Sprintf("Order saga not found: %v", err)
+	case orderSaga.Status != domain.SagaStatusCompleted:
+		failure = fmt.Sprintf("Order saga is %s, only completed orders can be refunded", orderSaga.Status)
+	default:
+		saga.Context["order_saga_id"] = orderSaga.ID
+		saga.Context["payment_id"] = orderSaga.Context["payment_id"]
+		saga.Context["transaction_id"] = orderSaga.Context["transaction_id"]
+		if saga.CustomerID == uuid.Nil {
+			saga.CustomerID = orderSaga.CustomerID
+		}
+	}
+
+	if err := s.sagaRepo.CreateSaga(saga); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Refund saga started", "saga_instance_id", saga.ID, "amount", payload["amount"])
+
+	if failure != "" {
+		return s.failRefund(ctx, saga, failure)
+	}
+	return s.processNextRefundStep(ctx, saga)
+}
+
+// handleRefundReply applies a reply to a refund saga. A failed payment refund
+// fails the saga; everything after the money went back only moves it forward.
+func (s *SagaOrchestrator) handleRefundReply(ctx context.Context, saga *domain.SagaInstance, event events.SagaEvent) error {
+	if saga.Status == domain.SagaStatusCompleted || saga.Status == domain.SagaStatusFailed {
+		slog.InfoContext(ctx, "Reply to finished refund saga ignored", "event_type", event.EventType)
+		return nil
+	}
+
+	payload, _ := event.Payload.(map[string]interface{})
+
+	if event.EventType == events.PaymentRefundFailedEvent {
+		metrics.StepDuration.WithLabelValues(string(domain.StepRefundPaymentRefunded), metrics.OutcomeFailure).
+			Observe(time.Since(saga.UpdatedAt).Seconds())
+		return s.failRefund(ctx, saga, fmt.Sprintf("Payment refund failed: %v", payload["reason"]))
+	}
+
+	step, ok := refundReplySteps[event.EventType]
+	if !ok {
+		slog.WarnContext(ctx, "Unexpected reply for refund saga", "event_type", event.EventType)
+		return nil
+	}
+	if saga.IsStepCompleted(step) {
+		slog.InfoContext(ctx, "Duplicate refund reply ignored", "step", step)
+		return nil
+	}
+
+	metrics.StepDuration.WithLabelValues(string(step), metrics.OutcomeSuccess).
+		Observe(time.Since(saga.UpdatedAt).Seconds())
+
+	switch step {
+	case domain.StepRefundPaymentRefunded:
+		saga.Context["refund_reference"] = payload["refund_reference"]
+		saga.Context["refunded_amount"] = payload["refunded_amount"]
+	case domain.StepRefundRestockFailed:
+		// The money is already back with the customer; stock is corrected by hand
+		saga.Context["restock_error"] = payload["reason"]
+		slog.WarnContext(ctx, "Refunded items could not be restocked", "reason", payload["reason"])
+	}
+	saga.MarkStepCompleted(step)
+
+	slog.InfoContext(ctx, "Refund step completed", "step", step)
+
+	return s.processNextRefundStep(ctx, saga)
+}
+
+func (s *SagaOrchestrator) processNextRefundStep(ctx context.Context, saga *domain.SagaInstance) error {
+	items, _ := saga.Context["items"].([]interface{})
+
+	nextStep := saga.GetNextRefundStep(len(items) > 0)
+	if nextStep == "" {
+		return s.completeRefund(ctx, saga)
+	}
+
+	saga.Status = domain.SagaStatusInProgress
+	saga.UpdatedAt = time.Now()
+
+	if err := s.sagaRepo.UpdateSaga(saga); err != nil {
+		return fmt.Errorf("refund saga update error: %v", err)
+	}
+
+	return s.sendRefundStepEvent(ctx, saga, nextStep)
+}
+
+func (s *SagaOrchestrator) completeRefund(ctx context.Context, saga *domain.SagaInstance) error {
+	saga.Status = domain.SagaStatusCompleted
+	now := time.Now()
+	saga.UpdatedAt = now
+	saga.CompletedAt = &now
+
+	if err := s.sagaRepo.UpdateSaga(saga); err != nil {
+		return fmt.Errorf("refund saga completion error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Refund saga completed", "restocked", saga.IsStepCompleted(domain.StepRefundItemsRestocked))
+
+	return s.publishRefundOutcome(ctx, saga, events.OrderRefundCompletedEvent, map[string]interface{}{
+		"refund_id":        saga.Context["refund_id"],
+		"order_id":         saga.OrderID,
+		"amount":           saga.Context["amount"],
+		"refund_reference": saga.Context["refund_reference"],
+		"restocked":        saga.IsStepCompleted(domain.StepRefundItemsRestocked),
+		"restock_error":    saga.Context["restock_error"],
+	})
+}
+
+func (s *SagaOrchestrator) failRefund(ctx context.Context, saga *domain.SagaInstance, reason string) error {
+	saga.Status = domain.SagaStatusFailed
+	saga.FailureReason = reason
+	now := time.Now()
+	saga.UpdatedAt = now
+	saga.CompletedAt = &now
+
+	if err := s.sagaRepo.UpdateSaga(saga); err != nil {
+		return fmt.Errorf("refund saga failure update error: %v", err)
+	}
+
+	slog.WarnContext(ctx, "Refund saga failed", "reason", reason)
+
+	return s.publishRefundOutcome(ctx, saga, events.OrderRefundFailedEvent, map[string]interface{}{
+		"refund_id": saga.Context["refund_id"],
+		"order_id":  saga.OrderID,
+		"reason":    reason,
+	})
+}
+
+func (s *SagaOrchestrator) publishRefundOutcome(ctx context.Context, saga *domain.SagaInstance, eventType events.SagaEventType, payload map[string]interface{}) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:            uuid.New(),
+		SagaID:        saga.ID,
+		OrderID:       saga.OrderID,
+		EventType:     eventType,
+		Service:       "saga-orchestrator",
+		Timestamp:     time.Now(),
+		CorrelationID: saga.CorrelationID,
+		Payload:       payload,
+	})
+
+	return s.publisher.PublishSagaEvent(ctx, event)
+}
+
+func (s *SagaOrchestrator) sendRefundStepEvent(ctx context.Context, saga *domain.SagaInstance, step domain.SagaStep) error {
+	var event events.SagaEvent
+
+	switch step {
+	case domain.StepRefundPaymentRefunded:
+		event = events.ReplyTo(ctx, events.SagaEvent{
+			ID:            uuid.New(),
+			SagaID:        saga.ID,
+			OrderID:       saga.OrderID,
+			EventType:     "payment.refund",
+			Service:       "saga-orchestrator",
+			Timestamp:     time.Now(),
+			CorrelationID: saga.CorrelationID,
+			Payload: map[string]interface{}{
+				"payment_id":     saga.Context["payment_id"],
+				"transaction_id": saga.Context["transaction_id"],
+				"amount":         saga.Context["amount"],
+				"reason":         saga.Context["reason"],
+			},
+		})
+
+	case domain.StepRefundItemsRestocked:
+		event = events.ReplyTo(ctx, events.SagaEvent{
+			ID:            uuid.New(),
+			SagaID:        saga.ID,
+			OrderID:       saga.OrderID,
+			EventType:     "inventory.restock",
+			Service:       "saga-orchestrator",
+			Timestamp:     time.Now(),
+			CorrelationID: saga.CorrelationID,
+			Payload: map[string]interface{}{
+				"order_id": saga.OrderID,
+				"items":    saga.Context["items"],
+			},
+		})
+
+	case domain.StepRefundCustomerNotified:
+		message := "We have refunded your order."
+		if amount, err := types.MoneyFromValue(saga.Context["amount"]); err == nil {
+			message = fmt.Sprintf("We have refunded %s of your order to your original payment method.", amount)
+		}
+
+		event = events.ReplyTo(ctx, events.SagaEvent{
+			ID:            uuid.New(),
+			SagaID:        saga.ID,
+			OrderID:       saga.OrderID,
+			EventType:     "notification.send",
+			Service:       "saga-orchestrator",
+			Timestamp:     time.Now(),
+			CorrelationID: saga.CorrelationID,
+			Payload: map[string]interface{}{
+				"order_id":    saga.OrderID,
+				"customer_id": saga.CustomerID,
+				"type":        "email",
+				"subject":     "Your refund has been issued",
+				"message":     message,
+			},
+		})
+
+	default:
+		return fmt.Errorf("unknown refund step: %s", step)
+	}
+
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
+		return fmt.Errorf("refund event publish error: %v", err)
+	}
+
+	slog.InfoContext(ctx, "Refund command sent", "step", step, "command", event.EventType)
+	return nil
+}
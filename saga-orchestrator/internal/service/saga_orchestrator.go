@@ -1,15 +1,17 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/domain"
 	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/repository"
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/google/uuid"
 )
@@ -26,7 +28,11 @@ func NewSagaOrchestrator(sagaRepo *repository.SagaRepository, publisher *messagi
 	}
 }
 
-func (s *SagaOrchestrator) StartSaga(order types.Order) error {
+func (s *SagaOrchestrator) StartSaga(ctx context.Context, order types.Order) error {
+	if err := order.ValidateCurrency(); err != nil {
+		return fmt.Errorf("invalid order %s: %w", order.ID, err)
+	}
+
 	sagaID := uuid.New()
 
 	saga := &domain.SagaInstance{
@@ -39,26 +45,34 @@ func (s *SagaOrchestrator) StartSaga(order types.Order) error {
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 		Context: map[string]interface{}{
-			"order":        order,
-			"total_amount": order.TotalAmount,
-			"items":        order.Items,
-			"retry_counts": map[string]int{},
+			"order":              order,
+			"total_amount":       order.TotalAmount,
+			"items":              order.Items,
+			"shipping_address":   order.ShippingAddress,
+			"fulfillment_policy": order.FulfillmentPolicy.OrDefault(),
+			"retry_counts":       map[string]int{},
 		},
 	}
 
+	// Every event of the saga keeps the correlation ID of the OrderCreatedEvent
+	if cause, ok := events.CauseFromContext(ctx); ok {
+		saga.CorrelationID = cause.CorrelationID
+	}
+
 	if err := s.sagaRepo.CreateSaga(saga); err != nil {
 		return err
 	}
 
-	log.Printf("Saga started: SagaID=%s, OrderID=%s", sagaID, order.ID)
+	metrics.SagasStarted.Inc()
+	slog.InfoContext(ctx, "Saga started", "saga_instance_id", sagaID)
 
-	return s.processNextStep(saga)
+	return s.processNextStep(ctx, saga)
 }
 
-func (s *SagaOrchestrator) processNextStep(saga *domain.SagaInstance) error {
+func (s *SagaOrchestrator) processNextStep(ctx context.Context, saga *domain.SagaInstance) error {
 	nextStep := saga.GetNextStep()
 	if nextStep == "" {
-		return s.completeSaga(saga)
+		return s.completeSaga(ctx, saga)
 	}
 
 	saga.Status = domain.SagaStatusInProgress
@@ -68,24 +82,56 @@ func (s *SagaOrchestrator) processNextStep(saga *domain.SagaInstance) error {
 		return err
 	}
 
-	return s.sendStepEvent(saga, nextStep)
+	return s.sendStepEvent(ctx, saga, nextStep)
 
 }
 
-func (s *SagaOrchestrator) HandleCompensationSuccess(sagaID uuid.UUID, completedCompensation domain.SagaStep) error {
+func (s *SagaOrchestrator) HandleCompensationSuccess(ctx context.Context, sagaID uuid.UUID, completedCompensation domain.SagaStep) error {
 	saga, err := s.sagaRepo.GetSagaByID(sagaID)
 	if err != nil {
 		return err
 	}
 
+	metrics.StepDuration.WithLabelValues(string(completedCompensation), metrics.OutcomeSuccess).
+		Observe(time.Since(saga.UpdatedAt).Seconds())
+
 	saga.MarkCompensationCompleted(completedCompensation)
 
-	log.Printf("✅ Compensation step completed: %s", completedCompensation)
+	slog.InfoContext(ctx, "Compensation step completed", "step", completedCompensation)
+
+	return s.startCompensation(ctx, saga)
+}
+
+// HandleVoidFailed records that the authorization of a compensating saga could
+// not be voided. Voiding is not retried: the provider refused it or the payment
+// can no longer be voided. The remaining compensation runs and the saga ends
+// failed instead of compensated, so ops release the hold by hand.
+func (s *SagaOrchestrator) HandleVoidFailed(ctx context.Context, sagaID uuid.UUID, eventData map[string]interface{}) error {
+	saga, err := s.sagaRepo.GetSagaByID(sagaID)
+	if err != nil {
+		return err
+	}
+
+	if saga.Status != domain.SagaStatusCompensating || saga.IsCompensationCompleted(domain.StepPaymentVoided) ||
+		saga.IsCompensationCompleted(domain.StepPaymentVoidFailed) {
+		slog.InfoContext(ctx, "Void failure for saga not voiding", "status", saga.Status)
+		return nil
+	}
+
+	metrics.StepDuration.WithLabelValues(string(domain.StepPaymentVoided), metrics.OutcomeFailure).
+		Observe(time.Since(saga.UpdatedAt).Seconds())
+
+	reason, _ := eventData["reason"].(string)
+	saga.Context["void_error"] = reason
+	saga.MarkCompensationCompleted(domain.StepPaymentVoidFailed)
 
-	return s.startCompensation(saga)
+	slog.ErrorContext(ctx, "Payment void failed, the authorization is still held",
+		"payment_id", saga.Context["payment_id"], "authorization_id", saga.Context["authorization_id"], "reason", reason)
+
+	return s.startCompensation(ctx, saga)
 }
 
-func (s *SagaOrchestrator) completeSaga(saga *domain.SagaInstance) error {
+func (s *SagaOrchestrator) completeSaga(ctx context.Context, saga *domain.SagaInstance) error {
 	saga.Status = domain.SagaStatusCompleted
 	saga.UpdatedAt = time.Now()
 	now := time.Now()
@@ -94,40 +140,57 @@ func (s *SagaOrchestrator) completeSaga(saga *domain.SagaInstance) error {
 	if err := s.sagaRepo.UpdateSaga(saga); err != nil {
 		return fmt.Errorf("saga completion error: %v", err)
 	}
+	metrics.SagasFinished.WithLabelValues(string(domain.SagaStatusCompleted)).Inc()
+
+	slog.InfoContext(ctx, "Saga completed successfully")
 
-	log.Printf("Saga completed successfully: SagaID=%s, OrderID=%s", saga.ID, saga.OrderID)
+	if saga.TypeOrDefault() == domain.SagaTypeBackorder {
+		return s.completeBackorder(ctx, saga)
+	}
 
-	event := events.SagaEvent{
+	event := events.ReplyTo(ctx, events.SagaEvent{
 		ID:            uuid.New(),
 		SagaID:        saga.ID,
 		OrderID:       saga.OrderID,
 		EventType:     events.OrderCompletedEvent,
 		Service:       "saga-orchestrator",
 		Timestamp:     time.Now(),
-		CorrelationID: uuid.New(),
-		Payload: events.OrderCompletedPayload{
-			OrderID: saga.OrderID,
-			Status:  "completed",
-		},
-	}
+		CorrelationID: saga.CorrelationID,
+		Payload:       orderCompletedPayload(saga),
+	})
 
-	return s.publisher.PublishSagaEvent(event)
+	return s.publisher.PublishSagaEvent(ctx, event)
 }
 
-func (s *SagaOrchestrator) ProcessIncomingEvent(event events.SagaEvent) error {
-	log.Printf("🚨 DEBUG: ProcessIncomingEvent called with event: %+v", event)
-	log.Printf("Event received: %s from %s", event.EventType, event.Service)
+func (s *SagaOrchestrator) ProcessIncomingEvent(ctx context.Context, event events.SagaEvent) error {
+	// Low stock is reported to ops; it names the saga that caused it but is no reply
+	if event.EventType == events.InventoryLowStockEvent {
+		return nil
+	}
+
+	// These start a new saga, there is nothing to verify against yet
+	if event.EventType != events.OrderCreatedEvent && event.EventType != events.OrderRefundRequestedEvent &&
+		event.EventType != events.InventoryBackorderReadyEvent {
+		accepted, err := s.verifyCorrelation(ctx, event)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return nil
+		}
+
+		if handled, err := s.routeFollowUpReply(ctx, event); handled {
+			return err
+		}
+	}
 
 	// Event type'a göre işle
 	switch event.EventType {
 
 	// Order created event - start new saga
 	case events.OrderCreatedEvent:
-		log.Printf("🎯 Processing OrderCreatedEvent: %+v", event)
 		if payload, ok := event.Payload.(map[string]interface{}); ok {
-			log.Printf("📦 Payload is map: %+v", payload)
 			if orderData, exists := payload["order"]; exists {
-				log.Printf("📋 Order data found: %+v", orderData)
 				// Convert map to Order struct
 				orderBytes, err := json.Marshal(orderData)
 				if err != nil {
@@ -137,64 +200,110 @@ func (s *SagaOrchestrator) ProcessIncomingEvent(event events.SagaEvent) error {
 				if err := json.Unmarshal(orderBytes, &order); err != nil {
 					return fmt.Errorf("order data conversion error: %v", err)
 				}
-				log.Printf("✅ Starting saga for order: %s", order.ID)
-				return s.StartSaga(order)
+				return s.StartSaga(ctx, order)
 			} else {
-				log.Printf("❌ Order data not found in payload")
+				slog.ErrorContext(ctx, "Order data not found in payload")
 			}
 		} else {
-			log.Printf("❌ Payload is not map, type: %T, value: %+v", event.Payload, event.Payload)
+			slog.ErrorContext(ctx, "Unexpected payload type", "type", fmt.Sprintf("%T", event.Payload))
 		}
 		return fmt.Errorf("invalid order created event payload")
 
+	// Merchant refund of a completed order - start refund saga
+	case events.OrderRefundRequestedEvent:
+		payload, ok := event.Payload.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid refund requested event payload")
+		}
+		return s.StartRefundSaga(ctx, event, payload)
+
+	// Stock arrived for a backorder - start backorder saga
+	case events.InventoryBackorderReadyEvent:
+		return s.StartBackorderSaga(ctx, event)
+
 	// Success events
+	case events.PaymentAuthorizedEvent:
+		return s.HandleStepSuccess(ctx, event.SagaID, domain.StepPaymentAuthorized,
+			event.Payload.(map[string]interface{}))
+
+	case events.PaymentCapturedEvent:
+		return s.HandleStepSuccess(ctx, event.SagaID, domain.StepPaymentCaptured,
+			event.Payload.(map[string]interface{}))
+
 	case events.PaymentProcessedEvent:
-		return s.HandleStepSuccess(event.SagaID, domain.StepPaymentProcessed,
+		return s.HandleStepSuccess(ctx, event.SagaID, domain.StepPaymentProcessed,
 			event.Payload.(map[string]interface{}))
 
 	case events.InventoryReservedEvent:
-		return s.HandleStepSuccess(event.SagaID, domain.StepInventoryReserved,
+		return s.HandleStepSuccess(ctx, event.SagaID, domain.StepInventoryReserved,
 			event.Payload.(map[string]interface{}))
 
 	case events.ShippingCreatedEvent:
-		return s.HandleStepSuccess(event.SagaID, domain.StepShippingCreated,
+		return s.HandleStepSuccess(ctx, event.SagaID, domain.StepShippingCreated,
 			event.Payload.(map[string]interface{}))
 
 	case events.NotificationSentEvent:
-		return s.HandleStepSuccess(event.SagaID, domain.StepNotificationSent,
+		return s.HandleStepSuccess(ctx, event.SagaID, domain.StepNotificationSent,
 			event.Payload.(map[string]interface{}))
 
 	// Failure events
 	case events.PaymentFailedEvent:
-		return s.HandleStepFailure(event.SagaID, domain.StepPaymentProcessed,
+		return s.HandleStepFailure(ctx, event.SagaID, domain.StepPaymentAuthorized,
+			event.Payload.(map[string]interface{}))
+
+	case events.PaymentCaptureFailedEvent:
+		return s.HandleStepFailure(ctx, event.SagaID, domain.StepPaymentCaptured,
 			event.Payload.(map[string]interface{}))
 
 	case events.InventoryFailedEvent:
-		return s.HandleStepFailure(event.SagaID, domain.StepInventoryReserved,
+		return s.HandleStepFailure(ctx, event.SagaID, domain.StepInventoryReserved,
 			event.Payload.(map[string]interface{}))
 
+	// Reported by the inventory sweeper, not a reply to a command
+	case events.InventoryReservationExpiredEvent:
+		return s.HandleReservationExpired(ctx, event.SagaID, event.Payload.(map[string]interface{}))
+
+	// Reported after completion
+	case events.PaymentDisputedEvent:
+		return s.HandlePaymentDisputed(ctx, event.SagaID, event.Payload.(map[string]interface{}))
+
+	case events.ShippingCancelFailedEvent:
+		slog.WarnContext(ctx, "Shipping cancellation failed", "payload", event.Payload)
+		return nil
+
 	// COMPENSATION SUCCESS EVENTS
 	case events.ShippingCancelledEvent:
-		return s.HandleCompensationSuccess(event.SagaID, domain.StepShippingCancelled)
+		return s.HandleCompensationSuccess(ctx, event.SagaID, domain.StepShippingCancelled)
 
 	case events.InventoryReleasedEvent:
-		return s.HandleCompensationSuccess(event.SagaID, domain.StepInventoryReleased)
+		return s.HandleCompensationSuccess(ctx, event.SagaID, domain.StepInventoryReleased)
 
 	case events.PaymentRefundedEvent:
-		return s.HandleCompensationSuccess(event.SagaID, domain.StepPaymentRefunded)
+		return s.HandleCompensationSuccess(ctx, event.SagaID, domain.StepPaymentRefunded)
+
+	case events.PaymentVoidedEvent:
+		return s.HandleCompensationSuccess(ctx, event.SagaID, domain.StepPaymentVoided)
+
+	case events.PaymentVoidFailedEvent:
+		return s.HandleVoidFailed(ctx, event.SagaID, event.Payload.(map[string]interface{}))
 
 	default:
-		log.Printf("Unknown event type: %s", event.EventType)
+		slog.WarnContext(ctx, "Unknown event type")
 		return nil
 	}
 }
 
-func (s *SagaOrchestrator) HandleStepFailure(sagaID uuid.UUID, failedStep domain.SagaStep, eventData map[string]interface{}) error {
+func (s *SagaOrchestrator) HandleStepFailure(ctx context.Context, sagaID uuid.UUID, failedStep domain.SagaStep, eventData map[string]interface{}) error {
 	saga, err := s.sagaRepo.GetSagaByID(sagaID)
 	if err != nil {
 		return fmt.Errorf("saga not found: %v", err)
 	}
 
+	// UpdatedAt was set when the step command was sent
+	metrics.StepDuration.WithLabelValues(string(failedStep), metrics.OutcomeFailure).
+		Observe(time.Since(saga.UpdatedAt).Seconds())
+	metrics.Compensations.WithLabelValues(string(failedStep)).Inc()
+
 	// Failure reason'u kaydet
 	if reason, ok := eventData["reason"].(string); ok {
 		saga.FailureReason = reason
@@ -203,54 +312,100 @@ func (s *SagaOrchestrator) HandleStepFailure(sagaID uuid.UUID, failedStep domain
 	saga.Status = domain.SagaStatusCompensating
 	saga.UpdatedAt = time.Now()
 
-	log.Printf("Step failure: %s for SagaID=%s, compensation could not start", failedStep, sagaID)
+	slog.WarnContext(ctx, "Step failed, starting compensation", "step", failedStep, "reason", saga.FailureReason)
 
-	return s.startCompensation(saga)
+	return s.startCompensation(ctx, saga)
 }
 
-func (s *SagaOrchestrator) HandleStepSuccess(sagaID uuid.UUID, completedStep domain.SagaStep, eventData map[string]interface{}) error {
+// HandleReservationExpired compensates a saga that was still running when its
+// stock reservation expired. The stock is already released, so the release
+// compensation finds nothing left to do. Finished sagas ignore the event.
+func (s *SagaOrchestrator) HandleReservationExpired(ctx context.Context, sagaID uuid.UUID, eventData map[string]interface{}) error {
 	saga, err := s.sagaRepo.GetSagaByID(sagaID)
 	if err != nil {
 		return fmt.Errorf("saga not found: %v", err)
 	}
 
+	if saga.Status != domain.SagaStatusStarted && saga.Status != domain.SagaStatusInProgress {
+		slog.InfoContext(ctx, "Reservation expired for saga no longer running", "status", saga.Status)
+		return nil
+	}
+
+	metrics.Compensations.WithLabelValues(string(domain.StepInventoryReserved)).Inc()
+
+	saga.FailureReason = "Inventory reservation expired"
+	if reason, ok := eventData["reason"].(string); ok {
+		saga.FailureReason = reason
+	}
+
+	saga.Status = domain.SagaStatusCompensating
+	saga.UpdatedAt = time.Now()
+
+	slog.WarnContext(ctx, "Inventory reservation expired, starting compensation",
+		"reservation_ids", eventData["reservation_ids"])
+
+	return s.startCompensation(ctx, saga)
+}
+
+func (s *SagaOrchestrator) HandleStepSuccess(ctx context.Context, sagaID uuid.UUID, completedStep domain.SagaStep, eventData map[string]interface{}) error {
+	saga, err := s.sagaRepo.GetSagaByID(sagaID)
+	if err != nil {
+		return fmt.Errorf("saga not found: %v", err)
+	}
+
+	metrics.StepDuration.WithLabelValues(string(completedStep), metrics.OutcomeSuccess).
+		Observe(time.Since(saga.UpdatedAt).Seconds())
+
 	saga.MarkStepCompleted(completedStep)
 
 	if eventData != nil {
 		switch completedStep {
+		case domain.StepPaymentAuthorized:
+			saga.Context["payment_id"] = eventData["payment_id"]
+			saga.Context["authorization_id"] = eventData["authorization_id"]
+		case domain.StepPaymentCaptured:
+			saga.Context["transaction_id"] = eventData["transaction_id"]
 		case domain.StepPaymentProcessed:
 			saga.Context["payment_id"] = eventData["payment_id"]
 			saga.Context["transaction_id"] = eventData["transaction_id"]
 		case domain.StepInventoryReserved:
 			saga.Context["reservation_ids"] = eventData["reservation_ids"]
+			saga.Context["origins"] = eventData["origins"] // Warehouses shipping the order
+			if err := recordItemOutcomes(saga, eventData); err != nil {
+				return err
+			}
 		case domain.StepShippingCreated:
 			saga.Context["shipment_id"] = eventData["shipment_id"]
 			saga.Context["tracking_id"] = eventData["tracking_id"]
 		}
 	}
 
-	log.Printf("Step completed: %s for SagaID=%s", completedStep, sagaID)
+	slog.InfoContext(ctx, "Step completed", "step", completedStep)
 
-	return s.processNextStep(saga)
+	return s.processNextStep(ctx, saga)
 }
 
-func (s *SagaOrchestrator) startCompensation(saga *domain.SagaInstance) error {
+func (s *SagaOrchestrator) startCompensation(ctx context.Context, saga *domain.SagaInstance) error {
 	compensationStep := saga.GetCompensationStep()
 	if compensationStep == "" {
-		return s.compensationCompleted(saga)
+		return s.compensationCompleted(ctx, saga)
 	}
 
-	log.Printf("Compensation started: %s for SagaID=%s", compensationStep, saga.ID)
+	slog.InfoContext(ctx, "Compensation started", "step", compensationStep)
 
 	if err := s.sagaRepo.UpdateSaga(saga); err != nil {
 		return fmt.Errorf("saga compensation update error: %v", err)
 	}
 
-	return s.sendCompensationEvent(saga, compensationStep)
+	return s.sendCompensationEvent(ctx, saga, compensationStep)
 }
 
-func (s *SagaOrchestrator) compensationCompleted(saga *domain.SagaInstance) error {
+func (s *SagaOrchestrator) compensationCompleted(ctx context.Context, saga *domain.SagaInstance) error {
+	// An authorization that is still held needs ops, the order is cancelled all the same
 	saga.Status = domain.SagaStatusCompensated
+	if saga.IsCompensationCompleted(domain.StepPaymentVoidFailed) {
+		saga.Status = domain.SagaStatusFailed
+	}
 	saga.UpdatedAt = time.Now()
 	now := time.Now()
 	saga.CompletedAt = &now
@@ -258,181 +413,273 @@ func (s *SagaOrchestrator) compensationCompleted(saga *domain.SagaInstance) erro
 	if err := s.sagaRepo.UpdateSaga(saga); err != nil {
 		return fmt.Errorf("saga compensation complete error: %v", err)
 	}
+	metrics.SagasFinished.WithLabelValues(string(saga.Status)).Inc()
 
-	log.Printf("Saga compensation completed: SagaID=%s, OrderID=%s", saga.ID, saga.OrderID)
+	if saga.Status == domain.SagaStatusFailed {
+		slog.ErrorContext(ctx, "Saga compensation finished, the payment authorization needs to be released by hand",
+			"authorization_id", saga.Context["authorization_id"], "void_error", saga.Context["void_error"])
+	} else {
+		slog.InfoContext(ctx, "Saga compensation completed")
+	}
 
-	event := events.SagaEvent{
+	if saga.TypeOrDefault() == domain.SagaTypeBackorder {
+		return s.backorderCompensated(ctx, saga)
+	}
+
+	event := events.ReplyTo(ctx, events.SagaEvent{
 		ID:            uuid.New(),
 		SagaID:        saga.ID,
 		OrderID:       saga.OrderID,
 		EventType:     events.OrderCancelledEvent,
 		Service:       "saga-orchestrator",
 		Timestamp:     time.Now(),
-		CorrelationID: uuid.New(),
+		CorrelationID: saga.CorrelationID,
 		Payload: map[string]interface{}{
 			"order_id": saga.OrderID,
 			"reason":   saga.FailureReason,
 		},
-	}
+	})
 
-	return s.publisher.PublishSagaEvent(event)
+	return s.publisher.PublishSagaEvent(ctx, event)
 }
 
-func (s *SagaOrchestrator) sendCompensationEvent(saga *domain.SagaInstance, compensationStep domain.SagaStep) error {
+func (s *SagaOrchestrator) sendCompensationEvent(ctx context.Context, saga *domain.SagaInstance, compensationStep domain.SagaStep) error {
 	var event events.SagaEvent
 
 	switch compensationStep {
 	case domain.StepShippingCancelled:
-		event = events.SagaEvent{
+		event = events.ReplyTo(ctx, events.SagaEvent{
 			ID:            uuid.New(),
 			SagaID:        saga.ID,
 			OrderID:       saga.OrderID,
 			EventType:     "shipping.cancel",
 			Service:       "saga-orchestrator",
 			Timestamp:     time.Now(),
-			CorrelationID: uuid.New(),
+			CorrelationID: saga.CorrelationID,
 			Payload: map[string]interface{}{
 				"shipment_id": saga.Context["shipment_id"],
 				"reason":      saga.FailureReason,
 			},
-		}
+		})
 
 	case domain.StepInventoryReleased:
-		event = events.SagaEvent{
+		event = events.ReplyTo(ctx, events.SagaEvent{
 			ID:            uuid.New(),
 			SagaID:        saga.ID,
 			OrderID:       saga.OrderID,
 			EventType:     "inventory.release",
 			Service:       "saga-orchestrator",
 			Timestamp:     time.Now(),
-			CorrelationID: uuid.New(),
+			CorrelationID: saga.CorrelationID,
 			Payload: map[string]interface{}{
 				"reservation_ids": saga.Context["reservation_ids"],
 				"reason":          saga.FailureReason,
 			},
-		}
+		})
 
 	case domain.StepPaymentRefunded:
-		event = events.SagaEvent{
+		event = events.ReplyTo(ctx, events.SagaEvent{
 			ID:            uuid.New(),
 			SagaID:        saga.ID,
 			OrderID:       saga.OrderID,
 			EventType:     "payment.refund",
 			Service:       "saga-orchestrator",
 			Timestamp:     time.Now(),
-			CorrelationID: uuid.New(),
+			CorrelationID: saga.CorrelationID,
 			Payload: map[string]interface{}{
 				"payment_id":     saga.Context["payment_id"],
 				"transaction_id": saga.Context["transaction_id"],
 				"amount":         saga.Context["total_amount"],
 				"reason":         saga.FailureReason,
 			},
-		}
+		})
+
+	case domain.StepPaymentVoided:
+		event = events.ReplyTo(ctx, events.SagaEvent{
+			ID:            uuid.New(),
+			SagaID:        saga.ID,
+			OrderID:       saga.OrderID,
+			EventType:     "payment.void",
+			Service:       "saga-orchestrator",
+			Timestamp:     time.Now(),
+			CorrelationID: saga.CorrelationID,
+			Payload: map[string]interface{}{
+				"payment_id":       saga.Context["payment_id"],
+				"authorization_id": saga.Context["authorization_id"],
+				"reason":           saga.FailureReason,
+			},
+		})
 
 	case domain.StepOrderCancelled:
-		event = events.SagaEvent{
+		event = events.ReplyTo(ctx, events.SagaEvent{
 			ID:            uuid.New(),
 			SagaID:        saga.ID,
 			OrderID:       saga.OrderID,
 			EventType:     "order.cancel",
 			Service:       "saga-orchestrator",
 			Timestamp:     time.Now(),
-			CorrelationID: uuid.New(),
+			CorrelationID: saga.CorrelationID,
 			Payload: map[string]interface{}{
 				"order_id": saga.OrderID,
 				"reason":   saga.FailureReason,
 			},
-		}
+		})
 
 	default:
 		return fmt.Errorf("unknown compensation step: %s", compensationStep)
 	}
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("compensation event publish error: %v", err)
 	}
 
-	log.Printf("Compensation event is sent: %s -> %s", compensationStep, event.EventType)
+	slog.InfoContext(ctx, "Compensation command sent", "step", compensationStep, "command", event.EventType)
 	return nil
 }
 
-func (s *SagaOrchestrator) sendStepEvent(saga *domain.SagaInstance, step domain.SagaStep) error {
+func (s *SagaOrchestrator) sendStepEvent(ctx context.Context, saga *domain.SagaInstance, step domain.SagaStep) error {
 	var event events.SagaEvent
 
 	switch step {
-	case domain.StepPaymentProcessed:
-		event = events.SagaEvent{
+	case domain.StepPaymentAuthorized:
+		event = events.ReplyTo(ctx, events.SagaEvent{
 			ID:            uuid.New(),
 			SagaID:        saga.ID,
 			OrderID:       saga.OrderID,
-			EventType:     "payment.process",
+			EventType:     "payment.authorize",
 			Service:       "saga-orchestrator",
 			Timestamp:     time.Now(),
-			CorrelationID: uuid.New(),
+			CorrelationID: saga.CorrelationID,
 			Payload: map[string]interface{}{
 				"order_id":       saga.OrderID,
 				"customer_id":    saga.CustomerID,
 				"amount":         saga.Context["total_amount"],
 				"payment_method": "credit_card",
 			},
-		}
+		})
 
 	case domain.StepInventoryReserved:
-		event = events.SagaEvent{
+		event = events.ReplyTo(ctx, events.SagaEvent{
 			ID:            uuid.New(),
 			SagaID:        saga.ID,
 			OrderID:       saga.OrderID,
 			EventType:     "inventory.reserve",
 			Service:       "saga-orchestrator",
 			Timestamp:     time.Now(),
-			CorrelationID: uuid.New(),
+			CorrelationID: saga.CorrelationID,
 			Payload: map[string]interface{}{
-				"order_id": saga.OrderID,
-				"items":    saga.Context["items"],
+				"order_id":           saga.OrderID,
+				"items":              saga.Context["items"],
+				"shipping_address":   saga.Context["shipping_address"],
+				"fulfillment_policy": saga.Context["fulfillment_policy"],
+				"backorder_id":       saga.Context["backorder_id"], // Backorder sagas only
 			},
-		}
+		})
 
 	case domain.StepShippingCreated:
-		event = events.SagaEvent{
+		event = events.ReplyTo(ctx, events.SagaEvent{
 			ID:            uuid.New(),
 			SagaID:        saga.ID,
 			OrderID:       saga.OrderID,
 			EventType:     "shipping.create",
 			Service:       "saga-orchestrator",
 			Timestamp:     time.Now(),
-			CorrelationID: uuid.New(),
+			CorrelationID: saga.CorrelationID,
 			Payload: map[string]interface{}{
 				"order_id":    saga.OrderID,
 				"customer_id": saga.CustomerID,
-				"items":       saga.Context["items"],
+				"items":       shippingItems(saga),
+				"address":     saga.Context["shipping_address"],
+				"origins":     saga.Context["origins"], // One shipment per origin warehouse
 			},
-		}
+		})
+
+	case domain.StepPaymentCaptured:
+		event = events.ReplyTo(ctx, events.SagaEvent{
+			ID:            uuid.New(),
+			SagaID:        saga.ID,
+			OrderID:       saga.OrderID,
+			EventType:     "payment.capture",
+			Service:       "saga-orchestrator",
+			Timestamp:     time.Now(),
+			CorrelationID: saga.CorrelationID,
+			Payload: map[string]interface{}{
+				"payment_id":       saga.Context["payment_id"],
+				"authorization_id": saga.Context["authorization_id"],
+				"amount":           saga.Context["capture_amount"], // Less than authorized when items were short
+			},
+		})
 
 	case domain.StepNotificationSent:
-		event = events.SagaEvent{
+		event = events.ReplyTo(ctx, events.SagaEvent{
 			ID:            uuid.New(),
 			SagaID:        saga.ID,
 			OrderID:       saga.OrderID,
 			EventType:     "notification.send",
 			Service:       "saga-orchestrator",
 			Timestamp:     time.Now(),
-			CorrelationID: uuid.New(),
+			CorrelationID: saga.CorrelationID,
 			Payload: map[string]interface{}{
 				"order_id":    saga.OrderID,
 				"customer_id": saga.CustomerID,
 				"type":        "order_confirmation",
-				"message":     "Your order has been created successfully!",
+				"message":     confirmationMessage(saga),
 			},
-		}
+		})
 
 	default:
 		return fmt.Errorf("unknown step: %s", step)
 	}
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("step event publish error: %v", err)
 	}
 
-	log.Printf("Step event sent: %s -> %s", step, event.EventType)
+	slog.InfoContext(ctx, "Step command sent", "step", step, "command", event.EventType)
 	return nil
 }
+
+// routeFollowUpReply hands replies of a refund saga or of a chargeback flow to
+// their own handlers; the step flow of an order saga gets everything else
+func (s *SagaOrchestrator) routeFollowUpReply(ctx context.Context, event events.SagaEvent) (handled bool, err error) {
+	saga, err := s.sagaRepo.GetSagaByID(event.SagaID)
+	if err != nil {
+		return false, nil
+	}
+
+	switch {
+	case saga.TypeOrDefault() == domain.SagaTypeRefund:
+		return true, s.handleRefundReply(ctx, saga, event)
+	case saga.Status == domain.SagaStatusDisputeHandling:
+		return s.handleDisputeReply(ctx, saga, event)
+	default:
+		return false, nil
+	}
+}
+
+// verifyCorrelation checks that a reply belongs to the saga it claims to answer.
+// Replies with a foreign correlation ID are recorded in saga_event_log and dropped,
+// since redelivering them would not change the outcome.
+func (s *SagaOrchestrator) verifyCorrelation(ctx context.Context, event events.SagaEvent) (bool, error) {
+	saga, err := s.sagaRepo.GetSagaByID(event.SagaID)
+	if err != nil {
+		return false, fmt.Errorf("saga not found: %v", err)
+	}
+
+	// Sagas started before correlation tracking cannot be verified
+	if saga.CorrelationID == uuid.Nil || event.CorrelationID == saga.CorrelationID {
+		if err := s.sagaRepo.LogEvent(event, ""); err != nil {
+			slog.ErrorContext(ctx, "Saga event log error", "error", err)
+		}
+		return true, nil
+	}
+
+	reason := fmt.Sprintf("correlation mismatch: expected %s, got %s", saga.CorrelationID, event.CorrelationID)
+	slog.WarnContext(ctx, "Event rejected", "from", event.Service, "reason", reason)
+
+	if err := s.sagaRepo.LogEvent(event, reason); err != nil {
+		slog.ErrorContext(ctx, "Saga event log error", "error", err)
+	}
+
+	return false, nil
+}
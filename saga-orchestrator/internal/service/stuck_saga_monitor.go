@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/saga-orchestrator/internal/repository"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+)
+
+// StuckSagaMonitor periodically exports the number of sagas that made no progress
+// within threshold as the saga_sagas_stuck gauge.
+type StuckSagaMonitor struct {
+	sagaRepo  *repository.SagaRepository
+	threshold time.Duration
+	interval  time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewStuckSagaMonitor(sagaRepo *repository.SagaRepository, threshold, interval time.Duration) *StuckSagaMonitor {
+	return &StuckSagaMonitor{
+		sagaRepo:  sagaRepo,
+		threshold: threshold,
+		interval:  interval,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (m *StuckSagaMonitor) Start() {
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			m.check()
+
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the monitor loop and waits for a running check to finish
+func (m *StuckSagaMonitor) Stop(ctx context.Context) error {
+	close(m.stop)
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *StuckSagaMonitor) check() {
+	count, err := m.sagaRepo.CountStuckSagas(time.Now().Add(-m.threshold))
+	if err != nil {
+		slog.Error("Stuck saga check error", "error", err)
+		return
+	}
+
+	metrics.SagasStuck.Set(float64(count))
+	if count > 0 {
+		slog.Warn("Sagas without progress", "count", count, "threshold", m.threshold)
+	}
+}
@@ -0,0 +1,11 @@
+-- Correlation ID of the OrderCreatedEvent that started the saga
+ALTER TABLE saga_instances ADD COLUMN IF NOT EXISTS correlation_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_saga_instances_correlation_id ON saga_instances(correlation_id);
+
+-- Causation chain and rejected events (correlation mismatch)
+ALTER TABLE saga_event_log ADD COLUMN IF NOT EXISTS causation_id UUID;
+ALTER TABLE saga_event_log ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_saga_event_log_rejected ON saga_event_log(saga_id)
+    WHERE rejection_reason IS NOT NULL;
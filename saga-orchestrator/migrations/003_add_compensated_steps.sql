@@ -0,0 +1,2 @@
+-- Compensation progress, so a multi-step compensation resumes where it left off
+ALTER TABLE saga_instances ADD COLUMN IF NOT EXISTS compensated_steps JSONB NOT NULL DEFAULT '[]';
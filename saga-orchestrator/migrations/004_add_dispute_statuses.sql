@@ -0,0 +1,11 @@
+-- Post-completion chargeback flow: a completed saga whose payment dispute is lost
+-- moves to dispute_handling and ends as charged_back
+ALTER TABLE saga_instances DROP CONSTRAINT IF EXISTS saga_instances_status_check;
+ALTER TABLE saga_instances ADD CONSTRAINT saga_instances_status_check CHECK (status IN (
+    'started', 'in_progress', 'completed', 'failed', 'compensating', 'compensated',
+    'dispute_handling', 'charged_back'
+));
+
+DROP INDEX IF EXISTS idx_saga_instances_in_progress;
+CREATE INDEX IF NOT EXISTS idx_saga_instances_in_progress ON saga_instances(status, created_at)
+    WHERE status IN ('started', 'in_progress', 'compensating', 'dispute_handling');
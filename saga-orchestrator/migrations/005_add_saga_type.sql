@@ -0,0 +1,8 @@
+-- Refund sagas run against an already completed order, so an order can now have
+-- several sagas: exactly one checkout saga and any number of refund sagas
+ALTER TABLE saga_instances ADD COLUMN IF NOT EXISTS saga_type VARCHAR(20) NOT NULL DEFAULT 'order'
+    CHECK (saga_type IN ('order', 'refund'));
+
+ALTER TABLE saga_instances DROP CONSTRAINT IF EXISTS saga_instances_order_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_saga_instances_order_saga ON saga_instances(order_id)
+    WHERE saga_type = 'order';
@@ -0,0 +1,5 @@
+-- Backorder sagas ship and charge items that were out of stock at checkout, so
+-- an order can have any number of them next to its checkout saga
+ALTER TABLE saga_instances DROP CONSTRAINT IF EXISTS saga_instances_saga_type_check;
+ALTER TABLE saga_instances ADD CONSTRAINT saga_instances_saga_type_check
+    CHECK (saga_type IN ('order', 'refund', 'backorder'));
@@ -24,13 +24,21 @@ CREATE TABLE IF NOT EXISTS orders (
     id UUID PRIMARY KEY,
     customer_id UUID NOT NULL,
     items JSONB NOT NULL,
-    total_amount DECIMAL(10,2) NOT NULL CHECK (total_amount >= 0),
+    total_amount BIGINT NOT NULL CHECK (total_amount >= 0),
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
     status VARCHAR(20) NOT NULL CHECK (status IN (
         'pending', 'processing', 'completed', 'cancelled', 'failed'
     )),
     shipping_address JSONB NOT NULL,
     saga_id UUID,
+    correlation_id UUID,
     failure_reason TEXT,
+    priced_at TIMESTAMP WITH TIME ZONE,
+    fulfillment_policy VARCHAR(20) NOT NULL DEFAULT 'all_or_nothing'
+        CHECK (fulfillment_policy IN ('all_or_nothing', 'allow_partial', 'allow_backorder')),
+    fulfillment JSONB,
+    backorders JSONB,
+    captured_amount BIGINT CHECK (captured_amount >= 0),
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
 );
@@ -38,44 +46,179 @@ CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id, created
 CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
 CREATE INDEX IF NOT EXISTS idx_orders_saga_id ON orders(saga_id) WHERE saga_id IS NOT NULL;
 
+-- Merchant initiated refunds of completed orders, each run by a refund saga
+CREATE TABLE IF NOT EXISTS order_refunds (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id),
+    saga_id UUID NOT NULL UNIQUE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    items JSONB NOT NULL DEFAULT '[]',
+    reason TEXT,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('requested', 'completed', 'failed')),
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_order_refunds_order_id ON order_refunds(order_id, created_at);
+
 \c payment_db;
 -- Payments table
 CREATE TABLE IF NOT EXISTS payments (
     id UUID PRIMARY KEY,
-    order_id UUID NOT NULL UNIQUE,
+    order_id UUID NOT NULL,
     customer_id UUID NOT NULL,
     saga_id UUID NOT NULL,
-    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
     payment_method VARCHAR(50) NOT NULL,
     status VARCHAR(20) NOT NULL CHECK (status IN (
-        'pending', 'completed', 'failed', 'refunded'
+        'pending', 'authorized', 'completed', 'failed', 'refunded', 'voided'
     )),
     transaction_id VARCHAR(255),
+    authorization_id VARCHAR(255),
     external_ref VARCHAR(255),
     failure_reason TEXT,
-    refunded_amount DECIMAL(10,2) NOT NULL DEFAULT 0.00 CHECK (refunded_amount >= 0),
+    refunded_amount BIGINT NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0),
     refund_reference VARCHAR(255),
+    settlement_amount BIGINT,
+    settlement_currency CHAR(3),
+    exchange_rate NUMERIC(20,10),
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    authorized_at TIMESTAMP WITH TIME ZONE,
     processed_at TIMESTAMP WITH TIME ZONE,
-    refunded_at TIMESTAMP WITH TIME ZONE
+    refunded_at TIMESTAMP WITH TIME ZONE,
+    voided_at TIMESTAMP WITH TIME ZONE,
+    reconciled_at TIMESTAMP WITH TIME ZONE,
+    dispute_id VARCHAR(255),
+    dispute_status VARCHAR(20),
+    dispute_reason VARCHAR(100),
+    dispute_amount BIGINT,
+    disputed_at TIMESTAMP WITH TIME ZONE,
+    dispute_evidence JSONB,
+    dispute_evidence_submitted_at TIMESTAMP WITH TIME ZONE,
+    dispute_closed_at TIMESTAMP WITH TIME ZONE,
+    correlation_id UUID
 );
 CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
 CREATE INDEX IF NOT EXISTS idx_payments_saga_id ON payments(saga_id);
 CREATE INDEX IF NOT EXISTS idx_payments_status ON payments(status);
+CREATE INDEX IF NOT EXISTS idx_payments_external_ref ON payments(external_ref) WHERE external_ref IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_payments_authorization_id ON payments(authorization_id) WHERE authorization_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_payments_dispute_id ON payments(dispute_id) WHERE dispute_id IS NOT NULL;
 ALTER TABLE payments ADD CONSTRAINT chk_refunded_amount_limit CHECK (refunded_amount <= amount);
 
+-- Saga commands keyed by saga and step, so redeliveries replay the recorded outcome
+CREATE TABLE IF NOT EXISTS payment_commands (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    saga_id UUID NOT NULL,
+    command VARCHAR(50) NOT NULL,
+    payment_id UUID,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'completed')),
+    result_event JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_payment_commands_saga_id ON payment_commands(saga_id);
+CREATE INDEX IF NOT EXISTS idx_payment_commands_payment_id ON payment_commands(payment_id);
+
+-- Reconciliation reports for finance, one entry per payment checked against the gateway
+CREATE INDEX IF NOT EXISTS idx_payments_unreconciled ON payments(status, updated_at) WHERE reconciled_at IS NULL;
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id UUID PRIMARY KEY,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    checked INTEGER NOT NULL DEFAULT 0,
+    corrected INTEGER NOT NULL DEFAULT 0,
+    discrepancies INTEGER NOT NULL DEFAULT 0,
+    unresolved INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_runs_started_at ON reconciliation_runs(started_at);
+CREATE TABLE IF NOT EXISTS reconciliation_entries (
+    id BIGSERIAL PRIMARY KEY,
+    run_id UUID NOT NULL REFERENCES reconciliation_runs(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    saga_id UUID NOT NULL,
+    local_status VARCHAR(20) NOT NULL,
+    provider_status VARCHAR(50),
+    action VARCHAR(50) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    detail TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_entries_run_id ON reconciliation_entries(run_id);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_entries_payment_id ON reconciliation_entries(payment_id);
+
+-- Provider webhook events, applied once each
+CREATE TABLE IF NOT EXISTS provider_webhook_events (
+    event_id VARCHAR(255) PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    payment_id UUID,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'processed')),
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Refund history, payments.refunded_amount is the running total
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id UUID PRIMARY KEY,
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    saga_id UUID,
+    refund_reference VARCHAR(255),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    reason TEXT,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('saga', 'provider')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_payment_id ON payment_refunds(payment_id, created_at);
+
 \c inventory_db;
+-- Warehouses stock is kept at and shipped from
+CREATE TABLE IF NOT EXISTS warehouses (
+    id UUID PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    street VARCHAR(255) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    state VARCHAR(100) NOT NULL DEFAULT '',
+    zip_code VARCHAR(20) NOT NULL DEFAULT '',
+    country VARCHAR(100) NOT NULL,
+    shipping_cost BIGINT NOT NULL DEFAULT 0 CHECK (shipping_cost >= 0), -- Per shipment, minor units
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE, -- Receives stock that names no warehouse
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_default ON warehouses(is_default) WHERE is_default;
+
+-- Insert sample warehouses (shipping costs in cents)
+INSERT INTO warehouses (id, code, name, street, city, state, zip_code, country, shipping_cost, is_default) VALUES
+    ('660e8400-e29b-41d4-a716-446655440001', 'MAIN', 'Main Warehouse', '1 Warehouse Way', 'New York', 'NY', '10001', 'US', 500, TRUE),
+    ('660e8400-e29b-41d4-a716-446655440002', 'WEST', 'West Coast Warehouse', '200 Harbor Blvd', 'Los Angeles', 'CA', '90021', 'US', 700, FALSE)
+ON CONFLICT (id) DO NOTHING;
+
 -- Products and inventory reservations
 CREATE TABLE IF NOT EXISTS products (
     id UUID PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
-    price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
+    sku VARCHAR(64) NOT NULL,
+    price BIGINT NOT NULL CHECK (price >= 0),
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
     stock INTEGER NOT NULL CHECK (stock >= 0),
     reserved_stock INTEGER NOT NULL DEFAULT 0 CHECK (reserved_stock >= 0),
+    sold_stock INTEGER NOT NULL DEFAULT 0 CHECK (sold_stock >= 0),
+    -- Ops are alerted when available stock falls below it, 0 never alerts
+    low_stock_threshold INTEGER NOT NULL DEFAULT 0 CHECK (low_stock_threshold >= 0),
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
 );
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products(sku);
+CREATE INDEX IF NOT EXISTS idx_products_name ON products(name) WHERE deleted_at IS NULL;
 
 CREATE TABLE IF NOT EXISTS inventory_reservations (
     id UUID PRIMARY KEY,
@@ -84,29 +227,146 @@ CREATE TABLE IF NOT EXISTS inventory_reservations (
     saga_id UUID NOT NULL,
     quantity INTEGER NOT NULL CHECK (quantity > 0),
     status VARCHAR(20) NOT NULL CHECK (status IN (
-        'available', 'reserved', 'released', 'sold'
+        'available', 'reserved', 'released', 'sold', 'expired'
     )),
     reserved_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    correlation_id UUID,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id)
 );
 CREATE INDEX IF NOT EXISTS idx_reservations_saga_id ON inventory_reservations(saga_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_expires ON inventory_reservations(expires_at) WHERE status = 'reserved';
+CREATE INDEX IF NOT EXISTS idx_reservations_product_id ON inventory_reservations(product_id, reserved_at);
+CREATE INDEX IF NOT EXISTS idx_reservations_order_id ON inventory_reservations(order_id);
 ALTER TABLE products ADD CONSTRAINT chk_reserved_stock_limit CHECK (reserved_stock <= stock);
 
--- Insert sample products
-INSERT INTO products (id, name, price, stock) VALUES 
-    ('550e8400-e29b-41d4-a716-446655440001', 'Laptop Pro 15', 1299.99, 50),
-    ('550e8400-e29b-41d4-a716-446655440002', 'Wireless Mouse', 49.99, 100),
-    ('550e8400-e29b-41d4-a716-446655440003', 'USB-C Hub', 79.99, 75),
-    ('550e8400-e29b-41d4-a716-446655440004', 'Mechanical Keyboard', 159.99, 30),
-    ('550e8400-e29b-41d4-a716-446655440005', 'Monitor 24 inch', 299.99, 25)
+-- Items returned by a refund saga, restocked once per saga and product
+CREATE TABLE IF NOT EXISTS inventory_restocks (
+    id UUID PRIMARY KEY,
+    saga_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (saga_id, product_id)
+);
+CREATE INDEX IF NOT EXISTS idx_inventory_restocks_order_id ON inventory_restocks(order_id);
+
+-- Manual stock changes with their reason code
+CREATE TABLE IF NOT EXISTS stock_adjustments (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id),
+    warehouse_id UUID REFERENCES warehouses(id),
+    quantity INTEGER NOT NULL CHECK (quantity <> 0),
+    reason VARCHAR(20) NOT NULL CHECK (reason IN (
+        'received', 'returned', 'damaged', 'lost', 'recount', 'correction'
+    )),
+    note TEXT,
+    stock_after INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_stock_adjustments_product_id ON stock_adjustments(product_id, created_at);
+
+-- Append-only ledger of every change to a product's stock levels
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id UUID PRIMARY KEY,
+    sequence BIGSERIAL NOT NULL UNIQUE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    warehouse_id UUID REFERENCES warehouses(id),
+    type VARCHAR(20) NOT NULL CHECK (type IN (
+        'opening', 'create', 'reserve', 'release', 'expire', 'sell', 'adjust', 'restock'
+    )),
+    saga_id UUID,
+    order_id UUID,
+    reference_id UUID, -- Reservation or stock adjustment
+    stock_delta INTEGER NOT NULL,
+    reserved_delta INTEGER NOT NULL,
+    sold_delta INTEGER NOT NULL,
+    stock_after INTEGER NOT NULL,
+    reserved_after INTEGER NOT NULL,
+    sold_after INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_id ON stock_movements(product_id, sequence);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_saga_id ON stock_movements(saga_id) WHERE saga_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_movements_order_id ON stock_movements(order_id) WHERE order_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_movements_warehouse_id ON stock_movements(warehouse_id, sequence) WHERE warehouse_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION stock_movements_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_stock_movements_append_only ON stock_movements;
+CREATE TRIGGER trg_stock_movements_append_only
+    BEFORE UPDATE OR DELETE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION stock_movements_append_only();
+
+-- Stock levels per warehouse; they add up to the levels in products
+CREATE TABLE IF NOT EXISTS warehouse_stock (
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+    product_id UUID NOT NULL REFERENCES products(id),
+    stock INTEGER NOT NULL DEFAULT 0,
+    reserved_stock INTEGER NOT NULL DEFAULT 0,
+    sold_stock INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (warehouse_id, product_id),
+    CHECK (stock >= 0 AND sold_stock >= 0 AND reserved_stock >= 0 AND reserved_stock <= stock)
+);
+CREATE INDEX IF NOT EXISTS idx_warehouse_stock_product_id ON warehouse_stock(product_id);
+
+-- Units of allow_backorder orders that were out of stock. Waiting backorders
+-- of a product are handed to follow-up sagas oldest first as stock arrives.
+CREATE TABLE IF NOT EXISTS backorders (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    order_saga_id UUID NOT NULL,
+    correlation_id UUID,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL CHECK (status IN (
+        'waiting', 'processing', 'reserved', 'fulfilled', 'cancelled'
+    )),
+    saga_id UUID, -- Follow-up saga, set once stock arrived
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_backorders_product_id ON backorders(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_backorders_waiting ON backorders(product_id, created_at) WHERE status = 'waiting';
+CREATE INDEX IF NOT EXISTS idx_backorders_saga_id ON backorders(saga_id) WHERE saga_id IS NOT NULL;
+
+-- Insert sample products (prices in cents)
+INSERT INTO products (id, name, sku, price, stock) VALUES 
+    ('550e8400-e29b-41d4-a716-446655440001', 'Laptop Pro 15', 'LAPTOP-PRO-15', 129999, 50),
+    ('550e8400-e29b-41d4-a716-446655440002', 'Wireless Mouse', 'MOUSE-WIRELESS', 4999, 100),
+    ('550e8400-e29b-41d4-a716-446655440003', 'USB-C Hub', 'HUB-USBC', 7999, 75),
+    ('550e8400-e29b-41d4-a716-446655440004', 'Mechanical Keyboard', 'KEYBOARD-MECH', 15999, 30),
+    ('550e8400-e29b-41d4-a716-446655440005', 'Monitor 24 inch', 'MONITOR-24', 29999, 25)
 ON CONFLICT (id) DO NOTHING;
 
+-- The sample products are kept at the main warehouse
+INSERT INTO warehouse_stock (warehouse_id, product_id, stock, reserved_stock, sold_stock)
+SELECT '660e8400-e29b-41d4-a716-446655440001', id, stock, reserved_stock, sold_stock
+FROM products
+ON CONFLICT (warehouse_id, product_id) DO NOTHING;
+
+-- Levels of the sample products open the ledger
+INSERT INTO stock_movements (
+    id, product_id, warehouse_id, type, stock_delta, reserved_delta, sold_delta,
+    stock_after, reserved_after, sold_after
+)
+SELECT gen_random_uuid(), p.id, '660e8400-e29b-41d4-a716-446655440001', 'opening',
+    p.stock, p.reserved_stock, p.sold_stock, p.stock, p.reserved_stock, p.sold_stock
+FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.product_id = p.id);
+
 \c shipping_db;
 -- Shipments table
 CREATE TABLE IF NOT EXISTS shipments (
     id UUID PRIMARY KEY,
-    order_id UUID NOT NULL UNIQUE,
+    order_id UUID NOT NULL, -- One shipment per origin warehouse
     customer_id UUID NOT NULL,
     saga_id UUID NOT NULL,
     status VARCHAR(20) NOT NULL CHECK (status IN (
@@ -114,6 +374,7 @@ CREATE TABLE IF NOT EXISTS shipments (
     )),
     tracking_id VARCHAR(255) NOT NULL,
     address JSONB NOT NULL,
+    origin JSONB, -- Warehouse and items, NULL for single shipments
     failure_reason TEXT,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
@@ -140,22 +401,49 @@ CREATE TABLE IF NOT EXISTS notifications (
 CREATE INDEX IF NOT EXISTS idx_notifications_order_id ON notifications(order_id);
 CREATE INDEX IF NOT EXISTS idx_notifications_saga_id ON notifications(saga_id);
 
+-- Alerts to the operations team; repeats within the debounce window are counted
+CREATE TABLE IF NOT EXISTS ops_alerts (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(30) NOT NULL CHECK (kind IN ('low_stock')),
+    alert_key VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'sent', 'failed')),
+    subject VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    suppressed INTEGER NOT NULL DEFAULT 0 CHECK (suppressed >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_ops_alerts_kind_key ON ops_alerts(kind, alert_key, created_at);
+
+CREATE TABLE IF NOT EXISTS ops_alert_windows (
+    kind VARCHAR(30) NOT NULL,
+    alert_key VARCHAR(100) NOT NULL,
+    alert_id UUID NOT NULL REFERENCES ops_alerts(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (kind, alert_key)
+);
+
 \c orchestrator_db;
 -- Saga instances and event log
 CREATE TABLE IF NOT EXISTS saga_instances (
     id UUID PRIMARY KEY,
-    order_id UUID NOT NULL UNIQUE,
+    saga_type VARCHAR(20) NOT NULL DEFAULT 'order' CHECK (saga_type IN ('order', 'refund', 'backorder')),
+    order_id UUID NOT NULL,
     customer_id UUID NOT NULL,
     status VARCHAR(20) NOT NULL CHECK (status IN (
-        'started', 'in_progress', 'completed', 'failed', 'compensating', 'compensated'
+        'started', 'in_progress', 'completed', 'failed', 'compensating', 'compensated',
+        'dispute_handling', 'charged_back'
     )),
     current_step VARCHAR(50) NOT NULL,
     completed_steps JSONB NOT NULL DEFAULT '[]',
+    compensated_steps JSONB NOT NULL DEFAULT '[]',
     failure_reason TEXT,
     context JSONB NOT NULL DEFAULT '{}',
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-    completed_at TIMESTAMP WITH TIME ZONE
+    completed_at TIMESTAMP WITH TIME ZONE,
+    correlation_id UUID
 );
 
 CREATE TABLE IF NOT EXISTS saga_event_log (
@@ -165,8 +453,12 @@ CREATE TABLE IF NOT EXISTS saga_event_log (
     event_data JSONB NOT NULL,
     service_name VARCHAR(50) NOT NULL,
     timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-    correlation_id UUID NOT NULL
+    correlation_id UUID NOT NULL,
+    causation_id UUID,
+    rejection_reason TEXT
 );
 CREATE INDEX IF NOT EXISTS idx_saga_instances_order_id ON saga_instances(order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_saga_instances_order_saga ON saga_instances(order_id) WHERE saga_type = 'order';
 CREATE INDEX IF NOT EXISTS idx_saga_instances_status ON saga_instances(status);
+CREATE INDEX IF NOT EXISTS idx_saga_instances_correlation_id ON saga_instances(correlation_id);
 CREATE INDEX IF NOT EXISTS idx_saga_event_log_saga_id ON saga_event_log(saga_id, timestamp);
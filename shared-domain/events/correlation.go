@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type causeKey struct{}
+
+// WithCause returns a copy of ctx carrying the event that is currently being handled.
+// The consumer stores every incoming event so replies can be linked to it.
+func WithCause(ctx context.Context, event SagaEvent) context.Context {
+	return context.WithValue(ctx, causeKey{}, event)
+}
+
+// CauseFromContext returns the incoming event stored with WithCause
+func CauseFromContext(ctx context.Context) (SagaEvent, bool) {
+	event, ok := ctx.Value(causeKey{}).(SagaEvent)
+	return event, ok
+}
+
+// ReplyTo links event to the incoming event carried by ctx.
+// The incoming event ID becomes the causation ID and the correlation ID of the
+// original OrderCreatedEvent is preserved, unless the caller already set one.
+// Without an incoming event a new correlation chain is started.
+func ReplyTo(ctx context.Context, event SagaEvent) SagaEvent {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if cause, ok := CauseFromContext(ctx); ok {
+		event.CausationID = cause.ID
+		if event.CorrelationID == uuid.Nil {
+			event.CorrelationID = cause.CorrelationID
+		}
+	}
+
+	if event.CorrelationID == uuid.Nil {
+		event.CorrelationID = event.ID
+	}
+
+	return event
+}
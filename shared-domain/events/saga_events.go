@@ -14,21 +14,47 @@ const (
 	OrderCreatedEvent   SagaEventType = "order.created"
 	OrderCompletedEvent SagaEventType = "order.completed"
 	OrderCancelledEvent SagaEventType = "order.cancelled"
+	// Refund of a completed order, requested by the merchant
+	OrderRefundRequestedEvent SagaEventType = "order.refund_requested"
+	OrderRefundCompletedEvent SagaEventType = "order.refund_completed"
+	OrderRefundFailedEvent    SagaEventType = "order.refund_failed"
+	// Outcome of the follow-up saga shipping a backordered item
+	OrderBackorderFulfilledEvent SagaEventType = "order.backorder_fulfilled"
+	OrderBackorderFailedEvent    SagaEventType = "order.backorder_failed"
 
 	// Payment Events
-	PaymentProcessedEvent SagaEventType = "payment.processed"
-	PaymentFailedEvent    SagaEventType = "payment.failed"
-	PaymentRefundedEvent  SagaEventType = "payment.refunded"
+	PaymentProcessedEvent     SagaEventType = "payment.processed"
+	PaymentFailedEvent        SagaEventType = "payment.failed"
+	PaymentRefundedEvent      SagaEventType = "payment.refunded"
+	PaymentRefundFailedEvent  SagaEventType = "payment.refund.failed"
+	PaymentAuthorizedEvent    SagaEventType = "payment.authorized"
+	PaymentCapturedEvent      SagaEventType = "payment.captured"
+	PaymentCaptureFailedEvent SagaEventType = "payment.capture_failed"
+	PaymentVoidedEvent        SagaEventType = "payment.voided"
+	PaymentVoidFailedEvent    SagaEventType = "payment.void.failed" // Authorization still held
+	// Reported by the provider after the saga step finished
+	PaymentDisputedEvent         SagaEventType = "payment.disputed"
+	PaymentProviderRefundedEvent SagaEventType = "payment.provider_refunded" // Refund issued outside any saga
 
 	// Inventory Events
 	InventoryReservedEvent SagaEventType = "inventory.reserved"
 	InventoryFailedEvent   SagaEventType = "inventory.failed"
 	InventoryReleasedEvent SagaEventType = "inventory.released"
+	// Items returned by a refund go back on the shelf
+	InventoryRestockedEvent     SagaEventType = "inventory.restocked"
+	InventoryRestockFailedEvent SagaEventType = "inventory.restock.failed"
+	// Reservation held past its expiry, the stock was released
+	InventoryReservationExpiredEvent SagaEventType = "inventory.reservation_expired"
+	// Stock arrived for a backorder, starts its follow-up saga
+	InventoryBackorderReadyEvent SagaEventType = "inventory.backorder_ready"
+	// Available stock fell below the product's threshold; for ops, not a saga reply
+	InventoryLowStockEvent SagaEventType = "inventory.low_stock"
 
 	// Shipping Events
-	ShippingCreatedEvent   SagaEventType = "shipping.created"
-	ShippingFailedEvent    SagaEventType = "shipping.failed"
-	ShippingCancelledEvent SagaEventType = "shipping.cancelled"
+	ShippingCreatedEvent      SagaEventType = "shipping.created"
+	ShippingFailedEvent       SagaEventType = "shipping.failed"
+	ShippingCancelledEvent    SagaEventType = "shipping.cancelled"
+	ShippingCancelFailedEvent SagaEventType = "shipping.cancel.failed" // Shipment already on its way
 
 	// Notification Events
 	NotificationSentEvent   SagaEventType = "notification.sent"
@@ -43,7 +69,8 @@ type SagaEvent struct {
 	Payload       interface{}   `json:"payload"`
 	Timestamp     time.Time     `json:"timestamp"`
 	Service       string        `json:"service"`        // Hangi servisten geldi
-	CorrelationID uuid.UUID     `json:"correlation_id"` // Event tracking
+	CorrelationID uuid.UUID     `json:"correlation_id"` // Same for every event of an order's saga
+	CausationID   uuid.UUID     `json:"causation_id"`   // ID of the event that triggered this one
 }
 
 type OrderCreatedPayload struct {
@@ -53,20 +80,81 @@ type OrderCreatedPayload struct {
 type OrderCompletedPayload struct {
 	OrderID uuid.UUID `json:"order_id"`
 	Status  string    `json:"status"`
+	// Items per product outcome, empty when everything was reserved
+	Items      []types.ItemOutcome `json:"items,omitempty"`
+	Backorders []types.Backorder   `json:"backorders,omitempty"`
+	// CapturedAmount charged now, less than the total when items were short
+	CapturedAmount *types.Money `json:"captured_amount,omitempty"`
+}
+
+type OrderBackorderPayload struct {
+	OrderID     uuid.UUID    `json:"order_id"`
+	BackorderID uuid.UUID    `json:"backorder_id"`
+	ProductID   uuid.UUID    `json:"product_id"`
+	Quantity    int          `json:"quantity"`
+	Amount      *types.Money `json:"amount,omitempty"` // Charged, fulfilled backorders only
+	Reason      string       `json:"reason,omitempty"` // Failed backorders only
 }
 
 type PaymentProcessedPayload struct {
 	Payment types.Payment `json:"payment"`
 }
 
+type PaymentAuthorizedPayload struct {
+	PaymentID       uuid.UUID   `json:"payment_id"`
+	AuthorizationID string      `json:"authorization_id"`
+	Amount          types.Money `json:"amount"`
+}
+
+type PaymentCapturedPayload struct {
+	PaymentID     uuid.UUID   `json:"payment_id"`
+	TransactionID string      `json:"transaction_id"`
+	Amount        types.Money `json:"amount"`
+}
+
+type PaymentVoidedPayload struct {
+	PaymentID       uuid.UUID `json:"payment_id"`
+	AuthorizationID string    `json:"authorization_id"`
+	Reason          string    `json:"reason"`
+}
+
+type PaymentDisputedPayload struct {
+	PaymentID uuid.UUID   `json:"payment_id"`
+	DisputeID string      `json:"dispute_id"`
+	Status    string      `json:"status"`
+	Reason    string      `json:"reason,omitempty"`
+	Amount    types.Money `json:"amount"`
+}
+
 type PaymentFailedPayload struct {
-	OrderID uuid.UUID `json:"order_id"`
-	Reason  string    `json:"reason"`
-	Amount  float64   `json:"amount"`
+	OrderID uuid.UUID   `json:"order_id"`
+	Reason  string      `json:"reason"`
+	Amount  types.Money `json:"amount"`
 }
 
 type InventoryReservedPayload struct {
 	Reservations []types.InventoryReservation `json:"reservations"`
+	// Warehouses the reservations were allocated to, one shipment each
+	Origins []types.ShipmentOrigin `json:"origins"`
+	// Items per product outcome, for orders that allow less than everything
+	Items      []types.ItemOutcome `json:"items,omitempty"`
+	Backorders []types.Backorder   `json:"backorders,omitempty"`
+}
+
+type InventoryBackorderReadyPayload struct {
+	Backorder types.Backorder `json:"backorder"`
+	// OrderSagaID the checkout saga that backordered the item
+	OrderSagaID uuid.UUID `json:"order_saga_id"`
+}
+
+type InventoryLowStockPayload struct {
+	ProductID     uuid.UUID `json:"product_id"`
+	SKU           string    `json:"sku"`
+	Name          string    `json:"name"`
+	Stock         int       `json:"stock"`
+	ReservedStock int       `json:"reserved_stock"`
+	Available     int       `json:"available"`
+	Threshold     int       `json:"threshold"`
 }
 
 type InventoryFailedPayload struct {
@@ -76,7 +164,8 @@ type InventoryFailedPayload struct {
 }
 
 type ShippingCreatedPayload struct {
-	Shipment types.Shipment `json:"shipment"`
+	Shipment  types.Shipment   `json:"shipment"`  // First of Shipments
+	Shipments []types.Shipment `json:"shipments"` // One per origin warehouse
 }
 
 type ShippingFailedPayload struct {
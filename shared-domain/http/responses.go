@@ -97,6 +97,20 @@ func ConflictResponse(c *fiber.Ctx, message string, details map[string]interface
 	})
 }
 
+func ServiceUnavailableResponse(c *fiber.Ctx, message string, details map[string]interface{}) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(APIResponse{
+		Success: false,
+		Message: message,
+		Error: &APIError{
+			Code:    "SERVICE_UNAVAILABLE",
+			Message: message,
+			Details: details,
+		},
+		Timestamp: time.Now(),
+		RequestID: getRequestID(c),
+	})
+}
+
 func getRequestID(c *fiber.Ctx) string {
 	requestID := c.Get("X-Request-ID")
 	if requestID == "" {
@@ -0,0 +1,159 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Stage defines the order in which shutdown hooks are executed.
+// Lower stages run first.
+type Stage int
+
+const (
+	StageHTTP      Stage = iota // Stop accepting HTTP requests
+	StageConsumers              // Stop consuming new messages
+	StageDrain                  // Wait for in-flight handlers to finish
+	StageOutbox                 // Flush pending outgoing messages
+	StageTelemetry              // Flush buffered traces and metrics
+	StageBroker                 // Close the message broker connection
+	StageDatabase               // Close the database connection
+)
+
+var stageNames = map[Stage]string{
+	StageHTTP:      "http",
+	StageConsumers: "consumers",
+	StageDrain:     "drain",
+	StageOutbox:    "outbox",
+	StageTelemetry: "telemetry",
+	StageBroker:    "broker",
+	StageDatabase:  "database",
+}
+
+func (s Stage) String() string {
+	if name, ok := stageNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("stage(%d)", int(s))
+}
+
+// Hook is a shutdown step. It must return once ctx is done.
+type Hook func(ctx context.Context) error
+
+type namedHook struct {
+	stage Stage
+	name  string
+	hook  Hook
+}
+
+// Manager coordinates the shutdown of a service binary.
+type Manager struct {
+	serviceName string
+	timeout     time.Duration
+
+	mu           sync.Mutex
+	hooks        []namedHook
+	triggerOnce  sync.Once
+	shutdownOnce sync.Once
+	shutdown     chan struct{}
+	err          error
+}
+
+func NewManager(serviceName string, timeout time.Duration) *Manager {
+	return &Manager{
+		serviceName: serviceName,
+		timeout:     timeout,
+		shutdown:    make(chan struct{}),
+	}
+}
+
+// Register adds a hook to the given stage. Hooks of the same stage run in registration order.
+func (m *Manager) Register(stage Stage, name string, hook Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, namedHook{stage: stage, name: name, hook: hook})
+}
+
+// Trigger starts the shutdown without waiting for a signal, e.g. when a server fails to start.
+func (m *Manager) Trigger() {
+	m.triggerOnce.Do(func() {
+		close(m.shutdown)
+	})
+}
+
+// Wait blocks until SIGINT/SIGTERM is received or Trigger is called, then runs all hooks.
+func (m *Manager) Wait() error {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	select {
+	case sig := <-sigChan:
+		slog.Info(m.serviceName+" is shutting down", "signal", sig.String())
+	case <-m.shutdown:
+		slog.Info(m.serviceName+" is shutting down", "signal", "trigger")
+	}
+
+	return m.Shutdown()
+}
+
+// Shutdown runs every registered hook stage by stage within the configured deadline.
+// A failing hook does not prevent the following ones from running. Calling it more than once is safe.
+func (m *Manager) Shutdown() error {
+	m.shutdownOnce.Do(m.runHooks)
+	return m.err
+}
+
+func (m *Manager) runHooks() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	m.mu.Lock()
+	hooks := make([]namedHook, len(m.hooks))
+	copy(hooks, m.hooks)
+	m.mu.Unlock()
+
+	var errs []error
+	for stage := StageHTTP; stage <= StageDatabase; stage++ {
+		for _, h := range hooks {
+			if h.stage != stage {
+				continue
+			}
+
+			started := time.Now()
+			if err := h.hook(ctx); err != nil {
+				slog.Error("Shutdown hook failed", "stage", stage.String(), "hook", h.name, "error", err)
+				errs = append(errs, fmt.Errorf("%s/%s: %v", stage, h.name, err))
+				continue
+			}
+			slog.Info("Shutdown hook completed", "stage", stage.String(), "hook", h.name, "duration", time.Since(started))
+		}
+	}
+
+	if ctx.Err() != nil {
+		errs = append(errs, fmt.Errorf("shutdown deadline exceeded after %s", m.timeout))
+	}
+
+	if len(errs) > 0 {
+		m.err = fmt.Errorf("shutdown completed with errors: %v", errs)
+	} else {
+		slog.Info(m.serviceName + " stopped gracefully")
+
+	}
+}
+
+// TimeoutFromEnv reads SHUTDOWN_TIMEOUT (e.g. "30s"), falling back to defaultTimeout.
+func TimeoutFromEnv(defaultTimeout time.Duration) time.Duration {
+	if value := os.Getenv("SHUTDOWN_TIMEOUT"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return defaultTimeout
+}
@@ -0,0 +1,162 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder the hooks in the order they ran
+type recorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *recorder) hook(name string, err error) Hook {
+	return func(ctx context.Context) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.names = append(r.names, name)
+		return err
+	}
+}
+
+func (r *recorder) ran() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.names...)
+}
+
+func TestShutdownRunsStagesInOrder(t *testing.T) {
+	m := NewManager("test-service", time.Second)
+	r := &recorder{}
+
+	// Registered as the binaries do, out of stage order
+	m.Register(StageDatabase, "postgres", r.hook("postgres", nil))
+	m.Register(StageTelemetry, "trace exporter", r.hook("trace exporter", nil))
+	m.Register(StageBroker, "rabbitmq", r.hook("rabbitmq", nil))
+	m.Register(StageOutbox, "event publisher", r.hook("event publisher", nil))
+	m.Register(StageConsumers, "rabbitmq consumer", r.hook("rabbitmq consumer", nil))
+	m.Register(StageConsumers, "sweeper", r.hook("sweeper", nil))
+	m.Register(StageDrain, "in-flight event handlers", r.hook("in-flight event handlers", nil))
+	m.Register(StageHTTP, "fiber", r.hook("fiber", nil))
+
+	if err := m.Shutdown(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	want := []string{
+		"fiber", "rabbitmq consumer", "sweeper", "in-flight event handlers",
+		"event publisher", "trace exporter", "rabbitmq", "postgres",
+	}
+	if got := r.ran(); !reflect.DeepEqual(got, want) {
+		t.Errorf("hooks ran as %v, want %v", got, want)
+	}
+}
+
+func TestShutdownContinuesAfterFailingHook(t *testing.T) {
+	m := NewManager("test-service", time.Second)
+	r := &recorder{}
+
+	m.Register(StageConsumers, "rabbitmq consumer", r.hook("rabbitmq consumer", errors.New("cancel failed")))
+	m.Register(StageDatabase, "postgres", r.hook("postgres", nil))
+
+	err := m.Shutdown()
+	if err == nil || !strings.Contains(err.Error(), "consumers/rabbitmq consumer: cancel failed") {
+		t.Errorf("got %v, want the failed consumer hook", err)
+	}
+	if got := r.ran(); !reflect.DeepEqual(got, []string{"rabbitmq consumer", "postgres"}) {
+		t.Errorf("hooks ran as %v, want both", got)
+	}
+
+	// A second call reports the same result without running the hooks again
+	if again := m.Shutdown(); again != err {
+		t.Errorf("second shutdown = %v, want %v", again, err)
+	}
+	if got := r.ran(); len(got) != 2 {
+		t.Errorf("hooks ran as %v after a second shutdown, want once", got)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	m := NewManager("test-service", 20*time.Millisecond)
+	r := &recorder{}
+
+	// A drain that never finishes uses up the deadline
+	m.Register(StageDrain, "in-flight event handlers", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	m.Register(StageDatabase, "postgres", r.hook("postgres", nil))
+
+	started := time.Now()
+	err := m.Shutdown()
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("shutdown took %s, want about the 20ms deadline", elapsed)
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "drain/in-flight event handlers") ||
+		!strings.Contains(err.Error(), "shutdown deadline exceeded after 20ms") {
+		t.Errorf("got %v, want the drain and the deadline reported", err)
+	}
+	// Later stages still close their resources
+	if got := r.ran(); !reflect.DeepEqual(got, []string{"postgres"}) {
+		t.Errorf("hooks ran as %v, want postgres", got)
+	}
+}
+
+func TestTriggerEndsWait(t *testing.T) {
+	m := NewManager("test-service", time.Second)
+	r := &recorder{}
+	m.Register(StageHTTP, "fiber", r.hook("fiber", nil))
+
+	done := make(chan error, 1)
+	go func() { done <- m.Wait() }()
+
+	m.Trigger()
+	m.Trigger() // Safe to call again
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after Trigger")
+	}
+	if got := r.ran(); !reflect.DeepEqual(got, []string{"fiber"}) {
+		t.Errorf("hooks ran as %v, want fiber", got)
+	}
+}
+
+func TestTimeoutFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 30 * time.Second},
+		{"45s", 45 * time.Second},
+		{"soon", 30 * time.Second},
+		{"-5s", 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("SHUTDOWN_TIMEOUT", tt.value)
+		if got := TimeoutFromEnv(30 * time.Second); got != tt.want {
+			t.Errorf("SHUTDOWN_TIMEOUT=%q: got %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestStageString(t *testing.T) {
+	if got := StageOutbox.String(); got != "outbox" {
+		t.Errorf("StageOutbox = %q, want outbox", got)
+	}
+	if got := Stage(42).String(); got != "stage(42)" {
+		t.Errorf("Stage(42) = %q, want stage(42)", got)
+	}
+}
@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FiberMiddleware writes one access log record per request. It must run after
+// tracing.FiberMiddleware so records carry the trace ID.
+func FiberMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		slog.Log(c.UserContext(), level, "HTTP request",
+			"method", c.Method(),
+			"path", c.Path(),
+			"status", status,
+			"latency", time.Since(started),
+			"ip", c.IP(),
+		)
+
+		return err
+	}
+}
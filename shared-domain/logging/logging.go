@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names added to every record whose context carries them
+const (
+	FieldService       = "service"
+	FieldSagaID        = "saga_id"
+	FieldOrderID       = "order_id"
+	FieldCorrelationID = "correlation_id"
+	FieldCausationID   = "causation_id"
+	FieldEventID       = "event_id"
+	FieldEventType     = "event_type"
+	FieldTraceID       = "trace_id"
+	FieldSpanID        = "span_id"
+)
+
+// Formats selected with LOG_FORMAT
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// DefaultRedactedFields are masked unless LOG_REDACT_FIELDS overrides them
+var DefaultRedactedFields = []string{
+	"shipping_address", "address", "street", "city", "state", "zip_code", "postal_code",
+	"recipient", "email", "phone",
+}
+
+// Init installs the service logger as the slog and standard library default.
+//
+// LOG_LEVEL sets the minimum level (DEBUG, INFO, WARN, ERROR; default INFO),
+// LOG_FORMAT selects "json" (default) or "text" and LOG_REDACT_FIELDS is a
+// comma separated list of field names whose values are masked.
+func Init(serviceName string) *slog.Logger {
+	logger := New(os.Stdout, serviceName, Config{
+		Level:          os.Getenv("LOG_LEVEL"),
+		Format:         os.Getenv("LOG_FORMAT"),
+		RedactedFields: redactedFieldsFromEnv(),
+	})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Config configures New
+type Config struct {
+	Level          string
+	Format         string
+	RedactedFields []string
+}
+
+func New(w io.Writer, serviceName string, cfg Config) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+
+	redactor := newRedactor(cfg.RedactedFields)
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactor.replaceAttr,
+	}
+
+	var handler slog.Handler
+	if strings.EqualFold(cfg.Format, FormatText) {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(&contextHandler{next: handler}).With(FieldService, serviceName)
+}
+
+// Fatal logs at error level and exits, replacing log.Fatalf
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type attrsKey struct{}
+
+// WithAttrs returns a copy of ctx whose log records carry the given attributes,
+// e.g. the order ID in an HTTP handler before any saga event exists.
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]any)
+	merged := make([]any, 0, len(existing)+len(args))
+	merged = append(merged, existing...)
+	merged = append(merged, args...)
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// contextHandler adds saga identifiers and trace IDs found in the record's context
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if event, ok := events.CauseFromContext(ctx); ok {
+			record.AddAttrs(
+				slog.String(FieldSagaID, event.SagaID.String()),
+				slog.String(FieldOrderID, event.OrderID.String()),
+				slog.String(FieldCorrelationID, event.CorrelationID.String()),
+				slog.String(FieldCausationID, event.CausationID.String()),
+				slog.String(FieldEventID, event.ID.String()),
+				slog.String(FieldEventType, string(event.EventType)),
+			)
+		}
+
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			record.AddAttrs(
+				slog.String(FieldTraceID, spanContext.TraceID().String()),
+				slog.String(FieldSpanID, spanContext.SpanID().String()),
+			)
+		}
+
+		if args, ok := ctx.Value(attrsKey{}).([]any); ok {
+			record.Add(args...)
+		}
+	}
+
+	return h.next.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
+
+func redactedFieldsFromEnv() []string {
+	value, ok := os.LookupEnv("LOG_REDACT_FIELDS")
+	if !ok {
+		return DefaultRedactedFields
+	}
+
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// redactor masks configured fields, including fields nested in logged structs and maps
+type redactor struct {
+	fields map[string]struct{}
+}
+
+func newRedactor(fields []string) *redactor {
+	r := &redactor{fields: make(map[string]struct{}, len(fields))}
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = struct{}{}
+	}
+	return r
+}
+
+func (r *redactor) isRedacted(key string) bool {
+	_, ok := r.fields[strings.ToLower(key)]
+	return ok
+}
+
+func (r *redactor) replaceAttr(_ []string, attr slog.Attr) slog.Attr {
+	if len(r.fields) == 0 {
+		return attr
+	}
+
+	if r.isRedacted(attr.Key) {
+		return slog.String(attr.Key, redactedValue)
+	}
+
+	if attr.Value.Kind() != slog.KindAny {
+		return attr
+	}
+
+	value := attr.Value.Any()
+	if _, isErr := value.(error); isErr || !isComposite(value) {
+		return attr
+	}
+
+	// Round trip through JSON so struct fields are matched by their json names
+	data, err := json.Marshal(value)
+	if err != nil {
+		return attr
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return attr
+	}
+
+	return slog.Any(attr.Key, r.redactValue(decoded))
+}
+
+func (r *redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if r.isRedacted(key) {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = r.redactValue(nested)
+		}
+		return v
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = r.redactValue(nested)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isComposite(value interface{}) bool {
+	t := reflect.TypeOf(value)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
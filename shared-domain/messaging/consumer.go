@@ -1,21 +1,40 @@
 package messaging
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/tracing"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type EventHandler func(event events.SagaEvent) error
+// ErrConsumerStarted ConsumeEvents was called on a consumer that already consumes
+var ErrConsumerStarted = errors.New("consumer already started")
+
+// EventHandler receives the decoded event with a context that carries the
+// producer's trace context extracted from the message headers.
+type EventHandler func(ctx context.Context, event events.SagaEvent) error
 
 type Consumer struct {
 	client      *RabbitMQClient
 	queueName   string
 	serviceName string
+
+	stopped  chan struct{}
+	stopOnce sync.Once
+	mu       sync.Mutex
+	loopDone chan struct{} // closed when the delivery loop has exited, nil until started
 }
 
 func NewConsumer(client *RabbitMQClient, queueName, serviceName string) *Consumer {
@@ -23,10 +42,19 @@ func NewConsumer(client *RabbitMQClient, queueName, serviceName string) *Consume
 		client:      client,
 		queueName:   queueName,
 		serviceName: serviceName,
+		stopped:     make(chan struct{}),
 	}
 }
 
+// ConsumeEvents starts the delivery loop. A consumer is started once; Drain
+// waits for that one loop.
 func (c *Consumer) ConsumeEvents(routingKeys []string, handler EventHandler) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loopDone != nil {
+		return fmt.Errorf("%w: %s", ErrConsumerStarted, c.queueName)
+	}
+
 	if !c.client.IsConnected() {
 		return fmt.Errorf("There is no connection to RabbitMQ")
 	}
@@ -56,7 +84,7 @@ func (c *Consumer) ConsumeEvents(routingKeys []string, handler EventHandler) err
 		if err != nil {
 			return fmt.Errorf("queue bind error (%s): %v", routingKey, err)
 		}
-		log.Printf("Queue %s bound to routing key: %s", queue.Name, routingKey)
+		slog.Debug("Queue bound", "queue", queue.Name, "routing_key", routingKey)
 	}
 
 	messages, err := channel.Consume(
@@ -72,52 +100,138 @@ func (c *Consumer) ConsumeEvents(routingKeys []string, handler EventHandler) err
 		return fmt.Errorf("consume start error: %v", err)
 	}
 
-	log.Printf("Consuming events on queue: %s", queue.Name)
+	slog.Info("Consuming events", "queue", queue.Name)
+
+	c.start(messages, handler)
+	return nil
+}
+
+// start runs the delivery loop until the deliveries end or the consumer is
+// stopped. The caller holds mu.
+func (c *Consumer) start(messages <-chan amqp.Delivery, handler EventHandler) {
+	c.loopDone = make(chan struct{})
 
 	go func() {
+		defer close(c.loopDone)
+
 		for {
 			select {
-			case msg := <-messages:
+			case msg, ok := <-messages:
+				if !ok {
+					slog.Info("Delivery channel closed", "consumer", c.serviceName)
+					return
+				}
 				c.handleMessage(msg, handler)
+			case <-c.stopped:
+				slog.Info("Consumer stopped", "consumer", c.serviceName)
+				return
 			case <-c.client.ctx.Done():
-				log.Printf("Consumer is stooed: %s", c.serviceName)
+				slog.Info("Consumer stopped", "consumer", c.serviceName)
 				return
 			}
 		}
 	}()
+}
 
-	return nil
+// Stop cancels the consumer on the broker so no new deliveries are received.
+// Unacknowledged messages are requeued by RabbitMQ.
+func (c *Consumer) Stop(ctx context.Context) error {
+	var err error
+	c.stopOnce.Do(func() {
+		close(c.stopped)
+
+		if channel := c.client.Channel(); channel != nil && c.client.IsConnected() {
+			if cancelErr := channel.Cancel(c.serviceName, false); cancelErr != nil {
+				err = fmt.Errorf("consumer cancel error: %v", cancelErr)
+			}
+		}
+	})
+	return err
+}
+
+// Drain waits until the message that is currently being handled is acked or nacked
+// and the delivery loop has exited. It should be called after Stop.
+func (c *Consumer) Drain(ctx context.Context) error {
+	c.mu.Lock()
+	loopDone := c.loopDone
+	c.mu.Unlock()
+	if loopDone == nil {
+		return nil
+	}
+
+	select {
+	case <-loopDone:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("in-flight handlers did not finish: %v", ctx.Err())
+	}
 }
 
 func (c *Consumer) handleMessage(msg amqp.Delivery, handler EventHandler) {
-	log.Printf("🔥 Raw message received: routing_key=%s, body_length=%d", msg.RoutingKey, len(msg.Body))
-	log.Printf("🔥 Raw message body: %s", string(msg.Body))
-	log.Printf("🔥 Message headers: %+v", msg.Headers)
+	ctx := tracing.Extract(context.Background(), amqpHeaderCarrier(msg.Headers))
+	ctx, span := tracing.Tracer().Start(ctx, msg.RoutingKey+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationDeliver,
+			semconv.MessagingDestinationName(msg.Exchange),
+			semconv.MessagingRabbitmqDestinationRoutingKey(msg.RoutingKey),
+			semconv.MessagingMessageID(msg.MessageId),
+			attribute.String("messaging.consumer", c.serviceName),
+		),
+	)
+	defer span.End()
 
 	var event events.SagaEvent
 
 	if err := json.Unmarshal(msg.Body, &event); err != nil {
-		log.Printf("Event deserialize error: %v", err)
+		slog.ErrorContext(ctx, "Event deserialize error",
+			"routing_key", msg.RoutingKey, "body_length", len(msg.Body), "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "event deserialize error")
+		metrics.MessagesConsumed.WithLabelValues(msg.RoutingKey, metrics.OutcomeInvalid).Inc()
+		metrics.MessagesDeadLettered.WithLabelValues(msg.RoutingKey).Inc()
 		msg.Nack(false, false)
 		return
 	}
 
-	log.Printf("Event received: %s from %s", event.EventType, event.Service)
+	span.SetAttributes(
+		attribute.String("saga.id", event.SagaID.String()),
+		attribute.String("order.id", event.OrderID.String()),
+		attribute.String("saga.event_type", string(event.EventType)),
+		attribute.String("saga.correlation_id", event.CorrelationID.String()),
+		attribute.String("saga.causation_id", event.CausationID.String()),
+	)
+
+	// Replies published by the handler are linked to this event
+	ctx = events.WithCause(ctx, event)
 
-	if err := handler(event); err != nil {
-		log.Printf("Event process error: %v", err)
+	slog.InfoContext(ctx, "Event received", "from", event.Service, "routing_key", msg.RoutingKey)
+
+	started := time.Now()
+	err := handler(ctx, event)
+	outcome := metrics.Outcome(err)
+	metrics.MessagesConsumed.WithLabelValues(msg.RoutingKey, outcome).Inc()
+	metrics.MessageHandleDuration.WithLabelValues(msg.RoutingKey, outcome).Observe(time.Since(started).Seconds())
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Event process error", "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 
 		if c.shouldRetry(msg) {
 			c.republishWithRetry(msg, event)
 		} else {
-			log.Printf("Max retry is reached, dead letter sent to queue: %s", event.EventType)
+			slog.WarnContext(ctx, "Max retry is reached, event sent to dead letter queue")
+			metrics.MessagesDeadLettered.WithLabelValues(msg.RoutingKey).Inc()
 			msg.Nack(false, false) // Dead letter queue
 		}
 		return
 	}
 
 	msg.Ack(false)
-	log.Printf("Event processed successfully: %s", event.EventType)
+	slog.DebugContext(ctx, "Event processed successfully")
+
 }
 
 func (c *Consumer) shouldRetry(msg amqp.Delivery) bool {
@@ -156,11 +270,14 @@ func (c *Consumer) republishWithRetry(msg amqp.Delivery, event events.SagaEvent)
 	)
 
 	if err != nil {
-		log.Printf("Retry publish error: %v", err)
+		slog.Error("Retry publish error", "routing_key", msg.RoutingKey, "error", err)
+		metrics.MessagesDeadLettered.WithLabelValues(msg.RoutingKey).Inc()
 		msg.Nack(false, false)
 		return
 	}
 
+	metrics.MessagesRetried.WithLabelValues(msg.RoutingKey).Inc()
 	msg.Ack(false)
-	log.Printf("Re-published: %s", event.EventType)
+	slog.Info("Event re-published for retry", "routing_key", msg.RoutingKey, "saga_id", event.SagaID)
+
 }
@@ -0,0 +1,113 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// startedConsumer a consumer running its delivery loop over messages, without a broker
+func startedConsumer(t *testing.T, messages <-chan amqp.Delivery, handler EventHandler) *Consumer {
+	t.Helper()
+
+	consumer := NewConsumer(NewRabbitMQClient(NewRabbitMQConfig()), "test.queue", "test-service")
+	consumer.mu.Lock()
+	consumer.start(messages, handler)
+	consumer.mu.Unlock()
+	return consumer
+}
+
+func delivery(t *testing.T) amqp.Delivery {
+	t.Helper()
+
+	body, err := json.Marshal(events.SagaEvent{ID: uuid.New(), SagaID: uuid.New(), EventType: events.OrderCreatedEvent})
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	return amqp.Delivery{RoutingKey: "saga.order.order.created", Body: body}
+}
+
+func TestDrainWaitsForTheHandler(t *testing.T) {
+	handling, release := make(chan struct{}), make(chan struct{})
+	messages := make(chan amqp.Delivery, 1)
+	consumer := startedConsumer(t, messages, func(ctx context.Context, event events.SagaEvent) error {
+		close(handling)
+		<-release
+		return nil
+	})
+
+	messages <- delivery(t)
+	<-handling
+
+	if err := consumer.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := consumer.Drain(ctx); err == nil {
+		t.Fatal("drain returned while the handler was still running")
+	}
+
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := consumer.Drain(ctx); err != nil {
+		t.Fatalf("drain after the handler finished: %v", err)
+	}
+}
+
+func TestDrainAfterDeliveriesEnd(t *testing.T) {
+	handled := 0
+	messages := make(chan amqp.Delivery, 2)
+	consumer := startedConsumer(t, messages, func(ctx context.Context, event events.SagaEvent) error {
+		handled++
+		return nil
+	})
+
+	messages <- delivery(t)
+	messages <- delivery(t)
+	close(messages) // The broker closed the channel
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := consumer.Drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	if handled != 2 {
+		t.Errorf("handled %d deliveries, want 2", handled)
+	}
+}
+
+func TestDrainBeforeStart(t *testing.T) {
+	consumer := NewConsumer(NewRabbitMQClient(NewRabbitMQConfig()), "test.queue", "test-service")
+	if err := consumer.Drain(context.Background()); err != nil {
+		t.Errorf("drain of a consumer that never started: %v", err)
+	}
+	// Stopping without a broker connection, and twice, is fine
+	for i := 0; i < 2; i++ {
+		if err := consumer.Stop(context.Background()); err != nil {
+			t.Errorf("stop %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestConsumeEventsRefusesSecondStart(t *testing.T) {
+	consumer := startedConsumer(t, make(chan amqp.Delivery), func(ctx context.Context, event events.SagaEvent) error {
+		return nil
+	})
+	defer consumer.Stop(context.Background())
+
+	err := consumer.ConsumeEvents([]string{"saga.order.*"}, func(ctx context.Context, event events.SagaEvent) error {
+		return nil
+	})
+	if !errors.Is(err, ErrConsumerStarted) {
+		t.Errorf("got %v, want ErrConsumerStarted", err)
+	}
+}
@@ -0,0 +1,34 @@
+package messaging
+
+import (
+	"fmt"
+
+	"github.com/streadway/amqp"
+)
+
+// amqpHeaderCarrier adapts AMQP headers to propagation.TextMapCarrier
+// so W3C trace context travels with every message.
+type amqpHeaderCarrier amqp.Table
+
+func (a amqpHeaderCarrier) Get(key string) string {
+	value, ok := a[key]
+	if !ok {
+		return ""
+	}
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func (a amqpHeaderCarrier) Set(key, value string) {
+	a[key] = value
+}
+
+func (a amqpHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(a))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	return keys
+}
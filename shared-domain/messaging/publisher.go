@@ -1,18 +1,30 @@
 package messaging
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/tracing"
 	"github.com/google/uuid"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Publisher struct {
 	client *RabbitMQClient
+
+	mu      sync.Mutex
+	pending int           // Publishes in progress
+	idle    chan struct{} // Closed when pending drops to zero
 }
 
 func NewPublisher(client *RabbitMQClient) *Publisher {
@@ -21,7 +33,35 @@ func NewPublisher(client *RabbitMQClient) *Publisher {
 	}
 }
 
-func (p *Publisher) PublishSagaEvent(event events.SagaEvent) error {
+func (p *Publisher) PublishSagaEvent(ctx context.Context, event events.SagaEvent) (err error) {
+	p.begin()
+	defer p.end()
+
+	routingKey := fmt.Sprintf("saga.%s.%s", event.Service, string(event.EventType))
+
+	ctx, span := tracing.Tracer().Start(ctx, routingKey+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(p.client.config.Exchange),
+			semconv.MessagingRabbitmqDestinationRoutingKey(routingKey),
+			attribute.String("saga.id", event.SagaID.String()),
+			attribute.String("order.id", event.OrderID.String()),
+			attribute.String("saga.event_type", string(event.EventType)),
+			attribute.String("saga.correlation_id", event.CorrelationID.String()),
+			attribute.String("saga.causation_id", event.CausationID.String()),
+		),
+	)
+	defer func() {
+		metrics.MessagesPublished.WithLabelValues(routingKey, metrics.Outcome(err)).Inc()
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	if !p.client.IsConnected() {
 		return fmt.Errorf("There is no connection to RabbitMQ")
 	}
@@ -39,7 +79,15 @@ func (p *Publisher) PublishSagaEvent(event events.SagaEvent) error {
 		return fmt.Errorf("event serialization error: %v", err)
 	}
 
-	routingKey := fmt.Sprintf("saga.%s.%s", event.Service, string(event.EventType))
+	headers := amqp.Table{
+		"saga_id":        event.SagaID.String(),
+		"order_id":       event.OrderID.String(),
+		"correlation_id": event.CorrelationID.String(),
+		"causation_id":   event.CausationID.String(),
+		"service":        event.Service,
+		"event_type":     string(event.EventType),
+	}
+	tracing.Inject(ctx, amqpHeaderCarrier(headers))
 
 	channel := p.client.Channel()
 	err = channel.Publish(
@@ -48,18 +96,13 @@ func (p *Publisher) PublishSagaEvent(event events.SagaEvent) error {
 		false,
 		false,
 		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         body,
-			DeliveryMode: amqp.Persistent, // Message persistence
-			MessageId:    event.ID.String(),
-			Timestamp:    event.Timestamp,
-			Headers: amqp.Table{
-				"saga_id":        event.SagaID.String(),
-				"order_id":       event.OrderID.String(),
-				"correlation_id": event.CorrelationID.String(),
-				"service":        event.Service,
-				"event_type":     string(event.EventType),
-			},
+			ContentType:   "application/json",
+			Body:          body,
+			DeliveryMode:  amqp.Persistent, // Message persistence
+			MessageId:     event.ID.String(),
+			CorrelationId: event.CorrelationID.String(),
+			Timestamp:     event.Timestamp,
+			Headers:       headers,
 		},
 	)
 
@@ -67,17 +110,21 @@ func (p *Publisher) PublishSagaEvent(event events.SagaEvent) error {
 		return fmt.Errorf("event publish error: %v", err)
 	}
 
-	log.Printf("Event published: %s -> %s", routingKey, event.EventType)
+	slog.DebugContext(ctx, "Event published", "routing_key", routingKey, "event_id", event.ID)
 	return nil
 }
 
-func (p *Publisher) PublishWithRetry(event events.SagaEvent, maxRetries int) error {
+func (p *Publisher) PublishWithRetry(ctx context.Context, event events.SagaEvent, maxRetries int) error {
+	// Pending across the backoff, so Flush waits for the retries too
+	p.begin()
+	defer p.end()
+
 	var lastErr error
 
 	for i := 0; i < maxRetries; i++ {
-		if err := p.PublishSagaEvent(event); err != nil {
+		if err := p.PublishSagaEvent(ctx, event); err != nil {
 			lastErr = err
-			log.Printf("Publish error (retry %d/%d): %v", i+1, maxRetries, err)
+			slog.WarnContext(ctx, "Publish error", "attempt", i+1, "max_retries", maxRetries, "error", err)
 
 			if i < maxRetries-1 {
 				time.Sleep(time.Second * time.Duration(i+1)) // Exponential backoff
@@ -90,3 +137,43 @@ func (p *Publisher) PublishWithRetry(event events.SagaEvent, maxRetries int) err
 
 	return fmt.Errorf("event publish başarısız (%d deneme): %v", maxRetries, lastErr)
 }
+
+// Flush waits until the events that are being published have been handed to
+// the broker, so closing the connection does not cut them off. It should be
+// called after the HTTP server and consumers have stopped.
+func (p *Publisher) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	if p.pending == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	idle := p.idle
+	p.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("pending publishes did not finish: %v", ctx.Err())
+	}
+}
+
+func (p *Publisher) begin() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.pending++
+}
+
+func (p *Publisher) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending--
+	if p.pending == 0 {
+		close(p.idle)
+	}
+}
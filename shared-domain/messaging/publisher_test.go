@@ -0,0 +1,45 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFlushWaitsForPendingPublishes(t *testing.T) {
+	publisher := NewPublisher(NewRabbitMQClient(NewRabbitMQConfig()))
+
+	if err := publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("flush with nothing pending: %v", err)
+	}
+
+	// Two publishes in progress, e.g. one retrying
+	publisher.begin()
+	publisher.begin()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := publisher.Flush(ctx); err == nil {
+		t.Fatal("flush returned while publishes were pending")
+	}
+
+	flushed := make(chan error, 1)
+	go func() { flushed <- publisher.Flush(context.Background()) }()
+
+	publisher.end()
+	select {
+	case <-flushed:
+		t.Fatal("flush returned with one publish still pending")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	publisher.end()
+	select {
+	case err := <-flushed:
+		if err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("flush did not return once the publishes finished")
+	}
+}
@@ -3,11 +3,8 @@ package messaging
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
-	"os/signal"
+	"log/slog"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/streadway/amqp"
@@ -26,30 +23,12 @@ type RabbitMQClient struct {
 func NewRabbitMQClient(config *RabbitMQConfig) *RabbitMQClient {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	client := &RabbitMQClient{
+	// Shutdown is coordinated by the lifecycle manager of the binary, see lifecycle.StageBroker
+	return &RabbitMQClient{
 		config: config,
 		ctx:    ctx,
 		cancel: cancel,
 	}
-
-	// Graceful shutdown için signal handling
-	go client.handleGracefulShutdown()
-
-	return client
-}
-
-func (r *RabbitMQClient) handleGracefulShutdown() {
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	select {
-	case sig := <-sigChan:
-		log.Printf("Signal received: %v. RabbitMQ connection is closing...", sig)
-		r.Close()
-	case <-r.ctx.Done():
-		log.Println("Context cancelled. RabbitMQ is closing...")
-		return
-	}
 }
 
 func (r *RabbitMQClient) Connect() error {
@@ -60,7 +39,7 @@ func (r *RabbitMQClient) Connect() error {
 	for i := 0; i < r.config.RetryCount; i++ {
 		r.connection, err = amqp.Dial(r.config.ConnectionURL())
 		if err != nil {
-			log.Printf("RabbitMQ connection error (attempt %d/%d): %v", i+1, r.config.RetryCount, err)
+			slog.Warn("RabbitMQ connection error", "attempt", i+1, "max_attempts", r.config.RetryCount, "error", err)
 			if i < r.config.RetryCount-1 {
 				time.Sleep(r.config.RetryDelay)
 				continue
@@ -89,7 +68,7 @@ func (r *RabbitMQClient) Connect() error {
 			return fmt.Errorf("failed to create exchange: %v", err)
 		}
 
-		log.Printf("Successfully connected to RabbitMQ: %s", r.config.Host)
+		slog.Info("Connected to RabbitMQ", "host", r.config.Host)
 
 		// Listen connection drops
 		go r.handleReconnection()
@@ -107,10 +86,10 @@ func (r *RabbitMQClient) handleReconnection() {
 	select {
 	case err := <-notifyClose:
 		if !r.isClosing {
-			log.Printf("RabbitMQ connection is lost: %v. Trying reconnect...", err)
+			slog.Warn("RabbitMQ connection is lost, trying to reconnect", "error", err)
 			time.Sleep(time.Second * 2)
 			if reconnectErr := r.Connect(); reconnectErr != nil {
-				log.Printf("Recconnect error: %v", reconnectErr)
+				slog.Error("RabbitMQ reconnect error", "error", reconnectErr)
 			}
 		}
 	}
@@ -138,7 +117,7 @@ func (r *RabbitMQClient) Close() error {
 	if r.channel != nil {
 		if err := r.channel.Close(); err != nil {
 			closeErr = fmt.Errorf("channel close error: %v", err)
-			log.Printf("Failed to close channel: %v", err)
+			slog.Error("Failed to close channel", "error", err)
 		}
 	}
 
@@ -149,12 +128,13 @@ func (r *RabbitMQClient) Close() error {
 			} else {
 				closeErr = fmt.Errorf("connection close error: %v", err)
 			}
-			log.Printf("Failed to close connection: %v", err)
+			slog.Error("Failed to close connection", "error", err)
 		}
 	}
 
 	if closeErr == nil {
-		log.Println("RabbitMQ connection closed successfully")
+		slog.Info("RabbitMQ connection closed")
+
 	}
 
 	return closeErr
@@ -0,0 +1,22 @@
+package metrics
+
+// Label names shared by every service so dashboards can join series across services
+const (
+	LabelService    = "service"
+	LabelRoutingKey = "routing_key"
+	LabelStep       = "step"
+	LabelReason     = "reason"
+	LabelStatus     = "status"
+	LabelOutcome    = "outcome"
+	LabelOperation  = "operation"
+	LabelDependency = "dependency"
+	LabelState      = "state"
+)
+
+// Outcome label values
+const (
+	OutcomeSuccess  = "success"
+	OutcomeFailure  = "failure"
+	OutcomeInvalid  = "invalid"  // message could not be decoded
+	OutcomeDeclined = "declined" // gateway answered but rejected the request
+)
@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "saga"
+
+// Messaging
+var (
+	MessagesPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_published_total",
+		Help:      "Saga events published to RabbitMQ.",
+	}, []string{LabelRoutingKey, LabelOutcome})
+
+	MessagesConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_consumed_total",
+		Help:      "Saga events received from RabbitMQ, by handler outcome.",
+	}, []string{LabelRoutingKey, LabelOutcome})
+
+	MessagesRetried = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_retried_total",
+		Help:      "Saga events re-published after a handler error.",
+	}, []string{LabelRoutingKey})
+
+	MessagesDeadLettered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_dead_lettered_total",
+		Help:      "Saga events rejected to the dead letter queue.",
+	}, []string{LabelRoutingKey})
+
+	MessageHandleDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "message_handle_duration_seconds",
+		Help:      "Time spent in the event handler.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{LabelRoutingKey, LabelOutcome})
+)
+
+// Saga orchestration
+var (
+	SagasStarted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sagas_started_total",
+		Help:      "Sagas started by the orchestrator.",
+	})
+
+	SagasFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sagas_finished_total",
+		Help:      "Sagas that reached a final status (completed or compensated).",
+	}, []string{LabelStatus})
+
+	SagasStuck = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sagas_stuck",
+		Help:      "Sagas that have not progressed within the stuck threshold.",
+	})
+
+	StepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "step_duration_seconds",
+		Help:      "Time between sending a step command and receiving its reply.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{LabelStep, LabelOutcome})
+
+	Compensations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "compensations_total",
+		Help:      "Compensations started, by the step whose failure triggered them.",
+	}, []string{LabelReason})
+)
+
+// Payment gateway
+var GatewayRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "gateway_request_duration_seconds",
+	Help:      "Latency of payment gateway calls.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{LabelOperation, LabelOutcome})
+
+// External providers (shared-domain/resilience)
+var (
+	CircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state per dependency: 0 closed, 1 half-open, 2 open.",
+	}, []string{LabelDependency})
+
+	CircuitBreakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_transitions_total",
+		Help:      "Circuit breaker state changes, by the state entered.",
+	}, []string{LabelDependency, LabelState})
+
+	ResilienceRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_retries_total",
+		Help:      "Provider calls retried after a transient failure.",
+	}, []string{LabelDependency})
+
+	ResilienceRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_rejections_total",
+		Help:      "Provider call attempts cut short by a guard (circuit_open, bulkhead_full, timeout).",
+	}, []string{LabelDependency, LabelReason})
+)
+
+// Inventory
+var InventoryLedgerDiscrepancies = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "inventory_ledger_discrepancies",
+	Help:      "Products whose stock levels differ from the sum of their stock movements.",
+})
+
+// Database
+var DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "db_query_duration_seconds",
+	Help:      "Latency of repository queries.",
+	Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+}, []string{LabelOperation})
+
+var initOnce sync.Once
+
+// Init registers all collectors with the service label attached.
+// Collectors a service does not use simply stay at zero.
+func Init(serviceName string) {
+	initOnce.Do(func() {
+		registerer := prometheus.WrapRegistererWith(
+			prometheus.Labels{LabelService: serviceName},
+			prometheus.DefaultRegisterer,
+		)
+
+		registerer.MustRegister(
+			MessagesPublished,
+			MessagesConsumed,
+			MessagesRetried,
+			MessagesDeadLettered,
+			MessageHandleDuration,
+			SagasStarted,
+			SagasFinished,
+			SagasStuck,
+			StepDuration,
+			Compensations,
+			GatewayRequestDuration,
+			CircuitBreakerState,
+			CircuitBreakerTransitions,
+			ResilienceRetries,
+			ResilienceRejections,
+			InventoryLedgerDiscrepancies,
+			DBQueryDuration,
+		)
+
+		slog.Debug("Metrics registered")
+
+	})
+}
+
+// Handler serves the default registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Outcome maps an error to the outcome label value
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+// ObserveDBQuery records the duration of a repository query. Usage:
+//
+//	defer metrics.ObserveDBQuery("CreateOrder", time.Now())
+func ObserveDBQuery(operation string, started time.Time) {
+	DBQueryDuration.WithLabelValues(operation).Observe(time.Since(started).Seconds())
+}
+
+// NewServer returns an HTTP server exposing /metrics, for services without a Fiber app
+func NewServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
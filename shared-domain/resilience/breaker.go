@@ -0,0 +1,203 @@
+package resilience
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+)
+
+// State of a circuit breaker. The numeric value is exported as the
+// saga_circuit_breaker_state gauge.
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failures that open the circuit, 0 disables the breaker
+	OpenTimeout      time.Duration // How long the circuit stays open before probing
+	HalfOpenProbes   int           // Trial calls let through while half-open; all must succeed to close
+}
+
+// CircuitBreaker fails fast while a dependency is down. After OpenTimeout it
+// lets a few probe calls through: success closes the circuit, a failure
+// opens it again.
+type CircuitBreaker struct {
+	name   string
+	config BreakerConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	failures  int // Consecutive failures while closed
+	probes    int // Probe calls in flight while half-open
+	successes int // Successful probes while half-open
+	openedAt  time.Time
+}
+
+func NewCircuitBreaker(name string, config BreakerConfig) *CircuitBreaker {
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+
+	breaker := &CircuitBreaker{name: name, config: config, now: time.Now}
+	metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(StateClosed))
+	register(breaker)
+	return breaker
+}
+
+// allow admits a call. probe reports that the call is a half-open trial.
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen {
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return false, fmt.Errorf("%w: %s", ErrCircuitOpen, b.name)
+		}
+		b.setState(StateHalfOpen)
+	}
+
+	if b.state == StateHalfOpen {
+		if b.probes >= b.config.HalfOpenProbes {
+			return false, fmt.Errorf("%w: %s (probing)", ErrCircuitOpen, b.name)
+		}
+		b.probes++
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// record reports the result of an admitted call. Results of calls admitted in
+// an earlier state are ignored.
+func (b *CircuitBreaker) record(probe, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case probe && b.state == StateHalfOpen:
+		b.probes--
+		if failed {
+			b.setState(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.setState(StateClosed)
+		}
+
+	case !probe && b.state == StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.setState(StateOpen)
+		}
+	}
+}
+
+// setState switches state and resets its counters; the caller holds b.mu
+func (b *CircuitBreaker) setState(state State) {
+	previous := b.state
+	b.state = state
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+
+	metrics.CircuitBreakerState.WithLabelValues(b.name).Set(float64(state))
+	metrics.CircuitBreakerTransitions.WithLabelValues(b.name, state.String()).Inc()
+
+	if state == StateOpen {
+		slog.Warn("Circuit breaker opened", "dependency", b.name, "from", previous.String(),
+			"retry_after", b.config.OpenTimeout)
+	} else {
+		slog.Info("Circuit breaker state changed", "dependency", b.name,
+			"from", previous.String(), "to", state.String())
+	}
+}
+
+// Status snapshot for health endpoints
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		Name:                b.name,
+		State:               b.state.String(),
+		ConsecutiveFailures: b.failures,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+type BreakerStatus struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+var registry struct {
+	sync.Mutex
+	breakers []*CircuitBreaker
+}
+
+func register(breaker *CircuitBreaker) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.breakers = append(registry.breakers, breaker)
+}
+
+// BreakerStatuses reports every circuit breaker created in this process
+func BreakerStatuses() []BreakerStatus {
+	registry.Lock()
+	breakers := append([]*CircuitBreaker(nil), registry.breakers...)
+	registry.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(breakers))
+	for _, breaker := range breakers {
+		statuses = append(statuses, breaker.Status())
+	}
+	return statuses
+}
+
+// Healthy reports false while any circuit breaker is not closed
+func Healthy() bool {
+	for _, status := range BreakerStatuses() {
+		if status.State != StateClosed.String() {
+			return false
+		}
+	}
+	return true
+}
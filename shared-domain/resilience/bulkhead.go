@@ -0,0 +1,52 @@
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Bulkhead caps concurrent calls to one dependency, so a slow provider cannot
+// tie up every worker of the service
+type Bulkhead struct {
+	name    string
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+func NewBulkhead(name string, maxConcurrent int, maxWait time.Duration) *Bulkhead {
+	return &Bulkhead{
+		name:    name,
+		slots:   make(chan struct{}, maxConcurrent),
+		maxWait: maxWait,
+	}
+}
+
+// acquire waits up to maxWait for a free slot. The returned func releases it.
+func (b *Bulkhead) acquire(ctx context.Context) (func(), error) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.release, nil
+	default:
+	}
+
+	if b.maxWait <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrBulkheadFull, b.name)
+	}
+
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+
+	select {
+	case b.slots <- struct{}{}:
+		return b.release, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: %s", ErrBulkheadFull, b.name)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *Bulkhead) release() {
+	<-b.slots
+}
@@ -0,0 +1,193 @@
+// Package resilience guards calls to external providers with per-attempt
+// timeouts, retries with jittered backoff, a circuit breaker and a bulkhead
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+)
+
+var (
+	ErrCircuitOpen  = errors.New("circuit breaker open")
+	ErrBulkheadFull = errors.New("bulkhead full")
+	ErrTimeout      = errors.New("call timed out")
+)
+
+type Config struct {
+	Timeout       time.Duration // Per attempt, 0 disables
+	Retry         RetryConfig
+	Breaker       BreakerConfig
+	MaxConcurrent int           // Bulkhead size, 0 disables
+	MaxWait       time.Duration // How long a call waits for a bulkhead slot
+
+	// Retryable decides whether a failed attempt is tried again; nil retries
+	// every error except cancellation. Timeouts are always retryable.
+	Retryable func(error) bool
+	// IsFailure decides whether an error counts against the breaker; nil uses Retryable
+	IsFailure func(error) bool
+}
+
+// ConfigFromEnv overrides defaults with <PREFIX>_TIMEOUT, <PREFIX>_RETRY_ATTEMPTS,
+// <PREFIX>_RETRY_BASE_DELAY, <PREFIX>_BREAKER_THRESHOLD, <PREFIX>_BREAKER_COOLDOWN
+// and <PREFIX>_MAX_CONCURRENT
+func ConfigFromEnv(prefix string, defaults Config) Config {
+	config := defaults
+	config.Timeout = envDuration(prefix+"_TIMEOUT", config.Timeout)
+	config.Retry.MaxAttempts = envInt(prefix+"_RETRY_ATTEMPTS", config.Retry.MaxAttempts)
+	config.Retry.BaseDelay = envDuration(prefix+"_RETRY_BASE_DELAY", config.Retry.BaseDelay)
+	config.Breaker.FailureThreshold = envInt(prefix+"_BREAKER_THRESHOLD", config.Breaker.FailureThreshold)
+	config.Breaker.OpenTimeout = envDuration(prefix+"_BREAKER_COOLDOWN", config.Breaker.OpenTimeout)
+	config.MaxConcurrent = envInt(prefix+"_MAX_CONCURRENT", config.MaxConcurrent)
+	return config
+}
+
+// Policy applies the configured guards to every call of one dependency. Each
+// attempt takes a bulkhead slot, asks the breaker and runs under the timeout;
+// retries wrap the attempts.
+type Policy struct {
+	name     string
+	config   Config
+	breaker  *CircuitBreaker
+	bulkhead *Bulkhead
+}
+
+func NewPolicy(name string, config Config) *Policy {
+	if config.Retry.MaxAttempts <= 0 {
+		config.Retry.MaxAttempts = 1
+	}
+
+	policy := &Policy{name: name, config: config}
+	if config.Breaker.FailureThreshold > 0 {
+		policy.breaker = NewCircuitBreaker(name, config.Breaker)
+	}
+	if config.MaxConcurrent > 0 {
+		policy.bulkhead = NewBulkhead(name, config.MaxConcurrent, config.MaxWait)
+	}
+	return policy
+}
+
+func (p *Policy) Name() string {
+	return p.name
+}
+
+// Execute runs fn until it succeeds, fails with a non-retryable error or runs
+// out of attempts. fn must honour the context it is given.
+func (p *Policy) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := p.attempt(ctx, fn)
+		if err == nil || attempt >= p.config.Retry.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+
+		delay := p.config.Retry.backoff(attempt - 1)
+		metrics.ResilienceRetries.WithLabelValues(p.name).Inc()
+		slog.WarnContext(ctx, "Retrying provider call",
+			"dependency", p.name, "attempt", attempt, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *Policy) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.bulkhead != nil {
+		release, err := p.bulkhead.acquire(ctx)
+		if err != nil {
+			if errors.Is(err, ErrBulkheadFull) {
+				metrics.ResilienceRejections.WithLabelValues(p.name, "bulkhead_full").Inc()
+			}
+			return err
+		}
+		defer release()
+	}
+
+	probe := false
+	if p.breaker != nil {
+		var err error
+		if probe, err = p.breaker.allow(); err != nil {
+			metrics.ResilienceRejections.WithLabelValues(p.name, "circuit_open").Inc()
+			return err
+		}
+	}
+
+	callCtx := ctx
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+
+	err := fn(callCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		metrics.ResilienceRejections.WithLabelValues(p.name, "timeout").Inc()
+		err = fmt.Errorf("%w: %s after %s: %w", ErrTimeout, p.name, p.config.Timeout, err)
+	}
+
+	if p.breaker != nil {
+		p.breaker.record(probe, err != nil && p.isFailure(err))
+	}
+	return err
+}
+
+func (p *Policy) retryable(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBulkheadFull) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrTimeout) {
+		return true
+	}
+	if p.config.Retryable != nil {
+		return p.config.Retryable(err)
+	}
+	return true
+}
+
+func (p *Policy) isFailure(err error) bool {
+	if p.config.IsFailure != nil {
+		return p.config.IsFailure(err)
+	}
+	return p.retryable(err)
+}
+
+// Call is Execute for functions that return a value
+func Call[T any](ctx context.Context, p *Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := p.Execute(ctx, func(ctx context.Context) error {
+		value, err := fn(ctx)
+		if err == nil {
+			result = value
+		}
+		return err
+	})
+	return result, err
+}
+
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func envInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
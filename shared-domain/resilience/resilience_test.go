@@ -0,0 +1,314 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errProvider = errors.New("provider down")
+
+// testClock a clock that only moves when told to
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(t *testing.T, config BreakerConfig) (*CircuitBreaker, *testClock) {
+	t.Helper()
+
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	breaker := NewCircuitBreaker(t.Name(), config)
+	breaker.now = clock.Now
+	return breaker, clock
+}
+
+// call runs one call through the breaker the way Policy does
+func call(b *CircuitBreaker, err error) error {
+	probe, rejected := b.allow()
+	if rejected != nil {
+		return rejected
+	}
+	b.record(probe, err != nil)
+	return err
+}
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	breaker, _ := newTestBreaker(t, BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute})
+
+	call(breaker, errProvider)
+	call(breaker, errProvider)
+	call(breaker, nil) // A success resets the count
+	call(breaker, errProvider)
+	call(breaker, errProvider)
+	if state := breaker.Status().State; state != "closed" {
+		t.Fatalf("state after 2 consecutive failures = %s, want closed", state)
+	}
+
+	call(breaker, errProvider)
+	if state := breaker.Status().State; state != "open" {
+		t.Fatalf("state after 3 consecutive failures = %s, want open", state)
+	}
+	if err := call(breaker, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("call while open error = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	breaker, clock := newTestBreaker(t, BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenProbes: 2})
+
+	call(breaker, errProvider)
+	clock.Advance(time.Minute - time.Second)
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow before the open timeout error = %v, want ErrCircuitOpen", err)
+	}
+
+	// After the timeout only HalfOpenProbes calls are let through at a time
+	clock.Advance(time.Second)
+	first, err := breaker.allow()
+	if err != nil || !first {
+		t.Fatalf("first probe = %v, %v, want a probe", first, err)
+	}
+	second, err := breaker.allow()
+	if err != nil || !second {
+		t.Fatalf("second probe = %v, %v, want a probe", second, err)
+	}
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("third call while probing error = %v, want ErrCircuitOpen", err)
+	}
+	if state := breaker.Status().State; state != "half_open" {
+		t.Fatalf("state while probing = %s, want half_open", state)
+	}
+
+	// All probes must succeed to close
+	breaker.record(first, false)
+	if state := breaker.Status().State; state != "half_open" {
+		t.Fatalf("state after one successful probe = %s, want half_open", state)
+	}
+	breaker.record(second, false)
+	if state := breaker.Status().State; state != "closed" {
+		t.Fatalf("state after all probes succeeded = %s, want closed", state)
+	}
+}
+
+func TestCircuitBreakerFailedProbeReopens(t *testing.T) {
+	breaker, clock := newTestBreaker(t, BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	call(breaker, errProvider)
+	clock.Advance(time.Minute)
+	if err := call(breaker, errProvider); !errors.Is(err, errProvider) {
+		t.Fatalf("probe error = %v, want the provider error", err)
+	}
+
+	status := breaker.Status()
+	if status.State != "open" || status.OpenedAt == nil || !status.OpenedAt.Equal(clock.Now()) {
+		t.Fatalf("status after a failed probe = %+v, want open since %s", status, clock.Now())
+	}
+
+	// The open timeout starts over from the failed probe
+	clock.Advance(time.Minute - time.Second)
+	if err := call(breaker, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("call before the new timeout error = %v, want ErrCircuitOpen", err)
+	}
+	clock.Advance(time.Second)
+	if err := call(breaker, nil); err != nil {
+		t.Errorf("probe after the new timeout error = %v", err)
+	}
+	if state := breaker.Status().State; state != "closed" {
+		t.Errorf("state after a successful probe = %s, want closed", state)
+	}
+}
+
+func TestCircuitBreakerIgnoresResultsOfEarlierState(t *testing.T) {
+	breaker, _ := newTestBreaker(t, BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	slow, _ := breaker.allow()
+	call(breaker, errProvider)
+
+	// A call admitted while closed that finishes after the circuit opened
+	breaker.record(slow, false)
+	if state := breaker.Status().State; state != "open" {
+		t.Errorf("state = %s, want open", state)
+	}
+}
+
+func TestBulkheadRejectsAtLimit(t *testing.T) {
+	bulkhead := NewBulkhead(t.Name(), 2, 0)
+	ctx := context.Background()
+
+	releaseFirst, err := bulkhead.acquire(ctx)
+	if err != nil {
+		t.Fatalf("first slot: %v", err)
+	}
+	if _, err := bulkhead.acquire(ctx); err != nil {
+		t.Fatalf("second slot: %v", err)
+	}
+	if _, err := bulkhead.acquire(ctx); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("third call error = %v, want ErrBulkheadFull", err)
+	}
+
+	releaseFirst()
+	if _, err := bulkhead.acquire(ctx); err != nil {
+		t.Errorf("slot after a release: %v", err)
+	}
+}
+
+func TestBulkheadWaitsForSlot(t *testing.T) {
+	bulkhead := NewBulkhead(t.Name(), 1, time.Hour)
+
+	release, err := bulkhead.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("first slot: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := bulkhead.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("waiting with a cancelled context error = %v, want context.Canceled", err)
+	}
+
+	acquired := make(chan error)
+	go func() {
+		_, err := bulkhead.acquire(context.Background())
+		acquired <- err
+	}()
+	release()
+	if err := <-acquired; err != nil {
+		t.Errorf("waiting call error = %v, want the released slot", err)
+	}
+}
+
+func TestPolicyBulkheadRejection(t *testing.T) {
+	policy := NewPolicy(t.Name(), Config{MaxConcurrent: 1})
+
+	inside, leave := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- policy.Execute(context.Background(), func(context.Context) error {
+			close(inside)
+			<-leave
+			return nil
+		})
+	}()
+	<-inside
+
+	calls := 0
+	err := policy.Execute(context.Background(), func(context.Context) error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, ErrBulkheadFull) || calls != 0 {
+		t.Errorf("call at the limit = %v after %d calls, want ErrBulkheadFull without calling", err, calls)
+	}
+
+	close(leave)
+	if err := <-done; err != nil {
+		t.Errorf("call holding the slot: %v", err)
+	}
+}
+
+func TestRetryBackoffCeiling(t *testing.T) {
+	defer func(original func(int64) int64) { jitter = original }(jitter)
+	// The highest delay jitter can draw is the ceiling itself
+	jitter = func(n int64) int64 { return n - 1 }
+
+	tests := []struct {
+		config RetryConfig
+		retry  int
+		want   time.Duration
+	}{
+		{RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, 0, 100 * time.Millisecond},
+		{RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, 1, 200 * time.Millisecond},
+		{RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, 3, 800 * time.Millisecond},
+		{RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, 4, time.Second},
+		{RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, 20, time.Second},
+		{RetryConfig{BaseDelay: 100 * time.Millisecond}, 4, 1600 * time.Millisecond},
+		{RetryConfig{}, 3, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.config.backoff(tt.retry); got != tt.want {
+			t.Errorf("backoff(%d) with %+v = %s, want at most %s", tt.retry, tt.config, got, tt.want)
+		}
+	}
+
+	jitter = func(int64) int64 { return 0 }
+	if got := (RetryConfig{BaseDelay: time.Second}).backoff(2); got != 0 {
+		t.Errorf("backoff with the lowest jitter = %s, want 0", got)
+	}
+}
+
+func TestPolicyRetries(t *testing.T) {
+	defer func(original func(int64) int64) { jitter = original }(jitter)
+	jitter = func(int64) int64 { return 0 }
+
+	retryConfig := RetryConfig{MaxAttempts: 3, BaseDelay: time.Hour}
+
+	t.Run("until success", func(t *testing.T) {
+		policy := NewPolicy(t.Name(), Config{Retry: retryConfig})
+		attempts := 0
+		err := policy.Execute(context.Background(), func(context.Context) error {
+			attempts++
+			if attempts < 2 {
+				return errProvider
+			}
+			return nil
+		})
+		if err != nil || attempts != 2 {
+			t.Errorf("got %v after %d attempts, want success after 2", err, attempts)
+		}
+	})
+
+	t.Run("up to max attempts", func(t *testing.T) {
+		policy := NewPolicy(t.Name(), Config{Retry: retryConfig})
+		attempts := 0
+		err := policy.Execute(context.Background(), func(context.Context) error {
+			attempts++
+			return errProvider
+		})
+		if !errors.Is(err, errProvider) || attempts != 3 {
+			t.Errorf("got %v after %d attempts, want the provider error after 3", err, attempts)
+		}
+	})
+
+	t.Run("not when not retryable", func(t *testing.T) {
+		policy := NewPolicy(t.Name(), Config{
+			Retry:     retryConfig,
+			Retryable: func(err error) bool { return !errors.Is(err, errProvider) },
+		})
+		attempts := 0
+		policy.Execute(context.Background(), func(context.Context) error {
+			attempts++
+			return errProvider
+		})
+		if attempts != 1 {
+			t.Errorf("got %d attempts, want 1", attempts)
+		}
+	})
+
+	t.Run("not when the circuit opens", func(t *testing.T) {
+		policy := NewPolicy(t.Name(), Config{Retry: retryConfig, Breaker: BreakerConfig{FailureThreshold: 1}})
+		attempts := 0
+		err := policy.Execute(context.Background(), func(context.Context) error {
+			attempts++
+			return errProvider
+		})
+		if !errors.Is(err, ErrCircuitOpen) || attempts != 1 {
+			t.Errorf("got %v after %d attempts, want ErrCircuitOpen after 1", err, attempts)
+		}
+	})
+}
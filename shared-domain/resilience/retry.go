@@ -0,0 +1,31 @@
+package resilience
+
+import (
+	"math/rand"
+	"time"
+)
+
+// jitter draws the random backoff in [0, n); replaced by tests
+var jitter = rand.Int63n
+
+type RetryConfig struct {
+	MaxAttempts int           // Including the first call; 1 disables retries
+	BaseDelay   time.Duration // Backoff ceiling of the first retry, doubled on each further retry
+	MaxDelay    time.Duration
+}
+
+// backoff full jitter: a random delay between zero and the exponential
+// ceiling, so callers that failed together do not retry together
+func (r RetryConfig) backoff(retry int) time.Duration {
+	ceiling := r.BaseDelay
+	for i := 0; i < retry && (r.MaxDelay <= 0 || ceiling < r.MaxDelay); i++ {
+		ceiling *= 2
+	}
+	if r.MaxDelay > 0 && ceiling > r.MaxDelay {
+		ceiling = r.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(jitter(int64(ceiling) + 1))
+}
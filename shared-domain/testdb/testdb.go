@@ -0,0 +1,34 @@
+// Package testdb opens the PostgreSQL databases that repository tests run
+// against. The caller imports the driver, e.g. github.com/lib/pq.
+package testdb
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+// Open connects to the database named by the environment variable and closes it
+// when the test ends. The test is skipped when the variable is not set. The
+// database must be initialized with scripts/init-databases.sql.
+func Open(t testing.TB, envVar string) *sql.DB {
+	t.Helper()
+
+	url := os.Getenv(envVar)
+	if url == "" {
+		t.Skip(envVar + " not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// Enough connections for the concurrency tests to contend for row locks
+	db.SetMaxOpenConns(20)
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Ping(); err != nil {
+		t.Fatalf("ping database: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fiberHeaderCarrier adapts request headers to propagation.TextMapCarrier
+type fiberHeaderCarrier struct {
+	c *fiber.Ctx
+}
+
+func (f fiberHeaderCarrier) Get(key string) string {
+	return f.c.Get(key)
+}
+
+func (f fiberHeaderCarrier) Set(key, value string) {
+	f.c.Set(key, value)
+}
+
+func (f fiberHeaderCarrier) Keys() []string {
+	keys := make([]string, 0)
+	f.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// FiberMiddleware starts a server span for every request and stores it in c.UserContext()
+// so handlers can pass it down to services and publishers.
+func FiberMiddleware(serviceName string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := Extract(c.UserContext(), fiberHeaderCarrier{c: c})
+
+		ctx, span := Tracer().Start(ctx, fmt.Sprintf("%s %s", c.Method(), c.Path()),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+				attribute.String("service", serviceName),
+			),
+		)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// Route is only resolved after the handler chain ran
+		route := c.Route().Path
+		span.SetName(fmt.Sprintf("%s %s", c.Method(), route))
+		span.SetAttributes(
+			semconv.HTTPRoute(route),
+			semconv.HTTPResponseStatusCode(c.Response().StatusCode()),
+		)
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else if c.Response().StatusCode() >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", c.Response().StatusCode()))
+		}
+
+		return err
+	}
+}
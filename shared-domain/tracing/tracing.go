@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/distributed-ecommerce-saga/shared-domain"
+
+// Exporter types selected with OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Init installs the global tracer provider and W3C propagators for the service.
+//
+// OTEL_TRACES_EXPORTER selects the exporter: "otlp" (OTLP/HTTP, configured by the
+// standard OTEL_EXPORTER_OTLP_* variables), "stdout" for local debugging, or "none" (default).
+// The returned function flushes pending spans and stops the exporter.
+func Init(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporterType := strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER"))
+	if exporterType == "" {
+		exporterType = ExporterNone
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch exporterType {
+	case ExporterNone:
+		slog.Info("Tracing exporter disabled")
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout, "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER: %s", exporterType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trace exporter init error: %v", err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("trace resource init error: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	slog.Info("Tracing enabled", "exporter", exporterType)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer used by shared-domain instrumentation
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Inject writes the trace context of ctx into carrier
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// Extract returns a context carrying the remote trace context found in carrier
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
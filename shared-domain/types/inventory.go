@@ -13,23 +13,67 @@ const (
 	InventoryStatusReserved  InventoryStatus = "reserved"
 	InventoryStatusReleased  InventoryStatus = "released"
 	InventoryStatusSold      InventoryStatus = "sold"
+	InventoryStatusExpired   InventoryStatus = "expired" // Released by the sweeper after ExpiresAt
 )
 
 type InventoryReservation struct {
-	ID         uuid.UUID       `json:"id"`
-	OrderID    uuid.UUID       `json:"order_id"`
-	ProductID  uuid.UUID       `json:"product_id"`
-	Quantity   int             `json:"quantity"`
-	Status     InventoryStatus `json:"status"`
-	ReservedAt time.Time       `json:"reserved_at"`
-	ExpiresAt  time.Time       `json:"expires_at"`
-	UpdatedAt  time.Time       `json:"updated_at"`
+	ID        uuid.UUID       `json:"id"`
+	OrderID   uuid.UUID       `json:"order_id"`
+	ProductID uuid.UUID       `json:"product_id"`
+	Quantity  int             `json:"quantity"`
+	Status    InventoryStatus `json:"status"`
+	// Warehouse the units are taken from
+	WarehouseID uuid.UUID `json:"warehouse_id"`
+	ReservedAt  time.Time `json:"reserved_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 type Product struct {
 	ID            uuid.UUID `json:"id"`
 	Name          string    `json:"name"`
-	Price         float64   `json:"price"`
+	SKU           string    `json:"sku"`
+	Price         Money     `json:"price"`
 	Stock         int       `json:"stock"`
 	ReservedStock int       `json:"reserved_stock"`
+	SoldStock     int       `json:"sold_stock"` // Units sold by completed orders
+}
+
+// ItemOutcome what became of one product of an order when its stock was
+// reserved. Requested = Reserved + Backordered + Unavailable.
+type ItemOutcome struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	Requested   int       `json:"requested"`
+	Reserved    int       `json:"reserved"`    // Shipped and charged now
+	Backordered int       `json:"backordered"` // Shipped and charged once stock arrives
+	Unavailable int       `json:"unavailable"` // Dropped from the order
+}
+
+// Short reports whether less than requested was reserved
+func (o ItemOutcome) Short() bool {
+	return o.Reserved < o.Requested
+}
+
+type BackorderStatus string
+
+const (
+	BackorderStatusWaiting    BackorderStatus = "waiting"    // For stock to arrive
+	BackorderStatusProcessing BackorderStatus = "processing" // Stock arrived, a follow-up saga reserves it
+	BackorderStatusReserved   BackorderStatus = "reserved"
+	BackorderStatusFulfilled  BackorderStatus = "fulfilled" // Follow-up saga completed
+	BackorderStatusCancelled  BackorderStatus = "cancelled" // Follow-up saga failed after reserving
+)
+
+// Backorder units of an order's product that were out of stock and are
+// fulfilled by a follow-up saga
+type Backorder struct {
+	ID        uuid.UUID       `json:"id"`
+	OrderID   uuid.UUID       `json:"order_id"`
+	ProductID uuid.UUID       `json:"product_id"`
+	Quantity  int             `json:"quantity"`
+	Status    BackorderStatus `json:"status"`
+	// SagaID of the follow-up saga, set once stock arrived
+	SagaID    uuid.UUID `json:"saga_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
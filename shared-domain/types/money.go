@@ -0,0 +1,396 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency ISO 4217 currency code
+type Currency string
+
+const (
+	CurrencyUSD Currency = "USD"
+	CurrencyEUR Currency = "EUR"
+	CurrencyGBP Currency = "GBP"
+	CurrencyTRY Currency = "TRY"
+	CurrencyJPY Currency = "JPY"
+
+	// DefaultCurrency is used when a request does not specify one
+	DefaultCurrency = CurrencyUSD
+)
+
+// currencyExponents number of minor unit digits for currencies that do not use 2
+var currencyExponents = map[Currency]int{
+	"JPY": 0,
+	"KRW": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+}
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidCurrency  = errors.New("invalid currency")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// ParseCurrency normalizes and validates a three letter currency code
+func ParseCurrency(code string) (Currency, error) {
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if !currency.Valid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+	}
+	return currency, nil
+}
+
+func (c Currency) Valid() bool {
+	if len(c) != 3 {
+		return false
+	}
+	for _, r := range c {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Exponent number of digits after the decimal point, e.g. 2 for USD and 0 for JPY
+func (c Currency) Exponent() int {
+	if exponent, ok := currencyExponents[c]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Money is an amount in minor units (cents for USD) of a single currency.
+// It never goes through float64, so sums and refunds are exact.
+type Money struct {
+	Amount   int64
+	Currency Currency
+
+	// decimal an amount decoded without a currency, which cannot be scaled to
+	// minor units until InCurrency names one
+	decimal string
+}
+
+// NewMoney creates money from minor units
+func NewMoney(minorUnits int64, currency Currency) Money {
+	return Money{Amount: minorUnits, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "12.34" in the given currency.
+// More fraction digits than the currency allows is an error, not a rounding.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	amount = strings.TrimSpace(amount)
+	if amount == "" {
+		return Money{}, fmt.Errorf("%w: empty", ErrInvalidAmount)
+	}
+
+	negative, whole, fraction, ok := splitDecimal(amount)
+	exponent := currency.Exponent()
+	if !ok || len(fraction) > exponent {
+		return Money{}, fmt.Errorf("%w: %q for %s", ErrInvalidAmount, amount, currency)
+	}
+
+	fraction += strings.Repeat("0", exponent-len(fraction))
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q: %v", ErrInvalidAmount, amount, err)
+	}
+
+	if negative {
+		minor = -minor
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// InCurrency fills in the currency of an amount decoded without one, scaling it
+// to the minor units of that currency. Money that has a currency is returned
+// as is.
+func (m Money) InCurrency(currency Currency) (Money, error) {
+	switch {
+	case m.Currency != "":
+		return m, nil
+	case m.decimal != "":
+		return ParseMoney(m.decimal, currency)
+	default:
+		return Money{Amount: m.Amount, Currency: currency}, nil
+	}
+}
+
+// MoneyFromValue converts a value decoded from a JSON map (saga payloads and
+// contexts) back into Money
+func MoneyFromValue(value interface{}) (Money, error) {
+	if money, ok := value.(Money); ok {
+		return money, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %v", ErrInvalidAmount, err)
+	}
+
+	var money Money
+	if err := json.Unmarshal(data, &money); err != nil {
+		return Money{}, err
+	}
+	return money, nil
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, fmt.Errorf("%w: overflow", ErrInvalidAmount)
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// Mul multiplies by a quantity, e.g. unit price times item count
+func (m Money) Mul(quantity int64) (Money, error) {
+	if m.decimal != "" {
+		return Money{}, fmt.Errorf("%w: amount without a currency", ErrInvalidCurrency)
+	}
+	if quantity != 0 && m.Amount != 0 {
+		product := m.Amount * quantity
+		// MinInt64 * -1 wraps to MinInt64, which the division does not catch
+		if product/quantity != m.Amount || (m.Amount == -1 && quantity == math.MinInt64) ||
+			(quantity == -1 && m.Amount == math.MinInt64) {
+			return Money{}, fmt.Errorf("%w: overflow", ErrInvalidAmount)
+		}
+		return Money{Amount: product, Currency: m.Currency}, nil
+	}
+	return Money{Currency: m.Currency}, nil
+}
+
+// Cmp returns -1, 0 or +1; comparing different currencies is an error
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (m Money) IsZero() bool     { return m.sign() == 0 }
+func (m Money) IsPositive() bool { return m.sign() > 0 }
+func (m Money) IsNegative() bool { return m.sign() < 0 }
+
+// sign of the amount, also of one decoded without a currency; scaling to minor
+// units never changes it
+func (m Money) sign() int {
+	if m.decimal != "" {
+		negative, whole, fraction, _ := splitDecimal(m.decimal)
+		if strings.Trim(whole+fraction, "0") == "" {
+			return 0
+		}
+		if negative {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case m.Amount < 0:
+		return -1
+	case m.Amount > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SameCurrency reports whether both amounts can be added or compared
+func (m Money) SameCurrency(other Money) bool {
+	return m.Currency == other.Currency
+}
+
+func (m Money) checkCurrency(other Money) error {
+	if m.decimal != "" || other.decimal != "" {
+		return fmt.Errorf("%w: amount without a currency", ErrInvalidCurrency)
+	}
+	if !m.SameCurrency(other) {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return nil
+}
+
+// Decimal formats the amount with the currency's minor unit digits, e.g. "12.34"
+func (m Money) Decimal() string {
+	if m.decimal != "" {
+		return m.decimal
+	}
+	exponent := m.Currency.Exponent()
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absUint64(amount), 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	split := len(digits) - exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + string(m.Currency)
+}
+
+func (m Money) LogValue() slog.Value {
+	return slog.StringValue(m.String())
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency Currency        `json:"currency"`
+}
+
+// MarshalJSON encodes money as {"amount":"12.34","currency":"USD"}; the amount
+// is a string so JSON clients never parse it into a float
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount, err := json.Marshal(m.Decimal())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(moneyJSON{Amount: amount, Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the object form with a string or numeric amount. A bare
+// number or string, or an object without a currency, is also accepted; the
+// currency decides the minor units, so the amount is only scaled once the
+// caller fills it in with InCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	} else {
+		raw.Amount = data
+	}
+
+	if string(raw.Amount) == "null" || len(raw.Amount) == 0 {
+		return fmt.Errorf("%w: missing amount", ErrInvalidAmount)
+	}
+
+	currency := raw.Currency
+	if currency != "" {
+		parsed, err := ParseCurrency(string(currency))
+		if err != nil {
+			return err
+		}
+		currency = parsed
+	}
+
+	amount := string(raw.Amount)
+	if unquoted, err := strconv.Unquote(amount); err == nil {
+		amount = unquoted
+	}
+
+	if currency == "" {
+		amount = strings.TrimSpace(amount)
+		if _, _, _, ok := splitDecimal(amount); !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+		}
+		*m = Money{decimal: amount}
+		return nil
+	}
+
+	parsed, err := ParseMoney(amount, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the minor units; the currency lives in its own column
+func (m Money) Value() (driver.Value, error) {
+	if m.decimal != "" {
+		return nil, fmt.Errorf("%w: amount without a currency", ErrInvalidCurrency)
+	}
+	return m.Amount, nil
+}
+
+// Scan reads minor units only. Repositories scan the currency column into
+// Money.Currency separately.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		m.Amount = v
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case nil:
+		m.Amount = 0
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(value string) error {
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+	m.Amount = amount
+	return nil
+}
+
+// splitDecimal splits a decimal such as "-12.34" into its sign and digits
+func splitDecimal(amount string) (negative bool, whole, fraction string, ok bool) {
+	if amount == "" {
+		return false, "", "", false
+	}
+	if amount[0] == '-' || amount[0] == '+' {
+		negative = amount[0] == '-'
+		amount = amount[1:]
+	}
+
+	whole, fraction, _ = strings.Cut(amount, ".")
+	ok = whole != "" && isDigits(whole) && isDigits(fraction)
+	return negative, whole, fraction, ok
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func absUint64(value int64) uint64 {
+	if value < 0 {
+		return uint64(-(value + 1)) + 1
+	}
+	return uint64(value)
+}
@@ -0,0 +1,253 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency Currency
+		want     int64
+		wantErr  bool
+	}{
+		{amount: "12.34", currency: CurrencyUSD, want: 1234},
+		{amount: "12.3", currency: CurrencyUSD, want: 1230},
+		{amount: "12", currency: CurrencyUSD, want: 1200},
+		{amount: "-0.05", currency: CurrencyUSD, want: -5},
+		{amount: " +7.00 ", currency: CurrencyUSD, want: 700},
+		{amount: "1000", currency: CurrencyJPY, want: 1000},
+		{amount: "1.234", currency: "KWD", want: 1234},
+		{amount: "1.5", currency: "KWD", want: 1500},
+		{amount: "12.345", currency: CurrencyUSD, wantErr: true},
+		{amount: "1.5", currency: CurrencyJPY, wantErr: true},
+		{amount: "", currency: CurrencyUSD, wantErr: true},
+		{amount: ".50", currency: CurrencyUSD, wantErr: true},
+		{amount: "1,50", currency: CurrencyUSD, wantErr: true},
+		{amount: "1e3", currency: CurrencyUSD, wantErr: true},
+		{amount: "92233720368547758.08", currency: CurrencyUSD, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.amount, tt.currency)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("ParseMoney(%q, %s) error = %v, want ErrInvalidAmount", tt.amount, tt.currency, err)
+			}
+			continue
+		}
+		if err != nil || got != NewMoney(tt.want, tt.currency) {
+			t.Errorf("ParseMoney(%q, %s) = %v, %v, want %d minor units", tt.amount, tt.currency, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{NewMoney(1234, CurrencyUSD), "12.34"},
+		{NewMoney(5, CurrencyUSD), "0.05"},
+		{NewMoney(-5, CurrencyUSD), "-0.05"},
+		{NewMoney(1000, CurrencyJPY), "1000"},
+		{NewMoney(1234, "KWD"), "1.234"},
+		{NewMoney(math.MinInt64, CurrencyJPY), "-9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.want {
+			t.Errorf("%d %s Decimal() = %q, want %q", tt.money.Amount, tt.money.Currency, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	usd := func(amount int64) Money { return NewMoney(amount, CurrencyUSD) }
+
+	if sum, err := usd(150).Add(usd(250)); err != nil || sum != usd(400) {
+		t.Errorf("Add = %v, %v, want 4.00 USD", sum, err)
+	}
+	if difference, err := usd(150).Sub(usd(250)); err != nil || difference != usd(-100) {
+		t.Errorf("Sub = %v, %v, want -1.00 USD", difference, err)
+	}
+	if product, err := usd(1999).Mul(3); err != nil || product != usd(5997) {
+		t.Errorf("Mul = %v, %v, want 59.97 USD", product, err)
+	}
+	if product, err := usd(1999).Mul(0); err != nil || product != usd(0) {
+		t.Errorf("Mul by zero = %v, %v, want 0.00 USD", product, err)
+	}
+	if cmp, err := usd(100).Cmp(usd(200)); err != nil || cmp != -1 {
+		t.Errorf("Cmp = %d, %v, want -1", cmp, err)
+	}
+	if cmp, err := usd(200).Cmp(usd(200)); err != nil || cmp != 0 {
+		t.Errorf("Cmp = %d, %v, want 0", cmp, err)
+	}
+}
+
+func TestMoneyOverflow(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func() (Money, error)
+	}{
+		{"add", func() (Money, error) { return NewMoney(math.MaxInt64, CurrencyUSD).Add(NewMoney(1, CurrencyUSD)) }},
+		{"add negative", func() (Money, error) { return NewMoney(math.MinInt64, CurrencyUSD).Add(NewMoney(-1, CurrencyUSD)) }},
+		{"sub", func() (Money, error) { return NewMoney(math.MinInt64+1, CurrencyUSD).Sub(NewMoney(2, CurrencyUSD)) }},
+		{"mul", func() (Money, error) { return NewMoney(math.MaxInt64/2+1, CurrencyUSD).Mul(2) }},
+		{"mul negative", func() (Money, error) { return NewMoney(math.MaxInt64, CurrencyUSD).Mul(-2) }},
+		{"mul min by -1", func() (Money, error) { return NewMoney(math.MinInt64, CurrencyUSD).Mul(-1) }},
+		{"mul -1 by min", func() (Money, error) { return NewMoney(-1, CurrencyUSD).Mul(math.MinInt64) }},
+	}
+
+	for _, tt := range tests {
+		if got, err := tt.op(); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("%s = %v, %v, want an overflow error", tt.name, got, err)
+		}
+	}
+}
+
+func TestMoneyCurrencyMismatch(t *testing.T) {
+	usd, eur := NewMoney(100, CurrencyUSD), NewMoney(100, CurrencyEUR)
+
+	if _, err := usd.Add(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add error = %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := usd.Sub(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Sub error = %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := usd.Cmp(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Cmp error = %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+func TestMoneyJSONRoundTrip(t *testing.T) {
+	for _, money := range []Money{
+		NewMoney(1234, CurrencyUSD),
+		NewMoney(-5, CurrencyEUR),
+		NewMoney(1000, CurrencyJPY),
+		NewMoney(1234, "KWD"),
+	} {
+		data, err := json.Marshal(money)
+		if err != nil {
+			t.Fatalf("marshal %v: %v", money, err)
+		}
+
+		var decoded Money
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != money {
+			t.Errorf("round trip of %v through %s = %v, %v", money, data, decoded, err)
+		}
+	}
+
+	data, _ := json.Marshal(NewMoney(1234, CurrencyUSD))
+	if string(data) != `{"amount":"12.34","currency":"USD"}` {
+		t.Errorf("marshal = %s", data)
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json     string
+		currency Currency // Filled in with InCurrency
+		want     Money
+	}{
+		{`{"amount":"12.34","currency":"usd"}`, CurrencyEUR, NewMoney(1234, CurrencyUSD)},
+		{`{"amount":12.34,"currency":"USD"}`, CurrencyEUR, NewMoney(1234, CurrencyUSD)},
+		{`{"amount":"1000","currency":"JPY"}`, CurrencyUSD, NewMoney(1000, CurrencyJPY)},
+		{`"12.34"`, CurrencyUSD, NewMoney(1234, CurrencyUSD)},
+		{`12.34`, CurrencyEUR, NewMoney(1234, CurrencyEUR)},
+		{`{"amount":"12.34"}`, CurrencyUSD, NewMoney(1234, CurrencyUSD)},
+		// Bare amounts are scaled by the currency filled in, not by the default
+		{`1000`, CurrencyJPY, NewMoney(1000, CurrencyJPY)},
+		{`"1000"`, CurrencyJPY, NewMoney(1000, CurrencyJPY)},
+		{`"1.234"`, "KWD", NewMoney(1234, "KWD")},
+		{`"1.5"`, "KWD", NewMoney(1500, "KWD")},
+	}
+
+	for _, tt := range tests {
+		var money Money
+		if err := json.Unmarshal([]byte(tt.json), &money); err != nil {
+			t.Errorf("unmarshal %s: %v", tt.json, err)
+			continue
+		}
+		got, err := money.InCurrency(tt.currency)
+		if err != nil || got != tt.want {
+			t.Errorf("unmarshal %s in %s = %v, %v, want %v", tt.json, tt.currency, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSONInvalid(t *testing.T) {
+	for _, data := range []string{
+		`null`,
+		`{"currency":"USD"}`,
+		`{"amount":"12.345","currency":"USD"}`,
+		`{"amount":"1.5","currency":"JPY"}`,
+		`{"amount":"1","currency":"US"}`,
+		`"twelve"`,
+		`"1e3"`,
+		`true`,
+	} {
+		var money Money
+		if err := json.Unmarshal([]byte(data), &money); err == nil {
+			t.Errorf("unmarshal %s = %v, want an error", data, money)
+		}
+	}
+}
+
+func TestMoneyWithoutCurrency(t *testing.T) {
+	var bare Money
+	if err := json.Unmarshal([]byte(`"1.5"`), &bare); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	// Too many fraction digits only show once the currency is known
+	if _, err := bare.InCurrency(CurrencyJPY); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("InCurrency(JPY) error = %v, want ErrInvalidAmount", err)
+	}
+	if _, err := bare.Cmp(bare); !errors.Is(err, ErrInvalidCurrency) {
+		t.Errorf("Cmp without a currency error = %v, want ErrInvalidCurrency", err)
+	}
+	if _, err := bare.Value(); !errors.Is(err, ErrInvalidCurrency) {
+		t.Errorf("Value without a currency error = %v, want ErrInvalidCurrency", err)
+	}
+
+	if _, err := bare.Mul(2); !errors.Is(err, ErrInvalidCurrency) {
+		t.Errorf("Mul without a currency error = %v, want ErrInvalidCurrency", err)
+	}
+
+	usd := NewMoney(150, CurrencyUSD)
+	if got, err := usd.InCurrency(CurrencyJPY); err != nil || got != usd {
+		t.Errorf("InCurrency keeps a named currency, got %v, %v", got, err)
+	}
+}
+
+func TestMoneySign(t *testing.T) {
+	tests := []struct {
+		json                     string
+		zero, positive, negative bool
+	}{
+		{`"12.50"`, false, true, false},
+		{`"0.01"`, false, true, false},
+		{`"-3"`, false, false, true},
+		{`"0.00"`, true, false, false},
+		{`"-0"`, true, false, false},
+		{`{"amount": "12.50", "currency": "USD"}`, false, true, false},
+		{`{"amount": "-0.50", "currency": "USD"}`, false, false, true},
+		{`{"amount": "0", "currency": "JPY"}`, true, false, false},
+	}
+
+	for _, tt := range tests {
+		var money Money
+		if err := json.Unmarshal([]byte(tt.json), &money); err != nil {
+			t.Fatalf("unmarshal %s: %v", tt.json, err)
+		}
+		// Amounts without a currency have the sign they will have in any currency
+		if money.IsZero() != tt.zero || money.IsPositive() != tt.positive || money.IsNegative() != tt.negative {
+			t.Errorf("%s: zero %v positive %v negative %v, want %v %v %v", tt.json,
+				money.IsZero(), money.IsPositive(), money.IsNegative(), tt.zero, tt.positive, tt.negative)
+		}
+	}
+}
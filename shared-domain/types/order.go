@@ -1,6 +1,7 @@
 package types
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,19 +17,63 @@ const (
 	OrderStatusFailed     OrderStatus = "failed"
 )
 
+// FulfillmentPolicy what the saga does with items that are out of stock
+type FulfillmentPolicy string
+
+const (
+	// FulfillAllOrNothing fails the whole order when any item is short
+	FulfillAllOrNothing FulfillmentPolicy = "all_or_nothing"
+	// FulfillAllowPartial ships what is in stock and drops the rest
+	FulfillAllowPartial FulfillmentPolicy = "allow_partial"
+	// FulfillAllowBackorder ships what is in stock and ships the rest, charged
+	// separately, once it arrives
+	FulfillAllowBackorder FulfillmentPolicy = "allow_backorder"
+)
+
+func (p FulfillmentPolicy) Valid() bool {
+	switch p {
+	case FulfillAllOrNothing, FulfillAllowPartial, FulfillAllowBackorder:
+		return true
+	}
+	return false
+}
+
+// OrDefault orders placed before policies existed are all or nothing
+func (p FulfillmentPolicy) OrDefault() FulfillmentPolicy {
+	if p == "" {
+		return FulfillAllOrNothing
+	}
+	return p
+}
+
 type Order struct {
-	ID              uuid.UUID        `json:"id"`
-	CustomerID      uuid.UUID        `json:"customer_id"`
-	Items           []OrderItem      `json:"items"`
-	TotalAmount     float64          `json:"total_amount"`
-	Status          OrderStatus      `json:"status"`
-	ShippingAddress *ShippingAddress `json:"shipping_address"`
-	CreatedAt       time.Time        `json:"created_at"`
-	UpdatedAt       time.Time        `json:"updated_at"`
+	ID                uuid.UUID         `json:"id"`
+	CustomerID        uuid.UUID         `json:"customer_id"`
+	Items             []OrderItem       `json:"items"`
+	TotalAmount       Money             `json:"total_amount"`
+	Status            OrderStatus       `json:"status"`
+	ShippingAddress   *ShippingAddress  `json:"shipping_address"`
+	FulfillmentPolicy FulfillmentPolicy `json:"fulfillment_policy,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
 }
 
 type OrderItem struct {
 	ProductID uuid.UUID `json:"product_id"`
 	Quantity  int       `json:"quantity"`
-	Price     float64   `json:"price"`
+	Price     Money     `json:"price"`
+	// SKU and Name as the catalog listed them when the item was priced
+	SKU  string `json:"sku,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// ValidateCurrency checks that every item price is in the order currency
+func (o Order) ValidateCurrency() error {
+	for i, item := range o.Items {
+		if !item.Price.SameCurrency(o.TotalAmount) {
+			return fmt.Errorf("item %d: %w: %s and %s",
+				i, ErrCurrencyMismatch, item.Price.Currency, o.TotalAmount.Currency)
+		}
+	}
+	return nil
 }
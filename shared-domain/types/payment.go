@@ -9,17 +9,19 @@ import (
 type PaymentStatus string
 
 const (
-	PaymentStatusPending   PaymentStatus = "pending"
-	PaymentStatusCompleted PaymentStatus = "completed"
-	PaymentStatusFailed    PaymentStatus = "failed"
-	PaymentStatusRefunded  PaymentStatus = "refunded"
+	PaymentStatusPending    PaymentStatus = "pending"
+	PaymentStatusAuthorized PaymentStatus = "authorized" // Funds held, not yet captured
+	PaymentStatusCompleted  PaymentStatus = "completed"
+	PaymentStatusFailed     PaymentStatus = "failed"
+	PaymentStatusRefunded   PaymentStatus = "refunded"
+	PaymentStatusVoided     PaymentStatus = "voided" // Authorization released without capture
 )
 
 type Payment struct {
 	ID            uuid.UUID     `json:"id"`
 	OrderID       uuid.UUID     `json:"order_id"`
 	CustomerID    uuid.UUID     `json:"customer_id"`
-	Amount        float64       `json:"amount"`
+	Amount        Money         `json:"amount"`
 	PaymentMethod string        `json:"payment_method"`
 	Status        PaymentStatus `json:"status"`
 	TransactionID string        `json:"transaction_id,omitempty"`
@@ -23,8 +23,11 @@ type Shipment struct {
 	Address    ShippingAddress `json:"address"`
 	Status     ShippingStatus  `json:"status"`
 	TrackingID string          `json:"tracking_id,omitempty"`
-	CreatedAt  time.Time       `json:"created_at"`
-	UpdatedAt  time.Time       `json:"updated_at"`
+	// Warehouse the shipment leaves from, nil for orders reserved before stock
+	// was kept per warehouse
+	Origin    *ShipmentOrigin `json:"origin,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
 }
 
 type ShippingAddress struct {
@@ -34,3 +37,16 @@ type ShippingAddress struct {
 	ZipCode string `json:"zip_code"`
 	Country string `json:"country"`
 }
+
+// ShipmentOrigin a warehouse that ships part of an order, and the items it ships
+type ShipmentOrigin struct {
+	WarehouseID   uuid.UUID       `json:"warehouse_id"`
+	WarehouseCode string          `json:"warehouse_code"`
+	Address       ShippingAddress `json:"address"`
+	Items         []ShipmentItem  `json:"items"`
+}
+
+type ShipmentItem struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+}
@@ -1,78 +1,104 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"os/signal"
 	"strconv"
-	"syscall"
+	"time"
 
+	"github.com/distributed-ecommerce-saga/shared-domain/lifecycle"
+	"github.com/distributed-ecommerce-saga/shared-domain/logging"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
+	"github.com/distributed-ecommerce-saga/shared-domain/resilience"
+	"github.com/distributed-ecommerce-saga/shared-domain/tracing"
 	"github.com/distributed-ecommerce-saga/shipping-service/internal/handlers"
+	"github.com/distributed-ecommerce-saga/shipping-service/internal/provider"
 	"github.com/distributed-ecommerce-saga/shipping-service/internal/repository"
 	"github.com/distributed-ecommerce-saga/shipping-service/internal/service"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	log.Println("🚀 Starting Shipping Service...")
+	logging.Init("shipping-service")
+	slog.Info("Starting Shipping Service")
+
+	shutdown := lifecycle.NewManager("Shipping Service", lifecycle.TimeoutFromEnv(30*time.Second))
+
+	shutdownTracing, err := tracing.Init(context.Background(), "shipping-service")
+	if err != nil {
+		logging.Fatal("Tracing init error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageTelemetry, "trace exporter", shutdownTracing)
+
+	metrics.Init("shipping-service")
 
 	db, err := initDatabase()
 	if err != nil {
-		log.Fatalf("Database connection error: %v", err)
+		logging.Fatal("Database connection error", "error", err)
 	}
-	defer db.Close()
+	shutdown.Register(lifecycle.StageDatabase, "postgres", func(ctx context.Context) error {
+		return db.Close()
+	})
 
 	rabbitConfig := messaging.NewRabbitMQConfig()
 	rabbitClient := messaging.NewRabbitMQClient(rabbitConfig)
 
 	if err := rabbitClient.Connect(); err != nil {
-		log.Fatalf("RabbitMQ connection error: %v", err)
+		logging.Fatal("RabbitMQ connection error", "error", err)
 	}
-	defer rabbitClient.Close()
+	shutdown.Register(lifecycle.StageBroker, "rabbitmq", func(ctx context.Context) error {
+		return rabbitClient.Close()
+	})
 
 	failureRate := getEnvFloat("SHIPPING_FAILURE_RATE", 0.05)
+	providerPolicy := resilience.NewPolicy("shipping-provider", resilience.ConfigFromEnv("SHIPPING_PROVIDER", resilience.Config{
+		Timeout:       5 * time.Second,
+		Retry:         resilience.RetryConfig{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+		Breaker:       resilience.BreakerConfig{FailureThreshold: 5, OpenTimeout: 30 * time.Second},
+		MaxConcurrent: 10,
+		MaxWait:       time.Second,
+	}))
+	shippingProvider := provider.NewResilientProvider(provider.NewMockShippingProvider(failureRate), providerPolicy)
 
 	publisher := messaging.NewPublisher(rabbitClient)
+	shutdown.Register(lifecycle.StageOutbox, "event publisher", publisher.Flush)
 	consumer := messaging.NewConsumer(rabbitClient, "shipping-service-queue", "shipping-service")
 
 	shippingRepo := repository.NewShippingRepository(db)
-	shippingService := service.NewShippingService(shippingRepo, publisher, failureRate)
+	shippingService := service.NewShippingService(shippingRepo, publisher, shippingProvider)
 	shippingHandler := handlers.NewShippingHandler(shippingService)
 
 	app := setupFiberApp()
 	setupRoutes(app, shippingHandler)
 
-	go func() {
-		log.Println("🐰 Starting RabbitMQ event consumption...")
-		if err := shippingHandler.StartConsuming(consumer); err != nil {
-			log.Printf("RabbitMQ consumption error: %v", err)
-		}
-	}()
+	if err := shippingHandler.StartConsuming(consumer); err != nil {
+		slog.Error("RabbitMQ consumption error", "error", err)
+	}
+	shutdown.Register(lifecycle.StageConsumers, "rabbitmq consumer", consumer.Stop)
+	shutdown.Register(lifecycle.StageDrain, "in-flight event handlers", consumer.Drain)
+	shutdown.Register(lifecycle.StageHTTP, "fiber", app.ShutdownWithContext)
 
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+	port := getEnvOrDefault("PORT", "8004")
+	slog.Info("Shipping Service listening", "port", port)
+	slog.Info("Mock Shipping Provider active", "failure_rate", failureRate)
 
-		log.Println("🛑 Shutting down Shipping Service...")
-		if err := app.Shutdown(); err != nil {
-			log.Printf("Shutdown error: %v", err)
+	go func() {
+		if err := app.Listen(":" + port); err != nil {
+			slog.Error("Server startup error", "error", err)
+			shutdown.Trigger()
 		}
 	}()
 
-	port := getEnvOrDefault("PORT", "8004")
-	log.Printf("🌍 Shipping Service running on: http://localhost:%s", port)
-	log.Printf("📦 Mock Shipping Provider active - Failure Rate: %.1f%%", failureRate*100)
-
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatalf("Server startup error: %v", err)
+	if err := shutdown.Wait(); err != nil {
+		logging.Fatal("Shutdown error", "error", err)
 	}
 }
 
@@ -100,7 +126,7 @@ func initDatabase() (*sql.DB, error) {
 		return nil, fmt.Errorf("database ping error: %v", err)
 	}
 
-	log.Printf("✅ Database connection successful: %s", dbName)
+	slog.Info("Database connection successful", "database", dbName)
 	return db, nil
 }
 
@@ -111,9 +137,8 @@ func setupFiberApp() *fiber.App {
 	})
 
 	app.Use(recover.New())
-	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${method} ${path} - ${latency}\n",
-	}))
+	app.Use(tracing.FiberMiddleware("shipping-service"))
+	app.Use(logging.FiberMiddleware())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
@@ -124,11 +149,15 @@ func setupFiberApp() *fiber.App {
 }
 
 func setupRoutes(app *fiber.App, shippingHandler *handlers.ShippingHandler) {
+	// Prometheus scrape endpoint
+	app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+
 	api := app.Group("/api/v1")
 	api.Get("/health", shippingHandler.HealthCheck)
 
 	orders := api.Group("/orders")
 	orders.Get("/:order_id/shipment", shippingHandler.GetShipmentByOrderID)
+	orders.Get("/:order_id/shipments", shippingHandler.GetShipmentsByOrderID)
 
 	app.Use("*", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -147,7 +176,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 		message = e.Message
 	}
 
-	log.Printf("Error: %v", err)
+	slog.ErrorContext(c.UserContext(), "Request error", "path", c.Path(), "error", err)
 
 	return c.Status(code).JSON(fiber.Map{
 		"success":   false,
@@ -14,15 +14,19 @@ type ShippingAggregate struct {
 	FailureReason string    `json:"failure_reason,omitempty" db:"failure_reason"`
 }
 
-func NewShippingAggregate(orderID, customerID, sagaID uuid.UUID, address types.ShippingAddress) *ShippingAggregate {
+// NewShippingAggregate a shipment of the order to address, leaving from origin;
+// origin is nil when the order ships as a whole from an unknown warehouse
+func NewShippingAggregate(orderID, customerID, sagaID uuid.UUID, address types.ShippingAddress, origin *types.ShipmentOrigin) *ShippingAggregate {
+	id := uuid.New()
 	return &ShippingAggregate{
 		Shipment: &types.Shipment{
-			ID:         uuid.New(),
+			ID:         id,
 			OrderID:    orderID,
 			CustomerID: customerID,
 			Address:    address,
 			Status:     types.ShippingStatusPending,
-			TrackingID: generateTrackingID(),
+			TrackingID: generateTrackingID(id),
+			Origin:     origin,
 			CreatedAt:  time.Now(),
 			UpdatedAt:  time.Now(),
 		},
@@ -55,8 +59,10 @@ func (s *ShippingAggregate) CanCancel() bool {
 	return s.Status == types.ShippingStatusPending || s.Status == types.ShippingStatusPreparing
 }
 
-func generateTrackingID() string {
-	return fmt.Sprintf("TRK_%d", time.Now().Unix())
+// generateTrackingID unique per shipment, also for the shipments of one order
+// created within the same second
+func generateTrackingID(shipmentID uuid.UUID) string {
+	return fmt.Sprintf("TRK_%d_%s", time.Now().Unix(), shipmentID.String()[:8])
 }
 
 type ShippingCreateRequest struct {
@@ -65,6 +71,8 @@ type ShippingCreateRequest struct {
 	CustomerID uuid.UUID             `json:"customer_id"`
 	Items      []ShippingItem        `json:"items"`
 	Address    types.ShippingAddress `json:"address"`
+	// Origins the warehouses inventory allocated the order to, one shipment each
+	Origins []types.ShipmentOrigin `json:"origins"`
 }
 
 type ShippingItem struct {
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/distributed-ecommerce-saga/shipping-service/internal/domain"
 	"github.com/google/uuid"
 )
 
@@ -15,7 +16,24 @@ type ShipmentResponse struct {
 	Status        string                `json:"status"`
 	TrackingID    string                `json:"tracking_id"`
 	Address       types.ShippingAddress `json:"address"`
+	Origin        *types.ShipmentOrigin `json:"origin,omitempty"`
 	FailureReason string                `json:"failure_reason,omitempty"`
 	CreatedAt     time.Time             `json:"created_at"`
 	UpdatedAt     time.Time             `json:"updated_at"`
 }
+
+func newShipmentResponse(shipment *domain.ShippingAggregate) ShipmentResponse {
+	return ShipmentResponse{
+		ID:            shipment.ID,
+		OrderID:       shipment.OrderID,
+		CustomerID:    shipment.CustomerID,
+		SagaID:        shipment.SagaID,
+		Status:        string(shipment.Status),
+		TrackingID:    shipment.TrackingID,
+		Address:       shipment.Address,
+		Origin:        shipment.Origin,
+		FailureReason: shipment.FailureReason,
+		CreatedAt:     shipment.CreatedAt,
+		UpdatedAt:     shipment.UpdatedAt,
+	}
+}
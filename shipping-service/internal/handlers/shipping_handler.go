@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
 	sharedHTTP "github.com/distributed-ecommerce-saga/shared-domain/http"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/resilience"
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/distributed-ecommerce-saga/shipping-service/internal/domain"
 	"github.com/distributed-ecommerce-saga/shipping-service/internal/service"
@@ -25,9 +27,16 @@ func NewShippingHandler(shippingService *service.ShippingService) *ShippingHandl
 }
 
 func (h *ShippingHandler) HealthCheck(c *fiber.Ctx) error {
-	return sharedHTTP.SuccessResponse(c, "Shipping service is healthy", map[string]interface{}{
-		"service": "shipping-service",
-		"status":  "healthy",
+	// An open provider circuit degrades the service but it still answers
+	status := "healthy"
+	if !resilience.Healthy() {
+		status = "degraded"
+	}
+
+	return sharedHTTP.SuccessResponse(c, "Shipping service is "+status, map[string]interface{}{
+		"service":          "shipping-service",
+		"status":           status,
+		"circuit_breakers": resilience.BreakerStatuses(),
 	})
 }
 
@@ -45,40 +54,58 @@ func (h *ShippingHandler) GetShipmentByOrderID(c *fiber.Ctx) error {
 		return sharedHTTP.NotFoundResponse(c, "Shipment not found")
 	}
 
-	response := ShipmentResponse{
-		ID:            shipment.ID,
-		OrderID:       shipment.OrderID,
-		CustomerID:    shipment.CustomerID,
-		SagaID:        shipment.SagaID,
-		Status:        string(shipment.Status),
-		TrackingID:    shipment.TrackingID,
-		Address:       shipment.Address,
-		FailureReason: shipment.FailureReason,
-		CreatedAt:     shipment.CreatedAt,
-		UpdatedAt:     shipment.UpdatedAt,
+	return sharedHTTP.SuccessResponse(c, "Shipment retrieved successfully", newShipmentResponse(shipment))
+}
+
+// GetShipmentsByOrderID lists every shipment of the order, one per warehouse it
+// ships from
+func (h *ShippingHandler) GetShipmentsByOrderID(c *fiber.Ctx) error {
+	orderIDStr := c.Params("order_id")
+	orderID, err := uuid.Parse(orderIDStr)
+	if err != nil {
+		return sharedHTTP.BadRequestResponse(c, "Invalid order ID", map[string]interface{}{
+			"order_id": orderIDStr,
+		})
+	}
+
+	shipments, err := h.shippingService.GetShipmentsByOrderID(orderID)
+	if err != nil {
+		return sharedHTTP.InternalServerErrorResponse(c, "Shipments retrieval failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if len(shipments) == 0 {
+		return sharedHTTP.NotFoundResponse(c, "Shipment not found")
+	}
+
+	response := make([]ShipmentResponse, 0, len(shipments))
+	for _, shipment := range shipments {
+		response = append(response, newShipmentResponse(shipment))
 	}
 
-	return sharedHTTP.SuccessResponse(c, "Shipment retrieved successfully", response)
+	return sharedHTTP.SuccessResponse(c, "Shipments retrieved successfully", map[string]interface{}{
+		"order_id":  orderID,
+		"shipments": response,
+	})
 }
 
-func (h *ShippingHandler) HandleSagaEvent(event events.SagaEvent) error {
-	log.Printf("Shipping service saga event received: %s from %s",
-		event.EventType, event.Service)
+func (h *ShippingHandler) HandleSagaEvent(ctx context.Context, event events.SagaEvent) error {
+	slog.DebugContext(ctx, "Shipping service saga event received", "from", event.Service)
 
 	switch event.EventType {
 	case "shipping.create":
-		return h.handleShippingCreateCommand(event)
+		return h.handleShippingCreateCommand(ctx, event)
 
 	case "shipping.cancel":
-		return h.handleShippingCancelCommand(event)
+		return h.handleShippingCancelCommand(ctx, event)
 
 	default:
-		log.Printf("Unhandled event type: %s", event.EventType)
+		slog.WarnContext(ctx, "Unhandled event type")
 		return nil
 	}
 }
 
-func (h *ShippingHandler) handleShippingCreateCommand(event events.SagaEvent) error {
+func (h *ShippingHandler) handleShippingCreateCommand(ctx context.Context, event events.SagaEvent) error {
 	payloadMap, ok := event.Payload.(map[string]interface{})
 	if !ok {
 		return h.logAndReturnError("Invalid payload format for shipping.create", event)
@@ -89,15 +116,15 @@ func (h *ShippingHandler) handleShippingCreateCommand(event events.SagaEvent) er
 		return h.logAndReturnError(fmt.Sprintf("Payload mapping error: %v", err), event)
 	}
 
-	if err := h.shippingService.CreateShipment(request); err != nil {
-		log.Printf("Shipping create error: %v", err)
+	if err := h.shippingService.CreateShipment(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Shipping create error", "error", err)
 		return err
 	}
 
 	return nil
 }
 
-func (h *ShippingHandler) handleShippingCancelCommand(event events.SagaEvent) error {
+func (h *ShippingHandler) handleShippingCancelCommand(ctx context.Context, event events.SagaEvent) error {
 	payloadMap, ok := event.Payload.(map[string]interface{})
 	if !ok {
 		return h.logAndReturnError("Invalid payload format for shipping.cancel", event)
@@ -108,8 +135,8 @@ func (h *ShippingHandler) handleShippingCancelCommand(event events.SagaEvent) er
 		return h.logAndReturnError(fmt.Sprintf("Cancel payload mapping error: %v", err), event)
 	}
 
-	if err := h.shippingService.CancelShipment(request); err != nil {
-		log.Printf("Shipping cancel error: %v", err)
+	if err := h.shippingService.CancelShipment(ctx, request); err != nil {
+		slog.ErrorContext(ctx, "Shipping cancel error", "error", err)
 		return err
 	}
 
@@ -155,18 +182,61 @@ func (h *ShippingHandler) mapToShippingCreateRequest(sagaID uuid.UUID, payload m
 	}
 
 	if addressData, ok := payload["address"].(map[string]interface{}); ok {
-		request.Address = types.ShippingAddress{
-			Street:  getStringFromPayload(addressData, "street"),
-			City:    getStringFromPayload(addressData, "city"),
-			State:   getStringFromPayload(addressData, "state"),
-			ZipCode: getStringFromPayload(addressData, "zip_code"),
-			Country: getStringFromPayload(addressData, "country"),
+		request.Address = mapToShippingAddress(addressData)
+	}
+
+	if originsData, ok := payload["origins"].([]interface{}); ok {
+		for _, originData := range originsData {
+			originMap, ok := originData.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			origin := types.ShipmentOrigin{
+				WarehouseCode: getStringFromPayload(originMap, "warehouse_code"),
+			}
+			warehouseID, err := uuid.Parse(getStringFromPayload(originMap, "warehouse_id"))
+			if err != nil {
+				return request, fmt.Errorf("invalid origin warehouse_id: %v", err)
+			}
+			origin.WarehouseID = warehouseID
+
+			if addressData, ok := originMap["address"].(map[string]interface{}); ok {
+				origin.Address = mapToShippingAddress(addressData)
+			}
+
+			if itemsData, ok := originMap["items"].([]interface{}); ok {
+				for _, itemData := range itemsData {
+					if itemMap, ok := itemData.(map[string]interface{}); ok {
+						item := types.ShipmentItem{}
+						if productID, err := uuid.Parse(getStringFromPayload(itemMap, "product_id")); err == nil {
+							item.ProductID = productID
+						}
+						if quantity, ok := itemMap["quantity"].(float64); ok {
+							item.Quantity = int(quantity)
+						}
+						origin.Items = append(origin.Items, item)
+					}
+				}
+			}
+
+			request.Origins = append(request.Origins, origin)
 		}
 	}
 
 	return request, nil
 }
 
+func mapToShippingAddress(addressData map[string]interface{}) types.ShippingAddress {
+	return types.ShippingAddress{
+		Street:  getStringFromPayload(addressData, "street"),
+		City:    getStringFromPayload(addressData, "city"),
+		State:   getStringFromPayload(addressData, "state"),
+		ZipCode: getStringFromPayload(addressData, "zip_code"),
+		Country: getStringFromPayload(addressData, "country"),
+	}
+}
+
 func (h *ShippingHandler) mapToShippingCancelRequest(sagaID uuid.UUID, payload map[string]interface{}) (domain.ShippingCancelRequest, error) {
 	request := domain.ShippingCancelRequest{
 		SagaID: sagaID,
@@ -199,7 +269,7 @@ func getStringFromPayload(payload map[string]interface{}, key string) string {
 }
 
 func (h *ShippingHandler) logAndReturnError(message string, event events.SagaEvent) error {
-	log.Printf("%s - Event: %+v", message, event)
+	slog.Error(message, "event_id", event.ID, "event_type", event.EventType, "saga_id", event.SagaID)
 	return fmt.Errorf(message)
 }
 
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/distributed-ecommerce-saga/shared-domain/resilience"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
+	"github.com/google/uuid"
+)
+
+var ErrProviderUnavailable = errors.New("shipping provider unavailable")
+
+// ShippingProvider external carrier API
+type ShippingProvider interface {
+	// CreateShipment books the shipment with the carrier under its tracking ID
+	CreateShipment(ctx context.Context, request ShipmentRequest) error
+}
+
+type ShipmentRequest struct {
+	OrderID    uuid.UUID             `json:"order_id"`
+	TrackingID string                `json:"tracking_id"`
+	Address    types.ShippingAddress `json:"address"`
+	// Origin the warehouse the carrier picks the shipment up at, nil if unknown
+	Origin *types.ShipmentOrigin `json:"origin,omitempty"`
+}
+
+// MockShippingProvider mock carrier for test
+type MockShippingProvider struct {
+	FailureRate float64 // 0.0 - 1.0 arası hata oranı
+}
+
+func NewMockShippingProvider(failureRate float64) *MockShippingProvider {
+	return &MockShippingProvider{FailureRate: failureRate}
+}
+
+func (m *MockShippingProvider) CreateShipment(ctx context.Context, request ShipmentRequest) error {
+	slog.DebugContext(ctx, "Mock Shipping Provider: creating shipment", "tracking_id", request.TrackingID)
+
+	timer := time.NewTimer(time.Millisecond * 300)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if rand.Float64() < m.FailureRate {
+		return ErrProviderUnavailable
+	}
+	return nil
+}
+
+// ResilientProvider runs carrier calls through a resilience policy
+type ResilientProvider struct {
+	next   ShippingProvider
+	policy *resilience.Policy
+}
+
+func NewResilientProvider(next ShippingProvider, policy *resilience.Policy) *ResilientProvider {
+	return &ResilientProvider{next: next, policy: policy}
+}
+
+func (p *ResilientProvider) CreateShipment(ctx context.Context, request ShipmentRequest) error {
+	return p.policy.Execute(ctx, func(ctx context.Context) error {
+		return p.next.CreateShipment(ctx, request)
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/distributed-ecommerce-saga/shared-domain/metrics"
 	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/distributed-ecommerce-saga/shipping-service/internal/domain"
 	"github.com/google/uuid"
@@ -19,17 +21,30 @@ func NewShippingRepository(db *sql.DB) *ShippingRepository {
 	return &ShippingRepository{db: db}
 }
 
+// shipmentColumns selected by every shipment query, in scanShipment order
+const shipmentColumns = `
+	id, order_id, customer_id, saga_id, status, tracking_id,
+	address, origin, failure_reason, created_at, updated_at`
+
 func (r *ShippingRepository) CreateShipment(shipment *domain.ShippingAggregate) error {
+	defer metrics.ObserveDBQuery("CreateShipment", time.Now())
+
 	addressJSON, err := json.Marshal(shipment.Address)
 	if err != nil {
 		return fmt.Errorf("address serialization error: %v", err)
 	}
+	var originJSON []byte
+	if shipment.Origin != nil {
+		if originJSON, err = json.Marshal(shipment.Origin); err != nil {
+			return fmt.Errorf("origin serialization error: %v", err)
+		}
+	}
 
 	query := `
 		INSERT INTO shipments (
 			id, order_id, customer_id, saga_id, status, tracking_id,
-			address, failure_reason, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			address, origin, failure_reason, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 
 	_, err = r.db.Exec(
@@ -41,6 +56,7 @@ func (r *ShippingRepository) CreateShipment(shipment *domain.ShippingAggregate)
 		shipment.Status,
 		shipment.TrackingID,
 		addressJSON,
+		originJSON,
 		shipment.FailureReason,
 		shipment.CreatedAt,
 		shipment.UpdatedAt,
@@ -50,6 +66,8 @@ func (r *ShippingRepository) CreateShipment(shipment *domain.ShippingAggregate)
 }
 
 func (r *ShippingRepository) UpdateShipment(shipment *domain.ShippingAggregate) error {
+	defer metrics.ObserveDBQuery("UpdateShipment", time.Now())
+
 	addressJSON, err := json.Marshal(shipment.Address)
 	if err != nil {
 		return fmt.Errorf("address serialization error: %v", err)
@@ -89,32 +107,17 @@ func (r *ShippingRepository) UpdateShipment(shipment *domain.ShippingAggregate)
 }
 
 func (r *ShippingRepository) GetShipmentByOrderID(orderID uuid.UUID) (*domain.ShippingAggregate, error) {
+	defer metrics.ObserveDBQuery("GetShipmentByOrderID", time.Now())
+
 	query := `
-		SELECT id, order_id, customer_id, saga_id, status, tracking_id,
-			   address, failure_reason, created_at, updated_at
+		SELECT ` + shipmentColumns + `
 		FROM shipments 
 		WHERE order_id = $1
 		ORDER BY created_at DESC
 		LIMIT 1
 	`
 
-	shipment := &domain.ShippingAggregate{Shipment: &types.Shipment{}}
-	var addressJSON []byte
-	var failureReason sql.NullString
-
-	err := r.db.QueryRow(query, orderID).Scan(
-		&shipment.ID,
-		&shipment.OrderID,
-		&shipment.CustomerID,
-		&shipment.SagaID,
-		&shipment.Status,
-		&shipment.TrackingID,
-		&addressJSON,
-		&failureReason,
-		&shipment.CreatedAt,
-		&shipment.UpdatedAt,
-	)
-
+	shipment, err := scanShipment(r.db.QueryRow(query, orderID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("shipment not found for order: %s", orderID)
@@ -122,66 +125,91 @@ func (r *ShippingRepository) GetShipmentByOrderID(orderID uuid.UUID) (*domain.Sh
 		return nil, fmt.Errorf("shipment retrieval error: %v", err)
 	}
 
-	if err := json.Unmarshal(addressJSON, &shipment.Address); err != nil {
-		return nil, fmt.Errorf("address deserialization error: %v", err)
-	}
+	return shipment, nil
+}
 
-	if failureReason.Valid {
-		shipment.FailureReason = failureReason.String
-	}
+// GetShipmentsByOrderID returns every shipment of the order, one per origin
+// warehouse, oldest first
+func (r *ShippingRepository) GetShipmentsByOrderID(orderID uuid.UUID) ([]*domain.ShippingAggregate, error) {
+	defer metrics.ObserveDBQuery("GetShipmentsByOrderID", time.Now())
 
-	return shipment, nil
+	return r.queryShipments(`
+		SELECT `+shipmentColumns+`
+		FROM shipments 
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`, orderID)
 }
 
 func (r *ShippingRepository) GetShipmentsBySagaID(sagaID uuid.UUID) ([]*domain.ShippingAggregate, error) {
-	query := `
-		SELECT id, order_id, customer_id, saga_id, status, tracking_id,
-			   address, failure_reason, created_at, updated_at
+	defer metrics.ObserveDBQuery("GetShipmentsBySagaID", time.Now())
+
+	return r.queryShipments(`
+		SELECT `+shipmentColumns+`
 		FROM shipments 
 		WHERE saga_id = $1
 		ORDER BY created_at DESC
-	`
+	`, sagaID)
+}
 
-	rows, err := r.db.Query(query, sagaID)
+func (r *ShippingRepository) queryShipments(query string, args ...interface{}) ([]*domain.ShippingAggregate, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var shipments []*domain.ShippingAggregate
-
 	for rows.Next() {
-		shipment := &domain.ShippingAggregate{Shipment: &types.Shipment{}}
-		var addressJSON []byte
-		var failureReason sql.NullString
-
-		err := rows.Scan(
-			&shipment.ID,
-			&shipment.OrderID,
-			&shipment.CustomerID,
-			&shipment.SagaID,
-			&shipment.Status,
-			&shipment.TrackingID,
-			&addressJSON,
-			&failureReason,
-			&shipment.CreatedAt,
-			&shipment.UpdatedAt,
-		)
-
+		shipment, err := scanShipment(rows)
 		if err != nil {
 			return nil, err
 		}
+		shipments = append(shipments, shipment)
+	}
 
-		if err := json.Unmarshal(addressJSON, &shipment.Address); err != nil {
-			return nil, fmt.Errorf("address deserialization error: %v", err)
-		}
+	return shipments, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-		if failureReason.Valid {
-			shipment.FailureReason = failureReason.String
+func scanShipment(row rowScanner) (*domain.ShippingAggregate, error) {
+	shipment := &domain.ShippingAggregate{Shipment: &types.Shipment{}}
+	var addressJSON, originJSON []byte
+	var failureReason sql.NullString
+
+	err := row.Scan(
+		&shipment.ID,
+		&shipment.OrderID,
+		&shipment.CustomerID,
+		&shipment.SagaID,
+		&shipment.Status,
+		&shipment.TrackingID,
+		&addressJSON,
+		&originJSON,
+		&failureReason,
+		&shipment.CreatedAt,
+		&shipment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(addressJSON, &shipment.Address); err != nil {
+		return nil, fmt.Errorf("address deserialization error: %v", err)
+	}
+	if originJSON != nil {
+		shipment.Origin = &types.ShipmentOrigin{}
+		if err := json.Unmarshal(originJSON, shipment.Origin); err != nil {
+			return nil, fmt.Errorf("origin deserialization error: %v", err)
 		}
+	}
 
-		shipments = append(shipments, shipment)
+	if failureReason.Valid {
+		shipment.FailureReason = failureReason.String
 	}
 
-	return shipments, nil
+	return shipment, nil
 }
@@ -1,178 +1,238 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"math/rand"
+	"log/slog"
 	"time"
 
 	"github.com/distributed-ecommerce-saga/shared-domain/events"
 	"github.com/distributed-ecommerce-saga/shared-domain/messaging"
+	"github.com/distributed-ecommerce-saga/shared-domain/types"
 	"github.com/distributed-ecommerce-saga/shipping-service/internal/domain"
+	"github.com/distributed-ecommerce-saga/shipping-service/internal/provider"
 	"github.com/distributed-ecommerce-saga/shipping-service/internal/repository"
 	"github.com/google/uuid"
 )
 
 type ShippingService struct {
-	shippingRepo *repository.ShippingRepository
-	publisher    *messaging.Publisher
-	failureRate  float64
+	shippingRepo     *repository.ShippingRepository
+	publisher        *messaging.Publisher
+	shippingProvider provider.ShippingProvider
 }
 
-func NewShippingService(shippingRepo *repository.ShippingRepository, publisher *messaging.Publisher, failureRate float64) *ShippingService {
+func NewShippingService(shippingRepo *repository.ShippingRepository, publisher *messaging.Publisher, shippingProvider provider.ShippingProvider) *ShippingService {
 	return &ShippingService{
-		shippingRepo: shippingRepo,
-		publisher:    publisher,
-		failureRate:  failureRate,
+		shippingRepo:     shippingRepo,
+		publisher:        publisher,
+		shippingProvider: shippingProvider,
 	}
 }
 
-func (s *ShippingService) CreateShipment(request domain.ShippingCreateRequest) error {
-	log.Printf("Shipping create started: OrderID=%s", request.OrderID)
-
-	time.Sleep(time.Millisecond * 300)
-
-	if rand.Float64() < s.failureRate {
-		return s.publishShippingFailedEvent(request.SagaID, request.OrderID,
-			"Shipping provider unavailable")
+// CreateShipment books one shipment per origin warehouse the order was
+// allocated to, or a single shipment when inventory named no origins. If any
+// shipment fails, the ones already booked are cancelled and the step fails.
+func (s *ShippingService) CreateShipment(ctx context.Context, request domain.ShippingCreateRequest) error {
+	slog.InfoContext(ctx, "Shipping create started", "origins", len(request.Origins))
+
+	origins := []*types.ShipmentOrigin{nil}
+	if len(request.Origins) > 0 {
+		origins = origins[:0]
+		for i := range request.Origins {
+			origins = append(origins, &request.Origins[i])
+		}
 	}
 
-	shipment := domain.NewShippingAggregate(request.OrderID, request.CustomerID, request.SagaID, request.Address)
-	shipment.CreateShipment()
+	var shipments []*domain.ShippingAggregate
+	for _, origin := range origins {
+		shipment := domain.NewShippingAggregate(request.OrderID, request.CustomerID, request.SagaID, request.Address, origin)
+
+		if err := s.shippingProvider.CreateShipment(ctx, provider.ShipmentRequest{
+			OrderID:    request.OrderID,
+			TrackingID: shipment.TrackingID,
+			Address:    request.Address,
+			Origin:     origin,
+		}); err != nil {
+			s.cancelBooked(ctx, shipments)
+			return s.publishShippingFailedEvent(ctx, request.SagaID, request.OrderID,
+				fmt.Sprintf("Shipping provider error: %v", err))
+		}
 
-	if err := s.shippingRepo.CreateShipment(shipment); err != nil {
-		return s.publishShippingFailedEvent(request.SagaID, request.OrderID,
-			fmt.Sprintf("Failed to create shipment: %v", err))
+		shipment.CreateShipment()
+
+		if err := s.shippingRepo.CreateShipment(shipment); err != nil {
+			s.cancelBooked(ctx, shipments)
+			return s.publishShippingFailedEvent(ctx, request.SagaID, request.OrderID,
+				fmt.Sprintf("Failed to create shipment: %v", err))
+		}
+		shipments = append(shipments, shipment)
 	}
 
-	return s.publishShippingCreatedEvent(shipment)
+	return s.publishShippingCreatedEvent(ctx, shipments)
 }
 
-func (s *ShippingService) CancelShipment(request domain.ShippingCancelRequest) error {
-	log.Printf("Shipping cancel started: OrderID=%s", request.OrderID)
+// cancelBooked cancels the shipments of a create command that failed part way
+func (s *ShippingService) cancelBooked(ctx context.Context, shipments []*domain.ShippingAggregate) {
+	for _, shipment := range shipments {
+		shipment.CancelShipment("Another shipment of the order failed")
+		if err := s.shippingRepo.UpdateShipment(shipment); err != nil {
+			slog.ErrorContext(ctx, "Booked shipment not cancelled", "shipment_id", shipment.ID, "error", err)
+		}
+	}
+}
 
-	var shipment *domain.ShippingAggregate
-	var err error
+// CancelShipment cancels every shipment of the saga, or none of them if any
+// has already left its warehouse
+func (s *ShippingService) CancelShipment(ctx context.Context, request domain.ShippingCancelRequest) error {
+	slog.InfoContext(ctx, "Shipping cancel started")
 
-	if request.ShipmentID != uuid.Nil {
-		shipments, err := s.shippingRepo.GetShipmentsBySagaID(request.SagaID)
-		if err == nil && len(shipments) > 0 {
-			shipment = shipments[0]
-		}
-	} else {
-		shipment, err = s.shippingRepo.GetShipmentByOrderID(request.OrderID)
+	// Only the saga's own shipments; a backorder saga's shipments of the same
+	// order are not cancelled by another saga
+	shipments, err := s.shippingRepo.GetShipmentsBySagaID(request.SagaID)
+	if err == nil && len(shipments) == 0 {
+		err = fmt.Errorf("no shipments for saga: %s", request.SagaID)
 	}
 
 	if err != nil {
-		return s.publishShippingCancelFailedEvent(request.SagaID, request.OrderID,
+		return s.publishShippingCancelFailedEvent(ctx, request.SagaID, request.OrderID,
 			fmt.Sprintf("Shipment not found: %v", err))
 	}
 
-	if !shipment.CanCancel() {
-		return s.publishShippingCancelFailedEvent(request.SagaID, request.OrderID,
-			fmt.Sprintf("Cannot cancel shipment in status: %s", shipment.Status))
+	for _, shipment := range shipments {
+		if !shipment.CanCancel() && shipment.Status != types.ShippingStatusCancelled {
+			return s.publishShippingCancelFailedEvent(ctx, request.SagaID, request.OrderID,
+				fmt.Sprintf("Cannot cancel shipment %s in status: %s", shipment.TrackingID, shipment.Status))
+		}
 	}
 
-	shipment.CancelShipment(request.Reason)
+	var cancelled []*domain.ShippingAggregate
+	for _, shipment := range shipments {
+		if shipment.Status == types.ShippingStatusCancelled {
+			continue
+		}
+		shipment.CancelShipment(request.Reason)
 
-	if err := s.shippingRepo.UpdateShipment(shipment); err != nil {
-		return s.publishShippingCancelFailedEvent(request.SagaID, request.OrderID,
-			fmt.Sprintf("Failed to cancel shipment: %v", err))
+		if err := s.shippingRepo.UpdateShipment(shipment); err != nil {
+			return s.publishShippingCancelFailedEvent(ctx, request.SagaID, request.OrderID,
+				fmt.Sprintf("Failed to cancel shipment: %v", err))
+		}
+		cancelled = append(cancelled, shipment)
 	}
 
-	return s.publishShippingCancelledEvent(shipment)
+	return s.publishShippingCancelledEvent(ctx, request.SagaID, request.OrderID, cancelled)
 }
 
 func (s *ShippingService) GetShipmentByOrderID(orderID uuid.UUID) (*domain.ShippingAggregate, error) {
 	return s.shippingRepo.GetShipmentByOrderID(orderID)
 }
 
-func (s *ShippingService) publishShippingCreatedEvent(shipment *domain.ShippingAggregate) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        shipment.SagaID,
-		OrderID:       shipment.OrderID,
-		EventType:     events.ShippingCreatedEvent,
-		Service:       "shipping-service",
-		CorrelationID: uuid.New(),
+func (s *ShippingService) GetShipmentsByOrderID(orderID uuid.UUID) ([]*domain.ShippingAggregate, error) {
+	return s.shippingRepo.GetShipmentsByOrderID(orderID)
+}
+
+func (s *ShippingService) publishShippingCreatedEvent(ctx context.Context, shipments []*domain.ShippingAggregate) error {
+	shipmentData := make([]types.Shipment, 0, len(shipments))
+	trackingIDs := make([]string, 0, len(shipments))
+	for _, shipment := range shipments {
+		shipmentData = append(shipmentData, *shipment.Shipment)
+		trackingIDs = append(trackingIDs, shipment.TrackingID)
+	}
+
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    shipments[0].SagaID,
+		OrderID:   shipments[0].OrderID,
+		EventType: events.ShippingCreatedEvent,
+		Service:   "shipping-service",
 		Payload: events.ShippingCreatedPayload{
-			Shipment: *shipment.Shipment,
+			Shipment:  shipmentData[0],
+			Shipments: shipmentData,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("shipping created event publish error: %v", err)
 	}
 
-	log.Printf("Shipping created event published: OrderID=%s, TrackingID=%s",
-		shipment.OrderID, shipment.TrackingID)
+	slog.InfoContext(ctx, "Shipping created event published", "tracking_ids", trackingIDs)
 	return nil
 }
 
-func (s *ShippingService) publishShippingFailedEvent(sagaID, orderID uuid.UUID, reason string) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        sagaID,
-		OrderID:       orderID,
-		EventType:     events.ShippingFailedEvent,
-		Service:       "shipping-service",
-		CorrelationID: uuid.New(),
+func (s *ShippingService) publishShippingFailedEvent(ctx context.Context, sagaID, orderID uuid.UUID, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.ShippingFailedEvent,
+		Service:   "shipping-service",
 		Payload: events.ShippingFailedPayload{
 			OrderID: orderID,
 			Reason:  reason,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("shipping failed event publish error: %v", err)
 	}
 
-	log.Printf("Shipping failed event published: OrderID=%s, Reason=%s", orderID, reason)
+	slog.InfoContext(ctx, "Shipping failed event published", "reason", reason)
 	return nil
 }
 
-func (s *ShippingService) publishShippingCancelledEvent(shipment *domain.ShippingAggregate) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        shipment.SagaID,
-		OrderID:       shipment.OrderID,
-		EventType:     events.ShippingCancelledEvent,
-		Service:       "shipping-service",
-		CorrelationID: uuid.New(),
-		Payload: map[string]interface{}{
-			"shipment_id":  shipment.ID,
-			"tracking_id":  shipment.TrackingID,
-			"cancelled_at": shipment.UpdatedAt,
-			"reason":       shipment.FailureReason,
-		},
+// publishShippingCancelledEvent reports the shipments cancelled by the command;
+// none if a redelivered command found them cancelled already
+func (s *ShippingService) publishShippingCancelledEvent(ctx context.Context, sagaID, orderID uuid.UUID, shipments []*domain.ShippingAggregate) error {
+	shipmentIDs := make([]uuid.UUID, 0, len(shipments))
+	trackingIDs := make([]string, 0, len(shipments))
+	for _, shipment := range shipments {
+		shipmentIDs = append(shipmentIDs, shipment.ID)
+		trackingIDs = append(trackingIDs, shipment.TrackingID)
+	}
+
+	payload := map[string]interface{}{
+		"shipment_ids": shipmentIDs,
+		"tracking_ids": trackingIDs,
+		"cancelled_at": time.Now(),
 	}
+	if len(shipments) > 0 {
+		payload["shipment_id"] = shipments[0].ID
+		payload["tracking_id"] = shipments[0].TrackingID
+		payload["reason"] = shipments[0].FailureReason
+	}
+
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.ShippingCancelledEvent,
+		Service:   "shipping-service",
+		Payload:   payload,
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("shipping cancelled event publish error: %v", err)
 	}
 
-	log.Printf("Shipping cancelled event published: OrderID=%s, TrackingID=%s",
-		shipment.OrderID, shipment.TrackingID)
+	slog.InfoContext(ctx, "Shipping cancelled event published", "tracking_ids", trackingIDs)
 	return nil
 }
 
-func (s *ShippingService) publishShippingCancelFailedEvent(sagaID, orderID uuid.UUID, reason string) error {
-	event := events.SagaEvent{
-		ID:            uuid.New(),
-		SagaID:        sagaID,
-		OrderID:       orderID,
-		EventType:     "shipping.cancel.failed",
-		Service:       "shipping-service",
-		CorrelationID: uuid.New(),
+func (s *ShippingService) publishShippingCancelFailedEvent(ctx context.Context, sagaID, orderID uuid.UUID, reason string) error {
+	event := events.ReplyTo(ctx, events.SagaEvent{
+		ID:        uuid.New(),
+		SagaID:    sagaID,
+		OrderID:   orderID,
+		EventType: events.ShippingCancelFailedEvent,
+		Service:   "shipping-service",
 		Payload: map[string]interface{}{
 			"reason": reason,
 		},
-	}
+	})
 
-	if err := s.publisher.PublishSagaEvent(event); err != nil {
+	if err := s.publisher.PublishSagaEvent(ctx, event); err != nil {
 		return fmt.Errorf("shipping cancel failed event publish error: %v", err)
 	}
 
-	log.Printf("Shipping cancel failed event published: OrderID=%s, Reason=%s", orderID, reason)
+	slog.InfoContext(ctx, "Shipping cancel failed event published", "reason", reason)
 	return nil
 }
@@ -0,0 +1,3 @@
+-- An order allocated to several warehouses ships once per warehouse
+ALTER TABLE shipments DROP CONSTRAINT IF EXISTS shipments_order_id_key;
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS origin JSONB; -- Warehouse and items, NULL for single shipments